@@ -292,6 +292,8 @@ func benchRistrettoDecompress(b *testing.B) {
 
 func BenchmarkMontgomery(b *testing.B) {
 	b.Run("Mul", benchMontgomeryMul)
+	b.Run("MulBasepoint", benchMontgomeryMulBasepoint)
+	b.Run("MulBasepointBatch", benchMontgomeryMulBasepointBatch)
 }
 
 func benchMontgomeryMul(b *testing.B) {
@@ -305,6 +307,30 @@ func benchMontgomeryMul(b *testing.B) {
 	}
 }
 
+func benchMontgomeryMulBasepoint(b *testing.B) {
+	s := scalar.New().Invert(scalar.NewFromUint64(897987897))
+
+	b.ResetTimer()
+
+	var tmp MontgomeryPoint
+	for i := 0; i < b.N; i++ {
+		tmp.MulBasepoint(ED25519_BASEPOINT_TABLE, s)
+	}
+}
+
+func benchMontgomeryMulBasepointBatch(b *testing.B) {
+	const batchSize = 64
+
+	scalars := newTestBenchRandomScalars(b, batchSize)
+	out := make([]MontgomeryPoint, batchSize)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i += batchSize {
+		MontgomeryMulBasepointBatch(out, ED25519_BASEPOINT_TABLE, scalars)
+	}
+}
+
 func newBenchRandomPoints(b *testing.B, n int) []*EdwardsPoint {
 	v := make([]*EdwardsPoint, 0, n)
 	for i := 0; i < n; i++ {
@@ -44,6 +44,8 @@ func TestConstants(t *testing.T) {
 	t.Run("TwoTorsion", testConstantsTwoTorsion)
 	t.Run("SqrtAdMinusOne", testConstantsSqrtAdMinusOne)
 	t.Run("D/VsRatio", testConstantsDVsRatio)
+	t.Run("MontgomerySqrtNegAPlusTwo", testConstantsMontgomerySqrtNegAPlusTwo)
+	t.Run("Wei25519Delta", testConstantsWei25519Delta)
 	t.Run("AffineBasepointOddLookupTable", testConstantsAffineBasepointOddLookupTable)
 	t.Run("AffineBasepointOddShl128LookupTable", testConstantsAffineBasepointOddShl128LookupTable)
 	// ED25519_BASEPOINT_TABLE is checked by `testEdwardsBasepointTableNew`.
@@ -119,6 +121,29 @@ func testConstantsSqrtAdMinusOne(t *testing.T) {
 	}
 }
 
+func testConstantsMontgomerySqrtNegAPlusTwo(t *testing.T) {
+	var negAPlusTwo field.Element
+	negAPlusTwo.Add(&constMONTGOMERY_A, &field.Two)
+	negAPlusTwo.Neg(&negAPlusTwo)
+
+	var shouldBeNegAPlusTwo field.Element
+	shouldBeNegAPlusTwo.Square(&constMONTGOMERY_SQRT_NEG_A_PLUS_TWO)
+
+	if shouldBeNegAPlusTwo.Equal(&negAPlusTwo) != 1 {
+		t.Fatalf("should_be_neg_a_plus_two != neg_a_plus_two (Got: %v, %v)", shouldBeNegAPlusTwo, negAPlusTwo)
+	}
+}
+
+func testConstantsWei25519Delta(t *testing.T) {
+	var shouldBeA field.Element
+	shouldBeA.Add(&constWEI25519_DELTA, &constWEI25519_DELTA)
+	shouldBeA.Add(&shouldBeA, &constWEI25519_DELTA)
+
+	if shouldBeA.Equal(&constMONTGOMERY_A) != 1 {
+		t.Fatalf("should_be_a != a (Got: %v)", shouldBeA)
+	}
+}
+
 func testConstantsAffineBasepointOddLookupTable(t *testing.T) {
 	gen := newAffineNielsPointNafLookupTable(ED25519_BASEPOINT_POINT)
 
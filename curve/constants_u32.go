@@ -153,6 +153,20 @@ var (
 	}
 )
 
+// Montgomery `A` value, equal to `486662`.
+var constMONTGOMERY_A = field.NewElement2625(486662, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+// Montgomery to Edwards scaling factor, equal to `sqrt(-(A+2))`, with the
+// root chosen such that the Ed25519 and X25519 basepoints correspond (RFC 7748).
+var constMONTGOMERY_SQRT_NEG_A_PLUS_TWO = field.NewElement2625(
+	12222951, 8312128, 11511410, 24486935, 15300784, 241793, 41652734, 19432880, 12187135, 29582408,
+)
+
+// Montgomery to Wei25519 offset, equal to `A/3 mod p`.
+var constWEI25519_DELTA = field.NewElement2625(
+	44901457, 11184810, 22369621, 22369621, 44739242, 11184810, 22369621, 22369621, 44739242, 11184810,
+)
+
 // The value of minus one, equal to `-Element.One()`.
 var constMINUS_ONE = field.NewElement2625(
 	67108844, 33554431, 67108863, 33554431, 67108863, 33554431, 67108863, 33554431, 67108863, 33554431,
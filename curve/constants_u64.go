@@ -207,6 +207,28 @@ var (
 	}
 )
 
+// Montgomery `A` value, equal to `486662`.
+var constMONTGOMERY_A = field.NewElement51(486662, 0, 0, 0, 0)
+
+// Montgomery to Edwards scaling factor, equal to `sqrt(-(A+2))`, with the
+// root chosen such that the Ed25519 and X25519 basepoints correspond (RFC 7748).
+var constMONTGOMERY_SQRT_NEG_A_PLUS_TWO = field.NewElement51(
+	557817479725543,
+	1643290402203250,
+	16226468853936,
+	1304118542701054,
+	1985241807451647,
+)
+
+// Montgomery to Wei25519 offset, equal to `A/3 mod p`.
+var constWEI25519_DELTA = field.NewElement51(
+	750599938057297,
+	1501199875790165,
+	750599937895082,
+	1501199875790165,
+	750599937895082,
+)
+
 // The value of minus one, equal to `-Element.One()`.
 var constMINUS_ONE = field.NewElement51(
 	2251799813685228,
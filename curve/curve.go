@@ -28,7 +28,8 @@
 // SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 // Package curve provides group operations on the Edwards and Montgomery
-// forms of Curve25519, and on the prime-order Ristretto group.
+// forms of Curve25519, and on the prime-order Ristretto group, along with
+// conversions to and from the short Weierstrass (Wei25519) form.
 //
 // Most users should NOT use this package.
 package curve
@@ -119,6 +119,34 @@ func (p *CompressedEdwardsY) SetEdwardsPoint(point *EdwardsPoint) *CompressedEdw
 	return p
 }
 
+// CompressEdwardsYBatch compresses a slice of Edwards points, sharing a
+// single field inversion across the entire batch.  This function will
+// execute in constant-time.
+//
+// WARNING: This routine will panic if len(out) != len(points).
+func CompressEdwardsYBatch(out []CompressedEdwardsY, points []*EdwardsPoint) {
+	if len(out) != len(points) {
+		panic("curve/edwards: mismatched output and point slice lengths")
+	}
+
+	recips := make([]field.Element, len(points))
+	recipPtrs := make([]*field.Element, len(points))
+	for i, point := range points {
+		recips[i].Set(&point.inner.Z)
+		recipPtrs[i] = &recips[i]
+	}
+	field.BatchInvert(recipPtrs)
+
+	var x, y field.Element
+	for i, point := range points {
+		x.Mul(&point.inner.X, &recips[i])
+		y.Mul(&point.inner.Y, &recips[i])
+
+		_ = y.ToBytes(out[i][:])
+		out[i][31] ^= byte(x.IsNegative()) << 7
+	}
+}
+
 // Equal returns 1 iff the compressed points are equal, 0 otherwise.
 // This function will execute in constant-time.
 //
@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package curve
+
+import (
+	"fmt"
+
+	pubfield "github.com/oasisprotocol/curve25519-voi/curve/field"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
+)
+
+var (
+	errNotOnCurve       = fmt.Errorf("curve/edwards: point not on curve")
+	errInvalidExtended  = fmt.Errorf("curve/edwards: invalid extended coordinates")
+	errIdentityInfinity = fmt.Errorf("curve/edwards: identity maps to the point at infinity")
+)
+
+// ExtendedCoordinates returns the extended twisted Edwards coordinates
+// `(X, Y, Z, T)` of p, where `x = X/Z`, `y = Y/Z`, and `T = XY/Z`.
+//
+// Note: The representation is not unique, as any non-zero multiple of
+// all coordinates describes the same point.
+func (p *EdwardsPoint) ExtendedCoordinates() (X, Y, Z, T *pubfield.Element) {
+	return toPublicElement(&p.inner.X),
+		toPublicElement(&p.inner.Y),
+		toPublicElement(&p.inner.Z),
+		toPublicElement(&p.inner.T)
+}
+
+// SetExtendedCoordinates sets p to the point with the extended twisted
+// Edwards coordinates `(X, Y, Z, T)`, and returns p.  The coordinates
+// must satisfy `Z != 0`, `XY = ZT`, and the curve equation.
+func (p *EdwardsPoint) SetExtendedCoordinates(X, Y, Z, T *pubfield.Element) (*EdwardsPoint, error) {
+	var inner edwardsPointInner
+	fromPublicElement(&inner.X, X)
+	fromPublicElement(&inner.Y, Y)
+	fromPublicElement(&inner.Z, Z)
+	fromPublicElement(&inner.T, T)
+
+	var XY, ZT field.Element
+	XY.Mul(&inner.X, &inner.Y)
+	ZT.Mul(&inner.Z, &inner.T)
+	if inner.Z.IsZero() == 1 || XY.Equal(&ZT) != 1 {
+		return nil, errInvalidExtended
+	}
+	if !isOnCurveProjective(&inner.X, &inner.Y, &inner.Z) {
+		return nil, errNotOnCurve
+	}
+
+	p.inner = inner
+	return p, nil
+}
+
+// AffineCoordinates returns the affine twisted Edwards coordinates
+// `(x, y)` of p.
+func (p *EdwardsPoint) AffineCoordinates() (x, y *pubfield.Element) {
+	var xAffine, yAffine field.Element
+	p.affineCoordinates(&xAffine, &yAffine)
+
+	return toPublicElement(&xAffine), toPublicElement(&yAffine)
+}
+
+// SetAffineCoordinates sets p to the point with the affine twisted Edwards
+// coordinates `(x, y)`, and returns p.  The coordinates must satisfy the
+// curve equation `-x^2 + y^2 = 1 + d*x^2*y^2`.
+func (p *EdwardsPoint) SetAffineCoordinates(x, y *pubfield.Element) (*EdwardsPoint, error) {
+	var xInner, yInner field.Element
+	fromPublicElement(&xInner, x)
+	fromPublicElement(&yInner, y)
+
+	if !isOnCurveProjective(&xInner, &yInner, &field.One) {
+		return nil, errNotOnCurve
+	}
+
+	p.setAffine(&xInner, &yInner)
+	return p, nil
+}
+
+// MontgomeryCoordinates returns the affine coordinates `(u, v)` of the
+// point on Curve25519 (`v^2 = u^3 + 486662*u^2 + u`) that is birationally
+// equivalent to p, per RFC 7748.
+//
+// The identity point maps to the point at infinity, which has no affine
+// representation, and an error is returned instead.
+func (p *EdwardsPoint) MontgomeryCoordinates() (u, v *pubfield.Element, err error) {
+	var uInner, vInner field.Element
+	if !p.montgomeryCoordinates(&uInner, &vInner) {
+		return nil, nil, errIdentityInfinity
+	}
+
+	return toPublicElement(&uInner), toPublicElement(&vInner), nil
+}
+
+// SetMontgomeryCoordinates sets p to the point that is birationally
+// equivalent to the point with the affine Curve25519 coordinates
+// `(u, v)`, per RFC 7748, and returns p.  The coordinates must satisfy
+// the curve equation `v^2 = u^3 + 486662*u^2 + u`.
+func (p *EdwardsPoint) SetMontgomeryCoordinates(u, v *pubfield.Element) (*EdwardsPoint, error) {
+	var uInner, vInner field.Element
+	fromPublicElement(&uInner, u)
+	fromPublicElement(&vInner, v)
+
+	if !p.setMontgomeryCoordinates(&uInner, &vInner) {
+		return nil, errNotOnCurve
+	}
+	return p, nil
+}
+
+// Wei25519Coordinates returns the affine coordinates `(x, y)` of the point
+// on Wei25519 (the short Weierstrass model of Curve25519) that is
+// isomorphic to p.
+//
+// The identity point maps to the point at infinity, which has no affine
+// representation, and an error is returned instead.
+func (p *EdwardsPoint) Wei25519Coordinates() (x, y *pubfield.Element, err error) {
+	var u, v field.Element
+	if !p.montgomeryCoordinates(&u, &v) {
+		return nil, nil, errIdentityInfinity
+	}
+
+	// (x, y) = (u + A/3, v)
+	u.Add(&u, &constWEI25519_DELTA)
+
+	return toPublicElement(&u), toPublicElement(&v), nil
+}
+
+// SetWei25519Coordinates sets p to the point that is isomorphic to the
+// point with the affine Wei25519 coordinates `(x, y)`, and returns p.
+// The coordinates must satisfy the Wei25519 curve equation.
+func (p *EdwardsPoint) SetWei25519Coordinates(x, y *pubfield.Element) (*EdwardsPoint, error) {
+	var u, v field.Element
+	fromPublicElement(&u, x)
+	fromPublicElement(&v, y)
+
+	// (u, v) = (x - A/3, y)
+	u.Sub(&u, &constWEI25519_DELTA)
+
+	if !p.setMontgomeryCoordinates(&u, &v) {
+		return nil, errNotOnCurve
+	}
+	return p, nil
+}
+
+func (p *EdwardsPoint) affineCoordinates(x, y *field.Element) {
+	var recip field.Element
+	recip.Invert(&p.inner.Z)
+	x.Mul(&p.inner.X, &recip)
+	y.Mul(&p.inner.Y, &recip)
+}
+
+func (p *EdwardsPoint) setAffine(x, y *field.Element) {
+	p.inner.X.Set(x)
+	p.inner.Y.Set(y)
+	p.inner.Z.One()
+	p.inner.T.Mul(x, y)
+}
+
+func (p *EdwardsPoint) montgomeryCoordinates(u, v *field.Element) bool {
+	// We have u = (1+y)/(1-y) = (Z+Y)/(Z-Y), and
+	// v = sqrt(-(A+2)) * u/x = sqrt(-(A+2)) * (Z+Y)/(X(Z-Y)) * Z.
+	//
+	// The denominator is zero only when y=1 (the identity point, which
+	// maps to the point at infinity), or x=0 (the 2-torsion point
+	// (0, -1), which maps to (0, 0), and is handled correctly by
+	// 0.invert() = 0).
+	var U, W, WX, inv field.Element
+	U.Add(&p.inner.Z, &p.inner.Y)
+	W.Sub(&p.inner.Z, &p.inner.Y)
+	WX.Mul(&W, &p.inner.X)
+
+	// Use a single inversion of X(Z-Y) for both coordinates.  If x=0,
+	// this also zeroes out u, but in that case Y = -Z, and U = 0 anyway.
+	inv.Invert(&WX)
+
+	var tmp field.Element
+	tmp.Mul(&inv, &p.inner.X)
+	u.Mul(&U, &tmp)
+
+	tmp.Mul(&inv, &p.inner.Z)
+	tmp.Mul(&tmp, &U)
+	v.Mul(&tmp, &constMONTGOMERY_SQRT_NEG_A_PLUS_TWO)
+
+	return W.IsZero() == 0
+}
+
+func (p *EdwardsPoint) setMontgomeryCoordinates(u, v *field.Element) bool {
+	// Check that v^2 = u^3 + A*u^2 + u = u*((u+A)*u + 1).
+	var lhs, rhs field.Element
+	lhs.Square(v)
+	rhs.Add(u, &constMONTGOMERY_A)
+	rhs.Mul(&rhs, u)
+	rhs.Add(&rhs, &field.One)
+	rhs.Mul(&rhs, u)
+	if lhs.Equal(&rhs) != 1 {
+		return false
+	}
+
+	// The birational map is (x, y) = (sqrt(-(A+2))*u/v, (u-1)/(u+1)).
+	//
+	// The exceptional points are v = 0 and u = -1.  The only point on
+	// the curve with v = 0 is the 2-torsion point (0, 0), which maps
+	// to (0, -1), and is handled correctly by 0.invert() = 0.  As
+	// u = -1 implies v^2 = 486660, which is nonsquare, it corresponds
+	// to a point on the twist, and is rejected by the check above.
+	var uPlusOne, denom, inv field.Element
+	uPlusOne.Add(u, &field.One)
+	denom.Mul(v, &uPlusOne)
+	inv.Invert(&denom)
+
+	var x, y, tmp field.Element
+	tmp.Mul(&inv, &uPlusOne)
+	x.Mul(&tmp, u)
+	x.Mul(&x, &constMONTGOMERY_SQRT_NEG_A_PLUS_TWO)
+
+	// y = (u-1)/(u+1) = (u-1)*v/(v(u+1)), which requires v != 0.
+	// For (0, 0), y must be -1.
+	var uMinusOne field.Element
+	uMinusOne.Sub(u, &field.One)
+	tmp.Mul(&inv, v)
+	y.Mul(&uMinusOne, &tmp)
+	y.ConditionalAssign(&field.MinusOne, v.IsZero())
+
+	p.setAffine(&x, &y)
+	return true
+}
+
+func isOnCurveProjective(X, Y, Z *field.Element) bool {
+	// Curve equation is    -x^2 + y^2 = 1 + d*x^2*y^2,
+	// homogenized as (-X^2 + Y^2)*Z^2 = Z^4 + d*X^2*Y^2
+	var XX, YY, ZZ, ZZZZ field.Element
+	XX.Square(X)
+	YY.Square(Y)
+	ZZ.Square(Z)
+	ZZZZ.Square(&ZZ)
+
+	var lhs, rhs field.Element
+	lhs.Sub(&YY, &XX)
+	lhs.Mul(&lhs, &ZZ)
+	rhs.Mul(&XX, &YY)
+	rhs.Mul(&rhs, &constEDWARDS_D)
+	rhs.Add(&rhs, &ZZZZ)
+
+	return lhs.Equal(&rhs) == 1
+}
+
+func toPublicElement(fe *field.Element) *pubfield.Element {
+	var b [field.ElementSize]byte
+	_ = fe.ToBytes(b[:])
+
+	// The encoding produced by ToBytes is always canonical.
+	ret, err := pubfield.NewFromCanonicalBytes(b[:])
+	if err != nil {
+		panic("curve: failed to convert field element: " + err.Error())
+	}
+	return ret
+}
+
+func fromPublicElement(dst *field.Element, fe *pubfield.Element) {
+	var b [field.ElementSize]byte
+	if err := fe.ToBytes(b[:]); err != nil {
+		panic("curve: failed to convert field element: " + err.Error())
+	}
+	if _, err := dst.SetBytes(b[:]); err != nil {
+		panic("curve: failed to convert field element: " + err.Error())
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package curve
+
+import (
+	"bytes"
+	"testing"
+
+	pubfield "github.com/oasisprotocol/curve25519-voi/curve/field"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+func TestEdwardsCoordinates(t *testing.T) {
+	t.Run("Affine/Basepoint", testEdwardsCoordinatesAffineBasepoint)
+	t.Run("Affine/RoundTrip", testEdwardsCoordinatesAffineRoundTrip)
+	t.Run("Affine/Invalid", testEdwardsCoordinatesAffineInvalid)
+	t.Run("Extended/RoundTrip", testEdwardsCoordinatesExtendedRoundTrip)
+	t.Run("Extended/Invalid", testEdwardsCoordinatesExtendedInvalid)
+	t.Run("Montgomery/Basepoint", testEdwardsCoordinatesMontgomeryBasepoint)
+	t.Run("Montgomery/RoundTrip", testEdwardsCoordinatesMontgomeryRoundTrip)
+	t.Run("Montgomery/Exceptional", testEdwardsCoordinatesMontgomeryExceptional)
+	t.Run("Montgomery/Invalid", testEdwardsCoordinatesMontgomeryInvalid)
+	t.Run("Wei25519/Basepoint", testEdwardsCoordinatesWei25519Basepoint)
+	t.Run("Wei25519/RoundTrip", testEdwardsCoordinatesWei25519RoundTrip)
+	t.Run("Wei25519/Invalid", testEdwardsCoordinatesWei25519Invalid)
+}
+
+func testEdwardsCoordinatesAffineBasepoint(t *testing.T) {
+	// The basepoint is (x, 4/5), with x being the positive root.
+	x, y := ED25519_BASEPOINT_POINT.AffineCoordinates()
+
+	expectedX := mustUnhexPublicElement(t, "1ad5258f602d56c9b2a7259560c72c695cdcd6fd31e2a4c0fe536ecdd3366921")
+	if x.Equal(expectedX) != 1 {
+		t.Fatalf("basepoint x != expected (Got: %v)", x)
+	}
+
+	four := pubfield.New().Add(pubfield.One(), pubfield.One())
+	four.Square(four)
+	expectedY := pubfield.New().Add(four, pubfield.One())
+	expectedY.Invert(expectedY)
+	expectedY.Mul(expectedY, four)
+	if y.Equal(expectedY) != 1 {
+		t.Fatalf("basepoint y != 4/5 (Got: %v)", y)
+	}
+}
+
+func testEdwardsCoordinatesAffineRoundTrip(t *testing.T) {
+	for i, p := range testEdwardsCoordinatesPoints(t) {
+		x, y := p.AffineCoordinates()
+
+		q, err := NewEdwardsPoint().SetAffineCoordinates(x, y)
+		if err != nil {
+			t.Fatalf("SetAffineCoordinates(points[%d]): %v", i, err)
+		}
+		if !q.debugIsValid() {
+			t.Fatalf("SetAffineCoordinates(points[%d]).debugIsValid() != true", i)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetAffineCoordinates(points[%d]) != points[%d]", i, i)
+		}
+	}
+}
+
+func testEdwardsCoordinatesAffineInvalid(t *testing.T) {
+	x, y := ED25519_BASEPOINT_POINT.AffineCoordinates()
+	x.Add(x, pubfield.One())
+
+	p := NewEdwardsPoint().Set(ED25519_BASEPOINT_POINT)
+	if _, err := p.SetAffineCoordinates(x, y); err == nil {
+		t.Fatalf("SetAffineCoordinates(off-curve) succeeded")
+	}
+	if p.Equal(ED25519_BASEPOINT_POINT) != 1 {
+		t.Fatalf("SetAffineCoordinates(off-curve) modified the point")
+	}
+}
+
+func testEdwardsCoordinatesExtendedRoundTrip(t *testing.T) {
+	for i, p := range testEdwardsCoordinatesPoints(t) {
+		X, Y, Z, T := p.ExtendedCoordinates()
+
+		q, err := NewEdwardsPoint().SetExtendedCoordinates(X, Y, Z, T)
+		if err != nil {
+			t.Fatalf("SetExtendedCoordinates(points[%d]): %v", i, err)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetExtendedCoordinates(points[%d]) != points[%d]", i, i)
+		}
+
+		// The extended coordinates must agree with the affine ones.
+		x, y := p.AffineCoordinates()
+		var tmp pubfield.Element
+		if tmp.Mul(x, Z).Equal(X) != 1 {
+			t.Fatalf("points[%d]: x * Z != X", i)
+		}
+		if tmp.Mul(y, Z).Equal(Y) != 1 {
+			t.Fatalf("points[%d]: y * Z != Y", i)
+		}
+
+		// The returned elements must be copies.
+		X.Zero()
+		if X2, _, _, _ := p.ExtendedCoordinates(); X2.Equal(tmp.Mul(x, Z)) != 1 {
+			t.Fatalf("points[%d]: ExtendedCoordinates aliases the point", i)
+		}
+	}
+}
+
+func testEdwardsCoordinatesExtendedInvalid(t *testing.T) {
+	p := testEdwardsCoordinatesPoints(t)[3]
+
+	// Scaling all coordinates by a non-zero value is fine.
+	X, Y, Z, T := p.ExtendedCoordinates()
+	two := pubfield.New().Add(pubfield.One(), pubfield.One())
+	for _, fe := range []*pubfield.Element{X, Y, Z, T} {
+		fe.Mul(fe, two)
+	}
+	if q, err := NewEdwardsPoint().SetExtendedCoordinates(X, Y, Z, T); err != nil || q.Equal(p) != 1 {
+		t.Fatalf("SetExtendedCoordinates(scaled): %v", err)
+	}
+
+	X, Y, Z, T = p.ExtendedCoordinates()
+	T.Add(T, pubfield.One())
+	if _, err := NewEdwardsPoint().SetExtendedCoordinates(X, Y, Z, T); err == nil {
+		t.Fatalf("SetExtendedCoordinates(XY != ZT) succeeded")
+	}
+
+	X, Y, Z, T = p.ExtendedCoordinates()
+	Z.Zero()
+	T.Zero()
+	X.Zero()
+	if _, err := NewEdwardsPoint().SetExtendedCoordinates(X, Y, Z, T); err == nil {
+		t.Fatalf("SetExtendedCoordinates(Z = 0) succeeded")
+	}
+
+	// (X, Y, Z, T) = (1, 1, 1, 1) satisfies the Segre relation, but
+	// is not on the curve.
+	one := pubfield.One()
+	if _, err := NewEdwardsPoint().SetExtendedCoordinates(one, one, one, one); err == nil {
+		t.Fatalf("SetExtendedCoordinates(off-curve) succeeded")
+	}
+}
+
+func testEdwardsCoordinatesMontgomeryBasepoint(t *testing.T) {
+	// RFC 7748 Section 4.1: The base point is u = 9,
+	// v = 14781619447589544791020593568409986887264606134616475288964881837755586237401.
+	u, v, err := ED25519_BASEPOINT_POINT.MontgomeryCoordinates()
+	if err != nil {
+		t.Fatalf("MontgomeryCoordinates(B): %v", err)
+	}
+
+	expectedU := mustUnhexPublicElement(t, "0900000000000000000000000000000000000000000000000000000000000000")
+	expectedV := mustUnhexPublicElement(t, "d9d3ce7ea2c5e929b2617c6d7e4d3d924cd148772cdd1ee0b486a0b8a119ae20")
+	if u.Equal(expectedU) != 1 {
+		t.Fatalf("basepoint u != 9 (Got: %v)", u)
+	}
+	if v.Equal(expectedV) != 1 {
+		t.Fatalf("basepoint v != expected (Got: %v)", v)
+	}
+
+	p, err := NewEdwardsPoint().SetMontgomeryCoordinates(expectedU, expectedV)
+	if err != nil {
+		t.Fatalf("SetMontgomeryCoordinates(9, v): %v", err)
+	}
+	if p.Equal(ED25519_BASEPOINT_POINT) != 1 {
+		t.Fatalf("SetMontgomeryCoordinates(9, v) != B")
+	}
+}
+
+func testEdwardsCoordinatesMontgomeryRoundTrip(t *testing.T) {
+	for i, p := range testEdwardsCoordinatesPoints(t) {
+		u, v, err := p.MontgomeryCoordinates()
+		if err != nil {
+			t.Fatalf("MontgomeryCoordinates(points[%d]): %v", i, err)
+		}
+
+		// The u-coordinate must match the existing conversion.
+		var mp MontgomeryPoint
+		mp.SetEdwards(p)
+		uBytes, _ := u.MarshalBinary()
+		if !bytes.Equal(uBytes, mp[:]) {
+			t.Fatalf("MontgomeryCoordinates(points[%d]) u != SetEdwards()", i)
+		}
+
+		q, err := NewEdwardsPoint().SetMontgomeryCoordinates(u, v)
+		if err != nil {
+			t.Fatalf("SetMontgomeryCoordinates(points[%d]): %v", i, err)
+		}
+		if !q.debugIsValid() {
+			t.Fatalf("SetMontgomeryCoordinates(points[%d]).debugIsValid() != true", i)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetMontgomeryCoordinates(points[%d]) != points[%d]", i, i)
+		}
+	}
+}
+
+func testEdwardsCoordinatesMontgomeryExceptional(t *testing.T) {
+	if _, _, err := NewEdwardsPoint().Identity().MontgomeryCoordinates(); err == nil {
+		t.Fatalf("MontgomeryCoordinates(identity) succeeded")
+	}
+
+	// The 2-torsion point (0, -1) maps to (0, 0).
+	u, v, err := EIGHT_TORSION[4].MontgomeryCoordinates()
+	if err != nil {
+		t.Fatalf("MontgomeryCoordinates(EIGHT_TORSION[4]): %v", err)
+	}
+	if u.IsZero() != 1 || v.IsZero() != 1 {
+		t.Fatalf("MontgomeryCoordinates(EIGHT_TORSION[4]) != (0, 0) (Got: %v, %v)", u, v)
+	}
+
+	p, err := NewEdwardsPoint().SetMontgomeryCoordinates(pubfield.New(), pubfield.New())
+	if err != nil {
+		t.Fatalf("SetMontgomeryCoordinates(0, 0): %v", err)
+	}
+	if p.Equal(EIGHT_TORSION[4]) != 1 {
+		t.Fatalf("SetMontgomeryCoordinates(0, 0) != (0, -1)")
+	}
+}
+
+func testEdwardsCoordinatesMontgomeryInvalid(t *testing.T) {
+	u, v, err := ED25519_BASEPOINT_POINT.MontgomeryCoordinates()
+	if err != nil {
+		t.Fatalf("MontgomeryCoordinates(B): %v", err)
+	}
+	v.Add(v, pubfield.One())
+	if _, err = NewEdwardsPoint().SetMontgomeryCoordinates(u, v); err == nil {
+		t.Fatalf("SetMontgomeryCoordinates(off-curve) succeeded")
+	}
+
+	// u = -1 is on the twist, no v is valid.
+	if _, err = NewEdwardsPoint().SetMontgomeryCoordinates(pubfield.MinusOne(), pubfield.New()); err == nil {
+		t.Fatalf("SetMontgomeryCoordinates(-1, 0) succeeded")
+	}
+}
+
+func testEdwardsCoordinatesWei25519Basepoint(t *testing.T) {
+	// draft-ietf-lwig-curve-representations-23 Appendix E.3.
+	x, y, err := ED25519_BASEPOINT_POINT.Wei25519Coordinates()
+	if err != nil {
+		t.Fatalf("Wei25519Coordinates(B): %v", err)
+	}
+
+	expectedX := mustUnhexPublicElement(t, "5a24adaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa2a")
+	expectedY := mustUnhexPublicElement(t, "d9d3ce7ea2c5e929b2617c6d7e4d3d924cd148772cdd1ee0b486a0b8a119ae20")
+	if x.Equal(expectedX) != 1 {
+		t.Fatalf("basepoint x != expected (Got: %v)", x)
+	}
+	if y.Equal(expectedY) != 1 {
+		t.Fatalf("basepoint y != expected (Got: %v)", y)
+	}
+}
+
+func testEdwardsCoordinatesWei25519RoundTrip(t *testing.T) {
+	a := mustUnhexPublicElement(t, "44a1144998aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa2a")
+	b := mustUnhexPublicElement(t, "64c810779c5e0b26b497d05e427b09ed25b497d05e427b09ed25b497d05e427b")
+
+	for i, p := range testEdwardsCoordinatesPoints(t) {
+		x, y, err := p.Wei25519Coordinates()
+		if err != nil {
+			t.Fatalf("Wei25519Coordinates(points[%d]): %v", i, err)
+		}
+
+		// y^2 = x^3 + a*x + b
+		var lhs, rhs pubfield.Element
+		lhs.Square(y)
+		rhs.Square(x)
+		rhs.Add(&rhs, a)
+		rhs.Mul(&rhs, x)
+		rhs.Add(&rhs, b)
+		if lhs.Equal(&rhs) != 1 {
+			t.Fatalf("Wei25519Coordinates(points[%d]) not on curve", i)
+		}
+
+		q, err := NewEdwardsPoint().SetWei25519Coordinates(x, y)
+		if err != nil {
+			t.Fatalf("SetWei25519Coordinates(points[%d]): %v", i, err)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetWei25519Coordinates(points[%d]) != points[%d]", i, i)
+		}
+	}
+
+	if _, _, err := NewEdwardsPoint().Identity().Wei25519Coordinates(); err == nil {
+		t.Fatalf("Wei25519Coordinates(identity) succeeded")
+	}
+}
+
+func testEdwardsCoordinatesWei25519Invalid(t *testing.T) {
+	x, y, err := ED25519_BASEPOINT_POINT.Wei25519Coordinates()
+	if err != nil {
+		t.Fatalf("Wei25519Coordinates(B): %v", err)
+	}
+	x.Add(x, pubfield.One())
+	if _, err = NewEdwardsPoint().SetWei25519Coordinates(x, y); err == nil {
+		t.Fatalf("SetWei25519Coordinates(off-curve) succeeded")
+	}
+}
+
+func testEdwardsCoordinatesPoints(t *testing.T) []*EdwardsPoint {
+	points := []*EdwardsPoint{
+		ED25519_BASEPOINT_POINT,
+		EIGHT_TORSION[1],
+		EIGHT_TORSION[4],
+		// Z != 1
+		NewEdwardsPoint().Add(ED25519_BASEPOINT_POINT, EIGHT_TORSION[1]),
+	}
+	for i := 0; i < 8; i++ {
+		s, err := scalar.New().SetRandom(nil)
+		if err != nil {
+			t.Fatalf("SetRandom: %v", err)
+		}
+		points = append(points, NewEdwardsPoint().MulBasepoint(ED25519_BASEPOINT_TABLE, s))
+	}
+	return points
+}
+
+func mustUnhexPublicElement(t *testing.T, x string) *pubfield.Element {
+	fe, err := pubfield.NewFromCanonicalBytes(testhelpers.MustUnhex(t, x))
+	if err != nil {
+		t.Fatalf("NewFromCanonicalBytes: %v", err)
+	}
+	return fe
+}
@@ -125,6 +125,7 @@ func TestEdwards(t *testing.T) {
 	t.Run("IsTorsionFree", testEdwardsIsTorsionFree)
 	t.Run("IsIdentity", testEdwardsIsIdentity)
 	t.Run("CompressedIdentity", testEdwardsCompressedIdentity)
+	t.Run("CompressBatch", testEdwardsCompressBatch)
 	t.Run("BasepointTable/New", testEdwardsBasepointTableNew)
 	t.Run("BasepointTable/Basepoint", testEdwardsBasepointTableBasepoint)
 	t.Run("BasepointTable/Mul", testEdwardsBasepointTableMul)
@@ -335,6 +336,33 @@ func testEdwardsCompressedIdentity(t *testing.T) {
 	}
 }
 
+func testEdwardsCompressBatch(t *testing.T) {
+	points := []*EdwardsPoint{
+		NewEdwardsPoint().Identity(),
+		ED25519_BASEPOINT_POINT,
+		EIGHT_TORSION[1],
+	}
+	for i := 0; i < 8; i++ {
+		s, err := scalar.New().SetRandom(nil)
+		if err != nil {
+			t.Fatalf("SetRandom: %v", err)
+		}
+		points = append(points, NewEdwardsPoint().MulBasepoint(ED25519_BASEPOINT_TABLE, s))
+	}
+
+	compressed := make([]CompressedEdwardsY, len(points))
+	CompressEdwardsYBatch(compressed, points)
+	for i, p := range points {
+		var expected CompressedEdwardsY
+		expected.SetEdwardsPoint(p)
+		if compressed[i].Equal(&expected) != 1 {
+			t.Fatalf("CompressEdwardsYBatch()[%d] != SetEdwardsPoint() (Got: %v)", i, compressed[i])
+		}
+	}
+
+	CompressEdwardsYBatch(nil, nil) // Should not panic.
+}
+
 func testEdwardsBasepointTableNew(t *testing.T) {
 	// Test table creation by regenerating the hard coded basepoint table.
 	// This also serves to sanity-check that the hardcoded table is correct.
@@ -0,0 +1,355 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package field implements arithmetic on field elements (integers mod
+// p = 2^255 - 19), the base field of Curve25519 and edwards25519.
+package field
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/disalloweq"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+const (
+	// ElementSize is the size of a field element in bytes.
+	ElementSize = field.ElementSize
+
+	// ElementWideSize is the size of a wide field element in bytes.
+	ElementWideSize = field.ElementWideSize
+)
+
+var (
+	errElementNotCanonical = fmt.Errorf("curve/field: representative not canonical")
+	errUnexpectedInputSize = fmt.Errorf("curve/field: unexpected input size")
+
+	// SQRT_M1 is the field element sqrt(-1).
+	SQRT_M1 = func() *Element {
+		var fe Element
+		fe.inner.Set(&field.SQRT_M1)
+		return &fe
+	}()
+)
+
+// Element holds an integer which represents an element of GF(2^255 - 19).
+type Element struct {
+	disalloweq.DisallowEqual //nolint:unused
+	inner                    field.Element
+}
+
+// MarshalBinary encodes the field element into a binary form and returns
+// the result.
+func (fe *Element) MarshalBinary() ([]byte, error) {
+	b := make([]byte, ElementSize)
+	return b, fe.ToBytes(b)
+}
+
+// UnmarshalBinary decodes a binary serialized field element.
+func (fe *Element) UnmarshalBinary(data []byte) error {
+	_, err := fe.SetCanonicalBytes(data)
+	return err
+}
+
+// Set sets fe to t, and returns fe.
+func (fe *Element) Set(t *Element) *Element {
+	fe.inner.Set(&t.inner)
+	return fe
+}
+
+// SetBytes sets fe to the field element constructed from the low 255
+// bits of a 256-bit little-endian integer, and returns fe.
+//
+// WARNING: This function masks the high bit, and does not check that the
+// input is the canonical representative (eg: 2^255 - 18 decodes to 1).
+// Use SetCanonicalBytes if a canonical encoding is required.
+func (fe *Element) SetBytes(in []byte) (*Element, error) {
+	if len(in) != ElementSize {
+		return nil, errUnexpectedInputSize
+	}
+
+	if _, err := fe.inner.SetBytes(in); err != nil {
+		return nil, err
+	}
+	return fe, nil
+}
+
+// SetCanonicalBytes sets fe from a canonical byte representation, and
+// returns fe.
+func (fe *Element) SetCanonicalBytes(in []byte) (*Element, error) {
+	if !IsCanonicalBytes(in) {
+		if len(in) != ElementSize {
+			return nil, errUnexpectedInputSize
+		}
+		return nil, errElementNotCanonical
+	}
+
+	return fe.SetBytes(in)
+}
+
+// SetBytesWide sets fe to the field element constructed by reducing a
+// 512-bit little-endian integer modulo p, and returns fe.
+func (fe *Element) SetBytesWide(in []byte) (*Element, error) {
+	if len(in) != ElementWideSize {
+		return nil, errUnexpectedInputSize
+	}
+
+	if _, err := fe.inner.SetBytesWide(in); err != nil {
+		return nil, err
+	}
+	return fe, nil
+}
+
+// ToBytes packs the field element into 32 bytes.  The encoding is canonical.
+func (fe *Element) ToBytes(out []byte) error {
+	if len(out) != ElementSize {
+		return errUnexpectedInputSize
+	}
+
+	return fe.inner.ToBytes(out)
+}
+
+// Zero sets fe to zero, and returns fe.
+func (fe *Element) Zero() *Element {
+	fe.inner.Zero()
+	return fe
+}
+
+// One sets fe to one, and returns fe.
+func (fe *Element) One() *Element {
+	fe.inner.One()
+	return fe
+}
+
+// MinusOne sets fe to -1, and returns fe.
+func (fe *Element) MinusOne() *Element {
+	fe.inner.MinusOne()
+	return fe
+}
+
+// Equal returns 1 iff the field elements are equal, 0 otherwise.
+// This function will execute in constant-time.
+func (fe *Element) Equal(other *Element) int {
+	return fe.inner.Equal(&other.inner)
+}
+
+// IsZero returns 1 iff the field element is zero, 0 otherwise.
+// This function will execute in constant-time.
+func (fe *Element) IsZero() int {
+	return fe.inner.IsZero()
+}
+
+// IsNegative returns 1 iff the field element is negative (the least
+// significant bit of the canonical encoding is set), 0 otherwise.
+// This function will execute in constant-time.
+func (fe *Element) IsNegative() int {
+	return fe.inner.IsNegative()
+}
+
+// Add sets `fe = a + b`, and returns fe.
+func (fe *Element) Add(a, b *Element) *Element {
+	fe.inner.Add(&a.inner, &b.inner)
+	return fe
+}
+
+// Sub sets `fe = a - b`, and returns fe.
+func (fe *Element) Sub(a, b *Element) *Element {
+	fe.inner.Sub(&a.inner, &b.inner)
+	return fe
+}
+
+// Neg sets `fe = -t`, and returns fe.
+func (fe *Element) Neg(t *Element) *Element {
+	fe.inner.Neg(&t.inner)
+	return fe
+}
+
+// Mul sets `fe = a * b`, and returns fe.
+func (fe *Element) Mul(a, b *Element) *Element {
+	fe.inner.Mul(&a.inner, &b.inner)
+	return fe
+}
+
+// Mul121666 sets `fe = t * 121666`, and returns fe.  This is intended
+// for the Montgomery ladder, as `121666 = (A + 2) / 4`.
+func (fe *Element) Mul121666(t *Element) *Element {
+	fe.inner.Mul121666(&t.inner)
+	return fe
+}
+
+// Square sets `fe = t^2`, and returns fe.
+func (fe *Element) Square(t *Element) *Element {
+	fe.inner.Square(&t.inner)
+	return fe
+}
+
+// Square2 sets `fe = 2*t^2`, and returns fe.
+func (fe *Element) Square2(t *Element) *Element {
+	fe.inner.Square2(&t.inner)
+	return fe
+}
+
+// Pow2k sets `fe = t^(2^k)`, given `k > 0`, and returns fe.
+func (fe *Element) Pow2k(t *Element, k uint) *Element {
+	if k == 0 {
+		panic("curve/field: k out of bounds")
+	}
+	fe.inner.Pow2k(&t.inner, k)
+	return fe
+}
+
+// Invert sets fe to the multiplicative inverse of t, and returns fe.
+//
+// On input zero, the field element is set to zero.
+func (fe *Element) Invert(t *Element) *Element {
+	fe.inner.Invert(&t.inner)
+	return fe
+}
+
+// SqrtRatioI sets fe to either `sqrt(u/v)` or `sqrt(i*u/v)` in constant
+// time, and returns fe, and 1 iff `u/v` was square, 0 otherwise.  This
+// function always selects the nonnegative square root.
+//
+// If v is zero, fe is set to zero, and 1 is returned iff u is zero.
+func (fe *Element) SqrtRatioI(u, v *Element) (*Element, int) {
+	_, wasSquare := fe.inner.SqrtRatioI(&u.inner, &v.inner)
+	return fe, wasSquare
+}
+
+// Sqrt sets fe to either `sqrt(t)` or `sqrt(i*t)` in constant time, and
+// returns fe, and 1 iff t was square, 0 otherwise.  This function always
+// selects the nonnegative square root.
+func (fe *Element) Sqrt(t *Element) (*Element, int) {
+	_, wasSquare := fe.inner.SqrtRatioI(&t.inner, &field.One)
+	return fe, wasSquare
+}
+
+// InvSqrt sets fe to either `sqrt(1/t)` or `sqrt(i/t)` in constant time,
+// and returns fe, and 1 iff t was square and nonzero, 0 otherwise.  This
+// function always selects the nonnegative square root.
+func (fe *Element) InvSqrt(t *Element) (*Element, int) {
+	_, wasSquare := fe.inner.SqrtRatioI(&field.One, &t.inner)
+	return fe, wasSquare
+}
+
+// ConditionalSelect sets fe to a iff choice == 0 and b iff choice == 1.
+func (fe *Element) ConditionalSelect(a, b *Element, choice int) {
+	fe.inner.ConditionalSelect(&a.inner, &b.inner, choice)
+}
+
+// ConditionalSwap swaps fe and other iff choice == 1.
+func (fe *Element) ConditionalSwap(other *Element, choice int) {
+	fe.inner.ConditionalSwap(&other.inner, choice)
+}
+
+// ConditionalAssign sets fe to other iff choice == 1.
+func (fe *Element) ConditionalAssign(other *Element, choice int) {
+	fe.inner.ConditionalAssign(&other.inner, choice)
+}
+
+// ConditionalNegate negates fe iff choice == 1.
+func (fe *Element) ConditionalNegate(choice int) {
+	fe.inner.ConditionalNegate(choice)
+}
+
+// BatchInvert computes the inverses of slice of `Element`s in a batch,
+// sharing a single field inversion, and replaces each element by its
+// inverse.  This function will execute in constant-time.
+//
+// When an input Element is zero, its value is unchanged.
+func BatchInvert(inputs []*Element) {
+	innerInputs := make([]*field.Element, 0, len(inputs))
+	for _, input := range inputs {
+		innerInputs = append(innerInputs, &input.inner)
+	}
+	field.BatchInvert(innerInputs)
+}
+
+// IsCanonicalBytes returns true iff the input is the canonical 32-byte
+// little-endian encoding of a field element (`< p`, with the high bit
+// clear).  This function will execute in constant-time.
+func IsCanonicalBytes(in []byte) bool {
+	if len(in) != ElementSize {
+		return false
+	}
+
+	var (
+		fe      field.Element
+		encoded [ElementSize]byte
+	)
+	if _, err := fe.SetBytes(in); err != nil {
+		return false
+	}
+	_ = fe.ToBytes(encoded[:])
+
+	// SetBytes masks the high bit, so ToBytes(SetBytes(in)) == in iff
+	// the high bit is clear and in < p.
+	var v byte
+	for i := range encoded {
+		v |= encoded[i] ^ in[i]
+	}
+	return v == 0
+}
+
+// New returns a field element set to zero.
+func New() *Element {
+	return &Element{}
+}
+
+// NewFromBytes constructs a field element from the low 255 bits of a
+// 256-bit little-endian integer.
+//
+// WARNING: This function does not check that the input is the canonical
+// representative.  See SetBytes.
+func NewFromBytes(in []byte) (*Element, error) {
+	return New().SetBytes(in)
+}
+
+// NewFromCanonicalBytes attempts to construct a field element from a
+// canonical byte representation.
+func NewFromCanonicalBytes(in []byte) (*Element, error) {
+	return New().SetCanonicalBytes(in)
+}
+
+// NewFromBytesWide constructs a field element by reducing a 512-bit
+// little-endian integer modulo p.
+func NewFromBytesWide(in []byte) (*Element, error) {
+	return New().SetBytesWide(in)
+}
+
+// One returns a field element set to 1.
+func One() *Element {
+	return New().One()
+}
+
+// MinusOne returns a field element set to -1.
+func MinusOne() *Element {
+	return New().MinusOne()
+}
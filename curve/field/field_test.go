@@ -0,0 +1,372 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package field
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+var bigP = func() *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), 255)
+	return p.Sub(p, big.NewInt(19))
+}()
+
+func TestElement(t *testing.T) {
+	t.Run("Arithmetic", testArithmetic)
+	t.Run("Encoding/Canonical", testEncodingCanonical)
+	t.Run("Encoding/Wide", testEncodingWide)
+	t.Run("Encoding/MarshalBinary", testMarshalBinary)
+	t.Run("Constants", testConstants)
+	t.Run("Sqrt", testSqrt)
+	t.Run("InvSqrt", testInvSqrt)
+	t.Run("Conditional", testConditional)
+	t.Run("BatchInvert", testBatchInvert)
+}
+
+func testArithmetic(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		a, aBig := randomElement(t)
+		b, bBig := randomElement(t)
+
+		var fe Element
+		checkBig(t, "Add", fe.Add(a, b), new(big.Int).Add(aBig, bBig))
+		checkBig(t, "Sub", fe.Sub(a, b), new(big.Int).Sub(aBig, bBig))
+		checkBig(t, "Neg", fe.Neg(a), new(big.Int).Neg(aBig))
+		checkBig(t, "Mul", fe.Mul(a, b), new(big.Int).Mul(aBig, bBig))
+		checkBig(t, "Mul121666", fe.Mul121666(a), new(big.Int).Mul(aBig, big.NewInt(121666)))
+		checkBig(t, "Square", fe.Square(a), new(big.Int).Mul(aBig, aBig))
+		checkBig(t, "Square2", fe.Square2(a), new(big.Int).Lsh(new(big.Int).Mul(aBig, aBig), 1))
+		checkBig(t, "Pow2k", fe.Pow2k(a, 5), new(big.Int).Exp(aBig, big.NewInt(32), bigP))
+		checkBig(t, "Invert", fe.Invert(a), new(big.Int).ModInverse(aBig, bigP))
+	}
+
+	var fe Element
+	if fe.Invert(New()).IsZero() != 1 {
+		t.Fatalf("Invert(0) != 0")
+	}
+}
+
+func testEncodingCanonical(t *testing.T) {
+	pBytes := bigToBytes(bigP)
+	if IsCanonicalBytes(pBytes) {
+		t.Fatalf("IsCanonicalBytes(p) != false")
+	}
+	if _, err := NewFromCanonicalBytes(pBytes); err == nil {
+		t.Fatalf("NewFromCanonicalBytes(p) succeeded")
+	}
+	fe, err := NewFromBytes(pBytes)
+	if err != nil {
+		t.Fatalf("NewFromBytes(p): %v", err)
+	}
+	if fe.IsZero() != 1 {
+		t.Fatalf("NewFromBytes(p) != 0")
+	}
+
+	pMinusOne := bigToBytes(new(big.Int).Sub(bigP, big.NewInt(1)))
+	if !IsCanonicalBytes(pMinusOne) {
+		t.Fatalf("IsCanonicalBytes(p - 1) != true")
+	}
+	if fe, err = NewFromCanonicalBytes(pMinusOne); err != nil {
+		t.Fatalf("NewFromCanonicalBytes(p - 1): %v", err)
+	}
+	if fe.Equal(MinusOne()) != 1 {
+		t.Fatalf("NewFromCanonicalBytes(p - 1) != -1")
+	}
+
+	highBit := make([]byte, ElementSize)
+	highBit[0], highBit[31] = 1, 0x80
+	if IsCanonicalBytes(highBit) {
+		t.Fatalf("IsCanonicalBytes(high bit set) != false")
+	}
+	if _, err = NewFromCanonicalBytes(highBit); err == nil {
+		t.Fatalf("NewFromCanonicalBytes(high bit set) succeeded")
+	}
+	if fe, err = NewFromBytes(highBit); err != nil {
+		t.Fatalf("NewFromBytes(high bit set): %v", err)
+	}
+	if fe.Equal(One()) != 1 {
+		t.Fatalf("NewFromBytes(high bit set) did not mask")
+	}
+
+	if _, err = NewFromBytes(pMinusOne[:31]); err == nil {
+		t.Fatalf("NewFromBytes(truncated) succeeded")
+	}
+	if _, err = NewFromCanonicalBytes(pMinusOne[:31]); err == nil {
+		t.Fatalf("NewFromCanonicalBytes(truncated) succeeded")
+	}
+	if err = fe.ToBytes(make([]byte, 31)); err == nil {
+		t.Fatalf("ToBytes(truncated) succeeded")
+	}
+}
+
+func testEncodingWide(t *testing.T) {
+	var b [ElementWideSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	fe, err := NewFromBytesWide(b[:])
+	if err != nil {
+		t.Fatalf("NewFromBytesWide: %v", err)
+	}
+
+	expected := bytesToBig(b[:])
+	checkBig(t, "SetBytesWide", fe, expected)
+
+	if _, err = NewFromBytesWide(b[:32]); err == nil {
+		t.Fatalf("NewFromBytesWide(short) succeeded")
+	}
+}
+
+func testMarshalBinary(t *testing.T) {
+	a, _ := randomElement(t)
+
+	b, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	var a2 Element
+	if err = a2.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if a.Equal(&a2) != 1 {
+		t.Fatalf("a != UnmarshalBinary(MarshalBinary(a))")
+	}
+
+	if err = a2.UnmarshalBinary(bigToBytes(bigP)); err == nil {
+		t.Fatalf("UnmarshalBinary(p) succeeded")
+	}
+}
+
+func testConstants(t *testing.T) {
+	checkBig(t, "One", One(), big.NewInt(1))
+	checkBig(t, "MinusOne", MinusOne(), big.NewInt(-1))
+	checkBig(t, "Zero", One().Zero(), big.NewInt(0))
+
+	var fe Element
+	if fe.Square(SQRT_M1).Equal(MinusOne()) != 1 {
+		t.Fatalf("SQRT_M1^2 != -1")
+	}
+}
+
+func testSqrt(t *testing.T) {
+	var (
+		fe Element
+		sq Element
+	)
+	for i := 0; i < 100; i++ {
+		a, _ := randomElement(t)
+		sq.Square(a)
+
+		r, wasSquare := fe.Sqrt(&sq)
+		if wasSquare != 1 {
+			t.Fatalf("Sqrt(a^2): not square")
+		}
+		if r.IsNegative() != 0 {
+			t.Fatalf("Sqrt(a^2): negative root")
+		}
+		if sq.Square(r).Equal(new(Element).Square(a)) != 1 {
+			t.Fatalf("Sqrt(a^2)^2 != a^2")
+		}
+	}
+
+	// 2 is not a square mod p.
+	two := new(Element).Add(One(), One())
+	r, wasSquare := fe.Sqrt(two)
+	if wasSquare != 0 {
+		t.Fatalf("Sqrt(2): square")
+	}
+	if sq.Square(r).Equal(new(Element).Mul(two, SQRT_M1)) != 1 {
+		t.Fatalf("Sqrt(2)^2 != i*2")
+	}
+
+	// 0/0 is considered square, x/0 is not.
+	if _, wasSquare = fe.SqrtRatioI(New(), New()); wasSquare != 1 || fe.IsZero() != 1 {
+		t.Fatalf("SqrtRatioI(0, 0) != (0, 1)")
+	}
+	if _, wasSquare = fe.SqrtRatioI(One(), New()); wasSquare != 0 || fe.IsZero() != 1 {
+		t.Fatalf("SqrtRatioI(1, 0) != (0, 0)")
+	}
+}
+
+func testInvSqrt(t *testing.T) {
+	var fe, check Element
+	for i := 0; i < 100; i++ {
+		a, _ := randomElement(t)
+		sq := new(Element).Square(a)
+
+		r, wasSquare := fe.InvSqrt(sq)
+		if wasSquare != 1 {
+			t.Fatalf("InvSqrt(a^2): not square")
+		}
+		check.Square(r)
+		if check.Mul(&check, sq).Equal(One()) != 1 {
+			t.Fatalf("InvSqrt(a^2)^2 * a^2 != 1")
+		}
+	}
+
+	if _, wasSquare := fe.InvSqrt(New()); wasSquare != 0 {
+		t.Fatalf("InvSqrt(0): square")
+	}
+}
+
+func testConditional(t *testing.T) {
+	a, _ := randomElement(t)
+	b, _ := randomElement(t)
+
+	var fe Element
+	fe.ConditionalSelect(a, b, 0)
+	if fe.Equal(a) != 1 {
+		t.Fatalf("ConditionalSelect(a, b, 0) != a")
+	}
+	fe.ConditionalSelect(a, b, 1)
+	if fe.Equal(b) != 1 {
+		t.Fatalf("ConditionalSelect(a, b, 1) != b")
+	}
+
+	fe.Set(a)
+	fe.ConditionalAssign(b, 0)
+	if fe.Equal(a) != 1 {
+		t.Fatalf("ConditionalAssign(b, 0) != a")
+	}
+	fe.ConditionalAssign(b, 1)
+	if fe.Equal(b) != 1 {
+		t.Fatalf("ConditionalAssign(b, 1) != b")
+	}
+
+	fe.Set(a)
+	other := new(Element).Set(b)
+	fe.ConditionalSwap(other, 0)
+	if fe.Equal(a) != 1 || other.Equal(b) != 1 {
+		t.Fatalf("ConditionalSwap(other, 0) swapped")
+	}
+	fe.ConditionalSwap(other, 1)
+	if fe.Equal(b) != 1 || other.Equal(a) != 1 {
+		t.Fatalf("ConditionalSwap(other, 1) did not swap")
+	}
+
+	fe.Set(a)
+	fe.ConditionalNegate(0)
+	if fe.Equal(a) != 1 {
+		t.Fatalf("ConditionalNegate(0) != a")
+	}
+	fe.ConditionalNegate(1)
+	if fe.Equal(new(Element).Neg(a)) != 1 {
+		t.Fatalf("ConditionalNegate(1) != -a")
+	}
+}
+
+func testBatchInvert(t *testing.T) {
+	const n = 16
+
+	var (
+		inputs   []*Element
+		expected []*Element
+	)
+	for i := 0; i < n; i++ {
+		fe, _ := randomElement(t)
+		if i == n/2 {
+			fe.Zero()
+		}
+		inputs = append(inputs, fe)
+		expected = append(expected, new(Element).Invert(fe))
+	}
+
+	BatchInvert(inputs)
+	for i := range inputs {
+		if inputs[i].Equal(expected[i]) != 1 {
+			t.Fatalf("BatchInvert(inputs)[%d] != Invert(inputs[%d])", i, i)
+		}
+	}
+
+	BatchInvert(nil)
+}
+
+func randomElement(t *testing.T) (*Element, *big.Int) {
+	var b [ElementWideSize]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	fe, err := NewFromBytesWide(b[:])
+	if err != nil {
+		t.Fatalf("NewFromBytesWide: %v", err)
+	}
+
+	var feBytes [ElementSize]byte
+	if err = fe.ToBytes(feBytes[:]); err != nil {
+		t.Fatalf("ToBytes: %v", err)
+	}
+	if fe.IsZero() == 1 {
+		// Astronomically unlikely, but the tests assume non-zero.
+		fe.One()
+		return fe, big.NewInt(1)
+	}
+
+	return fe, bytesToBig(feBytes[:])
+}
+
+func checkBig(t *testing.T, op string, fe *Element, expected *big.Int) {
+	t.Helper()
+
+	expected = new(big.Int).Mod(expected, bigP)
+
+	var b [ElementSize]byte
+	if err := fe.ToBytes(b[:]); err != nil {
+		t.Fatalf("%s: ToBytes: %v", op, err)
+	}
+	if !bytes.Equal(b[:], bigToBytes(expected)) {
+		t.Fatalf("%s: got %v, expected %v", op, bytesToBig(b[:]), expected)
+	}
+	if fe.IsNegative() != int(expected.Bit(0)) {
+		t.Fatalf("%s: IsNegative mismatch", op)
+	}
+}
+
+func bytesToBig(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func bigToBytes(x *big.Int) []byte {
+	var b [ElementSize]byte
+	x.FillBytes(b[:])
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b[:]
+}
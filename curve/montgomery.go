@@ -87,27 +87,104 @@ func (p *MontgomeryPoint) SetEdwards(edwardsPoint *EdwardsPoint) *MontgomeryPoin
 	return p
 }
 
+// MontgomeryFromEdwardsBatch converts a slice of Edwards points to
+// MontgomeryPoints, sharing a single field inversion across the entire
+// batch.  This function will execute in constant-time.
+//
+// As with SetEdwards, the identity point of the edwards curve is
+// converted to the 2-torsion point (0, 0) on the Montgomery curve.
+//
+// WARNING: This routine will panic if len(out) != len(points).
+func MontgomeryFromEdwardsBatch(out []MontgomeryPoint, points []*EdwardsPoint) {
+	if len(out) != len(points) {
+		panic("curve/montgomery: mismatched output and point slice lengths")
+	}
+
+	// We have u = (1+y)/(1-y) = (Z+Y)/(Z-Y).
+	//
+	// BatchInvert leaves zero elements unchanged, so the identity
+	// point is converted to (0,0), as in SetEdwards.
+	recips := make([]field.Element, len(points))
+	recipPtrs := make([]*field.Element, len(points))
+	for i, point := range points {
+		recips[i].Sub(&point.inner.Z, &point.inner.Y)
+		recipPtrs[i] = &recips[i]
+	}
+	field.BatchInvert(recipPtrs)
+
+	var U, u field.Element
+	for i, point := range points {
+		U.Add(&point.inner.Z, &point.inner.Y)
+		u.Mul(&U, &recips[i])
+
+		_ = u.ToBytes(out[i][:])
+	}
+}
+
+// MulBasepoint sets `p = basepoint * scalar` in constant-time, and returns p.
+//
+// This is considerably faster than Mul, as the multiplication is done
+// on the Edwards curve with a precomputed table, and the result is
+// converted with the birational map.
+func (p *MontgomeryPoint) MulBasepoint(basepoint *EdwardsBasepointTable, scalar *scalar.Scalar) *MontgomeryPoint {
+	var edP EdwardsPoint
+	return p.SetEdwards(edP.MulBasepoint(basepoint, scalar))
+}
+
+// MontgomeryMulBasepointBatch sets `out[i] = basepoint * scalars[i]` in
+// constant-time, sharing a single field inversion across the entire
+// batch.
+//
+// WARNING: This routine will panic if len(out) != len(scalars).
+func MontgomeryMulBasepointBatch(out []MontgomeryPoint, basepoint *EdwardsBasepointTable, scalars []*scalar.Scalar) {
+	if len(out) != len(scalars) {
+		panic("curve/montgomery: mismatched output and scalar slice lengths")
+	}
+
+	edPoints := make([]EdwardsPoint, len(scalars))
+	edPointPtrs := make([]*EdwardsPoint, len(scalars))
+	for i, s := range scalars {
+		edPointPtrs[i] = edPoints[i].MulBasepoint(basepoint, s)
+	}
+
+	MontgomeryFromEdwardsBatch(out, edPointPtrs)
+}
+
 // Mul sets `p = point * scalar` in constant-time, and returns p.
 func (p *MontgomeryPoint) Mul(point *MontgomeryPoint, scalar *scalar.Scalar) *MontgomeryPoint {
-	// Algorithm 8 of Costello-Smith 2017.
-	var affineU field.Element
-	_, _ = affineU.SetBytes(point[:])
-	var x0, x1 montgomeryProjectivePoint
-	x0.identity()
-	x1.U.Set(&affineU)
-	x1.W.One()
+	var x0 montgomeryProjectivePoint
+	x0.mul(point, scalar)
 
-	bits := scalar.Bits()
+	return p.fromProjective(&x0)
+}
 
-	for i := 254; i >= 0; i-- {
-		choice := int(bits[i+1] ^ bits[i])
+// MontgomeryMulBatch sets `out[i] = points[i] * scalars[i]` in
+// constant-time, sharing a single field inversion across the entire
+// batch.
+//
+// WARNING: This routine will panic if len(out) != len(points) or
+// len(out) != len(scalars).
+func MontgomeryMulBatch(out []MontgomeryPoint, points []*MontgomeryPoint, scalars []*scalar.Scalar) {
+	if len(out) != len(points) || len(out) != len(scalars) {
+		panic("curve/montgomery: mismatched output, point and scalar slice lengths")
+	}
 
-		x0.conditionalSwap(&x1, choice)
-		montgomeryDifferentialAddAndDouble(&x0, &x1, &affineU)
+	projPoints := make([]montgomeryProjectivePoint, len(points))
+	recipPtrs := make([]*field.Element, len(points))
+	for i := range points {
+		projPoints[i].mul(points[i], scalars[i])
+		recipPtrs[i] = &projPoints[i].W
 	}
-	x0.conditionalSwap(&x1, int(bits[0]))
 
-	return p.fromProjective(&x0)
+	// BatchInvert leaves zero elements unchanged, so the point at
+	// infinity is converted to (0, 0), as in Mul (0.invert() = 0).
+	field.BatchInvert(recipPtrs)
+
+	var u field.Element
+	for i := range projPoints {
+		u.Mul(&projPoints[i].U, &projPoints[i].W)
+		_ = u.ToBytes(out[i][:])
+	}
 }
 
 func (p *MontgomeryPoint) fromProjective(pp *montgomeryProjectivePoint) *MontgomeryPoint {
@@ -174,6 +251,26 @@ type montgomeryProjectivePoint struct {
 	W field.Element
 }
 
+func (p *montgomeryProjectivePoint) mul(point *MontgomeryPoint, scalar *scalar.Scalar) {
+	// Algorithm 8 of Costello-Smith 2017.
+	var affineU field.Element
+	_, _ = affineU.SetBytes(point[:])
+	var x1 montgomeryProjectivePoint
+	p.identity()
+	x1.U.Set(&affineU)
+	x1.W.One()
+
+	bits := scalar.Bits()
+
+	for i := 254; i >= 0; i-- {
+		choice := int(bits[i+1] ^ bits[i])
+
+		p.conditionalSwap(&x1, choice)
+		montgomeryDifferentialAddAndDouble(p, &x1, &affineU)
+	}
+	p.conditionalSwap(&x1, int(bits[0]))
+}
+
 func (p *montgomeryProjectivePoint) identity() *montgomeryProjectivePoint {
 	p.U.One()
 	p.W.Zero()
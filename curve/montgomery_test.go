@@ -34,6 +34,7 @@ package curve
 import (
 	"testing"
 
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
 	"github.com/oasisprotocol/curve25519-voi/internal/field"
 )
 
@@ -43,6 +44,10 @@ func TestMontgomery(t *testing.T) {
 	t.Run("FromEdwards", testMontgomeryFromEdwards)
 	t.Run("Equal", testMontgomeryEqual)
 	t.Run("Mul", testMontgomeryMul)
+	t.Run("MulBatch", testMontgomeryMulBatch)
+	t.Run("MulBasepoint", testMontgomeryMulBasepoint)
+	t.Run("MulBasepointBatch", testMontgomeryMulBasepointBatch)
+	t.Run("FromEdwardsBatch", testMontgomeryFromEdwardsBatch)
 }
 
 func testMontgomeryEdwardsPointFromMontgomery(t *testing.T) {
@@ -127,3 +132,90 @@ func testMontgomeryMul(t *testing.T) {
 		t.Fatalf("s * p_edwards != s * p_montgomery (Got: %v, %v)", expectedMontgomery, result)
 	}
 }
+
+func testMontgomeryMulBatch(t *testing.T) {
+	var (
+		points  []*MontgomeryPoint
+		scalars []*scalar.Scalar
+	)
+	for i := 0; i < 8; i++ {
+		var p MontgomeryPoint
+		p.SetEdwards(newTestBenchRandomPoint(t))
+		points = append(points, &p)
+		scalars = append(scalars, newTestBenchRandomScalar(t))
+	}
+
+	// Low order points map to (0, 0).
+	var lowOrder MontgomeryPoint
+	lowOrder.SetEdwards(EIGHT_TORSION[1])
+	points = append(points, &lowOrder)
+	scalars = append(scalars, scalar.NewFromUint64(8))
+
+	out := make([]MontgomeryPoint, len(points))
+	MontgomeryMulBatch(out, points, scalars)
+	for i := range points {
+		var expected MontgomeryPoint
+		expected.Mul(points[i], scalars[i])
+		if out[i].Equal(&expected) != 1 {
+			t.Fatalf("MontgomeryMulBatch()[%d] != Mul() (Got: %v)", i, out[i])
+		}
+	}
+
+	MontgomeryMulBatch(nil, nil, nil) // Should not panic.
+}
+
+func testMontgomeryMulBasepoint(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		s := newTestBenchRandomScalar(t)
+
+		var expected, result MontgomeryPoint
+		expected.Mul(X25519_BASEPOINT, s)
+		result.MulBasepoint(ED25519_BASEPOINT_TABLE, s)
+		if result.Equal(&expected) != 1 {
+			t.Fatalf("MulBasepoint(s) != Mul(X25519_BASEPOINT, s) (Got: %v, %v)", result, expected)
+		}
+	}
+}
+
+func testMontgomeryMulBasepointBatch(t *testing.T) {
+	scalars := []*scalar.Scalar{
+		scalar.New(), // Identity, handled like SetEdwards.
+	}
+	for i := 0; i < 8; i++ {
+		scalars = append(scalars, newTestBenchRandomScalar(t))
+	}
+
+	out := make([]MontgomeryPoint, len(scalars))
+	MontgomeryMulBasepointBatch(out, ED25519_BASEPOINT_TABLE, scalars)
+	for i, s := range scalars {
+		var expected MontgomeryPoint
+		expected.MulBasepoint(ED25519_BASEPOINT_TABLE, s)
+		if out[i].Equal(&expected) != 1 {
+			t.Fatalf("MontgomeryMulBasepointBatch()[%d] != MulBasepoint() (Got: %v)", i, out[i])
+		}
+	}
+
+	MontgomeryMulBasepointBatch(nil, ED25519_BASEPOINT_TABLE, nil) // Should not panic.
+}
+
+func testMontgomeryFromEdwardsBatch(t *testing.T) {
+	points := []*EdwardsPoint{
+		NewEdwardsPoint().Identity(),
+		ED25519_BASEPOINT_POINT,
+		EIGHT_TORSION[1],
+		EIGHT_TORSION[4],
+	}
+	for i := 0; i < 8; i++ {
+		points = append(points, newTestBenchRandomPoint(t))
+	}
+
+	out := make([]MontgomeryPoint, len(points))
+	MontgomeryFromEdwardsBatch(out, points)
+	for i, p := range points {
+		var expected MontgomeryPoint
+		expected.SetEdwards(p)
+		if out[i].Equal(&expected) != 1 {
+			t.Fatalf("MontgomeryFromEdwardsBatch()[%d] != SetEdwards() (Got: %v)", i, out[i])
+		}
+	}
+}
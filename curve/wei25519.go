@@ -0,0 +1,299 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package curve
+
+import (
+	"fmt"
+
+	pubfield "github.com/oasisprotocol/curve25519-voi/curve/field"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
+)
+
+const (
+	// Wei25519CompressedSize is the size of a SEC1 compressed Wei25519
+	// point in bytes.
+	Wei25519CompressedSize = 1 + field.ElementSize
+
+	// Wei25519UncompressedSize is the size of a SEC1 uncompressed Wei25519
+	// point in bytes.
+	Wei25519UncompressedSize = 1 + 2*field.ElementSize
+
+	sec1Infinity     = 0x00
+	sec1CompressedY0 = 0x02
+	sec1CompressedY1 = 0x03
+	sec1Uncompressed = 0x04
+)
+
+var (
+	errWei25519InvalidEncoding = fmt.Errorf("curve/wei25519: invalid SEC1 encoding")
+	errWei25519NotOnCurve      = fmt.Errorf("curve/wei25519: point not on curve")
+)
+
+// Wei25519Point represents a point on Wei25519, the short Weierstrass
+// model of Curve25519 (draft-ietf-lwig-curve-representations), given by
+// `y^2 = x^3 + a*x + b`.
+//
+// Internally the point is stored as the isomorphic EdwardsPoint, with
+// the point at infinity corresponding to the Edwards identity.
+//
+// The default value is NOT valid and MUST only be used as a receiver.
+type Wei25519Point struct {
+	inner EdwardsPoint
+}
+
+// MarshalBinary encodes the Wei25519 point into the SEC1 compressed
+// binary form and returns the result.
+func (p *Wei25519Point) MarshalBinary() ([]byte, error) {
+	return p.CompressedBytes(), nil
+}
+
+// UnmarshalBinary decodes a SEC1 encoded Wei25519 point.
+func (p *Wei25519Point) UnmarshalBinary(data []byte) error {
+	p.Identity() // Foot + gun avoidance.
+
+	_, err := p.SetBytes(data)
+	return err
+}
+
+// Identity sets the Wei25519 point to the point at infinity.
+func (p *Wei25519Point) Identity() *Wei25519Point {
+	p.inner.Identity()
+	return p
+}
+
+// Set sets `p = t`, and returns p.
+func (p *Wei25519Point) Set(t *Wei25519Point) *Wei25519Point {
+	p.inner.Set(&t.inner)
+	return p
+}
+
+// SetEdwards sets the Wei25519 point to the point isomorphic to the
+// EdwardsPoint, and returns p.
+func (p *Wei25519Point) SetEdwards(edwardsPoint *EdwardsPoint) *Wei25519Point {
+	p.inner.Set(edwardsPoint)
+	return p
+}
+
+// SetMontgomery attempts to set the Wei25519 point to the point with
+// the x-coordinate corresponding to the MontgomeryPoint, and the
+// y-coordinate with the supplied parity (least significant bit), and
+// returns p.
+func (p *Wei25519Point) SetMontgomery(montgomeryU *MontgomeryPoint, parity uint8) (*Wei25519Point, error) {
+	var u field.Element
+	if _, err := u.SetBytes(montgomeryU[:]); err != nil {
+		return nil, err
+	}
+
+	if !p.inner.setMontgomeryU(&u, int(parity&1)) {
+		return nil, errWei25519NotOnCurve
+	}
+	return p, nil
+}
+
+// SetBytes attempts to decode a SEC1 encoded Wei25519 point, and returns
+// p.  The compressed, uncompressed, and point at infinity encodings are
+// supported.
+func (p *Wei25519Point) SetBytes(in []byte) (*Wei25519Point, error) {
+	if len(in) == 0 {
+		return nil, errWei25519InvalidEncoding
+	}
+
+	switch in[0] {
+	case sec1Infinity:
+		if len(in) != 1 {
+			return nil, errWei25519InvalidEncoding
+		}
+		return p.Identity(), nil
+	case sec1CompressedY0, sec1CompressedY1:
+		if len(in) != Wei25519CompressedSize {
+			return nil, errWei25519InvalidEncoding
+		}
+
+		var x field.Element
+		if !setCanonicalBytesBE(&x, in[1:]) {
+			return nil, errWei25519InvalidEncoding
+		}
+
+		// u = x - A/3
+		x.Sub(&x, &constWEI25519_DELTA)
+		if !p.inner.setMontgomeryU(&x, int(in[0]&1)) {
+			return nil, errWei25519NotOnCurve
+		}
+	case sec1Uncompressed:
+		if len(in) != Wei25519UncompressedSize {
+			return nil, errWei25519InvalidEncoding
+		}
+
+		var x, y field.Element
+		if !setCanonicalBytesBE(&x, in[1:1+field.ElementSize]) || !setCanonicalBytesBE(&y, in[1+field.ElementSize:]) {
+			return nil, errWei25519InvalidEncoding
+		}
+
+		// (u, v) = (x - A/3, y)
+		x.Sub(&x, &constWEI25519_DELTA)
+		if !p.inner.setMontgomeryCoordinates(&x, &y) {
+			return nil, errWei25519NotOnCurve
+		}
+	default:
+		return nil, errWei25519InvalidEncoding
+	}
+
+	return p, nil
+}
+
+// SetCoordinates attempts to set the Wei25519 point to the point with
+// the affine coordinates `(x, y)`, and returns p.
+func (p *Wei25519Point) SetCoordinates(x, y *pubfield.Element) (*Wei25519Point, error) {
+	if _, err := p.inner.SetWei25519Coordinates(x, y); err != nil {
+		return nil, errWei25519NotOnCurve
+	}
+	return p, nil
+}
+
+// Coordinates returns the affine coordinates `(x, y)` of the Wei25519
+// point.  The point at infinity has no affine representation, and an
+// error is returned instead.
+func (p *Wei25519Point) Coordinates() (x, y *pubfield.Element, err error) {
+	return p.inner.Wei25519Coordinates()
+}
+
+// CompressedBytes returns the SEC1 compressed encoding of the Wei25519
+// point.  The point at infinity is encoded as a single 0x00 byte.
+func (p *Wei25519Point) CompressedBytes() []byte {
+	var x, y field.Element
+	if !p.weierstrassCoordinates(&x, &y) {
+		return []byte{sec1Infinity}
+	}
+
+	out := make([]byte, Wei25519CompressedSize)
+	out[0] = sec1CompressedY0 | byte(y.IsNegative())
+	toBytesBE(out[1:], &x)
+	return out
+}
+
+// UncompressedBytes returns the SEC1 uncompressed encoding of the Wei25519
+// point.  The point at infinity is encoded as a single 0x00 byte.
+func (p *Wei25519Point) UncompressedBytes() []byte {
+	var x, y field.Element
+	if !p.weierstrassCoordinates(&x, &y) {
+		return []byte{sec1Infinity}
+	}
+
+	out := make([]byte, Wei25519UncompressedSize)
+	out[0] = sec1Uncompressed
+	toBytesBE(out[1:1+field.ElementSize], &x)
+	toBytesBE(out[1+field.ElementSize:], &y)
+	return out
+}
+
+// Equal returns 1 iff the points are equal, 0 otherwise.
+// This function will execute in constant-time.
+func (p *Wei25519Point) Equal(other *Wei25519Point) int {
+	return p.inner.Equal(&other.inner)
+}
+
+// IsIdentity returns true iff the point is the point at infinity.
+func (p *Wei25519Point) IsIdentity() bool {
+	return p.inner.IsIdentity()
+}
+
+func (p *Wei25519Point) weierstrassCoordinates(x, y *field.Element) bool {
+	if !p.inner.montgomeryCoordinates(x, y) {
+		return false
+	}
+
+	// x = u + A/3
+	x.Add(x, &constWEI25519_DELTA)
+	return true
+}
+
+// SetWei25519 sets the EdwardsPoint to the point isomorphic to the
+// Wei25519 point, and returns p.
+func (p *EdwardsPoint) SetWei25519(weiPoint *Wei25519Point) *EdwardsPoint {
+	return p.Set(&weiPoint.inner)
+}
+
+// SetWei25519 sets the MontgomeryPoint to the u-coordinate corresponding
+// to the Wei25519 point, and returns p.
+//
+// This function has one exceptional case; the point at infinity is set
+// to the 2-torsion point (0, 0) on the Montgomery curve.
+func (p *MontgomeryPoint) SetWei25519(weiPoint *Wei25519Point) *MontgomeryPoint {
+	return p.SetEdwards(&weiPoint.inner)
+}
+
+// NewWei25519Point constructs a new Wei25519 point.
+func NewWei25519Point() *Wei25519Point {
+	return &Wei25519Point{}
+}
+
+func (p *EdwardsPoint) setMontgomeryU(u *field.Element, parity int) bool {
+	// v^2 = u^3 + A*u^2 + u = u*((u+A)*u + 1)
+	var vv, v field.Element
+	vv.Add(u, &constMONTGOMERY_A)
+	vv.Mul(&vv, u)
+	vv.Add(&vv, &field.One)
+	vv.Mul(&vv, u)
+
+	_, wasSquare := v.SqrtRatioI(&vv, &field.One)
+	if wasSquare != 1 {
+		return false
+	}
+
+	// SqrtRatioI returns the nonnegative (even) root.  A zero v can
+	// only be encoded with an even parity.
+	if v.IsZero() == 1 && parity == 1 {
+		return false
+	}
+	v.ConditionalNegate(parity)
+
+	return p.setMontgomeryCoordinates(u, &v)
+}
+
+func setCanonicalBytesBE(fe *field.Element, in []byte) bool {
+	var b [field.ElementSize]byte
+	for i := range b {
+		b[i] = in[field.ElementSize-1-i]
+	}
+	if !pubfield.IsCanonicalBytes(b[:]) {
+		return false
+	}
+
+	_, err := fe.SetBytes(b[:])
+	return err == nil
+}
+
+func toBytesBE(out []byte, fe *field.Element) {
+	var b [field.ElementSize]byte
+	_ = fe.ToBytes(b[:])
+	for i := range b {
+		out[i] = b[field.ElementSize-1-i]
+	}
+}
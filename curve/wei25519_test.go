@@ -0,0 +1,235 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package curve
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+// draft-ietf-lwig-curve-representations-23 Appendix E.3.
+const (
+	testWei25519BasepointX = "2aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaad245a"
+	testWei25519BasepointY = "20ae19a1b8a086b4e01edd2c7748d14c923d4d7e6d7c61b229e9c5a27eced3d9"
+
+	// The 2-torsion point (A/3, 0).
+	testWei25519TwoTorsionX = "2aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaad2451"
+)
+
+func TestWei25519(t *testing.T) {
+	t.Run("Basepoint", testWei25519Basepoint)
+	t.Run("RoundTrip", testWei25519RoundTrip)
+	t.Run("Identity", testWei25519Identity)
+	t.Run("TwoTorsion", testWei25519TwoTorsion)
+	t.Run("Montgomery", testWei25519Montgomery)
+	t.Run("Invalid", testWei25519Invalid)
+}
+
+func testWei25519Basepoint(t *testing.T) {
+	p := NewWei25519Point().SetEdwards(ED25519_BASEPOINT_POINT)
+
+	expectedCompressed := testhelpers.MustUnhex(t, "03"+testWei25519BasepointX)
+	if b := p.CompressedBytes(); !bytes.Equal(b, expectedCompressed) {
+		t.Fatalf("CompressedBytes(B) != expected (Got: %x)", b)
+	}
+	expectedUncompressed := testhelpers.MustUnhex(t, "04"+testWei25519BasepointX+testWei25519BasepointY)
+	if b := p.UncompressedBytes(); !bytes.Equal(b, expectedUncompressed) {
+		t.Fatalf("UncompressedBytes(B) != expected (Got: %x)", b)
+	}
+
+	for _, b := range [][]byte{expectedCompressed, expectedUncompressed} {
+		q, err := NewWei25519Point().SetBytes(b)
+		if err != nil {
+			t.Fatalf("SetBytes(%x): %v", b, err)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetBytes(%x) != B", b)
+		}
+		if NewEdwardsPoint().SetWei25519(q).Equal(ED25519_BASEPOINT_POINT) != 1 {
+			t.Fatalf("SetWei25519(SetBytes(%x)) != B", b)
+		}
+	}
+
+	x, y, err := p.Coordinates()
+	if err != nil {
+		t.Fatalf("Coordinates(B): %v", err)
+	}
+	q, err := NewWei25519Point().SetCoordinates(x, y)
+	if err != nil {
+		t.Fatalf("SetCoordinates(B): %v", err)
+	}
+	if q.Equal(p) != 1 {
+		t.Fatalf("SetCoordinates(Coordinates(B)) != B")
+	}
+}
+
+func testWei25519RoundTrip(t *testing.T) {
+	for i, ep := range testEdwardsCoordinatesPoints(t) {
+		p := NewWei25519Point().SetEdwards(ep)
+
+		b, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(points[%d]): %v", i, err)
+		}
+		var q Wei25519Point
+		if err = q.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary(points[%d]): %v", i, err)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("UnmarshalBinary(MarshalBinary(points[%d])) != points[%d]", i, i)
+		}
+
+		if _, err = q.SetBytes(p.UncompressedBytes()); err != nil {
+			t.Fatalf("SetBytes(UncompressedBytes(points[%d])): %v", i, err)
+		}
+		if q.Equal(p) != 1 {
+			t.Fatalf("SetBytes(UncompressedBytes(points[%d])) != points[%d]", i, i)
+		}
+
+		// The negation only differs in the parity, unless y = 0.
+		neg := NewWei25519Point().SetEdwards(NewEdwardsPoint().Neg(ep))
+		if neg.Equal(p) == 1 {
+			continue
+		}
+		nb := neg.CompressedBytes()
+		if nb[0] == b[0] || !bytes.Equal(nb[1:], b[1:]) {
+			t.Fatalf("CompressedBytes(-points[%d]) != parity flip", i)
+		}
+	}
+}
+
+func testWei25519Identity(t *testing.T) {
+	p := NewWei25519Point().Identity()
+	if !p.IsIdentity() {
+		t.Fatalf("Identity().IsIdentity() != true")
+	}
+	for _, b := range [][]byte{p.CompressedBytes(), p.UncompressedBytes()} {
+		if !bytes.Equal(b, []byte{0x00}) {
+			t.Fatalf("Bytes(identity) != 0x00 (Got: %x)", b)
+		}
+	}
+	if _, _, err := p.Coordinates(); err == nil {
+		t.Fatalf("Coordinates(identity) succeeded")
+	}
+
+	q, err := NewWei25519Point().SetBytes([]byte{0x00})
+	if err != nil {
+		t.Fatalf("SetBytes(0x00): %v", err)
+	}
+	if !q.IsIdentity() {
+		t.Fatalf("SetBytes(0x00).IsIdentity() != true")
+	}
+	if !NewEdwardsPoint().SetWei25519(q).IsIdentity() {
+		t.Fatalf("SetWei25519(identity) != identity")
+	}
+}
+
+func testWei25519TwoTorsion(t *testing.T) {
+	p := NewWei25519Point().SetEdwards(EIGHT_TORSION[4])
+
+	expectedCompressed := testhelpers.MustUnhex(t, "02"+testWei25519TwoTorsionX)
+	if b := p.CompressedBytes(); !bytes.Equal(b, expectedCompressed) {
+		t.Fatalf("CompressedBytes((A/3, 0)) != expected (Got: %x)", b)
+	}
+
+	q, err := NewWei25519Point().SetBytes(expectedCompressed)
+	if err != nil {
+		t.Fatalf("SetBytes((A/3, 0)): %v", err)
+	}
+	if q.Equal(p) != 1 {
+		t.Fatalf("SetBytes((A/3, 0)) != EIGHT_TORSION[4]")
+	}
+
+	// y = 0 has no odd representative.
+	if _, err = NewWei25519Point().SetBytes(testhelpers.MustUnhex(t, "03"+testWei25519TwoTorsionX)); err == nil {
+		t.Fatalf("SetBytes(03 || A/3) succeeded")
+	}
+}
+
+func testWei25519Montgomery(t *testing.T) {
+	p, err := NewWei25519Point().SetMontgomery(X25519_BASEPOINT, 1)
+	if err != nil {
+		t.Fatalf("SetMontgomery(9, 1): %v", err)
+	}
+	if NewEdwardsPoint().SetWei25519(p).Equal(ED25519_BASEPOINT_POINT) != 1 {
+		t.Fatalf("SetMontgomery(9, 1) != B")
+	}
+
+	q, err := NewWei25519Point().SetMontgomery(X25519_BASEPOINT, 0)
+	if err != nil {
+		t.Fatalf("SetMontgomery(9, 0): %v", err)
+	}
+	if NewEdwardsPoint().SetWei25519(q).Equal(NewEdwardsPoint().Neg(ED25519_BASEPOINT_POINT)) != 1 {
+		t.Fatalf("SetMontgomery(9, 0) != -B")
+	}
+
+	for i, ep := range testEdwardsCoordinatesPoints(t) {
+		var expected, got MontgomeryPoint
+		expected.SetEdwards(ep)
+		got.SetWei25519(NewWei25519Point().SetEdwards(ep))
+		if got.Equal(&expected) != 1 {
+			t.Fatalf("MontgomeryPoint.SetWei25519(points[%d]) != SetEdwards(points[%d])", i, i)
+		}
+	}
+
+	// u = -1 is on the twist.
+	var onTwist MontgomeryPoint
+	_ = constMINUS_ONE.ToBytes(onTwist[:])
+	if _, err = NewWei25519Point().SetMontgomery(&onTwist, 0); err == nil {
+		t.Fatalf("SetMontgomery(-1, 0) succeeded")
+	}
+}
+
+func testWei25519Invalid(t *testing.T) {
+	for _, v := range []struct {
+		n string
+		b string
+	}{
+		{"Empty", ""},
+		{"Identity/Long", "0000"},
+		{"Prefix/Hybrid", "06" + testWei25519BasepointX + testWei25519BasepointY},
+		{"Prefix/Unknown", "05" + testWei25519BasepointX},
+		{"Compressed/Short", "03" + testWei25519BasepointX[2:]},
+		{"Compressed/NonCanonical", "02" + "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed"},
+		{"Compressed/NotOnCurve", "02" + "0000000000000000000000000000000000000000000000000000000000000002"},
+		{"Uncompressed/Short", "04" + testWei25519BasepointX},
+		{"Uncompressed/NotOnCurve", "04" + testWei25519BasepointX + testWei25519TwoTorsionX},
+		{"Uncompressed/NonCanonical", "04" + testWei25519BasepointX + "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+	} {
+		t.Run(v.n, func(t *testing.T) {
+			b := testhelpers.MustUnhex(t, v.b)
+			if _, err := NewWei25519Point().SetBytes(b); err == nil {
+				t.Fatalf("SetBytes(%x) succeeded", b)
+			}
+		})
+	}
+}
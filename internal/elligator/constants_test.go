@@ -43,6 +43,7 @@ func TestConstants(t *testing.T) {
 	t.Run("SqrtNegAPlusTwo", testConstantsSqrtNegAPlusTwo)
 	t.Run("UFactor", testConstantsUFactor)
 	t.Run("VFactor", testConstantsVFactor)
+	t.Run("NegNonSquare", testConstantsNegNonSquare)
 }
 
 func testConstantsA(t *testing.T) {
@@ -101,6 +102,14 @@ func testConstantsVFactor(t *testing.T) {
 	}
 }
 
+func testConstantsNegNonSquare(t *testing.T) {
+	expected := new(field.Element).Neg(&field.Two)
+
+	if constMONTGOMERY_NEG_NON_SQUARE.Equal(expected) != 1 {
+		t.Fatalf("NEG_NON_SQUARE != -2 (Got: %v)", constMONTGOMERY_NEG_NON_SQUARE)
+	}
+}
+
 func feFromUint64(x uint64) *field.Element {
 	var feBytes [field.ElementSize]byte
 	binary.LittleEndian.PutUint64(feBytes[0:8], x)
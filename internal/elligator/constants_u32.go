@@ -63,4 +63,10 @@ var (
 		32595774, 7943725, 57730914, 30054016, 54719391, 272472, 25146209, 2005654, 66782178,
 		22147949,
 	)
+
+	// NEG_NON_SQUARE = -2
+	constMONTGOMERY_NEG_NON_SQUARE = field.NewElement2625(
+		67108843, 33554431, 67108863, 33554431, 67108863, 33554431, 67108863, 33554431, 67108863,
+		33554431,
+	)
 )
@@ -75,4 +75,13 @@ var (
 		134597186663265,
 		1486323764102114,
 	)
+
+	// NEG_NON_SQUARE = -2
+	constMONTGOMERY_NEG_NON_SQUARE = field.NewElement51(
+		2251799813685227,
+		2251799813685247,
+		2251799813685247,
+		2251799813685247,
+		2251799813685247,
+	)
 )
@@ -58,7 +58,7 @@ func SetEdwardsFromXY(p *curve.EdwardsPoint, x, y *field.Element) *curve.Edwards
 // EdwardsFlavor computes EdwardsPoint corresponding to the provided Elligator 2
 // representative.
 func EdwardsFlavor(r *field.Element) *curve.EdwardsPoint {
-	u, v := montgomeryFlavor(r)
+	u, v := MontgomeryFlavor(r)
 
 	// Per RFC 7748: (x, y) = (sqrt(-486664)*u/v, (u-1)/(u+1))
 
@@ -91,9 +91,9 @@ func EdwardsFlavor(r *field.Element) *curve.EdwardsPoint {
 	return SetEdwardsFromXY(&p, &x, &y)
 }
 
-// montgomeryFlavor computes Montgomery u and v coordinates corresponding
+// MontgomeryFlavor computes Montgomery u and v coordinates corresponding
 // to the provided Elligator 2 representative.
-func montgomeryFlavor(r *field.Element) (field.Element, field.Element) {
+func MontgomeryFlavor(r *field.Element) (field.Element, field.Element) {
 	// This is based off the public domain python implementation by
 	// Loup Vaillant, taken from the Monocypher package
 	// (tests/gen/elligator.py).
@@ -144,3 +144,44 @@ func montgomeryFlavor(r *field.Element) (field.Element, field.Element) {
 
 	return u, v
 }
+
+// MontgomeryInverse computes the Elligator 2 representative corresponding
+// to the provided Montgomery u coordinate, returning 1 iff such a
+// representative exists, 0 otherwise.  Every point that has a representative
+// has exactly two, and useAlternate selects between them.  The returned
+// representative is always in the range [0, (p-1)/2], and thus fits
+// in 254 bits.
+//
+// This function will execute in constant-time with respect to u.
+func MontgomeryInverse(u *field.Element, useAlternate int) (field.Element, int) {
+	// This is based off crypto_elligator_rev from Monocypher, and uses
+	// the same choice of representative.
+	//
+	// Let sq = -non_square * u * (u+A)
+	// If sq is not a square, or u = -A, there is no mapping.
+	//
+	// Otherwise:
+	//   isr = invsqrt(-non_square * u * (u+A))
+	//   r   = isr * u      (the "default" representative)
+	//   r   = isr * (u+A)  (the "alternate" representative)
+
+	var (
+		t1, t2, t3 field.Element
+		isSquare   int
+	)
+
+	t1.Set(u)
+	t2.Add(&t1, &constMONTGOMERY_A)
+	t3.Mul(&t1, &t2)
+	t3.Mul(&t3, &constMONTGOMERY_NEG_NON_SQUARE)
+	_, isSquare = t3.InvSqrt()
+
+	t1.ConditionalAssign(&t2, useAlternate)
+	t3.Mul(&t1, &t3)
+
+	// Pick the root in [0, (p-1)/2].  2*r is odd iff r > (p-1)/2.
+	t1.Add(&t3, &t3)
+	t3.ConditionalNegate(t1.IsNegative())
+
+	return t3, isSquare
+}
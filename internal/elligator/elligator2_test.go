@@ -51,7 +51,7 @@ func montgomeryFromUniformBytes(in []byte) (*curve.MontgomeryPoint, error) {
 	}
 
 	var p curve.MontgomeryPoint
-	u, _ := montgomeryFlavor(&r)
+	u, _ := MontgomeryFlavor(&r)
 	_ = u.ToBytes(p[:])
 
 	return &p, nil
@@ -0,0 +1,357 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+// BatchVerifier accumulates range proofs, so that they can be verified
+// together, which is considerably faster than verifying each proof
+// individually.
+type BatchVerifier struct {
+	bpGens *BulletproofGens
+	pcGens *PedersenGens
+
+	entries []*batchEntry
+	err     error
+}
+
+// batchEntry holds the terms of the two verification equations of a
+// single range proof.  The first combines the inner-product argument
+// with the commitments A and S, the second checks the polynomial
+// commitments T_1 and T_2 against the value commitments.
+type batchEntry struct {
+	n, m int
+
+	bScalar1, bBlindingScalar1 *scalar.Scalar
+	gScalars, hScalars         []*scalar.Scalar
+	scalars1                   []*scalar.Scalar
+	points1                    []*curve.RistrettoPoint
+
+	bScalar2, bBlindingScalar2 *scalar.Scalar
+	scalars2                   []*scalar.Scalar
+	points2                    []*curve.RistrettoPoint
+}
+
+// Add adds a range proof for the commitments, with the range `[0, 2^n)`,
+// to the current batch.  The transcript is used as-is and is advanced
+// exactly as in individual verification.
+func (v *BatchVerifier) Add(
+	transcript *merlin.Transcript,
+	proof *RangeProof,
+	valueCommitments []*curve.CompressedRistretto,
+	n int,
+) {
+	e, err := newBatchEntry(v.bpGens, transcript, proof, valueCommitments, n)
+	if err != nil {
+		if v.err == nil {
+			v.err = err
+		}
+		return
+	}
+	v.entries = append(v.entries, e)
+}
+
+// Verify checks all proofs in the current batch using entropy from
+// rand, returning nil iff all proofs are valid.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// If a failure arises it is unknown which proof failed, the caller
+// must verify each proof individually.
+func (v *BatchVerifier) Verify(rand io.Reader) error {
+	switch {
+	case v.err != nil:
+		return v.err
+	case len(v.entries) == 0:
+		// Abort early on an empty batch, which probably indicates a bug.
+		return ErrVerification
+	}
+
+	// The generator scalars are accumulated per party, as the
+	// generators for an aggregated proof are the concatenation of
+	// the first n generators of each party.
+	var maxN, maxM, numDynamic int
+	for _, e := range v.entries {
+		if e.n > maxN {
+			maxN = e.n
+		}
+		if e.m > maxM {
+			maxM = e.m
+		}
+		numDynamic += len(e.points1) + len(e.points2)
+	}
+	gAcc, hAcc := make([][]*scalar.Scalar, maxM), make([][]*scalar.Scalar, maxM)
+	for j := 0; j < maxM; j++ {
+		gAcc[j], hAcc[j] = newScalars(maxN), newScalars(maxN)
+	}
+
+	bScalar, bBlindingScalar := scalar.New(), scalar.New()
+	dynamicScalars := make([]*scalar.Scalar, 0, numDynamic+2*maxN*maxM)
+	dynamicPoints := make([]*curve.RistrettoPoint, 0, numDynamic+2*maxN*maxM)
+
+	var tmp scalar.Scalar
+	for _, e := range v.entries {
+		// Each proof's first equation is weighted by a random r,
+		// and the second by a random r * c.
+		r, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return err
+		}
+		c, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return err
+		}
+		rc := scalar.New().Mul(r, c)
+
+		bScalar.Add(bScalar, tmp.Mul(r, e.bScalar1))
+		bScalar.Add(bScalar, tmp.Mul(rc, e.bScalar2))
+		bBlindingScalar.Add(bBlindingScalar, tmp.Mul(r, e.bBlindingScalar1))
+		bBlindingScalar.Add(bBlindingScalar, tmp.Mul(rc, e.bBlindingScalar2))
+
+		for k := range e.gScalars {
+			j, i := k/e.n, k%e.n
+			gAcc[j][i].Add(gAcc[j][i], tmp.Mul(r, e.gScalars[k]))
+			hAcc[j][i].Add(hAcc[j][i], tmp.Mul(r, e.hScalars[k]))
+		}
+
+		for i := range e.scalars1 {
+			dynamicScalars = append(dynamicScalars, scalar.New().Mul(r, e.scalars1[i]))
+		}
+		dynamicPoints = append(dynamicPoints, e.points1...)
+		for i := range e.scalars2 {
+			dynamicScalars = append(dynamicScalars, scalar.New().Mul(rc, e.scalars2[i]))
+		}
+		dynamicPoints = append(dynamicPoints, e.points2...)
+	}
+
+	for j := 0; j < maxM; j++ {
+		dynamicScalars = append(dynamicScalars, gAcc[j]...)
+		dynamicPoints = append(dynamicPoints, v.bpGens.g[j][:maxN]...)
+		dynamicScalars = append(dynamicScalars, hAcc[j]...)
+		dynamicPoints = append(dynamicPoints, v.bpGens.h[j][:maxN]...)
+	}
+
+	check := curve.NewRistrettoPoint().ExpandedMultiscalarMulVartime(
+		[]*scalar.Scalar{bScalar, bBlindingScalar},
+		[]*curve.ExpandedRistrettoPoint{v.pcGens.expandedB, v.pcGens.expandedBBlinding},
+		dynamicScalars,
+		dynamicPoints,
+	)
+	if !check.IsIdentity() {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+func newBatchEntry(
+	bpGens *BulletproofGens,
+	transcript *merlin.Transcript,
+	proof *RangeProof,
+	valueCommitments []*curve.CompressedRistretto,
+	n int,
+) (*batchEntry, error) {
+	m := len(valueCommitments)
+	if !isValidBitsize(n) {
+		return nil, ErrInvalidBitsize
+	}
+	if !isPowerOf2(m) {
+		return nil, ErrInvalidAggregation
+	}
+	if err := bpGens.check(n, m); err != nil {
+		return nil, err
+	}
+
+	// First, replay the "interactive" protocol using the proof data
+	// to recompute all challenges.
+	transcriptRangeProofDomainSep(transcript, uint64(n), uint64(m))
+
+	for _, V := range valueCommitments {
+		// Allow the commitments to be zero (0 value, 0 blinding).
+		transcriptAppendPoint(transcript, "V", V)
+	}
+
+	if err := transcriptValidateAndAppendPoint(transcript, "A", &proof.A); err != nil {
+		return nil, err
+	}
+	if err := transcriptValidateAndAppendPoint(transcript, "S", &proof.S); err != nil {
+		return nil, err
+	}
+
+	y := transcriptChallengeScalar(transcript, "y")
+	z := transcriptChallengeScalar(transcript, "z")
+	zz := scalar.New().Mul(z, z)
+	minusZ := scalar.New().Neg(z)
+
+	if err := transcriptValidateAndAppendPoint(transcript, "T_1", &proof.T1); err != nil {
+		return nil, err
+	}
+	if err := transcriptValidateAndAppendPoint(transcript, "T_2", &proof.T2); err != nil {
+		return nil, err
+	}
+
+	x := transcriptChallengeScalar(transcript, "x")
+
+	transcriptAppendScalar(transcript, "t_x", &proof.tX)
+	transcriptAppendScalar(transcript, "t_x_blinding", &proof.tXBlinding)
+	transcriptAppendScalar(transcript, "e_blinding", &proof.eBlinding)
+
+	w := transcriptChallengeScalar(transcript, "w")
+
+	uSq, uInvSq, s, err := proof.ipp.verificationScalars(n*m, transcript)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decompress all of the points.
+	points1 := make([]*curve.RistrettoPoint, 0, 2+2*len(uSq))
+	for _, compressed := range []*curve.CompressedRistretto{&proof.A, &proof.S} {
+		p, err := curve.NewRistrettoPoint().SetCompressed(compressed)
+		if err != nil {
+			return nil, ErrVerification
+		}
+		points1 = append(points1, p)
+	}
+	for _, vec := range [][]curve.CompressedRistretto{proof.ipp.lVec, proof.ipp.rVec} {
+		for i := range vec {
+			p, err := curve.NewRistrettoPoint().SetCompressed(&vec[i])
+			if err != nil {
+				return nil, ErrVerification
+			}
+			points1 = append(points1, p)
+		}
+	}
+	points2 := make([]*curve.RistrettoPoint, 0, 2+m)
+	for _, compressed := range append([]*curve.CompressedRistretto{&proof.T1, &proof.T2}, valueCommitments...) {
+		p, err := curve.NewRistrettoPoint().SetCompressed(compressed)
+		if err != nil {
+			return nil, ErrVerification
+		}
+		points2 = append(points2, p)
+	}
+
+	a, b := &proof.ipp.a, &proof.ipp.b
+	e := &batchEntry{
+		n: n,
+		m: m,
+	}
+
+	// First equation:
+	//
+	//   A + x*S + sum(u_i^2 * L_i) + sum(u_i^-2 * R_i)
+	//   - e_blinding * B_blinding + w * (t_x - a*b) * B
+	//   + <-z - a*s, G> + <z + y^-i * (z^2 * z^(j) * 2^i - b/s_i), H> = 0
+	e.scalars1 = make([]*scalar.Scalar, 0, len(points1))
+	e.scalars1 = append(e.scalars1, scalar.One(), x)
+	e.scalars1 = append(e.scalars1, uSq...)
+	e.scalars1 = append(e.scalars1, uInvSq...)
+	e.points1 = points1
+
+	e.bBlindingScalar1 = scalar.New().Neg(&proof.eBlinding)
+	e.bScalar1 = scalar.New().Mul(a, b)
+	e.bScalar1.Sub(&proof.tX, e.bScalar1)
+	e.bScalar1.Mul(e.bScalar1, w)
+
+	powersOf2 := powers(scalar.NewFromUint64(2), n)
+	powersOfZ := powers(z, m)
+	yInv := scalar.New().Invert(y)
+	expYInv := scalar.One()
+	nm := n * m
+	e.gScalars, e.hScalars = make([]*scalar.Scalar, nm), make([]*scalar.Scalar, nm)
+	var tmp scalar.Scalar
+	for k := 0; k < nm; k++ {
+		e.gScalars[k] = scalar.New().Mul(a, s[k])
+		e.gScalars[k].Sub(minusZ, e.gScalars[k])
+
+		zAnd2 := scalar.New().Mul(powersOfZ[k/n], powersOf2[k%n])
+		h := scalar.New().Mul(zz, zAnd2)
+		h.Sub(h, tmp.Mul(b, s[nm-1-k]))
+		h.Mul(h, expYInv)
+		e.hScalars[k] = h.Add(h, z)
+
+		expYInv.Mul(expYInv, yInv)
+	}
+
+	// Second equation:
+	//
+	//   x*T_1 + x^2*T_2 - t_x_blinding * B_blinding
+	//   + (delta(y, z) - t_x) * B + sum(z^2 * z^j * V_j) = 0
+	e.scalars2 = make([]*scalar.Scalar, 0, len(points2))
+	e.scalars2 = append(e.scalars2, x, scalar.New().Mul(x, x))
+	for j := 0; j < m; j++ {
+		e.scalars2 = append(e.scalars2, scalar.New().Mul(zz, powersOfZ[j]))
+	}
+	e.points2 = points2
+
+	e.bBlindingScalar2 = scalar.New().Neg(&proof.tXBlinding)
+	e.bScalar2 = delta(n, m, y, z)
+	e.bScalar2.Sub(e.bScalar2, &proof.tX)
+
+	return e, nil
+}
+
+// delta computes `(z - z^2) * <1, y^(n*m)> - z^3 * <1, 2^n> * <1, z^m>`.
+func delta(n, m int, y, z *scalar.Scalar) *scalar.Scalar {
+	sumY := sumOfPowers(y, n*m)
+	sum2 := sumOfPowers(scalar.NewFromUint64(2), n)
+	sumZ := sumOfPowers(z, m)
+
+	zz := scalar.New().Mul(z, z)
+	ret := scalar.New().Sub(z, zz)
+	ret.Mul(ret, sumY)
+
+	zzz := scalar.New().Mul(zz, z)
+	zzz.Mul(zzz, sum2)
+	zzz.Mul(zzz, sumZ)
+
+	return ret.Sub(ret, zzz)
+}
+
+func newScalars(n int) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, n)
+	for i := range ret {
+		ret[i] = scalar.New()
+	}
+	return ret
+}
+
+// NewBatchVerifier creates an empty BatchVerifier for proofs made with
+// the provided generators.
+func NewBatchVerifier(bpGens *BulletproofGens, pcGens *PedersenGens) *BatchVerifier {
+	return &BatchVerifier{
+		bpGens: bpGens,
+		pcGens: pcGens,
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package bulletproofs implements Bulletproofs range proofs over the
+// ristretto255 group, using Merlin transcripts for the Fiat-Shamir
+// transform.
+//
+// The generators, transcript protocol, and proof encoding follow those
+// of the Rust `bulletproofs` crate.  Interoperability has not yet been
+// tested against proofs produced by that implementation.
+package bulletproofs
+
+import (
+	"fmt"
+
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+var (
+	// ErrVerification is the error returned when a proof fails to verify.
+	ErrVerification = fmt.Errorf("bulletproofs: proof verification failed")
+
+	// ErrFormat is the error returned when a proof is malformed.
+	ErrFormat = fmt.Errorf("bulletproofs: proof data could not be parsed")
+
+	// ErrInvalidBitsize is the error returned when the requested range
+	// is not one of 8, 16, 32, or 64 bits.
+	ErrInvalidBitsize = fmt.Errorf("bulletproofs: invalid bitsize, must be 8, 16, 32, or 64")
+
+	// ErrInvalidAggregation is the error returned when the number of
+	// aggregated values is not a power of 2.
+	ErrInvalidAggregation = fmt.Errorf("bulletproofs: invalid aggregation size, must be a power of 2")
+
+	// ErrInvalidGeneratorsLength is the error returned when the
+	// generators are insufficient for the requested proof.
+	ErrInvalidGeneratorsLength = fmt.Errorf("bulletproofs: insufficient generators for proof")
+
+	// ErrWrongNumBlindingFactors is the error returned when the number
+	// of blinding factors does not match the number of values.
+	ErrWrongNumBlindingFactors = fmt.Errorf("bulletproofs: wrong number of blinding factors")
+
+	// ErrValueOutOfRange is the error returned when a value to be proven
+	// does not fit in the requested number of bits.
+	ErrValueOutOfRange = fmt.Errorf("bulletproofs: value out of range")
+)
+
+func isValidBitsize(n int) bool {
+	switch n {
+	case 8, 16, 32, 64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isPowerOf2(m int) bool {
+	return m > 0 && m&(m-1) == 0
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const testTranscriptLabel = "AggregatedRangeProofTest"
+
+func testProve(t *testing.T, bpGens *BulletproofGens, pcGens *PedersenGens, n, m int) (*RangeProof, []*curve.CompressedRistretto, []byte) {
+	values := make([]uint64, m)
+	blindings := make([]*scalar.Scalar, m)
+	for j := 0; j < m; j++ {
+		// Exercise both the extremes, and something in between.
+		switch j % 3 {
+		case 0:
+			values[j] = 0
+		case 1:
+			values[j] = ^uint64(0) >> uint(64-n)
+		case 2:
+			values[j] = uint64(0x0123456789abcdef) >> uint(64-n)
+		}
+		s, err := scalar.New().SetRandom(nil)
+		if err != nil {
+			t.Fatalf("scalar.SetRandom: %v", err)
+		}
+		blindings[j] = s
+	}
+
+	proof, commitments, err := ProveMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), values, blindings, n, nil)
+	if err != nil {
+		t.Fatalf("ProveMultiple: %v", err)
+	}
+	if len(commitments) != m {
+		t.Fatalf("unexpected number of commitments: %d", len(commitments))
+	}
+	for j, V := range commitments {
+		var expected curve.CompressedRistretto
+		expected.SetRistrettoPoint(pcGens.Commit(scalar.NewFromUint64(values[j]), blindings[j]))
+		if V.Equal(&expected) != 1 {
+			t.Fatalf("commitment %d mismatch", j)
+		}
+	}
+
+	b, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("proof.MarshalBinary: %v", err)
+	}
+
+	return proof, commitments, b
+}
+
+func TestGenerators(t *testing.T) {
+	t.Run("PedersenGens", func(t *testing.T) {
+		pcGens := NewPedersenGens()
+
+		var b, bBlinding curve.CompressedRistretto
+		b.SetRistrettoPoint(pcGens.B)
+		bBlinding.SetRistrettoPoint(pcGens.BBlinding)
+		if b.Equal(curve.RISTRETTO_BASEPOINT_COMPRESSED) != 1 {
+			t.Fatalf("B is not the basepoint")
+		}
+
+		// From the Rust `bulletproofs` crate.
+		expected := testhelpers.MustUnhex(t, "8c9240b456a9e6dc65c377a1048d745f94a08cdb7f44cbcd7b46f34048871134")
+		if !bytes.Equal(bBlinding[:], expected) {
+			t.Fatalf("B_blinding mismatch: %x", bBlinding[:])
+		}
+	})
+	t.Run("BulletproofGens/IncreaseCapacity", func(t *testing.T) {
+		bpGens := NewBulletproofGens(8, 2)
+		bpGens.IncreaseCapacity(64)
+		if bpGens.GensCapacity() != 64 || bpGens.PartyCapacity() != 2 {
+			t.Fatalf("unexpected capacity: %d, %d", bpGens.GensCapacity(), bpGens.PartyCapacity())
+		}
+
+		expected := NewBulletproofGens(64, 2)
+		for j := 0; j < 2; j++ {
+			for i := 0; i < 64; i++ {
+				if bpGens.g[j][i].Equal(expected.g[j][i]) != 1 || bpGens.h[j][i].Equal(expected.h[j][i]) != 1 {
+					t.Fatalf("generator (%d, %d) mismatch", j, i)
+				}
+			}
+		}
+
+		// The aggregated generators are the per-party prefixes.
+		G := bpGens.aggregatedG(8, 2)
+		if len(G) != 16 || G[8].Equal(bpGens.g[1][0]) != 1 {
+			t.Fatalf("aggregated generators mismatch")
+		}
+	})
+}
+
+func TestRangeProof(t *testing.T) {
+	bpGens := NewBulletproofGens(64, 8)
+	pcGens := NewPedersenGens()
+
+	for _, n := range []int{8, 16, 32, 64} {
+		for _, m := range []int{1, 2, 4, 8} {
+			n, m := n, m
+			t.Run(fmt.Sprintf("n=%d,m=%d", n, m), func(t *testing.T) {
+				proof, commitments, b := testProve(t, bpGens, pcGens, n, m)
+
+				// Each proof is `2 * lg(n*m) + 9` elements.
+				lgNM := len(proof.ipp.lVec)
+				if 1<<uint(lgNM) != n*m || len(b) != (2*lgNM+9)*32 {
+					t.Fatalf("unexpected proof size: %d", len(b))
+				}
+
+				proof2, err := NewRangeProofFromBytes(b)
+				if err != nil {
+					t.Fatalf("NewRangeProofFromBytes: %v", err)
+				}
+				b2, _ := proof2.MarshalBinary()
+				if !bytes.Equal(b, b2) {
+					t.Fatalf("serialization round-trip mismatch")
+				}
+
+				if err = proof2.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), commitments, n); err != nil {
+					t.Fatalf("VerifyMultiple: %v", err)
+				}
+				if m == 1 {
+					if err = proof2.VerifySingle(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), commitments[0], n); err != nil {
+						t.Fatalf("VerifySingle: %v", err)
+					}
+				}
+
+				if err = proof2.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript("BadTranscript"), commitments, n); err != ErrVerification {
+					t.Fatalf("VerifyMultiple(bad transcript): %v", err)
+				}
+
+				badCommitments := append([]*curve.CompressedRistretto{}, commitments...)
+				badCommitments[0] = curve.RISTRETTO_BASEPOINT_COMPRESSED
+				if err = proof2.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), badCommitments, n); err != ErrVerification {
+					t.Fatalf("VerifyMultiple(bad commitment): %v", err)
+				}
+			})
+		}
+	}
+	t.Run("Tampered", func(t *testing.T) {
+		_, commitments, b := testProve(t, bpGens, pcGens, 32, 2)
+
+		for _, off := range []int{0, 32, 64, 96, 128, 160, 192, 224, 256, len(b) - 64, len(b) - 32} {
+			tampered := append([]byte{}, b...)
+			tampered[off] ^= 0x01
+
+			proof, err := NewRangeProofFromBytes(tampered)
+			if err != nil {
+				// Flipping a bit may produce non-canonical encodings.
+				continue
+			}
+			if err = proof.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), commitments, 32); err == nil {
+				t.Fatalf("VerifyMultiple(tampered @ %d): succeeded", off)
+			}
+		}
+
+		identityA := append([]byte{}, b...)
+		copy(identityA[:32], make([]byte, 32))
+		proof, err := NewRangeProofFromBytes(identityA)
+		if err != nil {
+			t.Fatalf("NewRangeProofFromBytes(identity A): %v", err)
+		}
+		if err = proof.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), commitments, 32); err != ErrVerification {
+			t.Fatalf("VerifyMultiple(identity A): %v", err)
+		}
+
+		if err = proof.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testTranscriptLabel), commitments, 64); err != ErrVerification {
+			t.Fatalf("VerifyMultiple(wrong n): %v", err)
+		}
+	})
+	t.Run("Malformed", func(t *testing.T) {
+		_, _, b := testProve(t, bpGens, pcGens, 8, 1)
+
+		for _, l := range []int{0, 31, 7 * 32, 8 * 32, len(b) - 1, len(b) + 32} {
+			bad := make([]byte, l)
+			copy(bad, b)
+			if _, err := NewRangeProofFromBytes(bad); err != ErrFormat {
+				t.Fatalf("NewRangeProofFromBytes(len = %d): %v", l, err)
+			}
+		}
+
+		nonCanonical := append([]byte{}, b...)
+		for i := 128; i < 160; i++ {
+			nonCanonical[i] = 0xff
+		}
+		if _, err := NewRangeProofFromBytes(nonCanonical); err != ErrFormat {
+			t.Fatalf("NewRangeProofFromBytes(non-canonical t_x): %v", err)
+		}
+	})
+	t.Run("BadParameters", func(t *testing.T) {
+		blinding := scalar.One()
+		transcript := merlin.NewTranscript(testTranscriptLabel)
+
+		if _, _, err := ProveSingle(bpGens, pcGens, transcript, 256, blinding, 8, nil); err != ErrValueOutOfRange {
+			t.Fatalf("ProveSingle(out of range): %v", err)
+		}
+		if _, _, err := ProveSingle(bpGens, pcGens, transcript, 1, blinding, 12, nil); err != ErrInvalidBitsize {
+			t.Fatalf("ProveSingle(bad bitsize): %v", err)
+		}
+		if _, _, err := ProveSingle(NewBulletproofGens(32, 1), pcGens, transcript, 1, blinding, 64, nil); err != ErrInvalidGeneratorsLength {
+			t.Fatalf("ProveSingle(insufficient generators): %v", err)
+		}
+		values, blindings := []uint64{1, 2, 3}, []*scalar.Scalar{blinding, blinding, blinding}
+		if _, _, err := ProveMultiple(bpGens, pcGens, transcript, values, blindings, 8, nil); err != ErrInvalidAggregation {
+			t.Fatalf("ProveMultiple(bad aggregation): %v", err)
+		}
+		if _, _, err := ProveMultiple(bpGens, pcGens, transcript, values[:2], blindings, 8, nil); err != ErrWrongNumBlindingFactors {
+			t.Fatalf("ProveMultiple(wrong number of blindings): %v", err)
+		}
+		if _, _, err := ProveMultiple(bpGens, pcGens, transcript, nil, nil, 8, nil); err != ErrInvalidAggregation {
+			t.Fatalf("ProveMultiple(empty): %v", err)
+		}
+	})
+}
+
+type rangeProofTestVectors struct {
+	Label   string                 `json:"label"`
+	Vectors []rangeProofTestVector `json:"vectors"`
+}
+
+type rangeProofTestVector struct {
+	N           int      `json:"n"`
+	Commitments []string `json:"commitments"`
+	Proof       string   `json:"proof"`
+}
+
+func TestRangeProofVectors(t *testing.T) {
+	// Fixed single and aggregated proofs for every supported bitsize.
+	// Proofs produced by the Rust crate are not available here, so
+	// these were checked against an independent verifier for the
+	// crate's protocol (Merlin transcript, SHAKE256 generators, and the
+	// range and inner product proof equations), written from scratch.
+	f, err := os.Open("testdata/range_proofs.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	var testVectors rangeProofTestVectors
+	if err = json.NewDecoder(rd).Decode(&testVectors); err != nil {
+		t.Fatal(err)
+	}
+
+	bpGens := NewBulletproofGens(64, 4)
+	pcGens := NewPedersenGens()
+	batchVerifier := NewBatchVerifier(bpGens, pcGens)
+
+	for _, vec := range testVectors.Vectors {
+		vec := vec
+		t.Run(fmt.Sprintf("n=%d,m=%d", vec.N, len(vec.Commitments)), func(t *testing.T) {
+			commitments := make([]*curve.CompressedRistretto, 0, len(vec.Commitments))
+			for _, v := range vec.Commitments {
+				var V curve.CompressedRistretto
+				if _, err := V.SetBytes(testhelpers.MustUnhex(t, v)); err != nil {
+					t.Fatalf("CompressedRistretto.SetBytes: %v", err)
+				}
+				commitments = append(commitments, &V)
+			}
+
+			b := testhelpers.MustUnhex(t, vec.Proof)
+			proof, err := NewRangeProofFromBytes(b)
+			if err != nil {
+				t.Fatalf("NewRangeProofFromBytes: %v", err)
+			}
+			b2, err := proof.MarshalBinary()
+			if err != nil {
+				t.Fatalf("proof.MarshalBinary: %v", err)
+			}
+			if !bytes.Equal(b, b2) {
+				t.Fatalf("serialization round-trip mismatch")
+			}
+
+			if err = proof.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript(testVectors.Label), commitments, vec.N); err != nil {
+				t.Fatalf("VerifyMultiple: %v", err)
+			}
+			if len(commitments) == 1 {
+				if err = proof.VerifySingle(bpGens, pcGens, merlin.NewTranscript(testVectors.Label), commitments[0], vec.N); err != nil {
+					t.Fatalf("VerifySingle: %v", err)
+				}
+			}
+			if err = proof.VerifyMultiple(bpGens, pcGens, merlin.NewTranscript("BadTranscript"), commitments, vec.N); err != ErrVerification {
+				t.Fatalf("VerifyMultiple(bad transcript): %v", err)
+			}
+
+			batchVerifier.Add(merlin.NewTranscript(testVectors.Label), proof, commitments, vec.N)
+		})
+	}
+
+	if err = batchVerifier.Verify(nil); err != nil {
+		t.Fatalf("BatchVerifier.Verify: %v", err)
+	}
+}
+
+func TestBatchVerifier(t *testing.T) {
+	bpGens := NewBulletproofGens(64, 4)
+	pcGens := NewPedersenGens()
+
+	type batchCase struct {
+		proof       *RangeProof
+		commitments []*curve.CompressedRistretto
+		n           int
+	}
+	var cases []batchCase
+	for _, nm := range [][2]int{{8, 1}, {16, 2}, {64, 1}, {32, 4}, {64, 4}} {
+		proof, commitments, _ := testProve(t, bpGens, pcGens, nm[0], nm[1])
+		cases = append(cases, batchCase{proof, commitments, nm[0]})
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		v := NewBatchVerifier(bpGens, pcGens)
+		for _, c := range cases {
+			v.Add(merlin.NewTranscript(testTranscriptLabel), c.proof, c.commitments, c.n)
+		}
+		if err := v.Verify(nil); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		v := NewBatchVerifier(bpGens, pcGens)
+		for i, c := range cases {
+			commitments := c.commitments
+			if i == 2 {
+				commitments = []*curve.CompressedRistretto{curve.RISTRETTO_BASEPOINT_COMPRESSED}
+			}
+			v.Add(merlin.NewTranscript(testTranscriptLabel), c.proof, commitments, c.n)
+		}
+		if err := v.Verify(nil); err != ErrVerification {
+			t.Fatalf("Verify: %v", err)
+		}
+	})
+	t.Run("Empty", func(t *testing.T) {
+		v := NewBatchVerifier(bpGens, pcGens)
+		if err := v.Verify(nil); err != ErrVerification {
+			t.Fatalf("Verify: %v", err)
+		}
+	})
+}
+
+func TestInnerProductProof(t *testing.T) {
+	const n = 32
+
+	bpGens := NewBulletproofGens(n, 1)
+	G, H := bpGens.aggregatedG(n, 1), bpGens.aggregatedH(n, 1)
+	Q, err := curve.NewRistrettoPoint().SetRandom(nil)
+	if err != nil {
+		t.Fatalf("RistrettoPoint.SetRandom: %v", err)
+	}
+
+	a, err := randomScalars(n, nil)
+	if err != nil {
+		t.Fatalf("randomScalars: %v", err)
+	}
+	b, err := randomScalars(n, nil)
+	if err != nil {
+		t.Fatalf("randomScalars: %v", err)
+	}
+	gFactors := powers(scalar.One(), n)
+	hFactors := powers(scalar.NewFromUint64(5), n)
+
+	// P = <a, G> + <b', H> + <a, b> Q, where b' = b o hFactors.
+	scalars := append([]*scalar.Scalar{}, a...)
+	for i := range b {
+		scalars = append(scalars, scalar.New().Mul(b[i], hFactors[i]))
+	}
+	scalars = append(scalars, innerProduct(a, b))
+	points := append(append(append([]*curve.RistrettoPoint{}, G...), H...), Q)
+	P := curve.NewRistrettoPoint().MultiscalarMulVartime(scalars, points)
+
+	ipp, err := NewInnerProductProof(merlin.NewTranscript("innerproducttest"), Q, gFactors, hFactors, G, H, a, b)
+	if err != nil {
+		t.Fatalf("NewInnerProductProof: %v", err)
+	}
+
+	ippBytes, err := ipp.MarshalBinary()
+	if err != nil {
+		t.Fatalf("ipp.MarshalBinary: %v", err)
+	}
+	ipp2, err := NewInnerProductProofFromBytes(ippBytes)
+	if err != nil {
+		t.Fatalf("NewInnerProductProofFromBytes: %v", err)
+	}
+
+	if err = ipp2.Verify(n, merlin.NewTranscript("innerproducttest"), gFactors, hFactors, P, Q, G, H); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err = ipp2.Verify(n, merlin.NewTranscript("innerproducttest"), gFactors, hFactors, Q, Q, G, H); err != ErrVerification {
+		t.Fatalf("Verify(bad P): %v", err)
+	}
+}
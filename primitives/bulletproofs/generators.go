@@ -0,0 +1,199 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// PedersenGens contains the generators used for Pedersen commitments
+// to a value and a blinding factor.
+type PedersenGens struct {
+	// B is the generator for the committed value.
+	B *curve.RistrettoPoint
+	// BBlinding is the generator for the blinding factor.
+	BBlinding *curve.RistrettoPoint
+
+	expandedB         *curve.ExpandedRistrettoPoint
+	expandedBBlinding *curve.ExpandedRistrettoPoint
+}
+
+// Commit returns the Pedersen commitment `value * B + blinding * BBlinding`.
+func (pg *PedersenGens) Commit(value, blinding *scalar.Scalar) *curve.RistrettoPoint {
+	return curve.NewRistrettoPoint().MultiscalarMul(
+		[]*scalar.Scalar{value, blinding},
+		[]*curve.RistrettoPoint{pg.B, pg.BBlinding},
+	)
+}
+
+// NewPedersenGens returns the default Pedersen generators, where B is
+// the ristretto255 basepoint, and BBlinding is derived by hashing the
+// encoding of B with SHA3-512.
+func NewPedersenGens() *PedersenGens {
+	h := sha3.Sum512(curve.RISTRETTO_BASEPOINT_COMPRESSED[:])
+	bBlinding, err := curve.NewRistrettoPoint().SetUniformBytes(h[:])
+	if err != nil {
+		panic("bulletproofs: failed to derive blinding generator: " + err.Error())
+	}
+
+	b := curve.NewRistrettoPoint().Set(curve.RISTRETTO_BASEPOINT_POINT)
+	return &PedersenGens{
+		B:                 b,
+		BBlinding:         bBlinding,
+		expandedB:         curve.NewExpandedRistrettoPoint(b),
+		expandedBBlinding: curve.NewExpandedRistrettoPoint(bBlinding),
+	}
+}
+
+// generatorsChain is a SHAKE256-based stream of generators.
+type generatorsChain struct {
+	reader io.Reader
+}
+
+func (gc *generatorsChain) skip(n int) {
+	var buf [curve.RistrettoUniformSize]byte
+	for i := 0; i < n; i++ {
+		_, _ = gc.reader.Read(buf[:])
+	}
+}
+
+func (gc *generatorsChain) next() *curve.RistrettoPoint {
+	var buf [curve.RistrettoUniformSize]byte
+	_, _ = gc.reader.Read(buf[:])
+	p, err := curve.NewRistrettoPoint().SetUniformBytes(buf[:])
+	if err != nil {
+		panic("bulletproofs: failed to derive generator: " + err.Error())
+	}
+	return p
+}
+
+func newGeneratorsChain(label []byte) *generatorsChain {
+	xof := sha3.NewShake256()
+	_, _ = xof.Write([]byte("GeneratorsChain"))
+	_, _ = xof.Write(label)
+	return &generatorsChain{
+		reader: xof,
+	}
+}
+
+// BulletproofGens contains the vector generators used for range proofs.
+//
+// Each party in an aggregated proof is allocated its own set of
+// generators, so that the generators for an m-party aggregated proof
+// are the concatenation of the first n generators of each party.
+type BulletproofGens struct {
+	gensCapacity  int
+	partyCapacity int
+
+	g [][]*curve.RistrettoPoint
+	h [][]*curve.RistrettoPoint
+}
+
+// GensCapacity returns the number of generators allocated per party.
+func (bg *BulletproofGens) GensCapacity() int {
+	return bg.gensCapacity
+}
+
+// PartyCapacity returns the maximum number of parties that can
+// produce an aggregated proof with the generators.
+func (bg *BulletproofGens) PartyCapacity() int {
+	return bg.partyCapacity
+}
+
+// IncreaseCapacity increases the number of generators allocated per
+// party to newCapacity.  If newCapacity is not larger than the current
+// capacity, this is a no-op.
+func (bg *BulletproofGens) IncreaseCapacity(newCapacity int) {
+	if bg.gensCapacity >= newCapacity {
+		return
+	}
+
+	var label [5]byte
+	for i := 0; i < bg.partyCapacity; i++ {
+		binary.LittleEndian.PutUint32(label[1:], uint32(i))
+
+		label[0] = 'G'
+		bg.g[i] = extendGenerators(bg.g[i], label[:], bg.gensCapacity, newCapacity)
+
+		label[0] = 'H'
+		bg.h[i] = extendGenerators(bg.h[i], label[:], bg.gensCapacity, newCapacity)
+	}
+	bg.gensCapacity = newCapacity
+}
+
+func (bg *BulletproofGens) aggregatedG(n, m int) []*curve.RistrettoPoint {
+	return aggregateGenerators(bg.g, n, m)
+}
+
+func (bg *BulletproofGens) aggregatedH(n, m int) []*curve.RistrettoPoint {
+	return aggregateGenerators(bg.h, n, m)
+}
+
+func (bg *BulletproofGens) check(n, m int) error {
+	if bg.gensCapacity < n || bg.partyCapacity < m {
+		return ErrInvalidGeneratorsLength
+	}
+	return nil
+}
+
+func extendGenerators(gens []*curve.RistrettoPoint, label []byte, oldCapacity, newCapacity int) []*curve.RistrettoPoint {
+	gc := newGeneratorsChain(label)
+	gc.skip(oldCapacity)
+	for i := oldCapacity; i < newCapacity; i++ {
+		gens = append(gens, gc.next())
+	}
+	return gens
+}
+
+func aggregateGenerators(gens [][]*curve.RistrettoPoint, n, m int) []*curve.RistrettoPoint {
+	ret := make([]*curve.RistrettoPoint, 0, n*m)
+	for j := 0; j < m; j++ {
+		ret = append(ret, gens[j][:n]...)
+	}
+	return ret
+}
+
+// NewBulletproofGens creates generators for proofs of up to gensCapacity
+// bits, aggregated across up to partyCapacity parties.
+func NewBulletproofGens(gensCapacity, partyCapacity int) *BulletproofGens {
+	bg := &BulletproofGens{
+		partyCapacity: partyCapacity,
+		g:             make([][]*curve.RistrettoPoint, partyCapacity),
+		h:             make([][]*curve.RistrettoPoint, partyCapacity),
+	}
+	bg.IncreaseCapacity(gensCapacity)
+	return bg
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const maxInnerProductRounds = 32
+
+// InnerProductProof is an inner-product argument, proving knowledge of
+// vectors a and b such that `P = <a, G> + <b, H> + <a, b> * Q`.
+type InnerProductProof struct {
+	lVec []curve.CompressedRistretto
+	rVec []curve.CompressedRistretto
+	a    scalar.Scalar
+	b    scalar.Scalar
+}
+
+// NewInnerProductProof creates an inner-product proof for the vectors
+// aVec and bVec, with respect to the generators `gFactors[i] * gVec[i]`
+// and `hFactors[i] * hVec[i]`, and Q.
+//
+// All of the vectors must have the same length, which must be a power
+// of 2.  The input vectors are not modified.
+func NewInnerProductProof(
+	transcript *merlin.Transcript,
+	Q *curve.RistrettoPoint,
+	gFactors, hFactors []*scalar.Scalar,
+	gVec, hVec []*curve.RistrettoPoint,
+	aVec, bVec []*scalar.Scalar,
+) (*InnerProductProof, error) {
+	n := len(gVec)
+	if len(hVec) != n || len(aVec) != n || len(bVec) != n || len(gFactors) != n || len(hFactors) != n {
+		return nil, fmt.Errorf("bulletproofs: inner product vector length mismatch")
+	}
+	if !isPowerOf2(n) {
+		return nil, fmt.Errorf("bulletproofs: inner product vector length not a power of 2")
+	}
+
+	G, H := copyPoints(gVec), copyPoints(hVec)
+	a, b := copyScalars(aVec), copyScalars(bVec)
+
+	transcriptInnerProductDomainSep(transcript, uint64(n))
+
+	var (
+		ipp   InnerProductProof
+		first = true
+		tmp   scalar.Scalar
+	)
+	for n != 1 {
+		n = n / 2
+		aL, aR := a[:n], a[n:]
+		bL, bR := b[:n], b[n:]
+		GL, GR := G[:n], G[n:]
+		HL, HR := H[:n], H[n:]
+
+		cL := innerProduct(aL, bR)
+		cR := innerProduct(aR, bL)
+
+		lScalars := make([]*scalar.Scalar, 0, 2*n+1)
+		rScalars := make([]*scalar.Scalar, 0, 2*n+1)
+		switch first {
+		case true:
+			// Fold the generator factors into the scalars on the
+			// first round, instead of scaling the generators.
+			for i := 0; i < n; i++ {
+				lScalars = append(lScalars, scalar.New().Mul(aL[i], gFactors[n+i]))
+				rScalars = append(rScalars, scalar.New().Mul(aR[i], gFactors[i]))
+			}
+			for i := 0; i < n; i++ {
+				lScalars = append(lScalars, scalar.New().Mul(bR[i], hFactors[i]))
+				rScalars = append(rScalars, scalar.New().Mul(bL[i], hFactors[n+i]))
+			}
+		case false:
+			lScalars = append(append(lScalars, aL...), bR...)
+			rScalars = append(append(rScalars, aR...), bL...)
+		}
+		lScalars = append(lScalars, cL)
+		rScalars = append(rScalars, cR)
+
+		lPoints := make([]*curve.RistrettoPoint, 0, 2*n+1)
+		lPoints = append(append(append(lPoints, GR...), HL...), Q)
+		rPoints := make([]*curve.RistrettoPoint, 0, 2*n+1)
+		rPoints = append(append(append(rPoints, GL...), HR...), Q)
+
+		var L, R curve.CompressedRistretto
+		L.SetRistrettoPoint(curve.NewRistrettoPoint().MultiscalarMulVartime(lScalars, lPoints))
+		R.SetRistrettoPoint(curve.NewRistrettoPoint().MultiscalarMulVartime(rScalars, rPoints))
+		ipp.lVec = append(ipp.lVec, L)
+		ipp.rVec = append(ipp.rVec, R)
+
+		transcriptAppendPoint(transcript, "L", &L)
+		transcriptAppendPoint(transcript, "R", &R)
+
+		u := transcriptChallengeScalar(transcript, "u")
+		uInv := scalar.New().Invert(u)
+
+		for i := 0; i < n; i++ {
+			aL[i] = scalar.New().Mul(aL[i], u)
+			aL[i].Add(aL[i], tmp.Mul(uInv, aR[i]))
+			bL[i] = scalar.New().Mul(bL[i], uInv)
+			bL[i].Add(bL[i], tmp.Mul(u, bR[i]))
+
+			gScalars := []*scalar.Scalar{uInv, u}
+			hScalars := []*scalar.Scalar{u, uInv}
+			if first {
+				gScalars = []*scalar.Scalar{
+					scalar.New().Mul(uInv, gFactors[i]),
+					scalar.New().Mul(u, gFactors[n+i]),
+				}
+				hScalars = []*scalar.Scalar{
+					scalar.New().Mul(u, hFactors[i]),
+					scalar.New().Mul(uInv, hFactors[n+i]),
+				}
+			}
+			GL[i] = curve.NewRistrettoPoint().MultiscalarMulVartime(gScalars, []*curve.RistrettoPoint{GL[i], GR[i]})
+			HL[i] = curve.NewRistrettoPoint().MultiscalarMulVartime(hScalars, []*curve.RistrettoPoint{HL[i], HR[i]})
+		}
+
+		a, b, G, H = aL, bL, GL, HL
+		first = false
+	}
+
+	ipp.a.Set(a[0])
+	ipp.b.Set(b[0])
+
+	return &ipp, nil
+}
+
+// verificationScalars recomputes the challenges from the transcript,
+// and returns `(u_k^2, ..., u_1^2)`, `(u_k^-2, ..., u_1^-2)`, and the
+// vector s used to fold the generators.
+func (ipp *InnerProductProof) verificationScalars(n int, transcript *merlin.Transcript) ([]*scalar.Scalar, []*scalar.Scalar, []*scalar.Scalar, error) {
+	lgN := len(ipp.lVec)
+	if lgN >= maxInnerProductRounds || len(ipp.rVec) != lgN || n != 1<<lgN {
+		return nil, nil, nil, ErrVerification
+	}
+
+	transcriptInnerProductDomainSep(transcript, uint64(n))
+
+	// 1. Recompute u_k, ..., u_1 from the transcript.
+	challenges := make([]*scalar.Scalar, 0, lgN)
+	for i := range ipp.lVec {
+		if err := transcriptValidateAndAppendPoint(transcript, "L", &ipp.lVec[i]); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := transcriptValidateAndAppendPoint(transcript, "R", &ipp.rVec[i]); err != nil {
+			return nil, nil, nil, err
+		}
+		challenges = append(challenges, transcriptChallengeScalar(transcript, "u"))
+	}
+
+	// 2. Compute 1/(u_k...u_1) and 1/u_k, ..., 1/u_1.
+	challengesInv := copyScalars(challenges)
+	allInv := scalar.New().BatchInvert(challengesInv)
+
+	// 3. Compute u_i^2 and (1/u_i)^2.
+	for i := 0; i < lgN; i++ {
+		challenges[i].Mul(challenges[i], challenges[i])
+		challengesInv[i].Mul(challengesInv[i], challengesInv[i])
+	}
+
+	// 4. Compute s inductively.
+	s := make([]*scalar.Scalar, 0, n)
+	s = append(s, allInv)
+	for i := 1; i < n; i++ {
+		lgI := bits.Len(uint(i)) - 1
+		k := 1 << lgI
+		// The challenges are stored in "creation order" as
+		// [u_k, ..., u_1], so u_{lg(i)+1} is indexed by
+		// (lgN - 1) - lgI.
+		s = append(s, scalar.New().Mul(s[i-k], challenges[(lgN-1)-lgI]))
+	}
+
+	return challenges, challengesInv, s, nil
+}
+
+// Verify verifies the inner-product proof against the commitment P,
+// with respect to the same generators used to create the proof.
+func (ipp *InnerProductProof) Verify(
+	n int,
+	transcript *merlin.Transcript,
+	gFactors, hFactors []*scalar.Scalar,
+	P, Q *curve.RistrettoPoint,
+	gVec, hVec []*curve.RistrettoPoint,
+) error {
+	if len(gFactors) != n || len(hFactors) != n || len(gVec) != n || len(hVec) != n {
+		return ErrVerification
+	}
+
+	uSq, uInvSq, s, err := ipp.verificationScalars(n, transcript)
+	if err != nil {
+		return err
+	}
+
+	lgN := len(ipp.lVec)
+	scalars := make([]*scalar.Scalar, 0, 1+2*n+2*lgN)
+	points := make([]*curve.RistrettoPoint, 0, 1+2*n+2*lgN)
+
+	scalars = append(scalars, scalar.New().Mul(&ipp.a, &ipp.b))
+	points = append(points, Q)
+	for i := 0; i < n; i++ {
+		gs := scalar.New().Mul(&ipp.a, s[i])
+		scalars = append(scalars, gs.Mul(gs, gFactors[i]))
+	}
+	points = append(points, gVec...)
+	for i := 0; i < n; i++ {
+		// 1/s[i] is s[!i], and !i runs from n-1 to 0 as i runs
+		// from 0 to n-1.
+		hs := scalar.New().Mul(&ipp.b, s[n-1-i])
+		scalars = append(scalars, hs.Mul(hs, hFactors[i]))
+	}
+	points = append(points, hVec...)
+	for i := 0; i < lgN; i++ {
+		L, err := curve.NewRistrettoPoint().SetCompressed(&ipp.lVec[i])
+		if err != nil {
+			return ErrVerification
+		}
+		scalars = append(scalars, scalar.New().Neg(uSq[i]))
+		points = append(points, L)
+	}
+	for i := 0; i < lgN; i++ {
+		R, err := curve.NewRistrettoPoint().SetCompressed(&ipp.rVec[i])
+		if err != nil {
+			return ErrVerification
+		}
+		scalars = append(scalars, scalar.New().Neg(uInvSq[i]))
+		points = append(points, R)
+	}
+
+	expectP := curve.NewRistrettoPoint().MultiscalarMulVartime(scalars, points)
+	if expectP.Equal(P) != 1 {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes an InnerProductProof into binary form, as
+// `L_0 || R_0 || ... || L_{k-1} || R_{k-1} || a || b`.
+func (ipp *InnerProductProof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, (2*len(ipp.lVec)+2)*32)
+	for i := range ipp.lVec {
+		b = append(b, ipp.lVec[i][:]...)
+		b = append(b, ipp.rVec[i][:]...)
+	}
+
+	var tmp [scalar.ScalarSize]byte
+	for _, s := range []*scalar.Scalar{&ipp.a, &ipp.b} {
+		if err := s.ToBytes(tmp[:]); err != nil {
+			return nil, fmt.Errorf("bulletproofs: failed to serialize scalar: %w", err)
+		}
+		b = append(b, tmp[:]...)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary marshaled InnerProductProof.
+func (ipp *InnerProductProof) UnmarshalBinary(data []byte) error {
+	l := len(data)
+	if l%32 != 0 {
+		return ErrFormat
+	}
+	numElements := l / 32
+	if numElements < 2 || numElements%2 != 0 {
+		return ErrFormat
+	}
+	lgN := (numElements - 2) / 2
+	if lgN >= maxInnerProductRounds {
+		return ErrFormat
+	}
+
+	lVec := make([]curve.CompressedRistretto, lgN)
+	rVec := make([]curve.CompressedRistretto, lgN)
+	for i := 0; i < lgN; i++ {
+		pos := 2 * i * 32
+		copy(lVec[i][:], data[pos:pos+32])
+		copy(rVec[i][:], data[pos+32:pos+64])
+	}
+
+	pos := 2 * lgN * 32
+	a, err := scalar.NewFromCanonicalBytes(data[pos : pos+32])
+	if err != nil {
+		return ErrFormat
+	}
+	b, err := scalar.NewFromCanonicalBytes(data[pos+32:])
+	if err != nil {
+		return ErrFormat
+	}
+
+	ipp.lVec, ipp.rVec = lVec, rVec
+	ipp.a.Set(a)
+	ipp.b.Set(b)
+
+	return nil
+}
+
+// NewInnerProductProofFromBytes constructs an InnerProductProof from the
+// byte representation.
+func NewInnerProductProofFromBytes(b []byte) (*InnerProductProof, error) {
+	var ipp InnerProductProof
+	if err := ipp.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &ipp, nil
+}
+
+func copyPoints(points []*curve.RistrettoPoint) []*curve.RistrettoPoint {
+	ret := make([]*curve.RistrettoPoint, len(points))
+	copy(ret, points)
+	return ret
+}
+
+func copyScalars(scalars []*scalar.Scalar) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, len(scalars))
+	for i, s := range scalars {
+		ret[i] = scalar.New().Set(s)
+	}
+	return ret
+}
@@ -0,0 +1,443 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const rangeProofFixedElements = 7
+
+// RangeProof is an (optionally aggregated) range proof, proving that
+// each of the committed values is in the range `[0, 2^n)`.
+type RangeProof struct {
+	A  curve.CompressedRistretto
+	S  curve.CompressedRistretto
+	T1 curve.CompressedRistretto
+	T2 curve.CompressedRistretto
+
+	tX         scalar.Scalar
+	tXBlinding scalar.Scalar
+	eBlinding  scalar.Scalar
+
+	ipp InnerProductProof
+}
+
+// ProveSingle creates a range proof that v is in the range `[0, 2^n)`,
+// and returns the proof and the commitment to v, using entropy from
+// rand.  If rand is nil, crypto/rand.Reader will be used.
+func ProveSingle(
+	bpGens *BulletproofGens,
+	pcGens *PedersenGens,
+	transcript *merlin.Transcript,
+	v uint64,
+	vBlinding *scalar.Scalar,
+	n int,
+	rand io.Reader,
+) (*RangeProof, *curve.CompressedRistretto, error) {
+	proof, commitments, err := ProveMultiple(bpGens, pcGens, transcript, []uint64{v}, []*scalar.Scalar{vBlinding}, n, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return proof, commitments[0], nil
+}
+
+// ProveMultiple creates an aggregated range proof that each of the
+// values is in the range `[0, 2^n)`, and returns the proof and the
+// commitments to each value, using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// The number of values must be a power of 2.
+func ProveMultiple(
+	bpGens *BulletproofGens,
+	pcGens *PedersenGens,
+	transcript *merlin.Transcript,
+	values []uint64,
+	blindings []*scalar.Scalar,
+	n int,
+	rand io.Reader,
+) (*RangeProof, []*curve.CompressedRistretto, error) {
+	m := len(values)
+	if len(blindings) != m {
+		return nil, nil, ErrWrongNumBlindingFactors
+	}
+	if !isValidBitsize(n) {
+		return nil, nil, ErrInvalidBitsize
+	}
+	if !isPowerOf2(m) {
+		return nil, nil, ErrInvalidAggregation
+	}
+	if err := bpGens.check(n, m); err != nil {
+		return nil, nil, err
+	}
+	for _, v := range values {
+		if n < 64 && v>>uint(n) != 0 {
+			return nil, nil, ErrValueOutOfRange
+		}
+	}
+
+	transcriptRangeProofDomainSep(transcript, uint64(n), uint64(m))
+
+	// Each value is handled by a "party", in the same order and using
+	// the same random draws as the aggregated protocol of the Rust
+	// implementation.
+	parties := make([]*party, m)
+	for j := range parties {
+		p, err := newParty(bpGens, pcGens, j, values[j], blindings[j], n, rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		parties[j] = p
+	}
+
+	// Commit to each V_j individually, and the aggregated A and S.
+	commitments := make([]*curve.CompressedRistretto, 0, m)
+	A, S := curve.NewRistrettoPoint().Identity(), curve.NewRistrettoPoint().Identity()
+	for _, p := range parties {
+		commitments = append(commitments, &p.vCompressed)
+		transcriptAppendPoint(transcript, "V", &p.vCompressed)
+		A.Add(A, p.A)
+		S.Add(S, p.S)
+	}
+
+	var proof RangeProof
+	proof.A.SetRistrettoPoint(A)
+	proof.S.SetRistrettoPoint(S)
+	transcriptAppendPoint(transcript, "A", &proof.A)
+	transcriptAppendPoint(transcript, "S", &proof.S)
+
+	y := transcriptChallengeScalar(transcript, "y")
+	z := transcriptChallengeScalar(transcript, "z")
+
+	T1, T2 := curve.NewRistrettoPoint().Identity(), curve.NewRistrettoPoint().Identity()
+	for _, p := range parties {
+		if err := p.applyBitChallenge(y, z, rand); err != nil {
+			return nil, nil, err
+		}
+		T1.Add(T1, p.T1)
+		T2.Add(T2, p.T2)
+	}
+
+	proof.T1.SetRistrettoPoint(T1)
+	proof.T2.SetRistrettoPoint(T2)
+	transcriptAppendPoint(transcript, "T_1", &proof.T1)
+	transcriptAppendPoint(transcript, "T_2", &proof.T2)
+
+	x := transcriptChallengeScalar(transcript, "x")
+
+	lVec := make([]*scalar.Scalar, 0, n*m)
+	rVec := make([]*scalar.Scalar, 0, n*m)
+	for _, p := range parties {
+		share := p.applyPolyChallenge(x)
+		proof.tX.Add(&proof.tX, share.tX)
+		proof.tXBlinding.Add(&proof.tXBlinding, share.tXBlinding)
+		proof.eBlinding.Add(&proof.eBlinding, share.eBlinding)
+		lVec = append(lVec, share.lVec...)
+		rVec = append(rVec, share.rVec...)
+	}
+
+	transcriptAppendScalar(transcript, "t_x", &proof.tX)
+	transcriptAppendScalar(transcript, "t_x_blinding", &proof.tXBlinding)
+	transcriptAppendScalar(transcript, "e_blinding", &proof.eBlinding)
+
+	// Get a challenge value to combine statements for the IPP.
+	w := transcriptChallengeScalar(transcript, "w")
+	Q := curve.NewRistrettoPoint().Mul(pcGens.B, w)
+
+	gFactors := make([]*scalar.Scalar, n*m)
+	for i := range gFactors {
+		gFactors[i] = scalar.One()
+	}
+	hFactors := powers(scalar.New().Invert(y), n*m)
+
+	ipp, err := NewInnerProductProof(
+		transcript,
+		Q,
+		gFactors,
+		hFactors,
+		bpGens.aggregatedG(n, m),
+		bpGens.aggregatedH(n, m),
+		lVec,
+		rVec,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof.ipp = *ipp
+
+	return &proof, commitments, nil
+}
+
+// VerifySingle verifies a range proof for the commitment V, with the
+// range `[0, 2^n)`.
+func (proof *RangeProof) VerifySingle(
+	bpGens *BulletproofGens,
+	pcGens *PedersenGens,
+	transcript *merlin.Transcript,
+	V *curve.CompressedRistretto,
+	n int,
+) error {
+	return proof.VerifyMultiple(bpGens, pcGens, transcript, []*curve.CompressedRistretto{V}, n)
+}
+
+// VerifyMultiple verifies an aggregated range proof for the commitments,
+// with the range `[0, 2^n)`.
+func (proof *RangeProof) VerifyMultiple(
+	bpGens *BulletproofGens,
+	pcGens *PedersenGens,
+	transcript *merlin.Transcript,
+	valueCommitments []*curve.CompressedRistretto,
+	n int,
+) error {
+	v := NewBatchVerifier(bpGens, pcGens)
+	v.Add(transcript, proof, valueCommitments, n)
+	return v.Verify(nil)
+}
+
+// MarshalBinary encodes a RangeProof into binary form, as
+// `A || S || T_1 || T_2 || t_x || t_x_blinding || e_blinding || ipp`.
+func (proof *RangeProof) MarshalBinary() ([]byte, error) {
+	ippBytes, err := proof.ipp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, rangeProofFixedElements*32+len(ippBytes))
+	b = append(b, proof.A[:]...)
+	b = append(b, proof.S[:]...)
+	b = append(b, proof.T1[:]...)
+	b = append(b, proof.T2[:]...)
+
+	var tmp [scalar.ScalarSize]byte
+	for _, s := range []*scalar.Scalar{&proof.tX, &proof.tXBlinding, &proof.eBlinding} {
+		if err := s.ToBytes(tmp[:]); err != nil {
+			return nil, fmt.Errorf("bulletproofs: failed to serialize scalar: %w", err)
+		}
+		b = append(b, tmp[:]...)
+	}
+
+	return append(b, ippBytes...), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled RangeProof.
+func (proof *RangeProof) UnmarshalBinary(data []byte) error {
+	l := len(data)
+	if l%32 != 0 || l < rangeProofFixedElements*32 {
+		return ErrFormat
+	}
+
+	var tmp RangeProof
+	copy(tmp.A[:], data[0:32])
+	copy(tmp.S[:], data[32:64])
+	copy(tmp.T1[:], data[64:96])
+	copy(tmp.T2[:], data[96:128])
+
+	for i, s := range []*scalar.Scalar{&tmp.tX, &tmp.tXBlinding, &tmp.eBlinding} {
+		pos := 128 + i*32
+		if _, err := s.SetCanonicalBytes(data[pos : pos+32]); err != nil {
+			return ErrFormat
+		}
+	}
+
+	if err := tmp.ipp.UnmarshalBinary(data[rangeProofFixedElements*32:]); err != nil {
+		return err
+	}
+
+	*proof = tmp
+
+	return nil
+}
+
+// NewRangeProofFromBytes constructs a RangeProof from the byte
+// representation.
+func NewRangeProofFromBytes(b []byte) (*RangeProof, error) {
+	var proof RangeProof
+	if err := proof.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// party is the per-value state of the aggregated proving protocol.
+type party struct {
+	pcGens *PedersenGens
+
+	j           int
+	n           int
+	v           uint64
+	vBlinding   *scalar.Scalar
+	vCompressed curve.CompressedRistretto
+
+	A, S      *curve.RistrettoPoint
+	aBlinding *scalar.Scalar
+	sBlinding *scalar.Scalar
+	sL, sR    []*scalar.Scalar
+
+	z, offsetZ   *scalar.Scalar
+	lPoly, rPoly *vecPoly1
+	tPoly        *poly2
+
+	T1, T2     *curve.RistrettoPoint
+	t1Blinding *scalar.Scalar
+	t2Blinding *scalar.Scalar
+}
+
+type proofShare struct {
+	tX         *scalar.Scalar
+	tXBlinding *scalar.Scalar
+	eBlinding  *scalar.Scalar
+	lVec, rVec []*scalar.Scalar
+}
+
+func newParty(bpGens *BulletproofGens, pcGens *PedersenGens, j int, v uint64, vBlinding *scalar.Scalar, n int, rand io.Reader) (*party, error) {
+	p := &party{
+		pcGens:    pcGens,
+		j:         j,
+		n:         n,
+		v:         v,
+		vBlinding: scalar.New().Set(vBlinding),
+	}
+	p.vCompressed.SetRistrettoPoint(pcGens.Commit(scalar.NewFromUint64(v), vBlinding))
+
+	var err error
+	if p.aBlinding, err = scalar.New().SetRandom(rand); err != nil {
+		return nil, err
+	}
+
+	// A = aBlinding * BBlinding + <aL, G> + <aR, H>, where aL is the
+	// bit decomposition of v, and aR = aL - 1.
+	G, H := bpGens.g[j][:n], bpGens.h[j][:n]
+	p.A = curve.NewRistrettoPoint().Mul(pcGens.BBlinding, p.aBlinding)
+	var point, negH curve.RistrettoPoint
+	for i := 0; i < n; i++ {
+		negH.Neg(H[i])
+		point.ConditionalSelect(&negH, G[i], int((v>>uint(i))&1))
+		p.A.Add(p.A, &point)
+	}
+
+	if p.sBlinding, err = scalar.New().SetRandom(rand); err != nil {
+		return nil, err
+	}
+	if p.sL, err = randomScalars(n, rand); err != nil {
+		return nil, err
+	}
+	if p.sR, err = randomScalars(n, rand); err != nil {
+		return nil, err
+	}
+
+	sScalars := make([]*scalar.Scalar, 0, 1+2*n)
+	sScalars = append(append(append(sScalars, p.sBlinding), p.sL...), p.sR...)
+	sPoints := make([]*curve.RistrettoPoint, 0, 1+2*n)
+	sPoints = append(append(append(sPoints, pcGens.BBlinding), G...), H...)
+	p.S = curve.NewRistrettoPoint().MultiscalarMul(sScalars, sPoints)
+
+	return p, nil
+}
+
+func (p *party) applyBitChallenge(y, z *scalar.Scalar, rand io.Reader) error {
+	n := p.n
+	offsetY := scalarExpVartime(y, uint64(p.j*n))
+	offsetZ := scalarExpVartime(z, uint64(p.j))
+
+	// Calculate t by calculating vectors l0, l1, r0, r1 and multiplying.
+	p.lPoly, p.rPoly = newVecPoly1(n), newVecPoly1(n)
+
+	offsetZZ := scalar.New().Mul(z, z)
+	offsetZZ.Mul(offsetZZ, offsetZ)
+	expY := offsetY      // y^(j*n)
+	exp2 := scalar.One() // 2^0
+	one := scalar.One()
+	var aL, aR, tmp scalar.Scalar
+	for i := 0; i < n; i++ {
+		aL.SetUint64((p.v >> uint(i)) & 1)
+		aR.Sub(&aL, one)
+
+		p.lPoly.a[i].Sub(&aL, z)
+		p.lPoly.b[i].Set(p.sL[i])
+		p.rPoly.a[i].Add(&aR, z)
+		p.rPoly.a[i].Mul(p.rPoly.a[i], expY)
+		p.rPoly.a[i].Add(p.rPoly.a[i], tmp.Mul(offsetZZ, exp2))
+		p.rPoly.b[i].Mul(expY, p.sR[i])
+
+		expY.Mul(expY, y)
+		exp2.Add(exp2, exp2)
+	}
+
+	p.tPoly = p.lPoly.innerProduct(p.rPoly)
+
+	var err error
+	if p.t1Blinding, err = scalar.New().SetRandom(rand); err != nil {
+		return err
+	}
+	if p.t2Blinding, err = scalar.New().SetRandom(rand); err != nil {
+		return err
+	}
+	p.T1 = p.pcGens.Commit(p.tPoly[1], p.t1Blinding)
+	p.T2 = p.pcGens.Commit(p.tPoly[2], p.t2Blinding)
+
+	p.z, p.offsetZ = z, offsetZ
+
+	return nil
+}
+
+func (p *party) applyPolyChallenge(x *scalar.Scalar) *proofShare {
+	t0Blinding := scalar.New().Mul(p.z, p.z)
+	t0Blinding.Mul(t0Blinding, p.offsetZ)
+	t0Blinding.Mul(t0Blinding, p.vBlinding)
+	tBlindingPoly := &poly2{t0Blinding, p.t1Blinding, p.t2Blinding}
+
+	eBlinding := scalar.New().Mul(p.sBlinding, x)
+	eBlinding.Add(eBlinding, p.aBlinding)
+
+	return &proofShare{
+		tX:         p.tPoly.eval(x),
+		tXBlinding: tBlindingPoly.eval(x),
+		eBlinding:  eBlinding,
+		lVec:       p.lPoly.eval(x),
+		rVec:       p.rPoly.eval(x),
+	}
+}
+
+func randomScalars(n int, rand io.Reader) ([]*scalar.Scalar, error) {
+	ret := make([]*scalar.Scalar, n)
+	for i := range ret {
+		s, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = s
+	}
+	return ret, nil
+}
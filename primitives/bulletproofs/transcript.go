@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import (
+	"encoding/binary"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+func transcriptAppendU64(t *merlin.Transcript, label string, x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	t.AppendMessage(label, b[:])
+}
+
+func transcriptRangeProofDomainSep(t *merlin.Transcript, n, m uint64) {
+	t.AppendMessage("dom-sep", []byte("rangeproof v1"))
+	transcriptAppendU64(t, "n", n)
+	transcriptAppendU64(t, "m", m)
+}
+
+func transcriptInnerProductDomainSep(t *merlin.Transcript, n uint64) {
+	t.AppendMessage("dom-sep", []byte("ipp v1"))
+	transcriptAppendU64(t, "n", n)
+}
+
+func transcriptAppendPoint(t *merlin.Transcript, label string, p *curve.CompressedRistretto) {
+	t.AppendMessage(label, p[:])
+}
+
+func transcriptValidateAndAppendPoint(t *merlin.Transcript, label string, p *curve.CompressedRistretto) error {
+	var identity curve.CompressedRistretto
+	if p.Equal(identity.Identity()) == 1 {
+		return ErrVerification
+	}
+	transcriptAppendPoint(t, label, p)
+	return nil
+}
+
+func transcriptAppendScalar(t *merlin.Transcript, label string, s *scalar.Scalar) {
+	var b [scalar.ScalarSize]byte
+	if err := s.ToBytes(b[:]); err != nil {
+		panic("bulletproofs: failed to serialize scalar: " + err.Error())
+	}
+	t.AppendMessage(label, b[:])
+}
+
+func transcriptChallengeScalar(t *merlin.Transcript, label string) *scalar.Scalar {
+	var b [scalar.ScalarWideSize]byte
+	t.ExtractBytes(b[:], label)
+	s, err := scalar.NewFromBytesModOrderWide(b[:])
+	if err != nil {
+		panic("bulletproofs: failed to derive challenge scalar: " + err.Error())
+	}
+	return s
+}
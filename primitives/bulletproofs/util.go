@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bulletproofs
+
+import "github.com/oasisprotocol/curve25519-voi/curve/scalar"
+
+// vecPoly1 is a vector polynomial `a + b*x`.
+type vecPoly1 struct {
+	a, b []*scalar.Scalar
+}
+
+func (p *vecPoly1) eval(x *scalar.Scalar) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, len(p.a))
+	for i := range ret {
+		ret[i] = scalar.New().Mul(p.b[i], x)
+		ret[i].Add(ret[i], p.a[i])
+	}
+	return ret
+}
+
+// innerProduct returns the degree 2 polynomial `<l, r>`, computed
+// with Karatsuba's method.
+func (p *vecPoly1) innerProduct(r *vecPoly1) *poly2 {
+	t0 := innerProduct(p.a, r.a)
+	t2 := innerProduct(p.b, r.b)
+
+	lSum, rSum := addVec(p.a, p.b), addVec(r.a, r.b)
+	t1 := innerProduct(lSum, rSum)
+	t1.Sub(t1, t0)
+	t1.Sub(t1, t2)
+
+	return &poly2{t0, t1, t2}
+}
+
+func newVecPoly1(n int) *vecPoly1 {
+	p := &vecPoly1{
+		a: make([]*scalar.Scalar, n),
+		b: make([]*scalar.Scalar, n),
+	}
+	for i := 0; i < n; i++ {
+		p.a[i], p.b[i] = scalar.New(), scalar.New()
+	}
+	return p
+}
+
+// poly2 is a scalar polynomial `t0 + t1*x + t2*x^2`.
+type poly2 [3]*scalar.Scalar
+
+func (p *poly2) eval(x *scalar.Scalar) *scalar.Scalar {
+	ret := scalar.New().Mul(p[2], x)
+	ret.Add(ret, p[1])
+	ret.Mul(ret, x)
+	return ret.Add(ret, p[0])
+}
+
+func innerProduct(a, b []*scalar.Scalar) *scalar.Scalar {
+	if len(a) != len(b) {
+		panic("bulletproofs: inner product of vectors with different lengths")
+	}
+
+	var tmp scalar.Scalar
+	ret := scalar.New()
+	for i := range a {
+		ret.Add(ret, tmp.Mul(a[i], b[i]))
+	}
+	return ret
+}
+
+func addVec(a, b []*scalar.Scalar) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, len(a))
+	for i := range a {
+		ret[i] = scalar.New().Add(a[i], b[i])
+	}
+	return ret
+}
+
+// powers returns `[1, x, x^2, ..., x^(n-1)]`.
+func powers(x *scalar.Scalar, n int) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, n)
+	if n == 0 {
+		return ret
+	}
+	ret[0] = scalar.One()
+	for i := 1; i < n; i++ {
+		ret[i] = scalar.New().Mul(ret[i-1], x)
+	}
+	return ret
+}
+
+// scalarExpVartime returns `x^n`.
+func scalarExpVartime(x *scalar.Scalar, n uint64) *scalar.Scalar {
+	ret, aux := scalar.One(), scalar.New().Set(x)
+	for n > 0 {
+		if n&1 == 1 {
+			ret.Mul(ret, aux)
+		}
+		n >>= 1
+		aux.Mul(aux, aux)
+	}
+	return ret
+}
+
+// sumOfPowers returns `1 + x + x^2 + ... + x^(n-1)`.
+func sumOfPowers(x *scalar.Scalar, n int) *scalar.Scalar {
+	return scalar.New().Sum(powers(x, n))
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package cpace implements the CPace balanced password-authenticated
+// key exchange as specified in the CFRG draft (draft-irtf-cfrg-cpace),
+// with the CPACE-RISTR255-SHA512 and CPACE-X25519-SHA512 ciphersuites.
+//
+// Interoperability has not yet been tested against the draft's published
+// test vectors.
+package cpace
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+const (
+	// ISKSize is the size of the intermediate session key in bytes.
+	ISKSize = sha512.Size
+
+	// hashBlockSize is the input block size of SHA-512 (s_in_bytes).
+	hashBlockSize = sha512.BlockSize
+
+	labelISK = "_ISK"
+	labelOC  = "oc"
+)
+
+var (
+	// ErrInvalidMessage is the error returned when the peer's message
+	// is malformed, or contains an invalid group element.
+	ErrInvalidMessage = fmt.Errorf("cpace: invalid message")
+
+	errInvalidRole = fmt.Errorf("cpace: invalid role")
+)
+
+// Role is a protocol participant role.
+type Role uint8
+
+const (
+	// RoleInitiator is the initiator in the initiator-responder setting.
+	RoleInitiator Role = iota
+	// RoleResponder is the responder in the initiator-responder setting.
+	RoleResponder
+	// RoleSymmetric is either party in the symmetric (parallel)
+	// setting, where the transcript uses ordered concatenation.
+	RoleSymmetric
+)
+
+// String returns the string representation of a Role.
+func (r Role) String() string {
+	switch r {
+	case RoleInitiator:
+		return "initiator"
+	case RoleResponder:
+		return "responder"
+	case RoleSymmetric:
+		return "symmetric"
+	default:
+		return "[invalid role]"
+	}
+}
+
+// Suite is a CPace ciphersuite.
+type Suite struct {
+	name string
+	g    group
+}
+
+// Name returns the name of the ciphersuite.
+func (s *Suite) Name() string {
+	return s.name
+}
+
+// String returns the string representation of the ciphersuite.
+func (s *Suite) String() string {
+	return s.name
+}
+
+var (
+	// Ristretto255SHA512 is the CPACE-RISTR255-SHA512 ciphersuite.
+	Ristretto255SHA512 = &Suite{
+		name: "CPACE-RISTR255-SHA512",
+		g:    ristretto255Group{},
+	}
+
+	// X25519SHA512 is the CPACE-X25519-SHA512 ciphersuite.
+	X25519SHA512 = &Suite{
+		name: "CPACE-X25519-SHA512",
+		g:    x25519Group{},
+	}
+)
+
+// Config is the per-session CPace configuration.
+type Config struct {
+	// ChannelID is the channel identifier (CI), which must match
+	// between the two parties, and may be empty.
+	ChannelID []byte
+
+	// SessionID is the session identifier (sid), which must match
+	// between the two parties, and should be unique per session.
+	SessionID []byte
+
+	// AssociatedData is the party's own associated data (ADa or ADb),
+	// which is sent to the peer in the clear, and authenticated by
+	// the shared key.
+	AssociatedData []byte
+}
+
+// State is a CPace session.
+type State struct {
+	suite *Suite
+	role  Role
+	sid   []byte
+	y     []byte
+	msg   []byte
+
+	finished bool
+}
+
+// New creates a new CPace session for the role and password-related
+// string (PRS), using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+func (s *Suite) New(role Role, prs []byte, cfg *Config, rand io.Reader) (*State, error) {
+	if role > RoleSymmetric {
+		return nil, errInvalidRole
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	y, err := s.g.sampleScalar(rand)
+	if err != nil {
+		return nil, fmt.Errorf("cpace: failed to sample scalar: %w", err)
+	}
+
+	return s.newState(role, prs, cfg, y), nil
+}
+
+func (s *Suite) newState(role Role, prs []byte, cfg *Config, y []byte) *State {
+	g := s.calculateGenerator(prs, cfg.ChannelID, cfg.SessionID)
+	Y := s.g.scalarMult(y, g)
+
+	return &State{
+		suite: s,
+		role:  role,
+		sid:   append([]byte{}, cfg.SessionID...),
+		y:     y,
+		msg:   lvCat(Y, cfg.AssociatedData),
+	}
+}
+
+// Message returns the message (MSGa or MSGb) to be sent to the peer.
+func (st *State) Message() []byte {
+	return append([]byte{}, st.msg...)
+}
+
+// Finish processes the peer's message, and returns the intermediate
+// session key (ISK) and the peer's associated data.
+//
+// Note: CPace does not provide explicit key confirmation, callers
+// should use the ISK in a way that implicitly or explicitly confirms
+// that both parties derived the same key.
+func (st *State) Finish(peerMsg []byte) ([]byte, []byte, error) {
+	if st.finished {
+		return nil, nil, fmt.Errorf("cpace: session already finished")
+	}
+
+	peerY, peerAD, err := parseMessage(peerMsg)
+	if err != nil {
+		return nil, nil, err
+	}
+	k, err := st.suite.g.scalarMultVfy(st.y, peerY)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var transcript []byte
+	switch st.role {
+	case RoleInitiator:
+		transcript = concat(st.msg, peerMsg)
+	case RoleResponder:
+		transcript = concat(peerMsg, st.msg)
+	case RoleSymmetric:
+		transcript = concat([]byte(labelOC), oCat(st.msg, peerMsg))
+	}
+
+	// ISK = H.hash(lv_cat(G.DSI || "_ISK", sid, K) || transcript)
+	h := sha512.New()
+	_, _ = h.Write(lvCat(concat([]byte(st.suite.g.dsi()), []byte(labelISK)), st.sid, k))
+	_, _ = h.Write(transcript)
+
+	st.finished = true
+
+	return h.Sum(nil), peerAD, nil
+}
+
+func (s *Suite) generatorString(prs, ci, sid []byte) []byte {
+	dsi := []byte(s.g.dsi())
+	zpadLen := hashBlockSize - 1 - len(prependLen(prs)) - len(prependLen(dsi))
+	if zpadLen < 0 {
+		zpadLen = 0
+	}
+	return lvCat(dsi, prs, make([]byte, zpadLen), ci, sid)
+}
+
+func (s *Suite) calculateGenerator(prs, ci, sid []byte) []byte {
+	genStrHash := sha512.Sum512(s.generatorString(prs, ci, sid))
+	return s.g.elementDerivation(genStrHash[:])
+}
+
+func parseMessage(msg []byte) ([]byte, []byte, error) {
+	Y, rest, err := splitPrependedLen(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	ad, rest, err := splitPrependedLen(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%w: trailing data", ErrInvalidMessage)
+	}
+	return Y, ad, nil
+}
+
+// prependLen prepends the LEB128 encoded length to data.
+func prependLen(data []byte) []byte {
+	var out []byte
+	l := len(data)
+	for {
+		if l < 128 {
+			out = append(out, byte(l))
+		} else {
+			out = append(out, byte(l&0x7f)|0x80)
+		}
+		l >>= 7
+		if l == 0 {
+			break
+		}
+	}
+	return append(out, data...)
+}
+
+func splitPrependedLen(b []byte) ([]byte, []byte, error) {
+	var (
+		l     uint64
+		shift uint
+	)
+	for i, v := range b {
+		if shift > 28 {
+			break
+		}
+		l |= uint64(v&0x7f) << shift
+		if v&0x80 == 0 {
+			b = b[i+1:]
+			if uint64(len(b)) < l {
+				return nil, nil, fmt.Errorf("%w: truncated", ErrInvalidMessage)
+			}
+			return b[:l], b[l:], nil
+		}
+		shift += 7
+	}
+	return nil, nil, fmt.Errorf("%w: invalid length", ErrInvalidMessage)
+}
+
+func lvCat(parts ...[]byte) []byte {
+	var out []byte
+	for _, v := range parts {
+		out = append(out, prependLen(v)...)
+	}
+	return out
+}
+
+// oCat returns the ordered concatenation of a and b, with the
+// lexicographically larger value first.
+func oCat(a, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		return concat(a, b)
+	}
+	return concat(b, a)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, v := range parts {
+		out = append(out, v...)
+	}
+	return out
+}
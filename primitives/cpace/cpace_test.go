@@ -0,0 +1,353 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cpace
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+func TestEncoding(t *testing.T) {
+	t.Run("PrependLen", func(t *testing.T) {
+		for _, v := range []struct {
+			length   int
+			expected string
+		}{
+			{0, "00"},
+			{1, "01"},
+			{127, "7f"},
+			{128, "8001"},
+			{300, "ac02"},
+		} {
+			b := prependLen(make([]byte, v.length))
+			prefix := b[:len(b)-v.length]
+			if !bytes.Equal(prefix, testhelpers.MustUnhex(t, v.expected)) {
+				t.Fatalf("prependLen(%d): got %x, expected %s", v.length, prefix, v.expected)
+			}
+
+			data, rest, err := splitPrependedLen(append(b, 0xff))
+			if err != nil {
+				t.Fatalf("splitPrependedLen(%d): %v", v.length, err)
+			}
+			if len(data) != v.length || !bytes.Equal(rest, []byte{0xff}) {
+				t.Fatalf("splitPrependedLen(%d): invalid split", v.length)
+			}
+		}
+
+		for _, b := range [][]byte{
+			nil,
+			{0x80},
+			{0x02, 0x00},
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		} {
+			if _, _, err := splitPrependedLen(b); !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("splitPrependedLen(%x): expected ErrInvalidMessage, got %v", b, err)
+			}
+		}
+	})
+
+	t.Run("GeneratorString", func(t *testing.T) {
+		prs := []byte("Password")
+		ci := testhelpers.MustUnhex(t, "0a41696e69746961746f720a42726573706f6e646572")
+		sid := testhelpers.MustUnhex(t, "7e4b4791d6a8ef019b936c79fb7f2c57")
+
+		// The zero padding fills the first hash block with the DSI
+		// and PRS: 128 - 1 - len(prepend_len(PRS)) -
+		// len(prepend_len(DSI)), 109 bytes for CPace255, and 100
+		// bytes for CPaceRistretto255.
+		for _, v := range []struct {
+			suite    *Suite
+			expected string
+		}{
+			{
+				X25519SHA512,
+				"0843506163653235350850617373776f72646d" + strings.Repeat("00", 109) +
+					"160a41696e69746961746f720a42726573706f6e646572107e4b4791d6a8ef019b936c79fb7f2c57",
+			},
+			{
+				Ristretto255SHA512,
+				"11435061636552697374726574746f3235350850617373776f726464" + strings.Repeat("00", 100) +
+					"160a41696e69746961746f720a42726573706f6e646572107e4b4791d6a8ef019b936c79fb7f2c57",
+			},
+		} {
+			genStr := v.suite.generatorString(prs, ci, sid)
+			if !bytes.Equal(genStr, testhelpers.MustUnhex(t, v.expected)) {
+				t.Fatalf("%s: generator string mismatch: %x", v.suite, genStr)
+			}
+		}
+	})
+
+	t.Run("OCat", func(t *testing.T) {
+		for _, v := range []struct {
+			a, b, expected string
+		}{
+			{"01", "02", "0201"},
+			{"02", "01", "0201"},
+			{"0101", "01", "010101"},
+			{"01", "0101", "010101"},
+		} {
+			a, b := testhelpers.MustUnhex(t, v.a), testhelpers.MustUnhex(t, v.b)
+			if out := oCat(a, b); !bytes.Equal(out, testhelpers.MustUnhex(t, v.expected)) {
+				t.Fatalf("oCat(%s, %s): got %x", v.a, v.b, out)
+			}
+		}
+	})
+}
+
+func TestVectors(t *testing.T) {
+	// These are NOT the draft's Appendix B test vectors.  The PRS, CI,
+	// sid and associated data mirror the draft's inputs, but the
+	// scalars differ and the expected values were computed locally,
+	// then cross-checked against an independent implementation written
+	// from the draft (and RFC 7748, RFC 9380 and RFC 9496) that shares
+	// no code with this package.
+	//
+	// TODO: Add the Appendix B vectors, citing the draft revision.
+	var (
+		prs  = []byte("Password")
+		ci   = []byte("\nAinitiator\nBresponder")
+		sid  = testhelpers.MustUnhex(t, "7e4b4791d6a8ef019b936c79fb7f2c57")
+		cfgA = &Config{ChannelID: ci, SessionID: sid, AssociatedData: []byte("ADa")}
+		cfgB = &Config{ChannelID: ci, SessionID: sid, AssociatedData: []byte("ADb")}
+	)
+
+	for _, v := range []struct {
+		suite        *Suite
+		g            string
+		ya, yb       string
+		Ya, Yb       string
+		K            string
+		iskIR        string
+		iskSymmetric string
+	}{
+		{
+			suite:        X25519SHA512,
+			g:            "4e6098733061c0e8486611a904fe5edb049804d26130a44131a6229e55c5c321",
+			ya:           "21b4f4bd9e64ed355c3eb676a28ebedaf6d8f17bdc365995b319097153044080",
+			yb:           "848b0779ff415f0af4ea14df9dd1d3c29ac41d836c7808896c4eba19c51ac40a",
+			Ya:           "f970e36f37cfcd9a39e37dd2d1fbc9156d6d2f9ae422f4722cbd9d32e9b1e704",
+			Yb:           "0178bbbab0804a4455b8f02e5d6e7d80997c6470bfb3618d7e74c39647af5a29",
+			K:            "42ba4c6dc4c184a1cf405d4503f64bf7f015e2a0107450e38b9efff3bee52412",
+			iskIR:        "f5ef3c13fdb9dfe839bdbf8a9256e8cee7db8a8f1dfa74958a925450cf8089cd560d9a4e7956b7334b6f625c8559b75ea0764ac2be894b8f3d434b30e87797d5",
+			iskSymmetric: "f4051edc63b2620e10d5ecf76d9f0c5ccd1447858a98d4bf847fafac737478c1350e14619bc0fcd4f028d10e4102dfca39f91fe9b829a503ab3e0549bd835edf",
+		},
+		{
+			suite:        Ristretto255SHA512,
+			g:            "5e25411ca1ad7c9debfd0b33ad987a95cefef2d3f15dcc8bd26415a5dfe2e15a",
+			ya:           "da3d23700a9e5699258aef94dc060dfda5ebb61f02a5ea77fad53f4ff0976d08",
+			yb:           "d2316b454718c35362d83d69df6320f38578ed5984651435e2949762d900b80d",
+			Ya:           "383a85dd236978f17f8c8545b50dabc52a39fcdab2cf8bc531ce040ff77ca82d",
+			Yb:           "a6206309c0e8e5f579295e35997ac4300ab3fecec3c17f7b604f3e698fa1383c",
+			K:            "fa1d0318864e2cacb26875f1b791c9ae83204fe8359addb53e95a2e98893853f",
+			iskIR:        "e91ccb2c0f5e0d0993a33956e3be59754f3f2b07db57631f5394452ea2e7b4354674eb1f5686c078462bf83bec72e8743df440108e638f3526d9b90e85be096f",
+			iskSymmetric: "1638fb6ff564a80a12af07c036870e10c4efb539fa847fdf3e9c46217bf52cd4df4ca0fe51146492a9ba6dd6a42ac402bc2d60adb4084c81758d754d1d81482a",
+		},
+	} {
+		suite := v.suite
+		t.Run(suite.Name(), func(t *testing.T) {
+			// For CPace255, this also checks that the generator is
+			// Elligator 2 applied to the 255-bit masked hash.
+			g := suite.calculateGenerator(prs, ci, sid)
+			if !bytes.Equal(g, testhelpers.MustUnhex(t, v.g)) {
+				t.Fatalf("g mismatch: got %x", g)
+			}
+
+			ya, yb := testhelpers.MustUnhex(t, v.ya), testhelpers.MustUnhex(t, v.yb)
+			if Ya := suite.g.scalarMult(ya, g); !bytes.Equal(Ya, testhelpers.MustUnhex(t, v.Ya)) {
+				t.Fatalf("Ya mismatch: got %x", Ya)
+			}
+			if Yb := suite.g.scalarMult(yb, g); !bytes.Equal(Yb, testhelpers.MustUnhex(t, v.Yb)) {
+				t.Fatalf("Yb mismatch: got %x", Yb)
+			}
+			K, err := suite.g.scalarMultVfy(ya, testhelpers.MustUnhex(t, v.Yb))
+			if err != nil {
+				t.Fatalf("scalarMultVfy: %v", err)
+			}
+			if !bytes.Equal(K, testhelpers.MustUnhex(t, v.K)) {
+				t.Fatalf("K mismatch: got %x", K)
+			}
+
+			for _, vv := range []struct {
+				name         string
+				roleA, roleB Role
+				expected     string
+			}{
+				{"InitiatorResponder", RoleInitiator, RoleResponder, v.iskIR},
+				{"Symmetric", RoleSymmetric, RoleSymmetric, v.iskSymmetric},
+			} {
+				a := suite.newState(vv.roleA, prs, cfgA, ya)
+				b := suite.newState(vv.roleB, prs, cfgB, yb)
+				iskA, _, err := a.Finish(b.Message())
+				if err != nil {
+					t.Fatalf("%s: A.Finish: %v", vv.name, err)
+				}
+				iskB, _, err := b.Finish(a.Message())
+				if err != nil {
+					t.Fatalf("%s: B.Finish: %v", vv.name, err)
+				}
+				expected := testhelpers.MustUnhex(t, vv.expected)
+				if !bytes.Equal(iskA, expected) || !bytes.Equal(iskB, expected) {
+					t.Fatalf("%s: ISK mismatch: got %x %x", vv.name, iskA, iskB)
+				}
+			}
+		})
+	}
+}
+
+func runExchange(t *testing.T, suite *Suite, roleA, roleB Role, prsA, prsB []byte, cfgA, cfgB *Config) ([]byte, []byte) {
+	a, err := suite.New(roleA, prsA, cfgA, nil)
+	if err != nil {
+		t.Fatalf("New(A): %v", err)
+	}
+	b, err := suite.New(roleB, prsB, cfgB, nil)
+	if err != nil {
+		t.Fatalf("New(B): %v", err)
+	}
+
+	iskA, adB, err := a.Finish(b.Message())
+	if err != nil {
+		t.Fatalf("A.Finish: %v", err)
+	}
+	iskB, adA, err := b.Finish(a.Message())
+	if err != nil {
+		t.Fatalf("B.Finish: %v", err)
+	}
+	if !bytes.Equal(adA, cfgA.AssociatedData) || !bytes.Equal(adB, cfgB.AssociatedData) {
+		t.Fatalf("associated data mismatch")
+	}
+	if len(iskA) != ISKSize || len(iskB) != ISKSize {
+		t.Fatalf("invalid ISK length")
+	}
+
+	return iskA, iskB
+}
+
+func TestCPace(t *testing.T) {
+	for _, suite := range []*Suite{
+		Ristretto255SHA512,
+		X25519SHA512,
+	} {
+		suite := suite
+		t.Run(suite.Name(), func(t *testing.T) {
+			testCPace(t, suite)
+		})
+	}
+}
+
+func testCPace(t *testing.T, suite *Suite) {
+	prs := []byte("Password")
+	cfgA := &Config{
+		ChannelID:      []byte("\nAinitiator\nBresponder"),
+		SessionID:      []byte("curve25519-voi/cpace: test sid"),
+		AssociatedData: []byte("ADa"),
+	}
+	cfgB := &Config{
+		ChannelID:      cfgA.ChannelID,
+		SessionID:      cfgA.SessionID,
+		AssociatedData: []byte("ADb"),
+	}
+
+	t.Run("InitiatorResponder", func(t *testing.T) {
+		iskA, iskB := runExchange(t, suite, RoleInitiator, RoleResponder, prs, prs, cfgA, cfgB)
+		if !bytes.Equal(iskA, iskB) {
+			t.Fatalf("ISK mismatch")
+		}
+		iskA2, _ := runExchange(t, suite, RoleInitiator, RoleResponder, prs, prs, cfgA, cfgB)
+		if bytes.Equal(iskA, iskA2) {
+			t.Fatalf("ISK is not fresh")
+		}
+	})
+
+	t.Run("Symmetric", func(t *testing.T) {
+		iskA, iskB := runExchange(t, suite, RoleSymmetric, RoleSymmetric, prs, prs, cfgA, cfgB)
+		if !bytes.Equal(iskA, iskB) {
+			t.Fatalf("ISK mismatch")
+		}
+	})
+
+	t.Run("RoleMismatch", func(t *testing.T) {
+		iskA, iskB := runExchange(t, suite, RoleInitiator, RoleInitiator, prs, prs, cfgA, cfgB)
+		if bytes.Equal(iskA, iskB) {
+			t.Fatalf("ISK matches with mismatched roles")
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		iskA, iskB := runExchange(t, suite, RoleInitiator, RoleResponder, prs, []byte("Passw0rd"), cfgA, cfgB)
+		if bytes.Equal(iskA, iskB) {
+			t.Fatalf("ISK matches with different PRS")
+		}
+
+		for _, cfg := range []*Config{
+			{ChannelID: []byte("other"), SessionID: cfgB.SessionID, AssociatedData: cfgB.AssociatedData},
+			{ChannelID: cfgB.ChannelID, SessionID: []byte("other"), AssociatedData: cfgB.AssociatedData},
+		} {
+			iskA, iskB = runExchange(t, suite, RoleInitiator, RoleResponder, prs, prs, cfgA, cfg)
+			if bytes.Equal(iskA, iskB) {
+				t.Fatalf("ISK matches with different CI/sid")
+			}
+		}
+	})
+
+	t.Run("InvalidMessage", func(t *testing.T) {
+		a, err := suite.New(RoleInitiator, prs, cfgA, nil)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		b, err := suite.New(RoleResponder, prs, cfgB, nil)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		msg := b.Message()
+
+		for _, badMsg := range [][]byte{
+			nil,
+			msg[:len(msg)-1],
+			append(append([]byte{}, msg...), 0x00),
+			lvCat(make([]byte, 32), nil), // Identity, or low-order for X25519.
+			lvCat(make([]byte, 31), nil),
+		} {
+			if _, _, err = a.Finish(badMsg); !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("Finish(%x): expected ErrInvalidMessage, got %v", badMsg, err)
+			}
+		}
+
+		if _, _, err = a.Finish(msg); err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		if _, _, err = a.Finish(msg); err == nil {
+			t.Fatalf("Finish: accepted twice")
+		}
+	})
+}
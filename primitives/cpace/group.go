@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cpace
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/elligator"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+// group is a CPace group, with elements and scalars handled in their
+// serialized forms.
+type group interface {
+	dsi() string
+	elementDerivation(genStrHash []byte) []byte
+	sampleScalar(rand io.Reader) ([]byte, error)
+	scalarMult(y, g []byte) []byte
+	scalarMultVfy(y, X []byte) ([]byte, error)
+}
+
+type ristretto255Group struct{}
+
+func (ristretto255Group) dsi() string {
+	return "CPaceRistretto255"
+}
+
+func (ristretto255Group) elementDerivation(genStrHash []byte) []byte {
+	// The generator is derived with the ristretto255 one-way map
+	// applied to the 64-byte hash of the generator string.
+	var (
+		p          curve.RistrettoPoint
+		compressed curve.CompressedRistretto
+	)
+	if _, err := p.SetUniformBytes(genStrHash); err != nil {
+		panic("cpace: failed to map to ristretto255: " + err.Error())
+	}
+	compressed.SetRistrettoPoint(&p)
+	return compressed[:]
+}
+
+func (ristretto255Group) sampleScalar(rand io.Reader) ([]byte, error) {
+	for {
+		y, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return nil, err
+		}
+		if y.Equal(scalar.New()) == 0 {
+			return y.MarshalBinary()
+		}
+	}
+}
+
+func (ristretto255Group) decode(b []byte) (*curve.RistrettoPoint, error) {
+	var (
+		compressed curve.CompressedRistretto
+		p          curve.RistrettoPoint
+	)
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if _, err := p.SetCompressed(&compressed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return &p, nil
+}
+
+func (g ristretto255Group) scalarMult(y, X []byte) []byte {
+	p, err := g.decode(X)
+	if err != nil {
+		panic("cpace: failed to decode generator: " + err.Error())
+	}
+	return g.mul(y, p)
+}
+
+func (g ristretto255Group) scalarMultVfy(y, X []byte) ([]byte, error) {
+	p, err := g.decode(X)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsIdentity() {
+		return nil, fmt.Errorf("%w: element is the identity", ErrInvalidMessage)
+	}
+	return g.mul(y, p), nil
+}
+
+func (ristretto255Group) mul(y []byte, p *curve.RistrettoPoint) []byte {
+	s, err := scalar.NewFromCanonicalBytes(y)
+	if err != nil {
+		panic("cpace: failed to decode scalar: " + err.Error())
+	}
+
+	var compressed curve.CompressedRistretto
+	compressed.SetRistrettoPoint(p.Mul(p, s))
+	return compressed[:]
+}
+
+type x25519Group struct{}
+
+func (x25519Group) dsi() string {
+	return "CPace255"
+}
+
+func (x25519Group) elementDerivation(genStrHash []byte) []byte {
+	// The generator is derived with the RFC 9380 Elligator 2 map to
+	// curve25519, applied to the field element decoded from the first
+	// 32 bytes of the hash of the generator string.
+	var (
+		uBytes [field.ElementSize]byte
+		r      field.Element
+		g      [x25519.PointSize]byte
+	)
+	copy(uBytes[:], genStrHash[:field.ElementSize])
+	uBytes[31] &= 0x7f
+	if _, err := r.SetBytes(uBytes[:]); err != nil {
+		panic("cpace: failed to decode field element: " + err.Error())
+	}
+
+	u, _ := elligator.MontgomeryFlavor(&r)
+	_ = u.ToBytes(g[:])
+	return g[:]
+}
+
+func (x25519Group) sampleScalar(rand io.Reader) ([]byte, error) {
+	y := make([]byte, x25519.ScalarSize)
+	if _, err := io.ReadFull(rand, y); err != nil {
+		return nil, err
+	}
+	return y, nil
+}
+
+func (x25519Group) scalarMult(y, g []byte) []byte {
+	var out [x25519.PointSize]byte
+	x25519.ScalarMult(&out, (*[x25519.ScalarSize]byte)(y), (*[x25519.PointSize]byte)(g))
+	return out[:]
+}
+
+func (g x25519Group) scalarMultVfy(y, X []byte) ([]byte, error) {
+	if len(X) != x25519.PointSize {
+		return nil, fmt.Errorf("%w: invalid element length", ErrInvalidMessage)
+	}
+
+	var (
+		priv x25519.PrivateKey
+		pub  x25519.PublicKey
+	)
+	copy(priv[:], y)
+	copy(pub[:], X)
+	k, err := priv.DiffieHellmanChecked(&pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return k[:], nil
+}
@@ -365,30 +365,8 @@ func (priv PrivateKey) Seed() []byte {
 //
 // Warning: This routine will panic if opts is nil.
 func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
-	var (
-		context    []byte
-		f          dom2Flag = fPure
-		addedRand  bool
-		selfVerify bool
-	)
-	if o, ok := opts.(*Options); ok {
-		f, context, err = o.verify()
-		if err != nil {
-			return nil, err
-		}
-
-		addedRand = o.AddedRandomness
-		if rand == nil {
-			rand = cryptorand.Reader
-		}
-
-		selfVerify = o.SelfVerify
-	}
-
-	// Now that the Options specific validation is done, see if the caller
-	// wants Ed25519ph instead.
-	f, err = checkHash(f, message, opts.HashFunc())
-	if err != nil {
+	var sp signingParams
+	if rand, err = sp.init(rand, message, opts); err != nil {
 		return nil, err
 	}
 
@@ -396,91 +374,10 @@ func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOp
 		return nil, fmt.Errorf("ed25519: bad private key length: %d", l)
 	}
 
-	var extsk [64]byte
-	h := sha512.New()
-	_, _ = h.Write(priv[:32])
-	h.Sum(extsk[:0])
-	extsk[0] &= 248
-	extsk[31] &= 127
-	extsk[31] |= 64
-
-	// r = H(dom2, aExt[32..64], m)
-	var (
-		hashr [64]byte
-		r     scalar.Scalar
-	)
-	h.Reset()
-	dom2 := makeDom2(f, context)
-	if dom2 != nil {
-		_, _ = h.Write(dom2)
-	}
-	if addedRand {
-		var entropy [addedRandomnessSize]byte
-		if _, err := io.ReadFull(rand, entropy[:]); err != nil {
-			return nil, fmt.Errorf("ed25519: failed to read Z: %w", err)
-		}
-		_, _ = h.Write(entropy[:])
-	}
-	_, _ = h.Write(extsk[32:])
-	if addedRand {
-		padSize := len(addedRandomnessPadding) - (len(dom2) + addedRandomnessSize + 32)
-		_, _ = h.Write(addedRandomnessPadding[:padSize])
-	}
-	_, _ = h.Write(message)
-	h.Sum(hashr[:0])
-	if _, err = r.SetBytesModOrderWide(hashr[:]); err != nil {
-		return nil, fmt.Errorf("ed25519: failed to deserialize r scalar: %w", err)
-	}
-
-	// R = rB
-	var (
-		R           curve.EdwardsPoint
-		rCompressed curve.CompressedEdwardsY
-	)
-	rCompressed.SetEdwardsPoint(R.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &r))
-
-	// S = H(R,A,m)
-	var (
-		hram [64]byte
-		S    scalar.Scalar
-	)
-	h.Reset()
-	if dom2 != nil {
-		_, _ = h.Write(dom2)
-	}
-	_, _ = h.Write(rCompressed[:])
-	_, _ = h.Write(priv[32:])
-	_, _ = h.Write(message)
-	h.Sum(hram[:0])
-	if _, err = S.SetBytesModOrderWide(hram[:]); err != nil {
-		return nil, fmt.Errorf("ed25519: failed to deserialize H(R,A,m) scalar: %w", err)
-	}
-
-	// S = H(R,A,m)a
-	var a scalar.Scalar
-	if _, err = a.SetBits(extsk[:32]); err != nil {
-		return nil, fmt.Errorf("ed25519: failed to deserialize a scalar: %w", err)
-	}
-	S.Mul(&S, &a)
-
-	// S = (r + H(R,A,m)a)
-	S.Add(&S, &r)
-
-	// S = (r + H(R,A,m)a) mod L
-	var RS [SignatureSize]byte
-	copy(RS[:32], rCompressed[:])
-	if err = S.ToBytes(RS[32:]); err != nil {
-		return nil, fmt.Errorf("ed25519: failed to serialize S scalar: %w", err)
-	}
-
-	// If opts.SelfVerify is set, verify the newly created signature.
-	if selfVerify {
-		if !VerifyWithOptions(PublicKey(priv[32:]), message, RS[:], opts.(*Options)) {
-			return nil, fmt.Errorf("ed25519: failed to self-verify signature")
-		}
-	}
+	var expanded ExpandedPrivateKey
+	expanded.setPrivateKey(priv)
 
-	return RS[:], nil
+	return expanded.sign(rand, message, opts, &sp)
 }
 
 // PublicKey is the type of Ed25519 public keys.
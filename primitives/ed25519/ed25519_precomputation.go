@@ -30,8 +30,11 @@
 package ed25519
 
 import (
+	"crypto"
+	cryptorand "crypto/rand"
 	"crypto/sha512"
 	"fmt"
+	"io"
 
 	"github.com/oasisprotocol/curve25519-voi/curve"
 	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
@@ -58,6 +61,29 @@ func (k *ExpandedPublicKey) CompressedY() curve.CompressedEdwardsY {
 	return k.compressed
 }
 
+// IsCanonical returns true iff the unexpanded public key is a valid
+// point, in canonical encoding.
+func (k *ExpandedPublicKey) IsCanonical() bool {
+	return k.isValidY && k.isCanonical
+}
+
+// IsSmallOrder returns true iff the public key is a point of small order.
+func (k *ExpandedPublicKey) IsSmallOrder() bool {
+	return k.isSmallOrder
+}
+
+// NegatedPoint returns the negation of the public key point in expanded
+// form, or nil iff the public key is invalid.
+//
+// Note: The returned point is shared with the ExpandedPublicKey, and
+// MUST NOT be modified.
+func (k *ExpandedPublicKey) NegatedPoint() *curve.ExpandedEdwardsPoint {
+	if !k.isValidY {
+		return nil
+	}
+	return &k.negA
+}
+
 func (vOpts *VerifyOptions) checkExpandedPublicKey(publicKey *ExpandedPublicKey) bool {
 	// This is equivalent to VerifyOptions.unpackPublicKey, but all of
 	// the outcomes are cached at the precompute step.
@@ -182,3 +208,289 @@ func verifyExpandedWithOptionsNoPanic(publicKey *ExpandedPublicKey, message, sig
 	var rDiff curve.EdwardsPoint
 	return rDiff.ExpandedTripleScalarMulBasepointVartime(&hram, negA, &S, &checkR).IsSmallOrder(), nil
 }
+
+// ExpandedPrivateKey is a PrivateKey stored in an expanded representation
+// for the purpose of accelerating repeated signing.  It implements
+// crypto.Signer.
+type ExpandedPrivateKey struct {
+	a         scalar.Scalar
+	prefix    [32]byte
+	publicKey [PublicKeySize]byte
+}
+
+// Public returns the PublicKey corresponding to k.
+func (k *ExpandedPrivateKey) Public() crypto.PublicKey {
+	pub := make([]byte, PublicKeySize)
+	copy(pub, k.publicKey[:])
+	return PublicKey(pub)
+}
+
+// Sign signs the given message with k.  The semantics are identical to
+// PrivateKey.Sign.
+//
+// Warning: This routine will panic if opts is nil.
+func (k *ExpandedPrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	var sp signingParams
+	if rand, err = sp.init(rand, message, opts); err != nil {
+		return nil, err
+	}
+
+	return k.sign(rand, message, opts, &sp)
+}
+
+func (k *ExpandedPrivateKey) sign(rand io.Reader, message []byte, opts crypto.SignerOpts, sp *signingParams) ([]byte, error) {
+	// r = H(dom2, aExt[32..64], m)
+	var r scalar.Scalar
+	if err := k.nonce(&r, rand, sp, message); err != nil {
+		return nil, err
+	}
+
+	// R = rB
+	var (
+		R           curve.EdwardsPoint
+		rCompressed curve.CompressedEdwardsY
+	)
+	rCompressed.SetEdwardsPoint(R.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &r))
+
+	// S = (r + H(R,A,m)a) mod L
+	var RS [SignatureSize]byte
+	if err := k.finish(&RS, sp, &rCompressed, &r, message); err != nil {
+		return nil, err
+	}
+
+	// If opts.SelfVerify is set, verify the newly created signature.
+	if sp.selfVerify {
+		if !VerifyWithOptions(PublicKey(k.publicKey[:]), message, RS[:], opts.(*Options)) {
+			return nil, fmt.Errorf("ed25519: failed to self-verify signature")
+		}
+	}
+
+	return RS[:], nil
+}
+
+// SignBatch signs each of the given messages with k.  The semantics
+// (and the signatures produced) are identical to calling Sign on each
+// message, but the per-signature overhead is reduced by batching the
+// point compression.
+//
+// If opts.HashFunc() is crypto.SHA512, each message is expected to be a
+// SHA-512 hash.
+//
+// Warning: This routine will panic if opts is nil.
+func (k *ExpandedPrivateKey) SignBatch(rand io.Reader, messages [][]byte, opts crypto.SignerOpts) ([][]byte, error) {
+	var (
+		sp  signingParams
+		err error
+	)
+	for _, message := range messages {
+		// Validate the options and all of the messages (pre-hash
+		// length) up front.
+		if rand, err = sp.init(rand, message, opts); err != nil {
+			return nil, err
+		}
+	}
+	if len(messages) == 0 {
+		return [][]byte{}, nil
+	}
+
+	n := len(messages)
+	rs := make([]scalar.Scalar, n)
+	Rs := make([]curve.EdwardsPoint, n)
+	RPtrs := make([]*curve.EdwardsPoint, n)
+	for i, message := range messages {
+		// r = H(dom2, aExt[32..64], m)
+		if err = k.nonce(&rs[i], rand, &sp, message); err != nil {
+			return nil, err
+		}
+
+		// R = rB
+		RPtrs[i] = Rs[i].MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &rs[i])
+	}
+
+	rCompressed := make([]curve.CompressedEdwardsY, n)
+	curve.CompressEdwardsYBatch(rCompressed, RPtrs)
+
+	sigs := make([]byte, n*SignatureSize)
+	signatures := make([][]byte, n)
+	for i, message := range messages {
+		// S = (r + H(R,A,m)a) mod L
+		RS := (*[SignatureSize]byte)(sigs[i*SignatureSize : (i+1)*SignatureSize])
+		if err = k.finish(RS, &sp, &rCompressed[i], &rs[i], message); err != nil {
+			return nil, err
+		}
+		signatures[i] = RS[:]
+
+		// If opts.SelfVerify is set, verify the newly created signature.
+		if sp.selfVerify {
+			if !VerifyWithOptions(PublicKey(k.publicKey[:]), message, RS[:], opts.(*Options)) {
+				return nil, fmt.Errorf("ed25519: failed to self-verify signature")
+			}
+		}
+	}
+
+	return signatures, nil
+}
+
+type signingParams struct {
+	dom2       []byte
+	addedRand  bool
+	selfVerify bool
+}
+
+func (sp *signingParams) init(rand io.Reader, message []byte, opts crypto.SignerOpts) (io.Reader, error) {
+	var (
+		context []byte
+		f       dom2Flag = fPure
+		err     error
+	)
+	if o, ok := opts.(*Options); ok {
+		f, context, err = o.verify()
+		if err != nil {
+			return nil, err
+		}
+
+		sp.addedRand = o.AddedRandomness
+		if rand == nil {
+			rand = cryptorand.Reader
+		}
+
+		sp.selfVerify = o.SelfVerify
+	}
+
+	// Now that the Options specific validation is done, see if the caller
+	// wants Ed25519ph instead.
+	f, err = checkHash(f, message, opts.HashFunc())
+	if err != nil {
+		return nil, err
+	}
+	sp.dom2 = makeDom2(f, context)
+
+	return rand, nil
+}
+
+func (k *ExpandedPrivateKey) nonce(r *scalar.Scalar, rand io.Reader, sp *signingParams, message []byte) error {
+	var hashr [64]byte
+	h := sha512.New()
+	if sp.dom2 != nil {
+		_, _ = h.Write(sp.dom2)
+	}
+	if sp.addedRand {
+		var entropy [addedRandomnessSize]byte
+		if _, err := io.ReadFull(rand, entropy[:]); err != nil {
+			return fmt.Errorf("ed25519: failed to read Z: %w", err)
+		}
+		_, _ = h.Write(entropy[:])
+	}
+	_, _ = h.Write(k.prefix[:])
+	if sp.addedRand {
+		padSize := len(addedRandomnessPadding) - (len(sp.dom2) + addedRandomnessSize + 32)
+		_, _ = h.Write(addedRandomnessPadding[:padSize])
+	}
+	_, _ = h.Write(message)
+	h.Sum(hashr[:0])
+	if _, err := r.SetBytesModOrderWide(hashr[:]); err != nil {
+		return fmt.Errorf("ed25519: failed to deserialize r scalar: %w", err)
+	}
+
+	return nil
+}
+
+func (k *ExpandedPrivateKey) finish(RS *[SignatureSize]byte, sp *signingParams, rCompressed *curve.CompressedEdwardsY, r *scalar.Scalar, message []byte) error {
+	// S = H(R,A,m)
+	var (
+		hram [64]byte
+		S    scalar.Scalar
+	)
+	h := sha512.New()
+	if sp.dom2 != nil {
+		_, _ = h.Write(sp.dom2)
+	}
+	_, _ = h.Write(rCompressed[:])
+	_, _ = h.Write(k.publicKey[:])
+	_, _ = h.Write(message)
+	h.Sum(hram[:0])
+	if _, err := S.SetBytesModOrderWide(hram[:]); err != nil {
+		return fmt.Errorf("ed25519: failed to deserialize H(R,A,m) scalar: %w", err)
+	}
+
+	// S = H(R,A,m)a
+	S.Mul(&S, &k.a)
+
+	// S = (r + H(R,A,m)a)
+	S.Add(&S, r)
+
+	// S = (r + H(R,A,m)a) mod L
+	copy(RS[:32], rCompressed[:])
+	if err := S.ToBytes(RS[32:]); err != nil {
+		return fmt.Errorf("ed25519: failed to serialize S scalar: %w", err)
+	}
+
+	return nil
+}
+
+func (k *ExpandedPrivateKey) setPrivateKey(priv PrivateKey) {
+	var extsk [64]byte
+	h := sha512.New()
+	_, _ = h.Write(priv[:32])
+	h.Sum(extsk[:0])
+	extsk[0] &= 248
+	extsk[31] &= 127
+	extsk[31] |= 64
+
+	if _, err := k.a.SetBits(extsk[:32]); err != nil {
+		panic("ed25519: failed to deserialize a scalar: " + err.Error())
+	}
+	copy(k.prefix[:], extsk[32:])
+	copy(k.publicKey[:], priv[32:])
+}
+
+// NewExpandedPrivateKey creates a new expanded private key from an existing
+// private key.
+func NewExpandedPrivateKey(privateKey PrivateKey) (*ExpandedPrivateKey, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		return nil, fmt.Errorf("ed25519: bad private key length: %d", l)
+	}
+
+	var k ExpandedPrivateKey
+	k.setPrivateKey(privateKey)
+
+	return &k, nil
+}
+
+// SignExpanded signs the message with privateKey and returns a signature.
+func SignExpanded(privateKey *ExpandedPrivateKey, message []byte) []byte {
+	signature, err := privateKey.Sign(nil, message, optionsDefault)
+	if err != nil {
+		panic(err)
+	}
+
+	return signature
+}
+
+// SignExpandedWithOptions signs the message with privateKey and returns
+// a signature, with the extra Options to support Ed25519ph (pre-hashed by
+// SHA-512), Ed25519ctx (includes a domain separation context), added
+// randomness (from crypto/rand.Reader), and self-verification.
+//
+// Warning: This routine will panic if opts is nil.
+func SignExpandedWithOptions(privateKey *ExpandedPrivateKey, message []byte, opts *Options) ([]byte, error) {
+	return privateKey.Sign(nil, message, opts)
+}
+
+// SignBatch signs each of the messages with privateKey and returns the
+// signatures.  The signatures are identical to those produced by calling
+// Sign on each message.  It will panic if len(privateKey) is not
+// PrivateKeySize.
+func SignBatch(privateKey PrivateKey, messages [][]byte) [][]byte {
+	expanded, err := NewExpandedPrivateKey(privateKey)
+	if err != nil {
+		panic(err)
+	}
+
+	signatures, err := expanded.SignBatch(nil, messages, optionsDefault)
+	if err != nil {
+		panic(err)
+	}
+
+	return signatures
+}
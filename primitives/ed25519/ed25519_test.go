@@ -37,6 +37,7 @@ import (
 	stded "crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha512"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -58,6 +59,163 @@ func TestStdLib(t *testing.T) {
 	t.Run("Malleability", testMalleability)
 }
 
+func TestSignErrorOrder(t *testing.T) {
+	// Options are validated before the private key length.
+	badKey := PrivateKey(make([]byte, PrivateKeySize-1))
+	badOpts := &Options{
+		Context: strings.Repeat("a", ContextMaxSize+1),
+	}
+
+	_, err := badKey.Sign(nil, []byte("test message"), badOpts)
+	if err == nil || !strings.Contains(err.Error(), "bad context length") {
+		t.Fatalf("Sign(bad key, bad opts): unexpected error: %v", err)
+	}
+
+	_, err = badKey.Sign(nil, []byte("test message"), &Options{})
+	if err == nil || !strings.Contains(err.Error(), "bad private key length") {
+		t.Fatalf("Sign(bad key): unexpected error: %v", err)
+	}
+}
+
+func TestExpandedPrivateKey(t *testing.T) {
+	var zero zeroreader.ZeroReader
+	public, private, _ := GenerateKey(zero)
+
+	expanded, err := NewExpandedPrivateKey(private)
+	if err != nil {
+		t.Fatalf("NewExpandedPrivateKey: %v", err)
+	}
+
+	signer := crypto.Signer(expanded)
+	if !public.Equal(signer.Public()) {
+		t.Fatalf("expanded.Public() is not Equal to public")
+	}
+
+	message := []byte("test message")
+	hash := sha512.Sum512(message)
+	for _, tc := range []struct {
+		n    string
+		msg  []byte
+		opts crypto.SignerOpts
+	}{
+		{"NoHash", message, crypto.Hash(0)},
+		{"Ed25519", message, &Options{}},
+		{"Ed25519ctx", message, &Options{Context: "test context"}},
+		{"Ed25519ph", hash[:], &Options{Hash: crypto.SHA512}},
+		{"Ed25519ph/Context", hash[:], &Options{Hash: crypto.SHA512, Context: "test context"}},
+		{"Ed25519ph/Hash", hash[:], crypto.SHA512},
+	} {
+		sig, err := private.Sign(nil, tc.msg, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: PrivateKey.Sign: %v", tc.n, err)
+		}
+		sig2, err := signer.Sign(nil, tc.msg, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: ExpandedPrivateKey.Sign: %v", tc.n, err)
+		}
+		if !bytes.Equal(sig, sig2) {
+			t.Fatalf("%s: signatures do not match: %x vs %x", tc.n, sig, sig2)
+		}
+	}
+
+	sig := SignExpanded(expanded, message)
+	if !bytes.Equal(sig, Sign(private, message)) {
+		t.Fatalf("SignExpanded: signature mismatch")
+	}
+
+	opts := &Options{
+		AddedRandomness: true,
+		SelfVerify:      true,
+	}
+	sig2, err := SignExpandedWithOptions(expanded, message, opts)
+	if err != nil {
+		t.Fatalf("SignExpandedWithOptions: %v", err)
+	}
+	if bytes.Equal(sig, sig2) {
+		t.Fatalf("SignExpandedWithOptions: standard signature matches entropy added signature")
+	}
+	if !Verify(public, message, sig2) {
+		t.Fatalf("SignExpandedWithOptions: valid signature rejected")
+	}
+
+	if _, err = SignExpandedWithOptions(expanded, message, &Options{Hash: crypto.SHA256}); err == nil {
+		t.Fatalf("SignExpandedWithOptions: accepted bad hash function")
+	}
+	if _, err = NewExpandedPrivateKey(private[:SeedSize]); err == nil {
+		t.Fatalf("NewExpandedPrivateKey: accepted truncated private key")
+	}
+}
+
+func TestSignBatch(t *testing.T) {
+	var zero zeroreader.ZeroReader
+	public, private, _ := GenerateKey(zero)
+
+	expanded, err := NewExpandedPrivateKey(private)
+	if err != nil {
+		t.Fatalf("NewExpandedPrivateKey: %v", err)
+	}
+
+	var messages, hashes [][]byte
+	for i := 0; i < 17; i++ {
+		msg := []byte(strings.Repeat("batch signing test message ", i))
+		hash := sha512.Sum512(msg)
+		messages = append(messages, msg)
+		hashes = append(hashes, hash[:])
+	}
+
+	sigs := SignBatch(private, messages)
+	if len(sigs) != len(messages) {
+		t.Fatalf("SignBatch: unexpected number of signatures: %d", len(sigs))
+	}
+	for i, msg := range messages {
+		if !bytes.Equal(sigs[i], Sign(private, msg)) {
+			t.Fatalf("SignBatch: signature %d mismatch", i)
+		}
+	}
+
+	for _, tc := range []struct {
+		n    string
+		msgs [][]byte
+		opts crypto.SignerOpts
+	}{
+		{"NoHash", messages, crypto.Hash(0)},
+		{"Ed25519ctx", messages, &Options{Context: "test context"}},
+		{"Ed25519ph", hashes, &Options{Hash: crypto.SHA512}},
+		{"SelfVerify", messages, &Options{SelfVerify: true}},
+	} {
+		sigs, err := expanded.SignBatch(nil, tc.msgs, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: SignBatch: %v", tc.n, err)
+		}
+		for i, msg := range tc.msgs {
+			sig, err := private.Sign(nil, msg, tc.opts)
+			if err != nil {
+				t.Fatalf("%s: Sign: %v", tc.n, err)
+			}
+			if !bytes.Equal(sigs[i], sig) {
+				t.Fatalf("%s: SignBatch: signature %d mismatch", tc.n, i)
+			}
+		}
+	}
+
+	sigs, err = expanded.SignBatch(nil, messages, &Options{AddedRandomness: true})
+	if err != nil {
+		t.Fatalf("SignBatch(AddedRandomness): %v", err)
+	}
+	for i, msg := range messages {
+		if !Verify(public, msg, sigs[i]) {
+			t.Fatalf("SignBatch(AddedRandomness): valid signature %d rejected", i)
+		}
+	}
+
+	if _, err = expanded.SignBatch(nil, append(hashes, []byte("not a hash")), crypto.SHA512); err == nil {
+		t.Fatalf("SignBatch: accepted bad pre-hashed message")
+	}
+	if sigs = SignBatch(private, nil); len(sigs) != 0 {
+		t.Fatalf("SignBatch(nil): returned signatures")
+	}
+}
+
 func testSignVerify(t *testing.T) {
 	var zero zeroreader.ZeroReader
 	public, private, _ := GenerateKey(zero)
@@ -426,6 +584,46 @@ func BenchmarkExpanded(b *testing.B) {
 		b.Fatal(err)
 	}
 
+	b.Run("NewExpandedPrivateKey", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewExpandedPrivateKey(priv)
+		}
+	})
+	b.Run("Signing", func(b *testing.B) {
+		message := []byte("Hello, world!")
+
+		expPriv, err := NewExpandedPrivateKey(priv)
+		if err != nil {
+			b.Fatalf("NewExpandedPrivateKey: %v", err)
+		}
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			SignExpanded(expPriv, message)
+		}
+	})
+	b.Run("SignBatch", func(b *testing.B) {
+		expPriv, err := NewExpandedPrivateKey(priv)
+		if err != nil {
+			b.Fatalf("NewExpandedPrivateKey: %v", err)
+		}
+
+		for _, n := range []int{1, 16, 128} {
+			messages := make([][]byte, 0, n)
+			for i := 0; i < n; i++ {
+				messages = append(messages, []byte("Hello, world!"))
+			}
+			b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := expPriv.SignBatch(nil, messages, optionsDefault); err != nil {
+						b.Fatalf("SignBatch: %v", err)
+					}
+				}
+			})
+		}
+	})
 	b.Run("NewExpandedPublicKey", func(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
@@ -28,13 +28,14 @@
 // SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 // Package cache implements a set of caching wrappers around Ed25519
-// signature verification to transparently accelerate repeated verification
-// with the same public key(s).
+// signature (and ECVRF proof) verification to transparently accelerate
+// repeated verification with the same public key(s).
 package cache
 
 import (
 	"github.com/oasisprotocol/curve25519-voi/curve"
 	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519/extra/ecvrf"
 )
 
 // Cache is an expanded public key cache.
@@ -87,6 +88,37 @@ func (v *Verifier) AddWithOptions(verifier *ed25519.BatchVerifier, publicKey ed2
 	verifier.AddExpandedWithOptions(expanded, message, sig, opts)
 }
 
+// VerifyECVRF implements ECVRF_verify for the suite
+// ECVRF-EDWARDS25519-SHA512-ELL2.
+func (v *Verifier) VerifyECVRF(publicKey ed25519.PublicKey, piString, alphaString []byte) (bool, []byte) {
+	return v.VerifyECVRFWithOptions(publicKey, piString, alphaString, nil)
+}
+
+// VerifyECVRFWithOptions implements ECVRF_verify for the RFC 9381 suite
+// specified in opts.
+func (v *Verifier) VerifyECVRFWithOptions(publicKey ed25519.PublicKey, piString, alphaString []byte, opts *ecvrf.Options) (bool, []byte) {
+	expanded, ok := v.upsertPublicKey(publicKey)
+	if !ok {
+		return false, nil
+	}
+
+	return ecvrf.VerifyExpandedWithOptions(expanded, piString, alphaString, opts)
+}
+
+// AddECVRF will add the batch compatible ECVRF proof to the batch verifier.
+func (v *Verifier) AddECVRF(verifier *ecvrf.BatchVerifier, publicKey ed25519.PublicKey, piString, alphaString []byte) {
+	v.AddECVRFWithOptions(verifier, publicKey, piString, alphaString, nil)
+}
+
+// AddECVRFWithOptions will add the batch compatible ECVRF proof to the
+// batch verifier, with extra Options.
+func (v *Verifier) AddECVRFWithOptions(verifier *ecvrf.BatchVerifier, publicKey ed25519.PublicKey, piString, alphaString []byte, opts *ecvrf.Options) {
+	// Note: BatchVerifier.AddExpandedWithOptions will do the right
+	// thing if the expanded public key is nil.
+	expanded, _ := v.upsertPublicKey(publicKey)
+	verifier.AddExpandedWithOptions(expanded, piString, alphaString, opts)
+}
+
 // AddPublicKey will expand and add the public key to the cache.
 func (v *Verifier) AddPublicKey(publicKey ed25519.PublicKey) {
 	v.upsertPublicKey(publicKey)
@@ -30,18 +30,75 @@
 package cache
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519/extra/ecvrf"
 )
 
 const testCacheSize = 10
 
 var testMsg = []byte("This is only a test of the emergency broadcast system")
 
+func TestVerifierECVRF(t *testing.T) {
+	v := NewVerifier(NewLRUCache(testCacheSize))
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pi := ecvrf.Prove(priv, testMsg)
+	expectedBeta, err := ecvrf.ProofToHash(pi)
+	if err != nil {
+		t.Fatalf("failed to compute beta: %v", err)
+	}
+
+	// Miss, followed by a hit.
+	for i := 0; i < 2; i++ {
+		ok, beta := v.VerifyECVRF(pub, pi, testMsg)
+		if !ok {
+			t.Fatalf("[%d] failed to verify proof", i)
+		}
+		if !bytes.Equal(beta, expectedBeta) {
+			t.Fatalf("[%d] beta mismatch", i)
+		}
+	}
+	if ok, _ := v.VerifyECVRF(pub, pi, []byte("wrong alpha")); ok {
+		t.Fatalf("verified proof with the wrong alpha")
+	}
+	if ok, _ := v.VerifyECVRF(pub[:31], pi, testMsg); ok {
+		t.Fatalf("verified proof with a truncated public key")
+	}
+
+	// Batch verification.
+	opts := &ecvrf.Options{
+		BatchCompatible: true,
+	}
+	piBatch, err := ecvrf.ProveWithOptions(priv, testMsg, opts)
+	if err != nil {
+		t.Fatalf("failed to generate batch compatible proof: %v", err)
+	}
+	bv := ecvrf.NewBatchVerifier()
+	v.AddECVRF(bv, pub, piBatch, testMsg)
+	v.AddECVRFWithOptions(bv, pub, piBatch, testMsg, opts)
+	v.AddECVRF(bv, pub[:31], piBatch, testMsg)
+	ok, valid, betas := bv.Verify(nil)
+	if ok {
+		t.Fatalf("batch verified with a truncated public key")
+	}
+	if !valid[0] || !valid[1] || valid[2] {
+		t.Fatalf("unexpected batch verification results: %v", valid)
+	}
+	if !bytes.Equal(betas[0], expectedBeta) {
+		t.Fatalf("batch beta mismatch")
+	}
+}
+
 func BenchmarkCache(b *testing.B) {
 	b.Run("Verify/Miss", benchCacheMiss)
 	b.Run("Verify/Hit", benchCacheHit)
+	b.Run("VerifyECVRF/Hit", benchCacheHitECVRF)
 }
 
 func benchCacheMiss(b *testing.B) {
@@ -83,3 +140,23 @@ func benchCacheHit(b *testing.B) {
 		}
 	}
 }
+
+func benchCacheHitECVRF(b *testing.B) {
+	v := NewVerifier(NewLRUCache(testCacheSize))
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		b.Fatalf("failed to generate key: %v", err)
+	}
+	v.AddPublicKey(pub)
+	pi := ecvrf.Prove(priv, testMsg)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if ok, _ := v.VerifyECVRF(pub, pi, testMsg); !ok {
+			b.Fatalf("failed to verify proof")
+		}
+	}
+}
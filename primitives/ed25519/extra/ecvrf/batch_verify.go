@@ -0,0 +1,344 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ecvrf
+
+import (
+	cryptorand "crypto/rand"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/scalar128"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// BatchVerifier accumulates batch entries with Add, before performing
+// batch verification with Verify.
+//
+// Note: Only batch compatible proofs (see Options.BatchCompatible) can
+// be batch verified.
+type BatchVerifier struct {
+	entries []entry
+
+	anyInvalid bool
+}
+
+type entry struct {
+	suite Suite
+
+	// Like ed25519.BatchVerifier, this stores the decoded points and
+	// scalars in the entry, so that it is possible to fall back to
+	// verifying each entry individually, without having to redo a
+	// non-trivial amount of computation.
+	key   verifyingKey
+	H     curve.EdwardsPoint
+	gamma curve.EdwardsPoint
+	U     curve.EdwardsPoint
+	V     curve.EdwardsPoint
+	c     scalar.Scalar
+	s     scalar.Scalar
+
+	canBeValid bool
+}
+
+func (e *entry) doInit(pk ed25519.PublicKey, expandedPk *ed25519.ExpandedPublicKey, piString, alphaString []byte, suite Suite, validateKey bool) {
+	// Until everything has been deserialized correctly, assume the
+	// entry is totally invalid.
+	e.canBeValid = false
+	e.suite = suite
+
+	// Y = string_to_point(PK_string), and optionally
+	// ECVRF_validate_key(Y).
+	if !e.key.init(pk, expandedPk, validateKey) {
+		return
+	}
+	pk = e.key.compressed[:]
+
+	// (Gamma, U, V, s) = pi_string
+	if len(piString) != BatchCompatibleProofSize {
+		return
+	}
+	for i, p := range []*curve.EdwardsPoint{&e.gamma, &e.U, &e.V} {
+		decoded, err := decodePoint(piString[i*32 : (i+1)*32])
+		if err != nil {
+			return
+		}
+		p.Set(decoded)
+	}
+	if !scalar.ScMinimalVartime(piString[96:]) {
+		return
+	}
+	if _, err := e.s.SetBytesModOrder(piString[96:]); err != nil {
+		return
+	}
+
+	// H = ECVRF_encode_to_curve(encode_to_curve_salt, alpha_string)
+	H, err := suite.encodeToCurve(pk, alphaString)
+	if err != nil {
+		return
+	}
+	e.H.Set(H)
+
+	// c = ECVRF_challenge_generation(Y, H, Gamma, U, V)
+	var hString, gammaString curve.CompressedEdwardsY
+	hString.SetEdwardsPoint(&e.H)
+	_, _ = gammaString.SetBytes(piString[:32])
+	e.c.Set(suite.challengeGeneration(pk, &hString, &gammaString, &e.U, &e.V))
+
+	e.canBeValid = true
+}
+
+func (e *entry) verify() bool {
+	// [8](s*B - c*Y - U) == 0
+	var tmp curve.EdwardsPoint
+	if !tmp.Sub(e.key.sBMinusCY(&e.s, &e.c), &e.U).IsSmallOrder() {
+		return false
+	}
+
+	// [8](s*H - c*Gamma - V) == 0
+	var negGamma curve.EdwardsPoint
+	negGamma.Neg(&e.gamma)
+	tmp.MultiscalarMulVartime(
+		[]*scalar.Scalar{&e.s, &e.c},
+		[]*curve.EdwardsPoint{&e.H, &negGamma},
+	)
+	return tmp.Sub(&tmp, &e.V).IsSmallOrder()
+}
+
+// Add adds a (public key, proof, alpha) triple to the current batch,
+// using the ECVRF-EDWARDS25519-SHA512-ELL2 suite.
+func (v *BatchVerifier) Add(pk ed25519.PublicKey, piString, alphaString []byte) {
+	v.AddWithOptions(pk, piString, alphaString, nil)
+}
+
+// AddWithOptions adds a (public key, proof, alpha, opts) quad to the
+// current batch.  The proof MUST be in the batch compatible format,
+// regardless of opts.BatchCompatible.
+func (v *BatchVerifier) AddWithOptions(pk ed25519.PublicKey, piString, alphaString []byte, opts *Options) {
+	v.doAdd(pk, nil, piString, alphaString, opts)
+}
+
+// AddExpanded adds a (expanded public key, proof, alpha) triple to the
+// current batch, using the ECVRF-EDWARDS25519-SHA512-ELL2 suite.
+func (v *BatchVerifier) AddExpanded(pk *ed25519.ExpandedPublicKey, piString, alphaString []byte) {
+	v.AddExpandedWithOptions(pk, piString, alphaString, nil)
+}
+
+// AddExpandedWithOptions adds a (expanded public key, proof, alpha, opts)
+// quad to the current batch.  The proof MUST be in the batch compatible
+// format, regardless of opts.BatchCompatible.
+func (v *BatchVerifier) AddExpandedWithOptions(pk *ed25519.ExpandedPublicKey, piString, alphaString []byte, opts *Options) {
+	if pk == nil {
+		// Ensure that the entry is treated as invalid, rather than
+		// as a request to decompress a nil public key.
+		v.anyInvalid = true
+		v.entries = append(v.entries, entry{})
+		return
+	}
+	v.doAdd(nil, pk, piString, alphaString, opts)
+}
+
+func (v *BatchVerifier) doAdd(pk ed25519.PublicKey, expandedPk *ed25519.ExpandedPublicKey, piString, alphaString []byte, opts *Options) {
+	var e entry
+
+	if suite, err := opts.suite(); err == nil {
+		validateKey := opts == nil || !opts.SkipKeyValidation
+		e.doInit(pk, expandedPk, piString, alphaString, suite, validateKey)
+	}
+	v.anyInvalid = v.anyInvalid || !e.canBeValid
+	v.entries = append(v.entries, e)
+}
+
+// VerifyBatchOnly checks all entries in the current batch using entropy
+// from rand, returning true if all entries are valid and false if any one
+// entry is invalid.  If rand is nil, crypto/rand.Reader will be used.
+//
+// If a failure arises it is unknown which entry failed, the caller must
+// verify each entry individually.
+//
+// Calling VerifyBatchOnly on an empty batch will return false.
+func (v *BatchVerifier) VerifyBatchOnly(rand io.Reader) bool {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	vl := len(v.entries)
+	numTerms := 1 + 5*vl
+
+	// Handle some early aborts.
+	switch {
+	case vl == 0:
+		// Abort early on an empty batch, which probably indicates a bug
+		return false
+	case v.anyInvalid:
+		// Abort early if any of the `Add`/`AddWithOptions` calls failed
+		// to fully execute, since at least one entry is invalid.
+		return false
+	}
+
+	zGen, err := scalar128.NewGenerator(rand)
+	if err != nil {
+		panic("ecvrf: failed to initialize random scalar generator: " + err.Error())
+	}
+
+	// The batch verification equation is
+	//
+	// [sum(z_i * s_i)]B - sum([z_i]U_i) - sum([z_i * c_i]Y_i) +
+	//   sum([w_i * s_i]H_i) - sum([w_i]V_i) - sum([w_i * c_i]Gamma_i) = 0.
+	// where for each proof i,
+	// - Y_i is the public key;
+	// - H_i is the output of encode_to_curve;
+	// - Gamma_i, U_i, V_i, s_i are the proof's values;
+	// - c_i is the challenge;
+	// - z_i, w_i are random 128-bit Scalars.
+	svals := make([]scalar.Scalar, numTerms)
+	scalars := make([]*scalar.Scalar, numTerms)
+	points := make([]*curve.EdwardsPoint, numTerms)
+
+	// Populate scalars variable with concrete scalars to reduce heap allocation
+	for i := range scalars {
+		scalars[i] = &svals[i]
+	}
+
+	Bcoeff := scalars[0]
+	points[0] = curve.ED25519_BASEPOINT_POINT
+
+	negPoints := make([]curve.EdwardsPoint, 3*vl)
+	for i := range v.entries {
+		// Avoid range copying each v.entries[i] literal.
+		entry := &v.entries[i]
+		off := 1 + 5*i
+		Ucoeff, Ycoeff, Hcoeff, Vcoeff, Gcoeff := scalars[off], scalars[off+1], scalars[off+2], scalars[off+3], scalars[off+4]
+
+		negU, negV, negGamma := &negPoints[3*i], &negPoints[3*i+1], &negPoints[3*i+2]
+		negU.Neg(&entry.U)
+		negV.Neg(&entry.V)
+		negGamma.Neg(&entry.gamma)
+		points[off], points[off+1], points[off+2], points[off+3], points[off+4] = negU, &entry.key.negY, &entry.H, negV, negGamma
+
+		var w scalar.Scalar
+		if err = zGen.SetScalarVartime(Ucoeff); err != nil { // Ucoeff = z_i
+			panic("ecvrf: failed to generate z_i: " + err.Error())
+		}
+		if err = zGen.SetScalarVartime(&w); err != nil {
+			panic("ecvrf: failed to generate w_i: " + err.Error())
+		}
+		Vcoeff.Set(&w) // Vcoeff = w_i
+
+		var sz scalar.Scalar
+		Bcoeff.Add(Bcoeff, sz.Mul(Ucoeff, &entry.s)) // Bcoeff += z_i * s_i
+		Ycoeff.Mul(Ucoeff, &entry.c)                 // Ycoeff = z_i * c_i
+		Hcoeff.Mul(&w, &entry.s)                     // Hcoeff = w_i * s_i
+		Gcoeff.Mul(&w, &entry.c)                     // Gcoeff = w_i * c_i
+	}
+
+	// Check the cofactored batch verification equation.
+	var shouldBeId curve.EdwardsPoint
+	shouldBeId.MultiscalarMulVartime(scalars, points)
+	return shouldBeId.IsSmallOrder()
+}
+
+// Verify checks all entries in the current batch using entropy from rand,
+// returning true if all entries in the current batch are valid.  If one or
+// more proof is invalid, each entry in the batch will be verified
+// serially, and the returned bit-vector will provide information about
+// each individual entry.  The VRF output (`beta_string`) of each valid
+// entry is also returned.  If rand is nil, crypto/rand.Reader will be
+// used.
+//
+// Note: This method is only faster than individually verifying each
+// proof if every proof is valid.
+func (v *BatchVerifier) Verify(rand io.Reader) (bool, []bool, [][]byte) {
+	vl := len(v.entries)
+	if vl == 0 {
+		return false, nil, nil
+	}
+
+	// Start by assuming everything is valid, unless we know for sure
+	// otherwise (ie: public key/proof/options were malformed).
+	valid := make([]bool, vl)
+	for i := range v.entries {
+		valid[i] = v.entries[i].canBeValid
+	}
+
+	// If batch verification is possible, do the batch verification,
+	// otherwise fall back to verifying each entry serially.
+	allValid := !v.anyInvalid && v.VerifyBatchOnly(rand)
+	if !allValid {
+		allValid = !v.anyInvalid
+		for i := range v.entries {
+			// If the entry is known to be invalid, skip the serial
+			// verification.
+			if !valid[i] {
+				continue
+			}
+			valid[i] = v.entries[i].verify()
+			allValid = allValid && valid[i]
+		}
+	}
+
+	betas := make([][]byte, vl)
+	for i := range v.entries {
+		if valid[i] {
+			entry := &v.entries[i]
+			betas[i] = entry.suite.gammaToHash(&entry.gamma)
+		}
+	}
+
+	return allValid, valid, betas
+}
+
+// Reset resets a batch for reuse.
+//
+// Note: This method will reuse the internal entires slice to reduce memory
+// reallocations.  If the next batch is known to be significantly smaller
+// it may be more memory efficient to simply create a new batch.
+func (v *BatchVerifier) Reset() *BatchVerifier {
+	v.entries = v.entries[:0]
+	v.anyInvalid = false
+
+	return v
+}
+
+// NewBatchVerifier creates an empty BatchVerifier.
+func NewBatchVerifier() *BatchVerifier {
+	return &BatchVerifier{}
+}
+
+// NewBatchVerifierWithCapacity creates an empty BatchVerifier, with
+// preallocations for a pre-determined batch size hint.
+func NewBatchVerifierWithCapacity(n int) *BatchVerifier {
+	v := NewBatchVerifier()
+	if n > 0 {
+		v.entries = make([]entry, 0, n)
+	}
+
+	return v
+}
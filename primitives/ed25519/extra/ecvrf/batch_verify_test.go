@@ -0,0 +1,335 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ecvrf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+const testBatchSize = 38
+
+type batchTest int
+
+const (
+	batchNoErrors batchTest = iota
+	batchCorruptGamma
+	batchCorruptU
+	batchCorruptV
+	batchCorruptS
+	batchCorruptAlpha
+	batchWrongKey
+	batchSmallOrderKey
+	batchStandardProof
+)
+
+var batchTestCases = []struct {
+	n          string
+	tst        batchTest
+	culpritIdx int
+}{
+	{"Verify", batchNoErrors, -1},
+	{"FailsOnCorruptGamma", batchCorruptGamma, 0},
+	{"FailsOnCorruptU", batchCorruptU, 1},
+	{"FailsOnCorruptV", batchCorruptV, 7},
+	{"FailsOnCorruptS", batchCorruptS, 19},
+	{"FailsOnCorruptAlpha", batchCorruptAlpha, 22},
+	{"FailsOnWrongKey", batchWrongKey, 36},
+	{"FailsOnSmallOrderKey", batchSmallOrderKey, 37},
+	{"FailsOnStandardProof", batchStandardProof, 5},
+}
+
+type batchTestEntry struct {
+	pk    ed25519.PublicKey
+	pi    []byte
+	alpha []byte
+	opts  *Options
+	beta  []byte
+}
+
+func newBatchTestEntries(t testing.TB, n int) []*batchTestEntry {
+	var entries []*batchTestEntry
+	for i := 0; i < n; i++ {
+		pk, sk, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+
+		// Alternate between the suites, and exercise the
+		// default options path.
+		var opts *Options
+		switch i % 3 {
+		case 1:
+			opts = &Options{
+				Suite: SuiteEdwards25519SHA512TAI,
+			}
+		case 2:
+			opts = &Options{
+				Suite:           SuiteEdwards25519SHA512ELL2,
+				AddedRandomness: true,
+			}
+		}
+
+		alpha := []byte(fmt.Sprintf("test-alpha-pls-ignore-%d", i))
+
+		var proveOpts Options
+		if opts != nil {
+			proveOpts = *opts
+		}
+		proveOpts.BatchCompatible = true
+		pi, err := ProveWithOptions(sk, alpha, &proveOpts)
+		if err != nil {
+			t.Fatalf("ProveWithOptions: %v", err)
+		}
+
+		// The VRF output is identical to that of a RFC 9381 proof.
+		piStandard, err := ProveWithOptions(sk, alpha, opts)
+		if err != nil {
+			t.Fatalf("ProveWithOptions: %v", err)
+		}
+		beta, err := ProofToHashWithOptions(piStandard, opts)
+		if err != nil {
+			t.Fatalf("ProofToHashWithOptions: %v", err)
+		}
+
+		entries = append(entries, &batchTestEntry{
+			pk:    pk,
+			pi:    pi,
+			alpha: alpha,
+			opts:  opts,
+			beta:  beta,
+		})
+	}
+	return entries
+}
+
+func TestBatchVerifier(t *testing.T) {
+	t.Run("Single", testBatchCompatibleSingle)
+	t.Run("Torsion", testBatchCompatibleTorsion)
+	for _, tc := range batchTestCases {
+		tc := tc
+		t.Run(tc.n, func(t *testing.T) {
+			entries := newBatchTestEntries(t, testBatchSize)
+
+			if tc.culpritIdx >= 0 {
+				culprit := entries[tc.culpritIdx]
+				switch tc.tst {
+				case batchCorruptGamma:
+					culprit.pi[1] ^= 0x69
+				case batchCorruptU:
+					culprit.pi[33] ^= 0x69
+				case batchCorruptV:
+					culprit.pi[65] ^= 0x69
+				case batchCorruptS:
+					culprit.pi[97] ^= 0x69
+				case batchCorruptAlpha:
+					culprit.alpha = []byte("a different alpha")
+				case batchWrongKey:
+					culprit.pk = entries[0].pk
+				case batchSmallOrderKey:
+					culprit.pk = make([]byte, ed25519.PublicKeySize)
+					culprit.pk[0] = 0x01 // Identity.
+				case batchStandardProof:
+					culprit.pi = culprit.pi[:ProofSize]
+				}
+			}
+
+			v := NewBatchVerifierWithCapacity(testBatchSize)
+			for _, e := range entries {
+				if e.opts == nil {
+					v.Add(e.pk, e.pi, e.alpha)
+				} else {
+					v.AddWithOptions(e.pk, e.pi, e.alpha, e.opts)
+				}
+			}
+
+			expectedValid := tc.culpritIdx < 0
+			if ok := v.VerifyBatchOnly(nil); ok != expectedValid {
+				t.Fatalf("VerifyBatchOnly: %v (expected %v)", ok, expectedValid)
+			}
+
+			allValid, valid, betas := v.Verify(nil)
+			if allValid != expectedValid {
+				t.Fatalf("Verify: %v (expected %v)", allValid, expectedValid)
+			}
+			for i, e := range entries {
+				isCulprit := i == tc.culpritIdx
+				if valid[i] == isCulprit {
+					t.Fatalf("Verify: valid[%d] = %v", i, valid[i])
+				}
+				if isCulprit {
+					if betas[i] != nil {
+						t.Fatalf("Verify: betas[%d] non-nil for invalid entry", i)
+					}
+					continue
+				}
+				if !bytes.Equal(betas[i], e.beta) {
+					t.Fatalf("Verify: betas[%d] mismatch", i)
+				}
+			}
+
+			// Ensure that the verifier can be reused.
+			v.Reset()
+			if ok, _, _ := v.Verify(nil); ok {
+				t.Fatalf("Verify(empty batch) passed")
+			}
+			for range entries {
+				v.AddWithOptions(entries[0].pk, entries[0].pi, entries[0].alpha, entries[0].opts)
+			}
+			if ok, _, _ := v.Verify(nil); ok != (tc.culpritIdx != 0) {
+				t.Fatalf("Verify(reused batch): %v", ok)
+			}
+		})
+	}
+}
+
+func testBatchCompatibleSingle(t *testing.T) {
+	entries := newBatchTestEntries(t, 6)
+	for i, e := range entries {
+		opts := &Options{
+			BatchCompatible: true,
+		}
+		if e.opts != nil {
+			opts.Suite = e.opts.Suite
+		}
+
+		ok, beta := VerifyWithOptions(e.pk, e.pi, e.alpha, opts)
+		if !ok {
+			t.Fatalf("[%d] VerifyWithOptions(BatchCompatible) failed", i)
+		}
+		if !bytes.Equal(beta, e.beta) {
+			t.Fatalf("[%d] VerifyWithOptions(BatchCompatible) beta mismatch", i)
+		}
+		beta, err := ProofToHashWithOptions(e.pi, opts)
+		if err != nil {
+			t.Fatalf("[%d] ProofToHashWithOptions(BatchCompatible): %v", i, err)
+		}
+		if !bytes.Equal(beta, e.beta) {
+			t.Fatalf("[%d] ProofToHashWithOptions(BatchCompatible) beta mismatch", i)
+		}
+
+		// Batch compatible proofs are not RFC 9381 proofs, and
+		// vice versa.
+		if ok, _ = VerifyWithOptions(e.pk, e.pi, e.alpha, e.opts); ok {
+			t.Fatalf("[%d] VerifyWithOptions(batch compatible proof) passed", i)
+		}
+		if ok, _ = VerifyWithOptions(e.pk, e.pi[:ProofSize], e.alpha, opts); ok {
+			t.Fatalf("[%d] VerifyWithOptions(BatchCompatible, truncated proof) passed", i)
+		}
+
+		e.pi[40] ^= 0xa5
+		if ok, _ = VerifyWithOptions(e.pk, e.pi, e.alpha, opts); ok {
+			t.Fatalf("[%d] VerifyWithOptions(BatchCompatible, bad pi) passed", i)
+		}
+	}
+}
+
+func testBatchCompatibleTorsion(t *testing.T) {
+	// Batch compatible proofs are verified with cofactored equations,
+	// so a proof with a small order component in U must be accepted by
+	// both the single and batch verification, so that they are
+	// consistent.
+	var (
+		x, k                  scalar.Scalar
+		Y, U, V, gamma        curve.EdwardsPoint
+		yString, hString, tmp curve.CompressedEdwardsY
+		gammaString           curve.CompressedEdwardsY
+	)
+	if _, err := x.SetRandom(nil); err != nil {
+		t.Fatalf("SetRandom: %v", err)
+	}
+	if _, err := k.SetRandom(nil); err != nil {
+		t.Fatalf("SetRandom: %v", err)
+	}
+	Y.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &x)
+	yString.SetEdwardsPoint(&Y)
+	pk := ed25519.PublicKey(yString[:])
+	alpha := []byte("test-alpha-pls-ignore")
+
+	suite := SuiteEdwards25519SHA512ELL2
+	H, err := suite.encodeToCurve(pk, alpha)
+	if err != nil {
+		t.Fatalf("encodeToCurve: %v", err)
+	}
+	hString.SetEdwardsPoint(H)
+	gamma.Mul(H, &x)
+	gammaString.SetEdwardsPoint(&gamma)
+	U.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &k)
+	U.Add(&U, curve.EIGHT_TORSION[1]) // U = k*B + T
+	V.Mul(H, &k)
+	c := suite.challengeGeneration(pk, &hString, &gammaString, &U, &V)
+
+	var s scalar.Scalar
+	s.Mul(c, &x)
+	s.Add(&s, &k)
+
+	var pi [BatchCompatibleProofSize]byte
+	copy(pi[:32], gammaString[:])
+	copy(pi[32:64], tmp.SetEdwardsPoint(&U)[:])
+	copy(pi[64:96], tmp.SetEdwardsPoint(&V)[:])
+	_ = s.ToBytes(pi[96:])
+
+	opts := &Options{
+		BatchCompatible: true,
+	}
+	if ok, _ := VerifyWithOptions(pk, pi[:], alpha, opts); !ok {
+		t.Fatalf("VerifyWithOptions(BatchCompatible, U + T) failed")
+	}
+
+	v := NewBatchVerifier()
+	v.Add(pk, pi[:], alpha)
+	if !v.VerifyBatchOnly(nil) {
+		t.Fatalf("VerifyBatchOnly(U + T) failed")
+	}
+}
+
+func BenchmarkBatchVerifier(b *testing.B) {
+	for _, n := range []int{1, 8, 64, 256} {
+		entries := newBatchTestEntries(b, n)
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v := NewBatchVerifierWithCapacity(n)
+				for _, e := range entries {
+					v.AddWithOptions(e.pk, e.pi, e.alpha, e.opts)
+				}
+				if !v.VerifyBatchOnly(nil) {
+					b.Fatalf("VerifyBatchOnly() failed")
+				}
+			}
+		})
+	}
+}
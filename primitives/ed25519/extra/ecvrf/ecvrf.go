@@ -28,7 +28,13 @@
 // SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 // Package ecvrf implements the "Verifiable Random Functions (VRFs)"
-// IETF draft, providing the ECVRF-EDWARDS25519-SHA512-ELL2 suite.
+// specification (RFC 9381), providing the ECVRF-EDWARDS25519-SHA512-ELL2
+// and ECVRF-EDWARDS25519-SHA512-TAI suites.  Compatibility with the
+// v10 (and earlier) IETF drafts is also provided.
+//
+// Additionally a non-standard "batch compatible" proof format is
+// provided, which allows many proofs to be verified at once with
+// BatchVerifier.
 package ecvrf
 
 import (
@@ -47,24 +53,105 @@ const (
 	// ProofSize is the size, in bytes, of proofs as used in this package.
 	ProofSize = 80
 
+	// BatchCompatibleProofSize is the size, in bytes, of batch compatible
+	// proofs as used in this package.
+	BatchCompatibleProofSize = 128
+
 	// OutputSize is the size, in bytes, of outputs as used in this package.
 	OutputSize = 64
 
 	zeroString  = 0x00
+	oneString   = 0x01
 	twoString   = 0x02
 	threeString = 0x03
-	suiteString = 0x04
 
 	addedRandomnessSize = 32
 )
 
+// Suite is a RFC 9381 ECVRF ciphersuite, identified by its suite_string.
+type Suite uint8
+
+const (
+	// SuiteEdwards25519SHA512TAI is the ECVRF-EDWARDS25519-SHA512-TAI
+	// suite, which uses the try-and-increment encode_to_curve method.
+	//
+	// Warning: The try-and-increment method is not constant-time with
+	// respect to alpha_string.
+	SuiteEdwards25519SHA512TAI Suite = 0x03
+
+	// SuiteEdwards25519SHA512ELL2 is the ECVRF-EDWARDS25519-SHA512-ELL2
+	// suite, which uses the Elligator 2 hash-to-curve encode_to_curve
+	// method.
+	SuiteEdwards25519SHA512ELL2 Suite = 0x04
+)
+
+// String returns the string representation of a Suite.
+func (s Suite) String() string {
+	switch s {
+	case SuiteEdwards25519SHA512TAI:
+		return "ECVRF-EDWARDS25519-SHA512-TAI"
+	case SuiteEdwards25519SHA512ELL2:
+		return "ECVRF-EDWARDS25519-SHA512-ELL2"
+	default:
+		return "[unknown suite]"
+	}
+}
+
+// Options can be used to select the suite and behavior of ProveWithOptions,
+// ProofToHashWithOptions, and VerifyWithOptions.
+type Options struct {
+	// Suite is the ciphersuite.  If left unspecified,
+	// SuiteEdwards25519SHA512ELL2 will be used.
+	Suite Suite
+
+	// AddedRandomness includes additional randomness when proving to
+	// attempt to mitigate certain fault injection and side-channel
+	// attacks.
+	//
+	// Warning: If this is set, proofs (`pi_string`) will be
+	// non-deterministic.  The VRF output (`beta_string`) is unaffected.
+	AddedRandomness bool
+
+	// SkipKeyValidation disables ECVRF_validate_key when verifying
+	// (`validate_key = FALSE`), allowing public keys of small order.
+	//
+	// Warning: If this is set, the "full uniqueness" and "full
+	// collision resistance" properties no longer hold, as proofs for
+	// such keys can be constructed for arbitrary outputs.
+	SkipKeyValidation bool
+
+	// BatchCompatible selects the batch compatible proof format
+	// (`Gamma || U || V || s`, BatchCompatibleProofSize bytes), which
+	// includes the prover's commitments U and V in place of the
+	// challenge c.  This is required by BatchVerifier, as it is not
+	// possible to recover U and V from a RFC 9381 proof without doing
+	// the very scalar multiplications that batching aims to combine.
+	//
+	// Batch compatible proofs are verified with cofactored equations,
+	// and produce the same VRF output (`beta_string`) as RFC 9381
+	// proofs.  They are NOT interchangeable with RFC 9381 proofs.
+	BatchCompatible bool
+}
+
+func (opts *Options) suite() (Suite, error) {
+	if opts == nil || opts.Suite == 0 {
+		return SuiteEdwards25519SHA512ELL2, nil
+	}
+	switch opts.Suite {
+	case SuiteEdwards25519SHA512TAI, SuiteEdwards25519SHA512ELL2:
+		return opts.Suite, nil
+	default:
+		return 0, fmt.Errorf("ecvrf: unsupported suite: 0x%02x", uint8(opts.Suite))
+	}
+}
+
 var (
 	// The domain separation tag DST, a parameter to the hash-to-curve
 	// suite, SHALL be set to "ECVRF_" || h2c_suite_ID_string || suite_string
 	h2cDST = []byte{
 		'E', 'C', 'V', 'R', 'F', '_', // "ECVRF_"
 		'e', 'd', 'w', 'a', 'r', 'd', 's', '2', '5', '5', '1', '9', '_', 'X', 'M', 'D', ':', 'S', 'H', 'A', '-', '5', '1', '2', '_', 'E', 'L', 'L', '2', '_', 'N', 'U', '_', // h2c_suite_ID_string
-		suiteString, // suite_string
+		byte(SuiteEdwards25519SHA512ELL2), // suite_string
 	}
 
 	addedRandomnessPadding [1024]byte
@@ -72,7 +159,7 @@ var (
 
 // Prove implements ECVRF_prove for the suite ECVRF-EDWARDS25519-SHA512-ELL2.
 func Prove(sk ed25519.PrivateKey, alphaString []byte) []byte {
-	piString, err := doProve(nil, sk, alphaString, false)
+	piString, err := doProve(nil, sk, alphaString, SuiteEdwards25519SHA512ELL2, false, false)
 	if err != nil {
 		panic(err)
 	}
@@ -81,7 +168,7 @@ func Prove(sk ed25519.PrivateKey, alphaString []byte) []byte {
 
 // Prove_v10 is Prove but using the v10 (and earlier) semantics.
 func Prove_v10(sk ed25519.PrivateKey, alphaString []byte) []byte {
-	piString, err := doProve(nil, sk, alphaString, true)
+	piString, err := doProve(nil, sk, alphaString, SuiteEdwards25519SHA512ELL2, true, false)
 	if err != nil {
 		panic(err)
 	}
@@ -98,7 +185,7 @@ func ProveWithAddedRandomness(rand io.Reader, sk ed25519.PrivateKey, alphaString
 	if rand == nil {
 		rand = cryptorand.Reader
 	}
-	return doProve(rand, sk, alphaString, false)
+	return doProve(rand, sk, alphaString, SuiteEdwards25519SHA512ELL2, false, false)
 }
 
 // ProveWithAddedRandomness_v10 is ProveWithAddedRandomness but using the
@@ -107,41 +194,57 @@ func ProveWithAddedRandomness_v10(rand io.Reader, sk ed25519.PrivateKey, alphaSt
 	if rand == nil {
 		rand = cryptorand.Reader
 	}
-	return doProve(rand, sk, alphaString, true)
+	return doProve(rand, sk, alphaString, SuiteEdwards25519SHA512ELL2, true, false)
+}
+
+// ProveWithOptions implements ECVRF_prove for the RFC 9381 suite
+// specified in opts.  If opts is nil, the behavior will be identical
+// to Prove.
+func ProveWithOptions(sk ed25519.PrivateKey, alphaString []byte, opts *Options) ([]byte, error) {
+	suite, err := opts.suite()
+	if err != nil {
+		return nil, err
+	}
+	var rand io.Reader
+	if opts != nil && opts.AddedRandomness {
+		rand = cryptorand.Reader
+	}
+	batchCompatible := opts != nil && opts.BatchCompatible
+	return doProve(rand, sk, alphaString, suite, false, batchCompatible)
 }
 
 func doProve(
 	rand io.Reader,
 	sk ed25519.PrivateKey,
 	alphaString []byte,
+	suite Suite,
 	draftPreV11 bool,
+	batchCompatible bool,
 ) ([]byte, error) {
 	// 1.  Use SK to derive the VRF secret scalar x and the VRF
 	// public key Y = x*B (this derivation depends on the ciphersuite,
 	// as per Section 5.5; these values can be cached, for example,
 	// after key generation, and need not be rederived each time)
-
-	if len(sk) != ed25519.PrivateKeySize {
-		return nil, fmt.Errorf("ecvrf: bad private key length")
+	esk, err := NewExpandedPrivateKey(sk)
+	if err != nil {
+		return nil, err
 	}
 
-	var (
-		extsk [64]byte
-		x     scalar.Scalar
-	)
-	h := sha512.New()
-	_, _ = h.Write(sk[:32])
-	h.Sum(extsk[:0])
-	extsk[0] &= 248
-	extsk[31] &= 127
-	extsk[31] |= 64
-	if _, err := x.SetBits(extsk[:32]); err != nil {
-		return nil, fmt.Errorf("ecvrf: failed to deserialize x scalar: %w", err)
-	}
-	Y := sk[32:]
+	return doProveExpanded(rand, esk, alphaString, suite, draftPreV11, batchCompatible)
+}
+
+func doProveExpanded(
+	rand io.Reader,
+	esk *ExpandedPrivateKey,
+	alphaString []byte,
+	suite Suite,
+	draftPreV11 bool,
+	batchCompatible bool,
+) ([]byte, error) {
+	x, Y := &esk.x, esk.publicKey[:]
 
 	// 2.  H = ECVRF_encode_to_curve(encode_to_curve_salt, alpha_string)
-	H, err := encodeToCurveH2cSuite(Y, alphaString)
+	H, err := suite.encodeToCurve(Y, alphaString)
 	if err != nil {
 		return nil, fmt.Errorf("ecvrf: failed to hash point to curve: %w", err)
 	}
@@ -155,7 +258,7 @@ func doProve(
 		gamma       curve.EdwardsPoint
 		gammaString curve.CompressedEdwardsY
 	)
-	gamma.Mul(H, &x)
+	gamma.Mul(H, x)
 	gammaString.SetEdwardsPoint(&gamma)
 
 	// 5.  k = ECVRF_nonce_generation(SK, h_string)
@@ -163,7 +266,7 @@ func doProve(
 		digest [64]byte
 		k      scalar.Scalar
 	)
-	h.Reset()
+	h := sha512.New()
 	if rand != nil {
 		var entropy [addedRandomnessSize]byte
 		if _, err := io.ReadFull(rand, entropy[:]); err != nil {
@@ -171,7 +274,7 @@ func doProve(
 		}
 		_, _ = h.Write(entropy[:])
 	}
-	_, _ = h.Write(extsk[32:])
+	_, _ = h.Write(esk.nonceKey[:])
 	if rand != nil {
 		padSize := len(addedRandomnessPadding) - (addedRandomnessSize + 32)
 		_, _ = h.Write(addedRandomnessPadding[:padSize])
@@ -201,13 +304,29 @@ func doProve(
 	if !draftPreV11 {
 		p1 = Y
 	}
-	c := challengeGeneration(p1, &hString, &gammaString, &kB, &kH)
+	c := suite.challengeGeneration(p1, &hString, &gammaString, &kB, &kH)
 
 	// 7.  s = (k + c*x) mod q
 	var s scalar.Scalar
-	s.Mul(c, &x)
+	s.Mul(c, x)
 	s.Add(&s, &k)
 
+	if batchCompatible {
+		// pi_string = point_to_string(Gamma) || point_to_string(k*B) ||
+		//             point_to_string(k*H) || int_to_string(s, qLen)
+		var (
+			piString [BatchCompatibleProofSize]byte
+			tmp      curve.CompressedEdwardsY
+		)
+		copy(piString[:32], gammaString[:])
+		copy(piString[32:64], tmp.SetEdwardsPoint(&kB)[:])
+		copy(piString[64:96], tmp.SetEdwardsPoint(&kH)[:])
+		if err = s.ToBytes(piString[96:]); err != nil {
+			return nil, fmt.Errorf("ecvrf: failed to serialize s scalar: %w", err)
+		}
+		return piString[:], nil
+	}
+
 	// 8.  pi_string = point_to_string(Gamma) || int_to_string(c, n) ||
 	//                 int_to_string(s, qLen)
 	var piString [ProofSize]byte
@@ -238,7 +357,35 @@ func ProofToHash(piString []byte) ([]byte, error) {
 	}
 
 	// Steps 4 .. 7 are in gammaToHash.
-	return gammaToHash(gamma), nil
+	return SuiteEdwards25519SHA512ELL2.gammaToHash(gamma), nil
+}
+
+// ProofToHashWithOptions implements ECVRF_proof_to_hash for the RFC 9381
+// suite specified in opts, in variable-time.  If opts is nil, the
+// behavior will be identical to ProofToHash.
+//
+// ECVRF_proof_to_hash should be run only on pi_string that is known
+// to have been produced by ECVRF_prove, or from within ECVRF_verify.
+func ProofToHashWithOptions(piString []byte, opts *Options) ([]byte, error) {
+	suite, err := opts.suite()
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.BatchCompatible {
+		if l := len(piString); l != BatchCompatibleProofSize {
+			return nil, fmt.Errorf("ecvrf: invalid proof size: %d", l)
+		}
+		gamma, err := decodePoint(piString[:32])
+		if err != nil {
+			return nil, fmt.Errorf("ecvrf: failed to decode gamma: %w", err)
+		}
+		return suite.gammaToHash(gamma), nil
+	}
+	gamma, _, _, err := decodeProof(piString)
+	if err != nil {
+		return nil, fmt.Errorf("ecvrf: failed to decode proof: %w", err)
+	}
+	return suite.gammaToHash(gamma), nil
 }
 
 // Verify implements ECVRF_verify for the suite ECVRF-EDWARDS25519-SHA512-ELL2.
@@ -246,42 +393,63 @@ func ProofToHash(piString []byte) ([]byte, error) {
 // The public key is validated such that the "full uniqueness" and
 // "full collision" properties are satisfied.
 func Verify(pk ed25519.PublicKey, piString, alphaString []byte) (bool, []byte) {
-	return doVerify(pk, piString, alphaString, false)
+	return doVerify(pk, nil, piString, alphaString, SuiteEdwards25519SHA512ELL2, true, false)
 }
 
 // Verify_v10 is Verify but using the v10 (and earlier) semantics.
 func Verify_v10(pk ed25519.PublicKey, piString, alphaString []byte) (bool, []byte) {
-	return doVerify(pk, piString, alphaString, true)
+	return doVerify(pk, nil, piString, alphaString, SuiteEdwards25519SHA512ELL2, true, true)
+}
+
+// VerifyWithOptions implements ECVRF_verify for the RFC 9381 suite
+// specified in opts.  If opts is nil, the behavior will be identical
+// to Verify.
+func VerifyWithOptions(pk ed25519.PublicKey, piString, alphaString []byte, opts *Options) (bool, []byte) {
+	return doVerifyWithOptions(pk, nil, piString, alphaString, opts)
+}
+
+func doVerifyWithOptions(
+	pk ed25519.PublicKey,
+	expandedPk *ed25519.ExpandedPublicKey,
+	piString []byte,
+	alphaString []byte,
+	opts *Options,
+) (bool, []byte) {
+	suite, err := opts.suite()
+	if err != nil {
+		return false, nil
+	}
+	validateKey := opts == nil || !opts.SkipKeyValidation
+	if opts != nil && opts.BatchCompatible {
+		var e entry
+		e.doInit(pk, expandedPk, piString, alphaString, suite, validateKey)
+		if !e.canBeValid || !e.verify() {
+			return false, nil
+		}
+		return true, suite.gammaToHash(&e.gamma)
+	}
+	return doVerify(pk, expandedPk, piString, alphaString, suite, validateKey, false)
 }
 
 func doVerify(
 	pk ed25519.PublicKey,
+	expandedPk *ed25519.ExpandedPublicKey,
 	piString []byte,
 	alphaString []byte,
+	suite Suite,
+	validateKey bool,
 	draftPreV11 bool,
 ) (bool, []byte) {
-	var (
-		Y       curve.EdwardsPoint
-		yString curve.CompressedEdwardsY
-	)
+	var key verifyingKey
 
 	// 1.   Y = string_to_point(PK_string)
-	if _, err := yString.SetBytes(pk); err != nil {
-		return false, nil
-	}
 	// 2.   If Y is "INVALID", output "INVALID" and stop
-	if !yString.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
-		return false, nil
-	}
-	if _, err := Y.SetCompressedY(&yString); err != nil {
-		return false, nil
-	}
 	// 3.   If validate_key, run ECVRF_validate_key(Y) (Section 5.4.5); if
 	//      it outputs "INVALID", output "INVALID" and stop
-	if Y.IsSmallOrder() { // Section 5.4.5 ECVRF Validate Key
-		// The IETF draft treats this as optional, but we always have enforced this.
+	if !key.init(pk, expandedPk, validateKey) {
 		return false, nil
 	}
+	pk = key.compressed[:]
 
 	// 4.   D = ECVRF_decode_proof(pi_string) (see Section 5.4.4)
 	// 5.   If D is "INVALID", output "INVALID" and stop
@@ -295,7 +463,7 @@ func doVerify(
 
 	// 7.   H = ECVRF_encode_to_curve(encode_to_curve_salt, alpha_string)
 	//      (see Section 5.4.1)
-	H, err := encodeToCurveH2cSuite(yString[:], alphaString)
+	H, err := suite.encodeToCurve(pk, alphaString)
 	if err != nil {
 		panic("ecvrf: failed to hash point to curve: " + err.Error())
 	}
@@ -303,9 +471,7 @@ func doVerify(
 	hString.SetEdwardsPoint(H)
 
 	// 8.   U = s*B - c*Y
-	var U curve.EdwardsPoint
-	Y.Neg(&Y)
-	U.DoubleScalarMulBasepointVartime(c, &Y, s)
+	U := key.sBMinusCY(s, c)
 
 	// 9.   V = s*H - c*Gamma
 	var V, negGamma curve.EdwardsPoint
@@ -324,7 +490,7 @@ func doVerify(
 	if !draftPreV11 {
 		p1 = pk[:]
 	}
-	cPrime := challengeGeneration(p1, &hString, &gammaString, &U, &V)
+	cPrime := suite.challengeGeneration(p1, &hString, &gammaString, U, &V)
 
 	// 11.  If c and c' are equal, output ("VALID",
 	//      ECVRF_proof_to_hash(pi_string)); else output "INVALID"
@@ -332,10 +498,62 @@ func doVerify(
 	if c.Equal(cPrime) == 0 {
 		return false, nil
 	}
-	return true, gammaToHash(gamma)
+	return true, suite.gammaToHash(gamma)
+}
+
+// verifyingKey is a decoded public key, optionally backed by an
+// ed25519.ExpandedPublicKey.
+type verifyingKey struct {
+	compressed curve.CompressedEdwardsY
+
+	negY         curve.EdwardsPoint
+	negYExpanded *curve.ExpandedEdwardsPoint
+}
+
+func (key *verifyingKey) init(pk ed25519.PublicKey, expandedPk *ed25519.ExpandedPublicKey, validateKey bool) bool {
+	if expandedPk != nil {
+		// The decompression, and the outcome of the checks are
+		// cached as part of the key expansion.
+		if !expandedPk.IsCanonical() { // Required by RFC 8032 decode semantics.
+			return false
+		}
+		if validateKey && expandedPk.IsSmallOrder() { // Section 5.4.5 ECVRF Validate Key
+			return false
+		}
+		key.compressed = expandedPk.CompressedY()
+		key.negYExpanded = expandedPk.NegatedPoint()
+		key.negY.SetExpanded(key.negYExpanded)
+		return true
+	}
+
+	if _, err := key.compressed.SetBytes(pk); err != nil {
+		return false
+	}
+	if !key.compressed.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
+		return false
+	}
+	if _, err := key.negY.SetCompressedY(&key.compressed); err != nil {
+		return false
+	}
+	if validateKey && key.negY.IsSmallOrder() { // Section 5.4.5 ECVRF Validate Key
+		// The IETF draft treats this as optional, but we enforce this
+		// unless explicitly requested otherwise.
+		return false
+	}
+	key.negY.Neg(&key.negY)
+	key.negYExpanded = nil
+	return true
+}
+
+func (key *verifyingKey) sBMinusCY(s, c *scalar.Scalar) *curve.EdwardsPoint {
+	var p curve.EdwardsPoint
+	if key.negYExpanded != nil {
+		return p.ExpandedDoubleScalarMulBasepointVartime(c, key.negYExpanded, s)
+	}
+	return p.DoubleScalarMulBasepointVartime(c, &key.negY, s)
 }
 
-func gammaToHash(gamma *curve.EdwardsPoint) []byte {
+func (s Suite) gammaToHash(gamma *curve.EdwardsPoint) []byte {
 	// 4.  three_string = 0x03 = int_to_string(3, 1), a single octet with
 	//     value 3
 	// 5.  zero_string = 0x00 = int_to_string(0, 1), a single octet with
@@ -349,9 +567,9 @@ func gammaToHash(gamma *curve.EdwardsPoint) []byte {
 	)
 	cGString.SetEdwardsPoint(cG.MulByCofactor(gamma))
 	h := sha512.New()
-	_, _ = h.Write([]byte{suiteString, threeString}) // suite_string, three_string
-	_, _ = h.Write(cGString[:])                      // point_to_string(cofactor * Gamma)
-	_, _ = h.Write([]byte{zeroString})               // zero_string
+	_, _ = h.Write([]byte{byte(s), threeString}) // suite_string, three_string
+	_, _ = h.Write(cGString[:])                  // point_to_string(cofactor * Gamma)
+	_, _ = h.Write([]byte{zeroString})           // zero_string
 	return h.Sum(nil)
 }
 
@@ -370,7 +588,68 @@ func encodeToCurveH2cSuite(encodeToCurveSalt, alphaString []byte) (*curve.Edward
 	return h2c.Edwards25519_XMD_SHA512_ELL2_NU(h2cDST, stringToHash)
 }
 
-func challengeGeneration(p1 []byte, p2, p3 *curve.CompressedEdwardsY, p4, p5 *curve.EdwardsPoint) *scalar.Scalar {
+func (s Suite) encodeToCurve(encodeToCurveSalt, alphaString []byte) (*curve.EdwardsPoint, error) {
+	switch s {
+	case SuiteEdwards25519SHA512TAI:
+		return encodeToCurveTryAndIncrement(encodeToCurveSalt, alphaString)
+	case SuiteEdwards25519SHA512ELL2:
+		return encodeToCurveH2cSuite(encodeToCurveSalt, alphaString)
+	default:
+		return nil, fmt.Errorf("ecvrf: unsupported suite: 0x%02x", uint8(s))
+	}
+}
+
+func encodeToCurveTryAndIncrement(encodeToCurveSalt, alphaString []byte) (*curve.EdwardsPoint, error) {
+	// 1.  ctr = 0
+	// 2.  encode_to_curve_domain_separator_front = 0x01
+	// 3.  encode_to_curve_domain_separator_back = 0x00
+	// 4.  H = "INVALID"
+	// 5.  While H is "INVALID" or H is the identity element of the
+	//     elliptic curve group:
+	var (
+		digest  [64]byte
+		hString curve.CompressedEdwardsY
+		H       curve.EdwardsPoint
+	)
+	h := sha512.New()
+	for ctr := 0; ctr < 256; ctr++ {
+		// A.  ctr_string = int_to_string(ctr, 1)
+		// B.  hash_string = Hash(suite_string ||
+		//     encode_to_curve_domain_separator_front ||
+		//     encode_to_curve_salt || alpha_string || ctr_string ||
+		//     encode_to_curve_domain_separator_back)
+		h.Reset()
+		_, _ = h.Write([]byte{byte(SuiteEdwards25519SHA512TAI), oneString})
+		_, _ = h.Write(encodeToCurveSalt)
+		_, _ = h.Write(alphaString)
+		_, _ = h.Write([]byte{byte(ctr), zeroString})
+		h.Sum(digest[:0])
+
+		// C.  H = interpret_hash_value_as_a_point(hash_string)
+		//
+		// For the Edwards25519 curve, this is string_to_point applied
+		// to the first 32 bytes of the hash value.
+		copy(hString[:], digest[:32])
+		if !hString.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
+			continue
+		}
+		if _, err := H.SetCompressedY(&hString); err != nil {
+			continue
+		}
+
+		// D.  If H is not "INVALID" and cofactor > 1, set H = cofactor * H
+		H.MulByCofactor(&H)
+		if !H.IsIdentity() {
+			// 6.  Output H
+			return &H, nil
+		}
+	}
+
+	// The probability of reaching this is negligible (2^-256).
+	return nil, fmt.Errorf("ecvrf: try-and-increment failed to find a point")
+}
+
+func (s Suite) challengeGeneration(p1 []byte, p2, p3 *curve.CompressedEdwardsY, p4, p5 *curve.EdwardsPoint) *scalar.Scalar {
 	// 1.  challenge_generation_domain_separator_front = 0x02
 	// 2.  Initialize str = suite_string || challenge_generation_domain_separator_front
 	var (
@@ -378,7 +657,7 @@ func challengeGeneration(p1 []byte, p2, p3 *curve.CompressedEdwardsY, p4, p5 *cu
 		digest [64]byte
 	)
 	h := sha512.New()
-	_, _ = h.Write([]byte{suiteString, twoString}) // suite_string || challenge_generation_domain_separator_front
+	_, _ = h.Write([]byte{byte(s), twoString}) // suite_string || challenge_generation_domain_separator_front
 
 	// 3.  for PJ in [P1, P2, P3, P4, P5]:
 	//       str = str || point_to_string(PJ)
@@ -426,17 +705,9 @@ func decodeProof(piString []byte) (*curve.EdwardsPoint, *scalar.Scalar, *scalar.
 
 	// 4.  Gamma = string_to_point(gamma_string)
 	// 5.  if Gamma = "INVALID" output "INVALID" and stop.
-	var gammaString curve.CompressedEdwardsY
-	if _, err := gammaString.SetBytes(piString[:32]); err != nil {
-		// Should *NEVER* happen.
-		panic("ecvrf: failed to copy gamma_string: " + err.Error())
-	}
-	if !gammaString.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
-		return nil, nil, nil, fmt.Errorf("ecvrf: non-canonical gamma")
-	}
-	var gamma curve.EdwardsPoint
-	if _, err := gamma.SetCompressedY(&gammaString); err != nil {
-		return nil, nil, nil, fmt.Errorf("ecvrf: failed to decompress gamma: %w", err)
+	gamma, err := decodePoint(piString[:32])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("ecvrf: failed to decode gamma: %w", err)
 	}
 
 	// 6.  c = string_to_int(c_string)
@@ -460,5 +731,20 @@ func decodeProof(piString []byte) (*curve.EdwardsPoint, *scalar.Scalar, *scalar.
 	}
 
 	// 8.  Output Gamma, c, and s
-	return &gamma, &c, &s, nil
+	return gamma, &c, &s, nil
+}
+
+func decodePoint(b []byte) (*curve.EdwardsPoint, error) {
+	var compressed curve.CompressedEdwardsY
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, err
+	}
+	if !compressed.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
+		return nil, fmt.Errorf("ecvrf: non-canonical point")
+	}
+	var p curve.EdwardsPoint
+	if _, err := p.SetCompressedY(&compressed); err != nil {
+		return nil, fmt.Errorf("ecvrf: failed to decompress point: %w", err)
+	}
+	return &p, nil
 }
@@ -31,15 +31,21 @@ package ecvrf
 
 import (
 	"bytes"
+	"crypto/sha512"
 	"io"
 	"testing"
 
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
 	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
 	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
 )
 
 func TestECVRF(t *testing.T) {
 	t.Run("TestVectors", testIETFVectors)
+	t.Run("TestVectors/RFC9381", testRFC9381Vectors)
+	t.Run("KeyValidation", testKeyValidation)
+	t.Run("Options", testOptions)
 }
 
 func testIETFVectors(t *testing.T) {
@@ -157,6 +163,206 @@ func testIETFVectors(t *testing.T) {
 	}
 }
 
+func testRFC9381Vectors(t *testing.T) {
+	testVectors := []struct {
+		suite Suite
+		sk    []byte
+		alpha []byte
+		pi    []byte
+		beta  []byte
+	}{
+		// RFC 9381 Appendix B.3 (ECVRF-EDWARDS25519-SHA512-TAI)
+		{
+			suite: SuiteEdwards25519SHA512TAI,
+			sk:    testhelpers.MustUnhex(t, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"),
+			alpha: []byte{},
+			pi:    testhelpers.MustUnhex(t, "8657106690b5526245a92b003bb079ccd1a92130477671f6fc01ad16f26f723f26f8a57ccaed74ee1b190bed1f479d9727d2d0f9b005a6e456a35d4fb0daab1268a1b0db10836d9826a528ca76567805"),
+			beta:  testhelpers.MustUnhex(t, "90cf1df3b703cce59e2a35b925d411164068269d7b2d29f3301c03dd757876ff66b71dda49d2de59d03450451af026798e8f81cd2e333de5cdf4f3e140fdd8ae"),
+		},
+		{
+			suite: SuiteEdwards25519SHA512TAI,
+			sk:    testhelpers.MustUnhex(t, "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb"),
+			alpha: []byte{0x72},
+			pi:    testhelpers.MustUnhex(t, "f3141cd382dc42909d19ec5110469e4feae18300e94f304590abdced48aed5933bf0864a62558b3ed7f2fea45c92a465301b3bbf5e3e54ddf2d935be3b67926da3ef39226bbc355bdc9850112c8f4b02"),
+			beta:  testhelpers.MustUnhex(t, "eb4440665d3891d668e7e0fcaf587f1b4bd7fbfe99d0eb2211ccec90496310eb5e33821bc613efb94db5e5b54c70a848a0bef4553a41befc57663b56373a5031"),
+		},
+		{
+			suite: SuiteEdwards25519SHA512TAI,
+			sk:    testhelpers.MustUnhex(t, "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7"),
+			alpha: []byte{0xaf, 0x82},
+			pi:    testhelpers.MustUnhex(t, "9bc0f79119cc5604bf02d23b4caede71393cedfbb191434dd016d30177ccbf8096bb474e53895c362d8628ee9f9ea3c0e52c7a5c691b6c18c9979866568add7a2d41b00b05081ed0f58ee5e31b3a970e"),
+			beta:  testhelpers.MustUnhex(t, "645427e5d00c62a23fb703732fa5d892940935942101e456ecca7bb217c61c452118fec1219202a0edcf038bb6373241578be7217ba85a2687f7a0310b2df19f"),
+		},
+		// RFC 9381 Appendix B.4 (ECVRF-EDWARDS25519-SHA512-ELL2)
+		{
+			suite: SuiteEdwards25519SHA512ELL2,
+			sk:    testhelpers.MustUnhex(t, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"),
+			alpha: []byte{},
+			pi:    testhelpers.MustUnhex(t, "7d9c633ffeee27349264cf5c667579fc583b4bda63ab71d001f89c10003ab46f14adf9a3cd8b8412d9038531e865c341cafa73589b023d14311c331a9ad15ff2fb37831e00f0acaa6d73bc9997b06501"),
+			beta:  testhelpers.MustUnhex(t, "9d574bf9b8302ec0fc1e21c3ec5368269527b87b462ce36dab2d14ccf80c53cccf6758f058c5b1c856b116388152bbe509ee3b9ecfe63d93c3b4346c1fbc6c54"),
+		},
+		{
+			suite: SuiteEdwards25519SHA512ELL2,
+			sk:    testhelpers.MustUnhex(t, "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb"),
+			alpha: []byte{0x72},
+			pi:    testhelpers.MustUnhex(t, "47b327393ff2dd81336f8a2ef10339112401253b3c714eeda879f12c509072ef055b48372bb82efbdce8e10c8cb9a2f9d60e93908f93df1623ad78a86a028d6bc064dbfc75a6a57379ef855dc6733801"),
+			beta:  testhelpers.MustUnhex(t, "38561d6b77b71d30eb97a062168ae12b667ce5c28caccdf76bc88e093e4635987cd96814ce55b4689b3dd2947f80e59aac7b7675f8083865b46c89b2ce9cc735"),
+		},
+		{
+			suite: SuiteEdwards25519SHA512ELL2,
+			sk:    testhelpers.MustUnhex(t, "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7"),
+			alpha: []byte{0xaf, 0x82},
+			pi:    testhelpers.MustUnhex(t, "926e895d308f5e328e7aa159c06eddbe56d06846abf5d98c2512235eaa57fdce35b46edfc655bc828d44ad09d1150f31374e7ef73027e14760d42e77341fe05467bb286cc2c9d7fde29120a0b2320d04"),
+			beta:  testhelpers.MustUnhex(t, "121b7f9b9aaaa29099fc04a94ba52784d44eac976dd1a3cca458733be5cd090a7b5fbd148444f17f8daf1fb55cb04b1ae85a626e30a54b4b0f8abf4a43314a58"),
+		},
+	}
+	for i, vec := range testVectors {
+		sk := ed25519.NewKeyFromSeed(vec.sk)
+		pk := sk.Public().(ed25519.PublicKey)
+		opts := &Options{
+			Suite: vec.suite,
+		}
+
+		pi, err := ProveWithOptions(sk, vec.alpha, opts)
+		if err != nil {
+			t.Fatalf("[%d] ProveWithOptions(): %v", i, err)
+		}
+		if !bytes.Equal(vec.pi, pi) {
+			t.Fatalf("[%d] pi mismatch (Got: %x)", i, pi)
+		}
+
+		ok, beta := VerifyWithOptions(pk, pi, vec.alpha, opts)
+		if !ok {
+			t.Fatalf("[%d] VerifyWithOptions() failed", i)
+		}
+		if !bytes.Equal(vec.beta, beta) {
+			t.Fatalf("[%d] beta mismatch (Got: %x)", i, beta)
+		}
+
+		beta, err = ProofToHashWithOptions(pi, opts)
+		if err != nil {
+			t.Fatalf("[%d] ProofToHashWithOptions(): %v", i, err)
+		}
+		if !bytes.Equal(vec.beta, beta) {
+			t.Fatalf("[%d] beta (ProofToHashWithOptions) mismatch (Got: %x)", i, beta)
+		}
+
+		// Test that adding entropy to the proving process produces
+		// different pi, but identical beta.
+		piNonDeterministic, err := ProveWithOptions(sk, vec.alpha, &Options{
+			Suite:           vec.suite,
+			AddedRandomness: true,
+		})
+		if err != nil {
+			t.Fatalf("[%d] ProveWithOptions(AddedRandomness): %v", i, err)
+		}
+		if bytes.Equal(piNonDeterministic, pi) {
+			t.Fatalf("[%d] pi (non-determinstic) matched (Got: %x)", i, piNonDeterministic)
+		}
+		ok, beta = VerifyWithOptions(pk, piNonDeterministic, vec.alpha, opts)
+		if !ok || !bytes.Equal(vec.beta, beta) {
+			t.Fatalf("[%d] VerifyWithOptions(pi_non_deterministic) failed", i)
+		}
+
+		// Proofs are not valid across suites.
+		otherSuite := SuiteEdwards25519SHA512ELL2
+		if vec.suite == otherSuite {
+			otherSuite = SuiteEdwards25519SHA512TAI
+		}
+		if ok, _ = VerifyWithOptions(pk, pi, vec.alpha, &Options{Suite: otherSuite}); ok {
+			t.Fatalf("[%d] VerifyWithOptions(other suite) passed", i)
+		}
+
+		pi[0] ^= 0xa5
+		if ok, _ = VerifyWithOptions(pk, pi, vec.alpha, opts); ok {
+			t.Fatalf("[%d] bad pi, VerifyWithOptions() passed", i)
+		}
+	}
+}
+
+func testKeyValidation(t *testing.T) {
+	// With a small order public key, it is trivial to construct a
+	// proof that verifies if ECVRF_validate_key is skipped, as
+	// Gamma can be chosen arbitrarily (here the identity).
+	var (
+		gamma, kB, kH curve.EdwardsPoint
+		gammaString   curve.CompressedEdwardsY
+	)
+	pk := ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))
+	pk[0] = 0x01 // Identity.
+	alpha := []byte("test-alpha-pls-ignore")
+
+	for _, suite := range []Suite{
+		SuiteEdwards25519SHA512TAI,
+		SuiteEdwards25519SHA512ELL2,
+	} {
+		H, err := suite.encodeToCurve(pk, alpha)
+		if err != nil {
+			t.Fatalf("encodeToCurve: %v", err)
+		}
+		var hString curve.CompressedEdwardsY
+		hString.SetEdwardsPoint(H)
+
+		digest := sha512.Sum512([]byte("k"))
+		k, err := scalar.NewFromBytesModOrderWide(digest[:])
+		if err != nil {
+			t.Fatalf("NewFromBytesModOrderWide: %v", err)
+		}
+		gamma.Identity()
+		gammaString.SetEdwardsPoint(&gamma)
+		kB.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, k)
+		kH.Mul(H, k)
+		c := suite.challengeGeneration(pk, &hString, &gammaString, &kB, &kH)
+
+		var pi [ProofSize]byte
+		copy(pi[:32], gammaString[:])
+		_ = c.ToBytes(pi[32:64])
+		_ = k.ToBytes(pi[48:])
+
+		if ok, _ := VerifyWithOptions(pk, pi[:], alpha, &Options{Suite: suite}); ok {
+			t.Fatalf("%s: VerifyWithOptions(small order pk) passed", suite)
+		}
+		ok, beta := VerifyWithOptions(pk, pi[:], alpha, &Options{
+			Suite:             suite,
+			SkipKeyValidation: true,
+		})
+		if !ok {
+			t.Fatalf("%s: VerifyWithOptions(small order pk, SkipKeyValidation) failed", suite)
+		}
+		if !bytes.Equal(beta, suite.gammaToHash(&gamma)) {
+			t.Fatalf("%s: beta mismatch", suite)
+		}
+	}
+}
+
+func testOptions(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	alpha := []byte("test-alpha-pls-ignore")
+
+	// The default suite is ECVRF-EDWARDS25519-SHA512-ELL2.
+	pi, err := ProveWithOptions(sk, alpha, nil)
+	if err != nil {
+		t.Fatalf("ProveWithOptions(nil): %v", err)
+	}
+	if !bytes.Equal(pi, Prove(sk, alpha)) {
+		t.Fatalf("ProveWithOptions(nil) != Prove")
+	}
+
+	badOpts := &Options{Suite: Suite(0x05)}
+	if _, err = ProveWithOptions(sk, alpha, badOpts); err == nil {
+		t.Fatalf("ProveWithOptions(bad suite) succeeded")
+	}
+	if _, err = ProofToHashWithOptions(pi, badOpts); err == nil {
+		t.Fatalf("ProofToHashWithOptions(bad suite) succeeded")
+	}
+	if ok, _ := VerifyWithOptions(sk.Public().(ed25519.PublicKey), pi, alpha, badOpts); ok {
+		t.Fatalf("VerifyWithOptions(bad suite) passed")
+	}
+}
+
 func BenchmarkECVRF(b *testing.B) {
 	b.Run("Prove", benchProve)
 	b.Run("ProofToHash", benchProofToHash)
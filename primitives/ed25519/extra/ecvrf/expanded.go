@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ecvrf
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// ExpandedPrivateKey is a PrivateKey stored in an expanded representation
+// for the purpose of accelerating repeated proving.
+//
+// Verification with a cached public key is done via ed25519.ExpandedPublicKey
+// (see VerifyExpanded), so that the same expanded public key can be shared
+// with Ed25519 signature verification.
+type ExpandedPrivateKey struct {
+	x         scalar.Scalar
+	nonceKey  [32]byte
+	publicKey [ed25519.PublicKeySize]byte
+}
+
+// PublicKey returns the public key corresponding to the expanded private key.
+func (esk *ExpandedPrivateKey) PublicKey() ed25519.PublicKey {
+	pk := make([]byte, ed25519.PublicKeySize)
+	copy(pk, esk.publicKey[:])
+	return pk
+}
+
+// NewExpandedPrivateKey creates a new expanded private key from an existing
+// private key.
+func NewExpandedPrivateKey(sk ed25519.PrivateKey) (*ExpandedPrivateKey, error) {
+	if len(sk) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ecvrf: bad private key length")
+	}
+
+	// Section 5.5: The secret scalar x and nonce key are derived as
+	// per RFC 8032 Section 5.1.5.
+	var (
+		esk   ExpandedPrivateKey
+		extsk [64]byte
+	)
+	h := sha512.New()
+	_, _ = h.Write(sk[:32])
+	h.Sum(extsk[:0])
+	extsk[0] &= 248
+	extsk[31] &= 127
+	extsk[31] |= 64
+	if _, err := esk.x.SetBits(extsk[:32]); err != nil {
+		return nil, fmt.Errorf("ecvrf: failed to deserialize x scalar: %w", err)
+	}
+	copy(esk.nonceKey[:], extsk[32:])
+	copy(esk.publicKey[:], sk[32:])
+
+	return &esk, nil
+}
+
+// ProveExpanded implements ECVRF_prove for the suite ECVRF-EDWARDS25519-SHA512-ELL2,
+// with an expanded private key.
+func ProveExpanded(sk *ExpandedPrivateKey, alphaString []byte) []byte {
+	piString, err := doProveExpanded(nil, sk, alphaString, SuiteEdwards25519SHA512ELL2, false, false)
+	if err != nil {
+		panic(err)
+	}
+	return piString
+}
+
+// ProveExpandedWithOptions implements ECVRF_prove for the RFC 9381 suite
+// specified in opts, with an expanded private key.  If opts is nil, the
+// behavior will be identical to ProveExpanded.
+func ProveExpandedWithOptions(sk *ExpandedPrivateKey, alphaString []byte, opts *Options) ([]byte, error) {
+	suite, err := opts.suite()
+	if err != nil {
+		return nil, err
+	}
+	var rand io.Reader
+	if opts != nil && opts.AddedRandomness {
+		rand = cryptorand.Reader
+	}
+	batchCompatible := opts != nil && opts.BatchCompatible
+	return doProveExpanded(rand, sk, alphaString, suite, false, batchCompatible)
+}
+
+// VerifyExpanded implements ECVRF_verify for the suite ECVRF-EDWARDS25519-SHA512-ELL2,
+// with an expanded public key.
+func VerifyExpanded(pk *ed25519.ExpandedPublicKey, piString, alphaString []byte) (bool, []byte) {
+	return VerifyExpandedWithOptions(pk, piString, alphaString, nil)
+}
+
+// VerifyExpandedWithOptions implements ECVRF_verify for the RFC 9381
+// suite specified in opts, with an expanded public key.  If opts is nil,
+// the behavior will be identical to VerifyExpanded.
+func VerifyExpandedWithOptions(pk *ed25519.ExpandedPublicKey, piString, alphaString []byte, opts *Options) (bool, []byte) {
+	if pk == nil {
+		return false, nil
+	}
+	return doVerifyWithOptions(nil, pk, piString, alphaString, opts)
+}
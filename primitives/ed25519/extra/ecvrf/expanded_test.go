@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ecvrf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+func TestExpanded(t *testing.T) {
+	pk, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	esk, err := NewExpandedPrivateKey(sk)
+	if err != nil {
+		t.Fatalf("NewExpandedPrivateKey: %v", err)
+	}
+	if !bytes.Equal(esk.PublicKey(), pk) {
+		t.Fatalf("ExpandedPrivateKey.PublicKey mismatch")
+	}
+	epk, err := ed25519.NewExpandedPublicKey(pk)
+	if err != nil {
+		t.Fatalf("NewExpandedPublicKey: %v", err)
+	}
+
+	alpha := []byte("test-alpha-pls-ignore")
+
+	for _, opts := range []*Options{
+		nil,
+		{Suite: SuiteEdwards25519SHA512TAI},
+		{Suite: SuiteEdwards25519SHA512ELL2, BatchCompatible: true},
+	} {
+		pi, err := ProveWithOptions(sk, alpha, opts)
+		if err != nil {
+			t.Fatalf("ProveWithOptions: %v", err)
+		}
+		piExpanded, err := ProveExpandedWithOptions(esk, alpha, opts)
+		if err != nil {
+			t.Fatalf("ProveExpandedWithOptions: %v", err)
+		}
+		if !bytes.Equal(pi, piExpanded) {
+			t.Fatalf("ProveExpandedWithOptions mismatch")
+		}
+
+		ok, beta := VerifyWithOptions(pk, pi, alpha, opts)
+		if !ok {
+			t.Fatalf("VerifyWithOptions failed")
+		}
+		ok, betaExpanded := VerifyExpandedWithOptions(epk, pi, alpha, opts)
+		if !ok {
+			t.Fatalf("VerifyExpandedWithOptions failed")
+		}
+		if !bytes.Equal(beta, betaExpanded) {
+			t.Fatalf("VerifyExpandedWithOptions beta mismatch")
+		}
+
+		if ok, _ = VerifyExpandedWithOptions(epk, pi, []byte("wrong alpha"), opts); ok {
+			t.Fatalf("VerifyExpandedWithOptions(wrong alpha) passed")
+		}
+	}
+
+	pi := ProveExpanded(esk, alpha)
+	if !bytes.Equal(pi, Prove(sk, alpha)) {
+		t.Fatalf("ProveExpanded mismatch")
+	}
+	if ok, _ := VerifyExpanded(epk, pi, alpha); !ok {
+		t.Fatalf("VerifyExpanded failed")
+	}
+	if ok, _ := VerifyExpanded(nil, pi, alpha); ok {
+		t.Fatalf("VerifyExpanded(nil) passed")
+	}
+
+	if _, err = NewExpandedPrivateKey(sk[:32]); err == nil {
+		t.Fatalf("NewExpandedPrivateKey(truncated) succeeded")
+	}
+}
+
+func TestExpandedKeyValidation(t *testing.T) {
+	alpha := []byte("test-alpha-pls-ignore")
+
+	// Small order (identity), and non-canonical (y = p) public keys.
+	smallOrderPk := make([]byte, ed25519.PublicKeySize)
+	smallOrderPk[0] = 0x01
+	nonCanonicalPk := []byte{
+		0xed, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f,
+	}
+
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	pi := Prove(sk, alpha)
+
+	for _, tc := range []struct {
+		n                string
+		pk               []byte
+		okSkipValidation bool
+	}{
+		{"SmallOrder", smallOrderPk, true},
+		{"NonCanonical", nonCanonicalPk, false},
+	} {
+		epk, err := ed25519.NewExpandedPublicKey(tc.pk)
+		if err != nil {
+			t.Fatalf("%s: NewExpandedPublicKey: %v", tc.n, err)
+		}
+
+		if ok, _ := VerifyExpanded(epk, pi, alpha); ok {
+			t.Fatalf("%s: VerifyExpanded passed", tc.n)
+		}
+
+		// Key validation must match between the expanded and
+		// unexpanded key.
+		var key, keyExpanded verifyingKey
+		for _, validateKey := range []bool{true, false} {
+			ok := key.init(tc.pk, nil, validateKey)
+			okExpanded := keyExpanded.init(nil, epk, validateKey)
+			if ok != okExpanded {
+				t.Fatalf("%s: validateKey %v: key.init mismatch (%v, %v)", tc.n, validateKey, ok, okExpanded)
+			}
+			if expected := !validateKey && tc.okSkipValidation; ok != expected {
+				t.Fatalf("%s: validateKey %v: key.init: %v", tc.n, validateKey, ok)
+			}
+			if ok && key.negY.Equal(&keyExpanded.negY) != 1 {
+				t.Fatalf("%s: -Y mismatch", tc.n)
+			}
+		}
+	}
+}
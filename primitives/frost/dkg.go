@@ -0,0 +1,354 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// DKGRound1Package is a participant's first round distributed key
+// generation message, which MUST be broadcast to all other participants.
+type DKGRound1Package struct {
+	cs         *Ciphersuite
+	id         Identifier
+	commitment []element
+
+	proofR element
+	proofZ *scalar.Scalar
+}
+
+// Identifier returns the identifier of the participant that produced
+// the package.
+func (pkg *DKGRound1Package) Identifier() Identifier {
+	return pkg.id
+}
+
+// MarshalBinary encodes a DKGRound1Package into binary form, excluding
+// the identifier.
+func (pkg *DKGRound1Package) MarshalBinary() ([]byte, error) {
+	zBytes, err := pkg.proofZ.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to serialize proof of knowledge: %w", err)
+	}
+
+	b := make([]byte, 0, (len(pkg.commitment)+1)*elementSize+scalar.ScalarSize)
+	for _, c := range pkg.commitment {
+		b = append(b, c.bytes()...)
+	}
+	b = append(b, pkg.proofR.bytes()...)
+	b = append(b, zBytes...)
+
+	return b, nil
+}
+
+// NewDKGRound1Package constructs a DKGRound1Package from the participant
+// identifier and byte representation.
+func (cs *Ciphersuite) NewDKGRound1Package(id Identifier, b []byte) (*DKGRound1Package, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("frost: invalid identifier")
+	}
+	l := len(b) - scalar.ScalarSize
+	if l < 3*elementSize || l%elementSize != 0 {
+		return nil, fmt.Errorf("frost: bad DKGRound1Package size: %v", len(b))
+	}
+
+	elements, err := cs.deserializeElements(b[:l])
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize DKGRound1Package: %w", err)
+	}
+	z, err := cs.deserializeScalar(b[l:])
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(elements) - 1
+	return &DKGRound1Package{
+		cs:         cs,
+		id:         id,
+		commitment: elements[:n],
+		proofR:     elements[n],
+		proofZ:     z,
+	}, nil
+}
+
+// DKGRound1Secret is a participant's secret state after the first round
+// of distributed key generation.
+type DKGRound1Secret struct {
+	cs         *Ciphersuite
+	id         Identifier
+	maxSigners int
+	minSigners int
+
+	coefficients []*scalar.Scalar
+	commitment   []element
+}
+
+// DKGRound2Package is a participant's second round distributed key
+// generation message to another participant, which MUST be sent over a
+// confidential and authenticated channel.
+type DKGRound2Package struct {
+	sender   Identifier
+	receiver Identifier
+	share    *scalar.Scalar
+}
+
+// Sender returns the identifier of the participant that produced the
+// package.
+func (pkg *DKGRound2Package) Sender() Identifier {
+	return pkg.sender
+}
+
+// Receiver returns the identifier of the participant that the package
+// is for.
+func (pkg *DKGRound2Package) Receiver() Identifier {
+	return pkg.receiver
+}
+
+// MarshalBinary encodes a DKGRound2Package into binary form, excluding
+// the identifiers.
+func (pkg *DKGRound2Package) MarshalBinary() ([]byte, error) {
+	return pkg.share.MarshalBinary()
+}
+
+// NewDKGRound2Package constructs a DKGRound2Package from the sender and
+// receiver identifiers and byte representation.
+func (cs *Ciphersuite) NewDKGRound2Package(sender, receiver Identifier, b []byte) (*DKGRound2Package, error) {
+	if sender == 0 || receiver == 0 {
+		return nil, fmt.Errorf("frost: invalid identifier")
+	}
+	share, err := cs.deserializeScalar(b)
+	if err != nil {
+		return nil, err
+	}
+	return &DKGRound2Package{
+		sender:   sender,
+		receiver: receiver,
+		share:    share,
+	}, nil
+}
+
+// DKGRound2Secret is a participant's secret state after the second
+// round of distributed key generation.
+type DKGRound2Secret struct {
+	cs         *Ciphersuite
+	id         Identifier
+	maxSigners int
+	minSigners int
+
+	share       *scalar.Scalar
+	commitments map[Identifier][]element
+}
+
+func (cs *Ciphersuite) dkgChallenge(id Identifier, verifyingKey, r element) *scalar.Scalar {
+	return cs.hdkg(id.bytes(), verifyingKey.bytes(), r.bytes())
+}
+
+// DKGPart1 starts the distributed key generation protocol for a
+// participant, using entropy from rng.  If rng is nil, crypto/rand.Reader
+// will be used.
+func (cs *Ciphersuite) DKGPart1(rng io.Reader, id Identifier, maxSigners, minSigners int) (*DKGRound1Secret, *DKGRound1Package, error) {
+	if err := validateParameters(maxSigners, minSigners); err != nil {
+		return nil, nil, err
+	}
+	if id == 0 {
+		return nil, nil, fmt.Errorf("frost: invalid identifier")
+	}
+	if rng == nil {
+		rng = cryptorand.Reader
+	}
+
+	coefficients := make([]*scalar.Scalar, 0, minSigners)
+	for i := 0; i < minSigners; i++ {
+		s, err := scalar.New().SetRandom(rng)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frost: failed to generate coefficient: %w", err)
+		}
+		coefficients = append(coefficients, s)
+	}
+	commitment := cs.vssCommit(coefficients)
+
+	// Prove knowledge of the secret (the constant term), to prevent
+	// rogue-key attacks.
+	//
+	// k <- random, R = k * G, c = H(id || C_0 || R), mu = k + a_0 * c
+	k, err := scalar.New().SetRandom(rng)
+	if err != nil {
+		return nil, nil, fmt.Errorf("frost: failed to generate nonce: %w", err)
+	}
+	r := cs.g.basepointMul(k)
+	c := cs.dkgChallenge(id, commitment[0], r)
+	z := scalar.New().Mul(coefficients[0], c)
+	z.Add(z, k)
+
+	secret := &DKGRound1Secret{
+		cs:           cs,
+		id:           id,
+		maxSigners:   maxSigners,
+		minSigners:   minSigners,
+		coefficients: coefficients,
+		commitment:   commitment,
+	}
+	pkg := &DKGRound1Package{
+		cs:         cs,
+		id:         id,
+		commitment: commitment,
+		proofR:     r,
+		proofZ:     z,
+	}
+
+	return secret, pkg, nil
+}
+
+// Part2 processes the first round packages of all other participants,
+// and returns the participant's second round state and packages for
+// each other participant.  The secret coefficients are erased, and
+// subsequent calls will return an error.
+func (secret *DKGRound1Secret) Part2(round1Packages []*DKGRound1Package) (*DKGRound2Secret, []*DKGRound2Package, error) {
+	if secret.coefficients == nil {
+		return nil, nil, fmt.Errorf("frost: DKG round 1 secret already used")
+	}
+	if len(round1Packages) != secret.maxSigners-1 {
+		return nil, nil, fmt.Errorf("frost: incorrect number of DKG round 1 packages")
+	}
+
+	cs := secret.cs
+	commitments := make(map[Identifier][]element)
+	for _, pkg := range round1Packages {
+		switch {
+		case pkg.cs != cs:
+			return nil, nil, fmt.Errorf("frost: ciphersuite mismatch")
+		case pkg.id == secret.id || commitments[pkg.id] != nil:
+			return nil, nil, fmt.Errorf("frost: unexpected DKG round 1 package: %d", pkg.id)
+		case len(pkg.commitment) != secret.minSigners:
+			return nil, nil, fmt.Errorf("frost: invalid VSS commitment: %d", pkg.id)
+		}
+
+		// R == mu * G - c * C_0
+		c := cs.dkgChallenge(pkg.id, pkg.commitment[0], pkg.proofR)
+		if !cs.g.basepointMul(pkg.proofZ).sub(pkg.commitment[0].mul(c)).equal(pkg.proofR) {
+			return nil, nil, fmt.Errorf("frost: invalid proof of knowledge: %d", pkg.id)
+		}
+
+		commitments[pkg.id] = pkg.commitment
+	}
+	commitments[secret.id] = secret.commitment
+
+	round2Packages := make([]*DKGRound2Package, 0, len(round1Packages))
+	for _, pkg := range round1Packages {
+		round2Packages = append(round2Packages, &DKGRound2Package{
+			sender:   secret.id,
+			receiver: pkg.id,
+			share:    polynomialEvaluate(pkg.id.scalar(), secret.coefficients),
+		})
+	}
+
+	round2Secret := &DKGRound2Secret{
+		cs:          cs,
+		id:          secret.id,
+		maxSigners:  secret.maxSigners,
+		minSigners:  secret.minSigners,
+		share:       polynomialEvaluate(secret.id.scalar(), secret.coefficients),
+		commitments: commitments,
+	}
+
+	// Erase the secret coefficients.
+	for _, coeff := range secret.coefficients {
+		coeff.Zero()
+	}
+	secret.coefficients = nil
+
+	return round2Secret, round2Packages, nil
+}
+
+// Part3 processes the second round packages sent to the participant by
+// all other participants, and returns the participant's KeyPackage and
+// the group's PublicKeyPackage.
+func (secret *DKGRound2Secret) Part3(round2Packages []*DKGRound2Package) (*KeyPackage, *PublicKeyPackage, error) {
+	if len(round2Packages) != secret.maxSigners-1 {
+		return nil, nil, fmt.Errorf("frost: incorrect number of DKG round 2 packages")
+	}
+
+	cs := secret.cs
+	seen := make(map[Identifier]bool)
+	signingShare := scalar.New().Set(secret.share)
+	for _, pkg := range round2Packages {
+		commitment := secret.commitments[pkg.sender]
+		if pkg.receiver != secret.id || pkg.sender == secret.id || commitment == nil || seen[pkg.sender] {
+			return nil, nil, fmt.Errorf("frost: unexpected DKG round 2 package: %d", pkg.sender)
+		}
+		seen[pkg.sender] = true
+
+		if err := cs.vssVerify(secret.id, pkg.share, commitment); err != nil {
+			return nil, nil, err
+		}
+		signingShare.Add(signingShare, pkg.share)
+	}
+
+	// The group commitment is the coefficient-wise sum of all the
+	// participants' commitments.
+	groupCommitment := make([]element, 0, secret.minSigners)
+	for i := 0; i < secret.minSigners; i++ {
+		sum := cs.g.identity()
+		for _, commitment := range secret.commitments {
+			sum = sum.add(commitment[i])
+		}
+		groupCommitment = append(groupCommitment, sum)
+	}
+	if groupCommitment[0].isIdentity() {
+		return nil, nil, fmt.Errorf("frost: group public key is the identity")
+	}
+
+	pkp := &PublicKeyPackage{
+		cs:              cs,
+		groupPublicKey:  groupCommitment[0],
+		verifyingShares: make(map[Identifier]element),
+	}
+	for id := range secret.commitments {
+		pkp.verifyingShares[id] = cs.vssEvaluate(id, groupCommitment)
+	}
+
+	kp := &KeyPackage{
+		cs:             cs,
+		id:             secret.id,
+		minSigners:     secret.minSigners,
+		signingShare:   signingShare,
+		verifyingShare: cs.g.basepointMul(signingShare),
+		groupPublicKey: groupCommitment[0],
+	}
+	if !kp.verifyingShare.equal(pkp.verifyingShares[secret.id]) {
+		return nil, nil, fmt.Errorf("frost: verifying share mismatch")
+	}
+
+	return kp, pkp, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+func TestDKG(t *testing.T) {
+	t.Run("Ed25519", func(t *testing.T) {
+		testDKG(t, Ed25519SHA512)
+	})
+	t.Run("Ristretto255", func(t *testing.T) {
+		testDKG(t, Ristretto255SHA512)
+	})
+}
+
+func testDKG(t *testing.T, cs *Ciphersuite) {
+	const (
+		maxSigners = 4
+		minSigners = 3
+	)
+
+	// Round 1.
+	var (
+		round1Secrets  []*DKGRound1Secret
+		round1Packages []*DKGRound1Package
+	)
+	for i := 1; i <= maxSigners; i++ {
+		secret, pkg, err := cs.DKGPart1(nil, Identifier(i), maxSigners, minSigners)
+		if err != nil {
+			t.Fatalf("DKGPart1: %v", err)
+		}
+		pkg, err = cs.NewDKGRound1Package(pkg.Identifier(), mustMarshal(t, pkg))
+		if err != nil {
+			t.Fatalf("NewDKGRound1Package: %v", err)
+		}
+		round1Secrets = append(round1Secrets, secret)
+		round1Packages = append(round1Packages, pkg)
+	}
+
+	othersRound1 := func(i int) []*DKGRound1Package {
+		var pkgs []*DKGRound1Package
+		for j, pkg := range round1Packages {
+			if j != i {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+		return pkgs
+	}
+
+	// Ensure that an invalid proof of knowledge is rejected.
+	badPkgs := othersRound1(0)
+	badPkg := *badPkgs[0]
+	badPkg.proofZ = scalar.New().Add(badPkg.proofZ, scalar.One())
+	badPkgs[0] = &badPkg
+	if _, _, err := round1Secrets[0].Part2(badPkgs); err == nil {
+		t.Fatalf("Part2: accepted invalid proof of knowledge")
+	}
+
+	// Round 2.
+	var (
+		round2Secrets  []*DKGRound2Secret
+		round2Packages = make(map[Identifier][]*DKGRound2Package)
+	)
+	for i, secret := range round1Secrets {
+		round2Secret, pkgs, err := secret.Part2(othersRound1(i))
+		if err != nil {
+			t.Fatalf("Part2: %v", err)
+		}
+		if _, _, err = secret.Part2(othersRound1(i)); err == nil {
+			t.Fatalf("Part2: allowed reuse")
+		}
+		round2Secrets = append(round2Secrets, round2Secret)
+		for _, pkg := range pkgs {
+			pkg, err = cs.NewDKGRound2Package(pkg.Sender(), pkg.Receiver(), mustMarshal(t, pkg))
+			if err != nil {
+				t.Fatalf("NewDKGRound2Package: %v", err)
+			}
+			round2Packages[pkg.Receiver()] = append(round2Packages[pkg.Receiver()], pkg)
+		}
+	}
+
+	// Round 3.
+	var (
+		keyPackages []*KeyPackage
+		pkp         *PublicKeyPackage
+	)
+	for i, secret := range round2Secrets {
+		kp, pkp2, err := secret.Part3(round2Packages[Identifier(i+1)])
+		if err != nil {
+			t.Fatalf("Part3: %v", err)
+		}
+		if pkp != nil {
+			for _, id := range pkp.Identifiers() {
+				if string(pkp.VerifyingShare(id)) != string(pkp2.VerifyingShare(id)) {
+					t.Fatalf("verifying share mismatch")
+				}
+			}
+			if string(pkp.GroupPublicKey()) != string(pkp2.GroupPublicKey()) {
+				t.Fatalf("group public key mismatch")
+			}
+		}
+		pkp = pkp2
+		keyPackages = append(keyPackages, kp)
+	}
+
+	testSign(t, cs, keyPackages, pkp)
+}
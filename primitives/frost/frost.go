@@ -0,0 +1,617 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package frost implements the "Two-Round Threshold Schnorr Signatures
+// with FROST" protocol as specified in RFC 9591.
+package frost
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/nocopy"
+)
+
+const (
+	// SigningCommitmentSize is the size of a serialized SigningCommitment
+	// in bytes, excluding the identifier.
+	SigningCommitmentSize = 2 * elementSize
+
+	// SignatureShareSize is the size of a serialized SignatureShare in
+	// bytes, excluding the identifier.
+	SignatureShareSize = scalar.ScalarSize
+
+	// SignatureSize is the size of a signature in bytes.
+	SignatureSize = elementSize + scalar.ScalarSize
+
+	nonceRandomnessSize = 32
+)
+
+// Ed25519SHA512 is the FROST(Ed25519, SHA-512) ciphersuite, which
+// produces signatures that can be verified with RFC 8032 Ed25519.
+var Ed25519SHA512 = &Ciphersuite{
+	name:          "FROST(Ed25519, SHA-512)",
+	contextString: "FROST-ED25519-SHA512-v1",
+	g:             edwards25519Group{},
+}
+
+// Ristretto255SHA512 is the FROST(ristretto255, SHA-512) ciphersuite.
+var Ristretto255SHA512 = &Ciphersuite{
+	name:          "FROST(ristretto255, SHA-512)",
+	contextString: "FROST-RISTRETTO255-SHA512-v1",
+	g:             ristretto255Group{},
+}
+
+// Ciphersuite is a FROST ciphersuite.
+type Ciphersuite struct {
+	name          string
+	contextString string
+	g             group
+}
+
+// Name returns the name of the ciphersuite.
+func (cs *Ciphersuite) Name() string {
+	return cs.name
+}
+
+// String returns the string representation of the ciphersuite.
+func (cs *Ciphersuite) String() string {
+	return cs.name
+}
+
+func (cs *Ciphersuite) h1(m ...[]byte) *scalar.Scalar {
+	return hashToScalar(cs.contextString, "rho", m...)
+}
+
+func (cs *Ciphersuite) h2(m ...[]byte) *scalar.Scalar {
+	return cs.g.h2(cs.contextString, m...)
+}
+
+func (cs *Ciphersuite) h3(m ...[]byte) *scalar.Scalar {
+	return hashToScalar(cs.contextString, "nonce", m...)
+}
+
+func (cs *Ciphersuite) h4(m ...[]byte) []byte {
+	return hashToBytes(cs.contextString, "msg", m...)
+}
+
+func (cs *Ciphersuite) h5(m ...[]byte) []byte {
+	return hashToBytes(cs.contextString, "com", m...)
+}
+
+func (cs *Ciphersuite) hdkg(m ...[]byte) *scalar.Scalar {
+	return hashToScalar(cs.contextString, "dkg", m...)
+}
+
+// Verify verifies a signature produced by the FROST protocol, with the
+// group public key.
+func (cs *Ciphersuite) Verify(groupPublicKey, message, signature []byte) bool {
+	pk, err := cs.g.deserializeElement(groupPublicKey)
+	if err != nil {
+		return false
+	}
+	return cs.verify(pk, message, signature)
+}
+
+func (cs *Ciphersuite) verify(pk element, message, signature []byte) bool {
+	if len(signature) != SignatureSize {
+		return false
+	}
+	r, err := cs.g.deserializeElement(signature[:elementSize])
+	if err != nil {
+		return false
+	}
+	z, err := scalar.NewFromCanonicalBytes(signature[elementSize:])
+	if err != nil {
+		return false
+	}
+
+	c := cs.computeChallenge(r, pk, message)
+	return cs.g.verifySignature(z, r, pk, c)
+}
+
+func (cs *Ciphersuite) deserializeScalar(b []byte) (*scalar.Scalar, error) {
+	if l := len(b); l != scalar.ScalarSize {
+		return nil, fmt.Errorf("frost: bad scalar size: %v", l)
+	}
+	s, err := scalar.NewFromCanonicalBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize scalar: %w", err)
+	}
+	return s, nil
+}
+
+func (cs *Ciphersuite) nonceGenerate(rng io.Reader, secret *scalar.Scalar) (*scalar.Scalar, error) {
+	// random_bytes = random_bytes(32)
+	// secret_enc = G.SerializeScalar(secret)
+	// return H3(random_bytes || secret_enc)
+	var randomBytes [nonceRandomnessSize]byte
+	if _, err := io.ReadFull(rng, randomBytes[:]); err != nil {
+		return nil, fmt.Errorf("frost: failed to read entropy: %w", err)
+	}
+	secretEnc, err := secret.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to serialize secret: %w", err)
+	}
+
+	return cs.h3(randomBytes[:], secretEnc), nil
+}
+
+func (cs *Ciphersuite) computeChallenge(groupCommitment, groupPublicKey element, message []byte) *scalar.Scalar {
+	return cs.h2(groupCommitment.bytes(), groupPublicKey.bytes(), message)
+}
+
+// Identifier is a participant identifier, which MUST be non-zero.
+type Identifier uint16
+
+func (id Identifier) scalar() *scalar.Scalar {
+	return scalar.NewFromUint64(uint64(id))
+}
+
+func (id Identifier) bytes() []byte {
+	b, _ := id.scalar().MarshalBinary()
+	return b
+}
+
+func deriveInterpolatingValue(participants []Identifier, id Identifier) (*scalar.Scalar, error) {
+	var (
+		found       bool
+		numerator   = scalar.One()
+		denominator = scalar.One()
+		tmp         scalar.Scalar
+	)
+	xi := id.scalar()
+	for _, v := range participants {
+		if v == id {
+			if found {
+				return nil, fmt.Errorf("frost: duplicate identifier: %d", id)
+			}
+			found = true
+			continue
+		}
+		xj := v.scalar()
+		numerator.Mul(numerator, xj)
+		denominator.Mul(denominator, tmp.Sub(xj, xi))
+	}
+	if !found {
+		return nil, fmt.Errorf("frost: identifier not in participant list: %d", id)
+	}
+
+	return numerator.Mul(numerator, denominator.Invert(denominator)), nil
+}
+
+// SigningNonces is a participant's secret nonce pair for a single
+// signing operation.  The nonces are erased once used to produce a
+// SignatureShare, and MUST NOT be reused.
+//
+// SigningNonces must not be copied.
+type SigningNonces struct {
+	_ nocopy.NoCopy
+
+	secrets *secretNonces
+
+	commitment *SigningCommitment
+}
+
+// secretNonces is the secret nonce pair of a SigningNonces.  It is
+// shared by reference, so that the nonces are consumed at most once,
+// even by copies of the SigningNonces.
+type secretNonces struct {
+	sync.Mutex
+
+	hiding  *scalar.Scalar
+	binding *scalar.Scalar
+}
+
+// take removes the secret nonces, so that no other caller can use them.
+func (nonces *secretNonces) take() (*scalar.Scalar, *scalar.Scalar, error) {
+	nonces.Lock()
+	hiding, binding := nonces.hiding, nonces.binding
+	nonces.hiding, nonces.binding = nil, nil
+	nonces.Unlock()
+
+	if hiding == nil || binding == nil {
+		return nil, nil, fmt.Errorf("frost: nonces already used")
+	}
+	zero := scalar.New()
+	if hiding.Equal(zero)|binding.Equal(zero) == 1 {
+		return nil, nil, fmt.Errorf("frost: invalid nonces")
+	}
+	return hiding, binding, nil
+}
+
+// Commitment returns the SigningCommitment corresponding to the nonces.
+func (nonces *SigningNonces) Commitment() *SigningCommitment {
+	return nonces.commitment
+}
+
+// SigningCommitment is a participant's public commitment to a
+// SigningNonces pair.
+type SigningCommitment struct {
+	cs *Ciphersuite
+	id Identifier
+
+	hiding  element
+	binding element
+}
+
+// Identifier returns the identifier of the participant that produced
+// the commitment.
+func (c *SigningCommitment) Identifier() Identifier {
+	return c.id
+}
+
+// MarshalBinary encodes a SigningCommitment into binary form, excluding
+// the identifier.
+func (c *SigningCommitment) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, SigningCommitmentSize)
+	b = append(b, c.hiding.bytes()...)
+	b = append(b, c.binding.bytes()...)
+	return b, nil
+}
+
+func (c *SigningCommitment) equal(other *SigningCommitment) bool {
+	return c.cs == other.cs && c.id == other.id && c.hiding.equal(other.hiding) && c.binding.equal(other.binding)
+}
+
+// NewSigningCommitment constructs a SigningCommitment from the
+// participant identifier and byte representation.
+func (cs *Ciphersuite) NewSigningCommitment(id Identifier, b []byte) (*SigningCommitment, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("frost: invalid identifier")
+	}
+	if l := len(b); l != SigningCommitmentSize {
+		return nil, fmt.Errorf("frost: bad SigningCommitment size: %v", l)
+	}
+
+	hiding, err := cs.g.deserializeElement(b[:elementSize])
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize hiding commitment: %w", err)
+	}
+	binding, err := cs.g.deserializeElement(b[elementSize:])
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize binding commitment: %w", err)
+	}
+
+	return &SigningCommitment{
+		cs:      cs,
+		id:      id,
+		hiding:  hiding,
+		binding: binding,
+	}, nil
+}
+
+// SigningPackage is the set of signing commitments and the message, that
+// is sent by the Coordinator to each participant in the second round.
+type SigningPackage struct {
+	cs          *Ciphersuite
+	commitments []*SigningCommitment
+	message     []byte
+}
+
+// signingState is the binding factors and group commitment derived from
+// a SigningPackage and a group public key.
+type signingState struct {
+	bindingFactors  map[Identifier]*scalar.Scalar
+	groupCommitment element
+}
+
+// Message returns the message to be signed.
+func (pkg *SigningPackage) Message() []byte {
+	return append([]byte{}, pkg.message...)
+}
+
+// Commitments returns the signing commitments, sorted by identifier.
+func (pkg *SigningPackage) Commitments() []*SigningCommitment {
+	return append([]*SigningCommitment{}, pkg.commitments...)
+}
+
+func (pkg *SigningPackage) participants() []Identifier {
+	ids := make([]Identifier, 0, len(pkg.commitments))
+	for _, c := range pkg.commitments {
+		ids = append(ids, c.id)
+	}
+	return ids
+}
+
+func (pkg *SigningPackage) commitment(id Identifier) *SigningCommitment {
+	for _, c := range pkg.commitments {
+		if c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (pkg *SigningPackage) prepare(groupPublicKey element) *signingState {
+	// This is recomputed on every call, rather than cached in the
+	// package, as the package may be shared between goroutines, and
+	// the result depends on the caller's group public key.
+
+	// compute_binding_factors(group_public_key, commitment_list, msg)
+	var encodedCommitments []byte
+	for _, c := range pkg.commitments {
+		encodedCommitments = append(encodedCommitments, c.id.bytes()...)
+		encodedCommitments = append(encodedCommitments, c.hiding.bytes()...)
+		encodedCommitments = append(encodedCommitments, c.binding.bytes()...)
+	}
+	rhoInputPrefix := make([]byte, 0, elementSize+2*64)
+	rhoInputPrefix = append(rhoInputPrefix, groupPublicKey.bytes()...)
+	rhoInputPrefix = append(rhoInputPrefix, pkg.cs.h4(pkg.message)...)
+	rhoInputPrefix = append(rhoInputPrefix, pkg.cs.h5(encodedCommitments)...)
+
+	bindingFactors := make(map[Identifier]*scalar.Scalar)
+	for _, c := range pkg.commitments {
+		bindingFactors[c.id] = pkg.cs.h1(rhoInputPrefix, c.id.bytes())
+	}
+
+	// compute_group_commitment(commitment_list, binding_factor_list)
+	var (
+		scalars  = make([]*scalar.Scalar, 0, len(pkg.commitments))
+		elements = make([]element, 0, len(pkg.commitments))
+	)
+	groupCommitment := pkg.cs.g.identity()
+	for _, c := range pkg.commitments {
+		groupCommitment = groupCommitment.add(c.hiding)
+		scalars = append(scalars, bindingFactors[c.id])
+		elements = append(elements, c.binding)
+	}
+
+	return &signingState{
+		bindingFactors:  bindingFactors,
+		groupCommitment: groupCommitment.add(pkg.cs.g.multiscalarMulVartime(scalars, elements)),
+	}
+}
+
+// NewSigningPackage constructs a SigningPackage from the participants'
+// signing commitments and the message.
+func NewSigningPackage(commitments []*SigningCommitment, message []byte) (*SigningPackage, error) {
+	if len(commitments) == 0 {
+		return nil, fmt.Errorf("frost: no signing commitments")
+	}
+
+	cs := commitments[0].cs
+	sorted := append([]*SigningCommitment{}, commitments...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].id < sorted[j].id
+	})
+	for i, c := range sorted {
+		if c.cs != cs {
+			return nil, fmt.Errorf("frost: ciphersuite mismatch")
+		}
+		if i > 0 && sorted[i-1].id == c.id {
+			return nil, fmt.Errorf("frost: duplicate identifier: %d", c.id)
+		}
+	}
+
+	return &SigningPackage{
+		cs:          cs,
+		commitments: sorted,
+		message:     append([]byte{}, message...),
+	}, nil
+}
+
+// SignatureShare is a participant's signature share.
+type SignatureShare struct {
+	id Identifier
+	z  *scalar.Scalar
+}
+
+// Identifier returns the identifier of the participant that produced
+// the signature share.
+func (share *SignatureShare) Identifier() Identifier {
+	return share.id
+}
+
+// MarshalBinary encodes a SignatureShare into binary form, excluding the
+// identifier.
+func (share *SignatureShare) MarshalBinary() ([]byte, error) {
+	return share.z.MarshalBinary()
+}
+
+// NewSignatureShare constructs a SignatureShare from the participant
+// identifier and byte representation.
+func (cs *Ciphersuite) NewSignatureShare(id Identifier, b []byte) (*SignatureShare, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("frost: invalid identifier")
+	}
+	z, err := cs.deserializeScalar(b)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureShare{
+		id: id,
+		z:  z,
+	}, nil
+}
+
+// Commit generates a SigningNonces pair and the corresponding
+// SigningCommitment, using entropy from rng.  If rng is nil,
+// crypto/rand.Reader will be used.
+func (kp *KeyPackage) Commit(rng io.Reader) (*SigningNonces, error) {
+	if rng == nil {
+		rng = cryptorand.Reader
+	}
+
+	hidingNonce, err := kp.cs.nonceGenerate(rng, kp.signingShare)
+	if err != nil {
+		return nil, err
+	}
+	bindingNonce, err := kp.cs.nonceGenerate(rng, kp.signingShare)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningNonces{
+		secrets: &secretNonces{
+			hiding:  hidingNonce,
+			binding: bindingNonce,
+		},
+		commitment: &SigningCommitment{
+			cs:      kp.cs,
+			id:      kp.id,
+			hiding:  kp.cs.g.basepointMul(hidingNonce),
+			binding: kp.cs.g.basepointMul(bindingNonce),
+		},
+	}, nil
+}
+
+// Sign produces the participant's SignatureShare over the
+// SigningPackage.  The nonces are erased, and subsequent calls with the
+// same nonces will return an error.
+func (kp *KeyPackage) Sign(nonces *SigningNonces, pkg *SigningPackage) (*SignatureShare, error) {
+	if pkg.cs != kp.cs {
+		return nil, fmt.Errorf("frost: ciphersuite mismatch")
+	}
+	if len(pkg.commitments) < kp.minSigners {
+		return nil, fmt.Errorf("frost: insufficient signing commitments")
+	}
+	if c := pkg.commitment(kp.id); c == nil || !c.equal(nonces.commitment) {
+		return nil, fmt.Errorf("frost: signing commitment mismatch")
+	}
+
+	state := pkg.prepare(kp.groupPublicKey)
+	lambda, err := deriveInterpolatingValue(pkg.participants(), kp.id)
+	if err != nil {
+		return nil, err
+	}
+	challenge := kp.cs.computeChallenge(state.groupCommitment, kp.groupPublicKey, pkg.message)
+
+	// Consume the nonces before using them, so that concurrent or
+	// repeated calls can not sign with the same nonces twice.
+	hiding, binding, err := nonces.secrets.take()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		hiding.Zero()
+		binding.Zero()
+	}()
+
+	// sig_share = hiding_nonce + (binding_nonce * binding_factor) +
+	//             (lambda_i * sk_i * challenge)
+	var tmp scalar.Scalar
+	z := scalar.New().Mul(lambda, kp.signingShare)
+	z.Mul(z, challenge)
+	z.Add(z, tmp.Mul(binding, state.bindingFactors[kp.id]))
+	z.Add(z, hiding)
+
+	return &SignatureShare{
+		id: kp.id,
+		z:  z,
+	}, nil
+}
+
+// VerifySignatureShare verifies a participant's SignatureShare over the
+// SigningPackage.
+func (pkp *PublicKeyPackage) VerifySignatureShare(pkg *SigningPackage, share *SignatureShare) error {
+	if pkg.cs != pkp.cs {
+		return fmt.Errorf("frost: ciphersuite mismatch")
+	}
+	pkI, ok := pkp.verifyingShares[share.id]
+	if !ok {
+		return fmt.Errorf("frost: unknown participant: %d", share.id)
+	}
+	commitment := pkg.commitment(share.id)
+	if commitment == nil {
+		return fmt.Errorf("frost: no signing commitment for participant: %d", share.id)
+	}
+
+	state := pkg.prepare(pkp.groupPublicKey)
+	lambda, err := deriveInterpolatingValue(pkg.participants(), share.id)
+	if err != nil {
+		return err
+	}
+	challenge := pkp.cs.computeChallenge(state.groupCommitment, pkp.groupPublicKey, pkg.message)
+
+	// comm_share = hiding_nonce_commitment + binding_nonce_commitment * binding_factor
+	// l = G.ScalarBaseMult(sig_share_i)
+	// r = comm_share + G.ScalarMult(PK_i, challenge * lambda_i)
+	// return l == r
+	var cl scalar.Scalar
+	cl.Mul(challenge, lambda)
+	r := commitment.hiding.add(pkp.cs.g.multiscalarMulVartime(
+		[]*scalar.Scalar{state.bindingFactors[share.id], &cl},
+		[]element{commitment.binding, pkI},
+	))
+	if !pkp.cs.g.basepointMul(share.z).equal(r) {
+		return fmt.Errorf("frost: invalid signature share: %d", share.id)
+	}
+
+	return nil
+}
+
+// Aggregate combines the participants' SignatureShares into a signature.
+// If the resulting signature fails to verify, each signature share is
+// verified, and the returned error will identify the misbehaving
+// participants.
+func (pkp *PublicKeyPackage) Aggregate(pkg *SigningPackage, shares []*SignatureShare) ([]byte, error) {
+	if pkg.cs != pkp.cs {
+		return nil, fmt.Errorf("frost: ciphersuite mismatch")
+	}
+	if len(shares) != len(pkg.commitments) {
+		return nil, fmt.Errorf("frost: signature share count mismatch")
+	}
+
+	state := pkg.prepare(pkp.groupPublicKey)
+
+	seen := make(map[Identifier]bool)
+	z := scalar.New()
+	for _, share := range shares {
+		if pkg.commitment(share.id) == nil || seen[share.id] {
+			return nil, fmt.Errorf("frost: unexpected signature share: %d", share.id)
+		}
+		seen[share.id] = true
+		z.Add(z, share.z)
+	}
+
+	zBytes, err := z.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to serialize z: %w", err)
+	}
+	signature := make([]byte, 0, SignatureSize)
+	signature = append(signature, state.groupCommitment.bytes()...)
+	signature = append(signature, zBytes...)
+
+	if !pkp.cs.verify(pkp.groupPublicKey, pkg.message, signature) {
+		var culprits []Identifier
+		for _, share := range shares {
+			if err := pkp.VerifySignatureShare(pkg, share); err != nil {
+				culprits = append(culprits, share.id)
+			}
+		}
+		return nil, fmt.Errorf("frost: invalid signature shares: %v", culprits)
+	}
+
+	return signature, nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	"crypto/sha512"
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+const elementSize = 32
+
+// element is a prime-order group element.
+type element interface {
+	add(other element) element
+	sub(other element) element
+	mul(s *scalar.Scalar) element
+	equal(other element) bool
+	isIdentity() bool
+	bytes() []byte
+}
+
+// group is a prime-order group, along with the ciphersuite specific
+// hash functions.
+type group interface {
+	identity() element
+	basepointMul(s *scalar.Scalar) element
+	multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element
+	deserializeElement(b []byte) (element, error)
+
+	// h2 is the challenge hash function, which differs between
+	// ciphersuites for compatibility with pre-existing signature
+	// schemes.
+	h2(contextString string, m ...[]byte) *scalar.Scalar
+
+	// verifySignature checks the signature equation, which differs
+	// between ciphersuites for compatibility with pre-existing
+	// signature schemes.
+	verifySignature(z *scalar.Scalar, r, pk element, c *scalar.Scalar) bool
+}
+
+func hashToScalar(contextString, tag string, m ...[]byte) *scalar.Scalar {
+	var digest [sha512.Size]byte
+	h := sha512.New()
+	_, _ = h.Write([]byte(contextString))
+	_, _ = h.Write([]byte(tag))
+	for _, v := range m {
+		_, _ = h.Write(v)
+	}
+	h.Sum(digest[:0])
+
+	s, err := scalar.NewFromBytesModOrderWide(digest[:])
+	if err != nil {
+		panic("frost: failed to deserialize hash output: " + err.Error())
+	}
+	return s
+}
+
+func hashToBytes(contextString, tag string, m ...[]byte) []byte {
+	h := sha512.New()
+	_, _ = h.Write([]byte(contextString))
+	_, _ = h.Write([]byte(tag))
+	for _, v := range m {
+		_, _ = h.Write(v)
+	}
+	return h.Sum(nil)
+}
+
+type edwardsElement struct {
+	p curve.EdwardsPoint
+}
+
+func (e *edwardsElement) add(other element) element {
+	var v edwardsElement
+	v.p.Add(&e.p, &other.(*edwardsElement).p)
+	return &v
+}
+
+func (e *edwardsElement) sub(other element) element {
+	var v edwardsElement
+	v.p.Sub(&e.p, &other.(*edwardsElement).p)
+	return &v
+}
+
+func (e *edwardsElement) mul(s *scalar.Scalar) element {
+	var v edwardsElement
+	v.p.Mul(&e.p, s)
+	return &v
+}
+
+func (e *edwardsElement) equal(other element) bool {
+	return e.p.Equal(&other.(*edwardsElement).p) == 1
+}
+
+func (e *edwardsElement) isIdentity() bool {
+	return e.p.IsIdentity()
+}
+
+func (e *edwardsElement) bytes() []byte {
+	var compressed curve.CompressedEdwardsY
+	compressed.SetEdwardsPoint(&e.p)
+	return compressed[:]
+}
+
+type edwards25519Group struct{}
+
+func (g edwards25519Group) identity() element {
+	var v edwardsElement
+	v.p.Identity()
+	return &v
+}
+
+func (g edwards25519Group) basepointMul(s *scalar.Scalar) element {
+	var v edwardsElement
+	v.p.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, s)
+	return &v
+}
+
+func (g edwards25519Group) multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element {
+	points := make([]*curve.EdwardsPoint, 0, len(elements))
+	for _, e := range elements {
+		points = append(points, &e.(*edwardsElement).p)
+	}
+
+	var v edwardsElement
+	v.p.MultiscalarMulVartime(scalars, points)
+	return &v
+}
+
+func (g edwards25519Group) deserializeElement(b []byte) (element, error) {
+	// DeserializeElement(buf): Implemented as specified in [RFC8032],
+	// Section 5.1.3.  Additionally, this function validates that the
+	// resulting element is not the group identity element and is in
+	// the prime-order subgroup.
+	var compressed curve.CompressedEdwardsY
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize element: %w", err)
+	}
+	if !compressed.IsCanonicalVartime() { // Required by RFC 8032 decode semantics.
+		return nil, fmt.Errorf("frost: non-canonical element")
+	}
+
+	var v edwardsElement
+	if _, err := v.p.SetCompressedY(&compressed); err != nil {
+		return nil, fmt.Errorf("frost: failed to decompress element: %w", err)
+	}
+	if v.p.IsIdentity() {
+		return nil, fmt.Errorf("frost: element is the identity")
+	}
+	if !v.p.IsTorsionFree() {
+		return nil, fmt.Errorf("frost: element is not in the prime-order subgroup")
+	}
+
+	return &v, nil
+}
+
+func (g edwards25519Group) h2(contextString string, m ...[]byte) *scalar.Scalar {
+	// H2(m): Implemented by computing H(m), and interpreting the 64-byte
+	// digest as a little-endian integer.  Note that the context string
+	// is omitted, for compatibility with RFC 8032.
+	return hashToScalar("", "", m...)
+}
+
+func (g edwards25519Group) verifySignature(z *scalar.Scalar, r, pk element, c *scalar.Scalar) bool {
+	// [8][z]B == [8]R + [8][c]PK
+	var (
+		negPk curve.EdwardsPoint
+		rDiff curve.EdwardsPoint
+	)
+	negPk.Neg(&pk.(*edwardsElement).p)
+	rDiff.TripleScalarMulBasepointVartime(c, &negPk, z, &r.(*edwardsElement).p)
+	return rDiff.IsSmallOrder()
+}
+
+type ristrettoElement struct {
+	p curve.RistrettoPoint
+}
+
+func (e *ristrettoElement) add(other element) element {
+	var v ristrettoElement
+	v.p.Add(&e.p, &other.(*ristrettoElement).p)
+	return &v
+}
+
+func (e *ristrettoElement) sub(other element) element {
+	var v ristrettoElement
+	v.p.Sub(&e.p, &other.(*ristrettoElement).p)
+	return &v
+}
+
+func (e *ristrettoElement) mul(s *scalar.Scalar) element {
+	var v ristrettoElement
+	v.p.Mul(&e.p, s)
+	return &v
+}
+
+func (e *ristrettoElement) equal(other element) bool {
+	return e.p.Equal(&other.(*ristrettoElement).p) == 1
+}
+
+func (e *ristrettoElement) isIdentity() bool {
+	return e.p.IsIdentity()
+}
+
+func (e *ristrettoElement) bytes() []byte {
+	var compressed curve.CompressedRistretto
+	compressed.SetRistrettoPoint(&e.p)
+	return compressed[:]
+}
+
+type ristretto255Group struct{}
+
+func (g ristretto255Group) identity() element {
+	var v ristrettoElement
+	v.p.Identity()
+	return &v
+}
+
+func (g ristretto255Group) basepointMul(s *scalar.Scalar) element {
+	var v ristrettoElement
+	v.p.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, s)
+	return &v
+}
+
+func (g ristretto255Group) multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element {
+	points := make([]*curve.RistrettoPoint, 0, len(elements))
+	for _, e := range elements {
+		points = append(points, &e.(*ristrettoElement).p)
+	}
+
+	var v ristrettoElement
+	v.p.MultiscalarMulVartime(scalars, points)
+	return &v
+}
+
+func (g ristretto255Group) deserializeElement(b []byte) (element, error) {
+	// DeserializeElement(buf): Implemented using the 'Decode' function
+	// from [RISTRETTO].  Additionally, this function validates that the
+	// resulting element is not the group identity element.
+	var compressed curve.CompressedRistretto
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize element: %w", err)
+	}
+
+	var v ristrettoElement
+	if _, err := v.p.SetCompressed(&compressed); err != nil {
+		return nil, fmt.Errorf("frost: failed to decompress element: %w", err)
+	}
+	if v.p.IsIdentity() {
+		return nil, fmt.Errorf("frost: element is the identity")
+	}
+
+	return &v, nil
+}
+
+func (g ristretto255Group) h2(contextString string, m ...[]byte) *scalar.Scalar {
+	return hashToScalar(contextString, "chal", m...)
+}
+
+func (g ristretto255Group) verifySignature(z *scalar.Scalar, r, pk element, c *scalar.Scalar) bool {
+	// [z]B == R + [c]PK
+	var (
+		negPk curve.RistrettoPoint
+		rDiff curve.RistrettoPoint
+	)
+	negPk.Neg(&pk.(*ristrettoElement).p)
+	return rDiff.TripleScalarMulBasepointVartime(c, &negPk, z, &r.(*ristrettoElement).p).IsIdentity()
+}
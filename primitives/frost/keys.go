@@ -0,0 +1,406 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+const (
+	// KeyPackageSize is the size of a serialized KeyPackage in bytes.
+	KeyPackageSize = 2 + 2 + scalar.ScalarSize + 2*elementSize
+
+	maxParticipants = 1<<16 - 1
+)
+
+// KeyPackage is a participant's long-lived signing key material.
+type KeyPackage struct {
+	cs         *Ciphersuite
+	id         Identifier
+	minSigners int
+
+	signingShare   *scalar.Scalar
+	verifyingShare element
+	groupPublicKey element
+}
+
+// Identifier returns the participant's identifier.
+func (kp *KeyPackage) Identifier() Identifier {
+	return kp.id
+}
+
+// MinSigners returns the minimum number of participants required to
+// produce a signature.
+func (kp *KeyPackage) MinSigners() int {
+	return kp.minSigners
+}
+
+// GroupPublicKey returns the serialized group public key.
+func (kp *KeyPackage) GroupPublicKey() []byte {
+	return kp.groupPublicKey.bytes()
+}
+
+// VerifyingShare returns the participant's serialized public key share.
+func (kp *KeyPackage) VerifyingShare() []byte {
+	return kp.verifyingShare.bytes()
+}
+
+// MarshalBinary encodes a KeyPackage into binary form.
+func (kp *KeyPackage) MarshalBinary() ([]byte, error) {
+	skBytes, err := kp.signingShare.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to serialize signing share: %w", err)
+	}
+
+	b := make([]byte, 4, KeyPackageSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(kp.id))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(kp.minSigners))
+	b = append(b, skBytes...)
+	b = append(b, kp.verifyingShare.bytes()...)
+	b = append(b, kp.groupPublicKey.bytes()...)
+
+	return b, nil
+}
+
+// NewKeyPackageFromBytes constructs a KeyPackage from the byte
+// representation.
+func (cs *Ciphersuite) NewKeyPackageFromBytes(b []byte) (*KeyPackage, error) {
+	if l := len(b); l != KeyPackageSize {
+		return nil, fmt.Errorf("frost: bad KeyPackage size: %v", l)
+	}
+
+	kp := &KeyPackage{
+		cs:         cs,
+		id:         Identifier(binary.LittleEndian.Uint16(b[0:2])),
+		minSigners: int(binary.LittleEndian.Uint16(b[2:4])),
+	}
+	if kp.id == 0 || kp.minSigners == 0 {
+		return nil, fmt.Errorf("frost: invalid KeyPackage parameters")
+	}
+
+	b = b[4:]
+	var err error
+	if kp.signingShare, err = cs.deserializeScalar(b[:scalar.ScalarSize]); err != nil {
+		return nil, err
+	}
+	b = b[scalar.ScalarSize:]
+	if kp.verifyingShare, err = cs.g.deserializeElement(b[:elementSize]); err != nil {
+		return nil, err
+	}
+	if kp.groupPublicKey, err = cs.g.deserializeElement(b[elementSize:]); err != nil {
+		return nil, err
+	}
+	if !cs.g.basepointMul(kp.signingShare).equal(kp.verifyingShare) {
+		return nil, fmt.Errorf("frost: bad KeyPackage, verifying share mismatch")
+	}
+
+	return kp, nil
+}
+
+// PublicKeyPackage is the group's public key material, used to verify
+// signature shares and aggregate signatures.
+type PublicKeyPackage struct {
+	cs              *Ciphersuite
+	groupPublicKey  element
+	verifyingShares map[Identifier]element
+}
+
+// GroupPublicKey returns the serialized group public key.
+func (pkp *PublicKeyPackage) GroupPublicKey() []byte {
+	return pkp.groupPublicKey.bytes()
+}
+
+// VerifyingShare returns the serialized public key share of a
+// participant, or nil if the participant is unknown.
+func (pkp *PublicKeyPackage) VerifyingShare(id Identifier) []byte {
+	pk, ok := pkp.verifyingShares[id]
+	if !ok {
+		return nil
+	}
+	return pk.bytes()
+}
+
+// Identifiers returns the sorted list of participant identifiers.
+func (pkp *PublicKeyPackage) Identifiers() []Identifier {
+	ids := make([]Identifier, 0, len(pkp.verifyingShares))
+	for id := range pkp.verifyingShares {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// NewPublicKeyPackage constructs a PublicKeyPackage from the serialized
+// group public key and participant public key shares.
+func (cs *Ciphersuite) NewPublicKeyPackage(groupPublicKey []byte, verifyingShares map[Identifier][]byte) (*PublicKeyPackage, error) {
+	pkp := &PublicKeyPackage{
+		cs:              cs,
+		verifyingShares: make(map[Identifier]element),
+	}
+
+	var err error
+	if pkp.groupPublicKey, err = cs.g.deserializeElement(groupPublicKey); err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize group public key: %w", err)
+	}
+	for id, b := range verifyingShares {
+		if id == 0 {
+			return nil, fmt.Errorf("frost: invalid identifier")
+		}
+		if pkp.verifyingShares[id], err = cs.g.deserializeElement(b); err != nil {
+			return nil, fmt.Errorf("frost: failed to deserialize verifying share %d: %w", id, err)
+		}
+	}
+
+	return pkp, nil
+}
+
+// SecretShare is a participant's secret share, and the VSS commitment
+// that allows the participant to verify it, as produced by a trusted
+// dealer.
+type SecretShare struct {
+	cs         *Ciphersuite
+	id         Identifier
+	share      *scalar.Scalar
+	commitment []element
+}
+
+// Identifier returns the identifier of the participant that the secret
+// share is for.
+func (ss *SecretShare) Identifier() Identifier {
+	return ss.id
+}
+
+// MarshalBinary encodes a SecretShare into binary form.
+func (ss *SecretShare) MarshalBinary() ([]byte, error) {
+	shareBytes, err := ss.share.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("frost: failed to serialize secret share: %w", err)
+	}
+
+	b := make([]byte, 2, 2+scalar.ScalarSize+len(ss.commitment)*elementSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(ss.id))
+	b = append(b, shareBytes...)
+	for _, c := range ss.commitment {
+		b = append(b, c.bytes()...)
+	}
+
+	return b, nil
+}
+
+// NewSecretShareFromBytes constructs a SecretShare from the byte
+// representation.
+func (cs *Ciphersuite) NewSecretShareFromBytes(b []byte) (*SecretShare, error) {
+	const hdrSize = 2 + scalar.ScalarSize
+	if l := len(b); l < hdrSize+elementSize || (l-hdrSize)%elementSize != 0 {
+		return nil, fmt.Errorf("frost: bad SecretShare size: %v", l)
+	}
+
+	ss := &SecretShare{
+		cs: cs,
+		id: Identifier(binary.LittleEndian.Uint16(b[0:2])),
+	}
+	if ss.id == 0 {
+		return nil, fmt.Errorf("frost: invalid identifier")
+	}
+
+	var err error
+	if ss.share, err = cs.deserializeScalar(b[2:hdrSize]); err != nil {
+		return nil, err
+	}
+	if ss.commitment, err = cs.deserializeElements(b[hdrSize:]); err != nil {
+		return nil, fmt.Errorf("frost: failed to deserialize VSS commitment: %w", err)
+	}
+
+	return ss, nil
+}
+
+// KeyPackage verifies the secret share against the VSS commitment, and
+// returns the participant's KeyPackage on success.
+func (ss *SecretShare) KeyPackage() (*KeyPackage, error) {
+	if err := ss.cs.vssVerify(ss.id, ss.share, ss.commitment); err != nil {
+		return nil, err
+	}
+
+	return &KeyPackage{
+		cs:             ss.cs,
+		id:             ss.id,
+		minSigners:     len(ss.commitment),
+		signingShare:   scalar.New().Set(ss.share),
+		verifyingShare: ss.cs.g.basepointMul(ss.share),
+		groupPublicKey: ss.commitment[0],
+	}, nil
+}
+
+// TrustedDealerKeygen splits the secret into maxSigners shares, such
+// that any minSigners of them can produce a signature, using entropy
+// from rng.  If secret is nil, a random secret will be generated.  If
+// rng is nil, crypto/rand.Reader will be used.
+//
+// The secret shares MUST be distributed to the participants over a
+// confidential and authenticated channel.
+func (cs *Ciphersuite) TrustedDealerKeygen(rng io.Reader, secret *scalar.Scalar, maxSigners, minSigners int) ([]*SecretShare, *PublicKeyPackage, error) {
+	if err := validateParameters(maxSigners, minSigners); err != nil {
+		return nil, nil, err
+	}
+	if rng == nil {
+		rng = cryptorand.Reader
+	}
+
+	coefficients := make([]*scalar.Scalar, 0, minSigners)
+	if secret == nil {
+		s, err := scalar.New().SetRandom(rng)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frost: failed to generate secret: %w", err)
+		}
+		coefficients = append(coefficients, s)
+	} else {
+		coefficients = append(coefficients, scalar.New().Set(secret))
+	}
+	for i := 1; i < minSigners; i++ {
+		s, err := scalar.New().SetRandom(rng)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frost: failed to generate coefficient: %w", err)
+		}
+		coefficients = append(coefficients, s)
+	}
+
+	shares := cs.secretShareShard(coefficients, maxSigners)
+	commitment := cs.vssCommit(coefficients)
+
+	pkp := cs.deriveGroupInfo(maxSigners, commitment)
+	secretShares := make([]*SecretShare, 0, maxSigners)
+	for i, share := range shares {
+		secretShares = append(secretShares, &SecretShare{
+			cs:         cs,
+			id:         Identifier(i + 1),
+			share:      share,
+			commitment: commitment,
+		})
+	}
+
+	return secretShares, pkp, nil
+}
+
+func validateParameters(maxSigners, minSigners int) error {
+	if minSigners < 2 {
+		return fmt.Errorf("frost: minSigners must be at least 2")
+	}
+	if maxSigners < minSigners || maxSigners > maxParticipants {
+		return fmt.Errorf("frost: invalid maxSigners")
+	}
+	return nil
+}
+
+func (cs *Ciphersuite) deserializeElements(b []byte) ([]element, error) {
+	elements := make([]element, 0, len(b)/elementSize)
+	for len(b) > 0 {
+		e, err := cs.g.deserializeElement(b[:elementSize])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, e)
+		b = b[elementSize:]
+	}
+	return elements, nil
+}
+
+func polynomialEvaluate(x *scalar.Scalar, coefficients []*scalar.Scalar) *scalar.Scalar {
+	// Horner's method.
+	value := scalar.New()
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		value.Mul(value, x)
+		value.Add(value, coefficients[i])
+	}
+	return value
+}
+
+func (cs *Ciphersuite) secretShareShard(coefficients []*scalar.Scalar, maxSigners int) []*scalar.Scalar {
+	shares := make([]*scalar.Scalar, 0, maxSigners)
+	for i := 1; i <= maxSigners; i++ {
+		shares = append(shares, polynomialEvaluate(Identifier(i).scalar(), coefficients))
+	}
+	return shares
+}
+
+func (cs *Ciphersuite) vssCommit(coefficients []*scalar.Scalar) []element {
+	commitment := make([]element, 0, len(coefficients))
+	for _, coeff := range coefficients {
+		commitment = append(commitment, cs.g.basepointMul(coeff))
+	}
+	return commitment
+}
+
+func (cs *Ciphersuite) vssEvaluate(id Identifier, commitment []element) element {
+	// S_i' = sum(commitment[j] * i^j)
+	var (
+		x      = id.scalar()
+		xPow   = scalar.One()
+		powers = make([]*scalar.Scalar, 0, len(commitment))
+	)
+	for range commitment {
+		powers = append(powers, scalar.New().Set(xPow))
+		xPow.Mul(xPow, x)
+	}
+	return cs.g.multiscalarMulVartime(powers, commitment)
+}
+
+func (cs *Ciphersuite) vssVerify(id Identifier, share *scalar.Scalar, commitment []element) error {
+	if id == 0 {
+		return fmt.Errorf("frost: invalid identifier")
+	}
+	if len(commitment) < 2 {
+		return fmt.Errorf("frost: invalid VSS commitment")
+	}
+	if !cs.g.basepointMul(share).equal(cs.vssEvaluate(id, commitment)) {
+		return fmt.Errorf("frost: secret share does not match VSS commitment: %d", id)
+	}
+	return nil
+}
+
+func (cs *Ciphersuite) deriveGroupInfo(maxSigners int, commitment []element) *PublicKeyPackage {
+	pkp := &PublicKeyPackage{
+		cs:              cs,
+		groupPublicKey:  commitment[0],
+		verifyingShares: make(map[Identifier]element),
+	}
+	for i := 1; i <= maxSigners; i++ {
+		id := Identifier(i)
+		pkp.verifyingShares[id] = cs.vssEvaluate(id, commitment)
+	}
+	return pkp
+}
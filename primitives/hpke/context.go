@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package hpke
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrOpen is the error returned when decryption fails.
+	ErrOpen = fmt.Errorf("hpke: message authentication failed")
+
+	// ErrMessageLimitReached is the error returned when the sequence
+	// number has been exhausted.
+	ErrMessageLimitReached = fmt.Errorf("hpke: message limit reached")
+
+	errExportOnly = fmt.Errorf("hpke: AEAD is export-only")
+)
+
+type context struct {
+	suite *Suite
+
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+func (ctx *context) computeNonce() []byte {
+	nonce := make([]byte, len(ctx.baseNonce))
+	copy(nonce, ctx.baseNonce)
+
+	seq := ctx.seq
+	for i := len(nonce) - 1; i >= len(nonce)-8; i-- {
+		nonce[i] ^= byte(seq)
+		seq >>= 8
+	}
+
+	return nonce
+}
+
+// Export derives a secret of length l bytes from the context, bound to
+// the exporter context.
+func (ctx *context) Export(exporterContext []byte, l int) ([]byte, error) {
+	h := ctx.suite.kdf.hash()
+	if l < 0 || l > 255*h.Size() {
+		return nil, fmt.Errorf("hpke: invalid export length: %d", l)
+	}
+
+	return labeledExpand(h, ctx.suite.id[:], ctx.exporterSecret, "sec", exporterContext, l), nil
+}
+
+// SequenceNumber returns the sequence number of the next message.
+func (ctx *context) SequenceNumber() uint64 {
+	return ctx.seq
+}
+
+// SenderContext is a HPKE sender context.
+type SenderContext struct {
+	context
+}
+
+// Seal encrypts and authenticates the plaintext, and authenticates the
+// additional data, and returns the ciphertext.
+func (ctx *SenderContext) Seal(aad, pt []byte) ([]byte, error) {
+	if ctx.aead == nil {
+		return nil, errExportOnly
+	}
+	// The sequence number is limited to 2^(8*Nn) - 1 by the RFC, which
+	// is larger than what can be represented by a uint64, so stop short
+	// of wrapping around.
+	if ctx.seq == math.MaxUint64 {
+		return nil, ErrMessageLimitReached
+	}
+
+	ct := ctx.aead.Seal(nil, ctx.computeNonce(), pt, aad)
+	ctx.seq++
+
+	return ct, nil
+}
+
+// ReceiverContext is a HPKE recipient context.
+type ReceiverContext struct {
+	context
+}
+
+// Open authenticates and decrypts the ciphertext, and authenticates the
+// additional data, and returns the plaintext.
+func (ctx *ReceiverContext) Open(aad, ct []byte) ([]byte, error) {
+	if ctx.aead == nil {
+		return nil, errExportOnly
+	}
+	if ctx.seq == math.MaxUint64 {
+		return nil, ErrMessageLimitReached
+	}
+
+	pt, err := ctx.aead.Open(nil, ctx.computeNonce(), ct, aad)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	ctx.seq++
+
+	return pt, nil
+}
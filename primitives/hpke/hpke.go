@@ -0,0 +1,395 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package hpke implements the Hybrid Public Key Encryption scheme as
+// specified in RFC 9180, with the DHKEM(X25519, HKDF-SHA256) KEM.
+package hpke
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+const versionLabel = "HPKE-v1"
+
+var (
+	errInconsistentPSKInputs = fmt.Errorf("hpke: inconsistent PSK inputs")
+	errInvalidPSKMode        = fmt.Errorf("hpke: PSK input provided when not needed")
+	errMissingPSKMode        = fmt.Errorf("hpke: missing required PSK input")
+	errInvalidAuthMode       = fmt.Errorf("hpke: sender key provided when not needed")
+	errMissingAuthMode       = fmt.Errorf("hpke: missing required sender key")
+)
+
+// Mode is a HPKE mode.
+type Mode uint8
+
+const (
+	// ModeBase is the base mode.
+	ModeBase Mode = 0x00
+	// ModePSK is the pre-shared key authenticated mode.
+	ModePSK Mode = 0x01
+	// ModeAuth is the asymmetric key authenticated mode.
+	ModeAuth Mode = 0x02
+	// ModeAuthPSK is the pre-shared key and asymmetric key authenticated
+	// mode.
+	ModeAuthPSK Mode = 0x03
+)
+
+// KDF is a HPKE KDF identifier.
+type KDF uint16
+
+const (
+	// KDFHKDFSHA256 is HKDF-SHA256.
+	KDFHKDFSHA256 KDF = 0x0001
+	// KDFHKDFSHA384 is HKDF-SHA384.
+	KDFHKDFSHA384 KDF = 0x0002
+	// KDFHKDFSHA512 is HKDF-SHA512.
+	KDFHKDFSHA512 KDF = 0x0003
+)
+
+func (kdf KDF) hash() crypto.Hash {
+	switch kdf {
+	case KDFHKDFSHA256:
+		return crypto.SHA256
+	case KDFHKDFSHA384:
+		return crypto.SHA384
+	case KDFHKDFSHA512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+// AEAD is a HPKE AEAD identifier.
+type AEAD uint16
+
+const (
+	// AEADAES128GCM is AES-128-GCM.
+	AEADAES128GCM AEAD = 0x0001
+	// AEADAES256GCM is AES-256-GCM.
+	AEADAES256GCM AEAD = 0x0002
+	// AEADChaCha20Poly1305 is ChaCha20Poly1305.
+	AEADChaCha20Poly1305 AEAD = 0x0003
+	// AEADExportOnly is the "export-only" AEAD, for which only the
+	// secret export interface is available.
+	AEADExportOnly AEAD = 0xffff
+)
+
+func (aead AEAD) keySize() int {
+	switch aead {
+	case AEADAES128GCM:
+		return 16
+	case AEADAES256GCM, AEADChaCha20Poly1305:
+		return 32
+	default:
+		return 0
+	}
+}
+
+func (aead AEAD) nonceSize() int {
+	switch aead {
+	case AEADAES128GCM, AEADAES256GCM, AEADChaCha20Poly1305:
+		return 12
+	default:
+		return 0
+	}
+}
+
+func (aead AEAD) new(key []byte) (cipher.AEAD, error) {
+	switch aead {
+	case AEADAES128GCM, AEADAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AEADChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("hpke: unsupported AEAD: %04x", uint16(aead))
+	}
+}
+
+// Suite is a HPKE ciphersuite, with the DHKEM(X25519, HKDF-SHA256) KEM.
+type Suite struct {
+	kdf  KDF
+	aead AEAD
+
+	id [10]byte
+}
+
+// NewSuite constructs a new HPKE ciphersuite with the DHKEM(X25519,
+// HKDF-SHA256) KEM, and the specified KDF and AEAD.
+func NewSuite(kdf KDF, aead AEAD) (*Suite, error) {
+	if kdf.hash() == 0 {
+		return nil, fmt.Errorf("hpke: unsupported KDF: %04x", uint16(kdf))
+	}
+	if aead != AEADExportOnly && aead.keySize() == 0 {
+		return nil, fmt.Errorf("hpke: unsupported AEAD: %04x", uint16(aead))
+	}
+
+	s := &Suite{
+		kdf:  kdf,
+		aead: aead,
+	}
+	copy(s.id[0:4], "HPKE")
+	binary.BigEndian.PutUint16(s.id[4:6], uint16(KEMX25519HKDFSHA256))
+	binary.BigEndian.PutUint16(s.id[6:8], uint16(kdf))
+	binary.BigEndian.PutUint16(s.id[8:10], uint16(aead))
+
+	return s, nil
+}
+
+// KDF returns the suite's KDF identifier.
+func (s *Suite) KDF() KDF {
+	return s.kdf
+}
+
+// AEAD returns the suite's AEAD identifier.
+func (s *Suite) AEAD() AEAD {
+	return s.aead
+}
+
+// SetupBaseS sets up a base mode sender context, and returns the
+// encapsulated key and the context.  If rand is nil, crypto/rand.Reader
+// will be used.
+func (s *Suite) SetupBaseS(rand io.Reader, pkR *x25519.PublicKey, info []byte) ([]byte, *SenderContext, error) {
+	return s.setupS(rand, ModeBase, pkR, nil, info, nil, nil)
+}
+
+// SetupBaseR sets up a base mode recipient context.
+func (s *Suite) SetupBaseR(enc []byte, skR *x25519.PrivateKey, info []byte) (*ReceiverContext, error) {
+	return s.setupR(ModeBase, enc, skR, nil, info, nil, nil)
+}
+
+// SetupPSKS sets up a PSK mode sender context, and returns the
+// encapsulated key and the context.  If rand is nil, crypto/rand.Reader
+// will be used.
+func (s *Suite) SetupPSKS(rand io.Reader, pkR *x25519.PublicKey, info, psk, pskID []byte) ([]byte, *SenderContext, error) {
+	return s.setupS(rand, ModePSK, pkR, nil, info, psk, pskID)
+}
+
+// SetupPSKR sets up a PSK mode recipient context.
+func (s *Suite) SetupPSKR(enc []byte, skR *x25519.PrivateKey, info, psk, pskID []byte) (*ReceiverContext, error) {
+	return s.setupR(ModePSK, enc, skR, nil, info, psk, pskID)
+}
+
+// SetupAuthS sets up an Auth mode sender context, and returns the
+// encapsulated key and the context.  If rand is nil, crypto/rand.Reader
+// will be used.
+func (s *Suite) SetupAuthS(rand io.Reader, pkR *x25519.PublicKey, info []byte, skS *x25519.PrivateKey) ([]byte, *SenderContext, error) {
+	return s.setupS(rand, ModeAuth, pkR, skS, info, nil, nil)
+}
+
+// SetupAuthR sets up an Auth mode recipient context.
+func (s *Suite) SetupAuthR(enc []byte, skR *x25519.PrivateKey, info []byte, pkS *x25519.PublicKey) (*ReceiverContext, error) {
+	return s.setupR(ModeAuth, enc, skR, pkS, info, nil, nil)
+}
+
+// SetupAuthPSKS sets up an AuthPSK mode sender context, and returns the
+// encapsulated key and the context.  If rand is nil, crypto/rand.Reader
+// will be used.
+func (s *Suite) SetupAuthPSKS(rand io.Reader, pkR *x25519.PublicKey, info, psk, pskID []byte, skS *x25519.PrivateKey) ([]byte, *SenderContext, error) {
+	return s.setupS(rand, ModeAuthPSK, pkR, skS, info, psk, pskID)
+}
+
+// SetupAuthPSKR sets up an AuthPSK mode recipient context.
+func (s *Suite) SetupAuthPSKR(enc []byte, skR *x25519.PrivateKey, info, psk, pskID []byte, pkS *x25519.PublicKey) (*ReceiverContext, error) {
+	return s.setupR(ModeAuthPSK, enc, skR, pkS, info, psk, pskID)
+}
+
+func (s *Suite) setupS(rand io.Reader, mode Mode, pkR *x25519.PublicKey, skS *x25519.PrivateKey, info, psk, pskID []byte) ([]byte, *SenderContext, error) {
+	if err := verifyPSKInputs(mode, psk, pskID); err != nil {
+		return nil, nil, err
+	}
+	if err := verifyAuthInputs(mode, skS != nil); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		sharedSecret, enc []byte
+		err               error
+	)
+	switch mode {
+	case ModeAuth, ModeAuthPSK:
+		sharedSecret, enc, err = AuthEncap(rand, pkR, skS)
+	default:
+		sharedSecret, enc, err = Encap(rand, pkR)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return enc, &SenderContext{*ctx}, nil
+}
+
+func (s *Suite) setupR(mode Mode, enc []byte, skR *x25519.PrivateKey, pkS *x25519.PublicKey, info, psk, pskID []byte) (*ReceiverContext, error) {
+	if err := verifyPSKInputs(mode, psk, pskID); err != nil {
+		return nil, err
+	}
+	if err := verifyAuthInputs(mode, pkS != nil); err != nil {
+		return nil, err
+	}
+
+	var (
+		sharedSecret []byte
+		err          error
+	)
+	switch mode {
+	case ModeAuth, ModeAuthPSK:
+		sharedSecret, err = AuthDecap(enc, skR, pkS)
+	default:
+		sharedSecret, err = Decap(enc, skR)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReceiverContext{*ctx}, nil
+}
+
+func (s *Suite) keySchedule(mode Mode, sharedSecret, info, psk, pskID []byte) (*context, error) {
+	h := s.kdf.hash()
+
+	pskIDHash := labeledExtract(h, s.id[:], nil, "psk_id_hash", pskID)
+	infoHash := labeledExtract(h, s.id[:], nil, "info_hash", info)
+	keyScheduleContext := make([]byte, 0, 1+len(pskIDHash)+len(infoHash))
+	keyScheduleContext = append(keyScheduleContext, byte(mode))
+	keyScheduleContext = append(keyScheduleContext, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+
+	secret := labeledExtract(h, s.id[:], sharedSecret, "secret", psk)
+
+	ctx := &context{
+		suite:          s,
+		exporterSecret: labeledExpand(h, s.id[:], secret, "exp", keyScheduleContext, h.Size()),
+	}
+	if s.aead != AEADExportOnly {
+		key := labeledExpand(h, s.id[:], secret, "key", keyScheduleContext, s.aead.keySize())
+		ctx.baseNonce = labeledExpand(h, s.id[:], secret, "base_nonce", keyScheduleContext, s.aead.nonceSize())
+
+		var err error
+		if ctx.aead, err = s.aead.new(key); err != nil {
+			return nil, fmt.Errorf("hpke: failed to initialize AEAD: %w", err)
+		}
+	}
+
+	return ctx, nil
+}
+
+func verifyPSKInputs(mode Mode, psk, pskID []byte) error {
+	gotPSK, gotPSKID := len(psk) > 0, len(pskID) > 0
+	if gotPSK != gotPSKID {
+		return errInconsistentPSKInputs
+	}
+
+	switch mode {
+	case ModeBase, ModeAuth:
+		if gotPSK {
+			return errInvalidPSKMode
+		}
+	case ModePSK, ModeAuthPSK:
+		if !gotPSK {
+			return errMissingPSKMode
+		}
+	default:
+		return fmt.Errorf("hpke: invalid mode: %02x", uint8(mode))
+	}
+
+	return nil
+}
+
+func verifyAuthInputs(mode Mode, gotKey bool) error {
+	// verifyPSKInputs has already rejected invalid modes.
+	switch mode {
+	case ModeBase, ModePSK:
+		if gotKey {
+			return errInvalidAuthMode
+		}
+	case ModeAuth, ModeAuthPSK:
+		if !gotKey {
+			return errMissingAuthMode
+		}
+	}
+
+	return nil
+}
+
+func labeledExtract(h crypto.Hash, suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := make([]byte, 0, len(versionLabel)+len(suiteID)+len(label)+len(ikm))
+	labeledIKM = append(labeledIKM, versionLabel...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+
+	return hkdf.Extract(h.New, labeledIKM, salt)
+}
+
+func labeledExpand(h crypto.Hash, suiteID, prk []byte, label string, info []byte, l int) []byte {
+	if l > 0xffff {
+		panic("hpke: invalid labeled expand length")
+	}
+
+	labeledInfo := make([]byte, 2, 2+len(versionLabel)+len(suiteID)+len(label)+len(info))
+	binary.BigEndian.PutUint16(labeledInfo, uint16(l))
+	labeledInfo = append(labeledInfo, versionLabel...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+
+	out := make([]byte, l)
+	if _, err := io.ReadFull(hkdf.Expand(h.New, prk, labeledInfo), out); err != nil {
+		panic("hpke: failed to expand: " + err.Error())
+	}
+
+	return out
+}
@@ -0,0 +1,470 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package hpke
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+const (
+	vectorInfo  = "4f6465206f6e2061204772656369616e2055726e"
+	vectorPSK   = "0247fd33b913760fa1fa51e1892d9f307fbe65eb171e8132c2af18555a738b82"
+	vectorPSKID = "456e6e796e20447572696e206172616e204d6f726961"
+	vectorPT    = "4265617574792069732074727574682c20747275746820626561757479"
+)
+
+type vectorTestCase struct {
+	name string
+	mode Mode
+	aead AEAD
+
+	ikmE string
+	ikmR string
+	ikmS string
+
+	enc         string
+	ciphertexts []string
+	exports     []string
+}
+
+var vectorExporterContexts = []string{
+	"",
+	"00",
+	"54657374436f6e74657874",
+}
+
+// Test vectors from RFC 9180 Appendix A.1 and A.2.
+var vectorTestCases = []vectorTestCase{
+	{
+		name: "A.1.1/Base",
+		mode: ModeBase,
+		aead: AEADAES128GCM,
+		ikmE: "7268600d403fce431561aef583ee1613527cff655c1343f29812e66706df3234",
+		ikmR: "6db9df30aa07dd42ee5e8181afdb977e538f5e1fec8a06223f33f7013e525037",
+		enc:  "37fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431",
+		ciphertexts: []string{
+			"f938558b5d72f1a23810b4be2ab4f84331acc02fc97babc53a52ae8218a355a96d8770ac83d07bea87e13c512a",
+		},
+		exports: []string{
+			"3853fe2b4035195a573ffc53856e77058e15d9ea064de3e59f4961d0095250ee",
+			"2e8f0b54673c7029649d4eb9d5e33bf1872cf76d623ff164ac185da9e88c21a5",
+			"e9e43065102c3836401bed8c3c3c75ae46be1639869391d62c61f1ec7af54931",
+		},
+	},
+	{
+		name: "A.1.2/PSK",
+		mode: ModePSK,
+		aead: AEADAES128GCM,
+		ikmE: "78628c354e46f3e169bd231be7b2ff1c77aa302460a26dbfa15515684c00130b",
+		ikmR: "d4a09d09f575fef425905d2ab396c1449141463f698f8efdb7accfaff8995098",
+		enc:  "0ad0950d9fb9588e59690b74f1237ecdf1d775cd60be2eca57af5a4b0471c91b",
+		ciphertexts: []string{
+			"e52c6fed7f758d0cf7145689f21bc1be6ec9ea097fef4e959440012f4feb73fb611b946199e681f4cfc34db8ea",
+		},
+	},
+	{
+		name: "A.1.3/Auth",
+		mode: ModeAuth,
+		aead: AEADAES128GCM,
+		ikmE: "6e6d8f200ea2fb20c30b003a8b4f433d2f4ed4c2658d5bc8ce2fef718059c9f7",
+		ikmR: "f1d4a30a4cef8d6d4e3b016e6fd3799ea057db4f345472ed302a67ce1c20cdec",
+		ikmS: "94b020ce91d73fca4649006c7e7329a67b40c55e9e93cc907d282bbbff386f58",
+		enc:  "23fb952571a14a25e3d678140cd0e5eb47a0961bb18afcf85896e5453c312e76",
+		ciphertexts: []string{
+			"5fd92cc9d46dbf8943e72a07e42f363ed5f721212cd90bcfd072bfd9f44e06b80fd17824947496e21b680c141b",
+		},
+	},
+	{
+		name: "A.1.4/AuthPSK",
+		mode: ModeAuthPSK,
+		aead: AEADAES128GCM,
+		ikmE: "4303619085a20ebcf18edd22782952b8a7161e1dbae6e46e143a52a96127cf84",
+		ikmR: "4b16221f3b269a88e207270b5e1de28cb01f847841b344b8314d6a622fe5ee90",
+		ikmS: "62f77dcf5df0dd7eac54eac9f654f426d4161ec850cc65c54f8b65d2e0b4e345",
+		enc:  "820818d3c23993492cc5623ab437a48a0a7ca3e9639c140fe1e33811eb844b7c",
+		ciphertexts: []string{
+			"a84c64df1e11d8fd11450039d4fe64ff0c8a99fca0bd72c2d4c3e0400bc14a40f27e45e141a24001697737533e",
+		},
+	},
+	{
+		name: "A.2.1/Base",
+		mode: ModeBase,
+		aead: AEADChaCha20Poly1305,
+		ikmE: "909a9b35d3dc4713a5e72a4da274b55d3d3821a37e5d099e74a647db583a904b",
+		ikmR: "1ac01f181fdf9f352797655161c58b75c656a6cc2716dcb66372da835542e1df",
+		enc:  "1afa08d3dec047a643885163f1180476fa7ddb54c6a8029ea33f95796bf2ac4a",
+		ciphertexts: []string{
+			"1c5250d8034ec2b784ba2cfd69dbdb8af406cfe3ff938e131f0def8c8b60b4db21993c62ce81883d2dd1b51a28",
+		},
+		exports: []string{
+			"4bbd6243b8bb54cec311fac9df81841b6fd61f56538a775e7c80a9f40160606e",
+			"8c1df14732580e5501b00f82b10a1647b40713191b7c1240ac80e2b68808ba69",
+			"5acb09211139c43b3090489a9da433e8a30ee7188ba8b0a9a1ccf0c229283e53",
+		},
+	},
+}
+
+func (tc *vectorTestCase) Run(t *testing.T) {
+	suite, err := NewSuite(KDFHKDFSHA256, tc.aead)
+	if err != nil {
+		t.Fatalf("NewSuite: %v", err)
+	}
+
+	info := testhelpers.MustUnhex(t, vectorInfo)
+	var psk, pskID []byte
+	if tc.mode == ModePSK || tc.mode == ModeAuthPSK {
+		psk = testhelpers.MustUnhex(t, vectorPSK)
+		pskID = testhelpers.MustUnhex(t, vectorPSKID)
+	}
+
+	pkR, skR, err := DeriveKeyPair(testhelpers.MustUnhex(t, tc.ikmR))
+	if err != nil {
+		t.Fatalf("DeriveKeyPair(ikmR): %v", err)
+	}
+	var (
+		pkS *x25519.PublicKey
+		skS *x25519.PrivateKey
+	)
+	if tc.ikmS != "" {
+		if pkS, skS, err = DeriveKeyPair(testhelpers.MustUnhex(t, tc.ikmS)); err != nil {
+			t.Fatalf("DeriveKeyPair(ikmS): %v", err)
+		}
+	}
+
+	rng := bytes.NewReader(testhelpers.MustUnhex(t, tc.ikmE))
+
+	var (
+		enc       []byte
+		senderCtx *SenderContext
+		recvCtx   *ReceiverContext
+	)
+	switch tc.mode {
+	case ModeBase:
+		enc, senderCtx, err = suite.SetupBaseS(rng, pkR, info)
+	case ModePSK:
+		enc, senderCtx, err = suite.SetupPSKS(rng, pkR, info, psk, pskID)
+	case ModeAuth:
+		enc, senderCtx, err = suite.SetupAuthS(rng, pkR, info, skS)
+	case ModeAuthPSK:
+		enc, senderCtx, err = suite.SetupAuthPSKS(rng, pkR, info, psk, pskID, skS)
+	}
+	if err != nil {
+		t.Fatalf("SetupS: %v", err)
+	}
+	if expected := testhelpers.MustUnhex(t, tc.enc); !bytes.Equal(enc, expected) {
+		t.Fatalf("enc mismatch (Got: %x)", enc)
+	}
+
+	switch tc.mode {
+	case ModeBase:
+		recvCtx, err = suite.SetupBaseR(enc, skR, info)
+	case ModePSK:
+		recvCtx, err = suite.SetupPSKR(enc, skR, info, psk, pskID)
+	case ModeAuth:
+		recvCtx, err = suite.SetupAuthR(enc, skR, info, pkS)
+	case ModeAuthPSK:
+		recvCtx, err = suite.SetupAuthPSKR(enc, skR, info, psk, pskID, pkS)
+	}
+	if err != nil {
+		t.Fatalf("SetupR: %v", err)
+	}
+
+	pt := testhelpers.MustUnhex(t, vectorPT)
+	for i, v := range tc.ciphertexts {
+		aad := []byte(fmt.Sprintf("Count-%d", i))
+
+		ct, err := senderCtx.Seal(aad, pt)
+		if err != nil {
+			t.Fatalf("Seal(%d): %v", i, err)
+		}
+		if expected := testhelpers.MustUnhex(t, v); !bytes.Equal(ct, expected) {
+			t.Fatalf("ct[%d] mismatch (Got: %x)", i, ct)
+		}
+
+		opened, err := recvCtx.Open(aad, ct)
+		if err != nil {
+			t.Fatalf("Open(%d): %v", i, err)
+		}
+		if !bytes.Equal(opened, pt) {
+			t.Fatalf("pt[%d] mismatch (Got: %x)", i, opened)
+		}
+	}
+
+	for i, v := range tc.exports {
+		exporterContext := testhelpers.MustUnhex(t, vectorExporterContexts[i])
+		expected := testhelpers.MustUnhex(t, v)
+
+		for _, ctx := range []*context{&senderCtx.context, &recvCtx.context} {
+			exported, err := ctx.Export(exporterContext, len(expected))
+			if err != nil {
+				t.Fatalf("Export(%d): %v", i, err)
+			}
+			if !bytes.Equal(exported, expected) {
+				t.Fatalf("export[%d] mismatch (Got: %x)", i, exported)
+			}
+		}
+	}
+}
+
+func TestHPKE(t *testing.T) {
+	t.Run("TestVectors", func(t *testing.T) {
+		for i := range vectorTestCases {
+			tc := vectorTestCases[i]
+			t.Run(tc.name, tc.Run)
+		}
+	})
+	t.Run("RoundTrip", testRoundTrip)
+	t.Run("ExportOnly", testExportOnly)
+	t.Run("PSKInputs", testPSKInputs)
+	t.Run("AuthInputs", testAuthInputs)
+	t.Run("BadInputs", testBadInputs)
+}
+
+func testRoundTrip(t *testing.T) {
+	pkR, skR, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+	pkS, skS, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+	info := []byte("test info")
+
+	for _, kdf := range []KDF{KDFHKDFSHA256, KDFHKDFSHA384, KDFHKDFSHA512} {
+		for _, aead := range []AEAD{AEADAES128GCM, AEADAES256GCM, AEADChaCha20Poly1305} {
+			suite, err := NewSuite(kdf, aead)
+			if err != nil {
+				t.Fatalf("NewSuite(%04x, %04x): %v", kdf, aead, err)
+			}
+
+			enc, senderCtx, err := suite.SetupAuthS(nil, pkR, info, skS)
+			if err != nil {
+				t.Fatalf("SetupAuthS: %v", err)
+			}
+			recvCtx, err := suite.SetupAuthR(enc, skR, info, pkS)
+			if err != nil {
+				t.Fatalf("SetupAuthR: %v", err)
+			}
+
+			for i := 0; i < 10; i++ {
+				pt := []byte(fmt.Sprintf("message %d", i))
+				ct, err := senderCtx.Seal(nil, pt)
+				if err != nil {
+					t.Fatalf("Seal(%d): %v", i, err)
+				}
+				opened, err := recvCtx.Open(nil, ct)
+				if err != nil {
+					t.Fatalf("Open(%d): %v", i, err)
+				}
+				if !bytes.Equal(opened, pt) {
+					t.Fatalf("pt[%d] mismatch", i)
+				}
+			}
+			if senderCtx.SequenceNumber() != 10 || recvCtx.SequenceNumber() != 10 {
+				t.Fatalf("unexpected sequence numbers")
+			}
+
+			// Out of order messages must fail to open.
+			ct1, _ := senderCtx.Seal(nil, []byte("first"))
+			ct2, _ := senderCtx.Seal(nil, []byte("second"))
+			if _, err = recvCtx.Open(nil, ct2); err != ErrOpen {
+				t.Fatalf("Open(out of order): expected ErrOpen, got %v", err)
+			}
+			if _, err = recvCtx.Open(nil, ct1); err != nil {
+				t.Fatalf("Open(ct1): %v", err)
+			}
+
+			// The wrong sender public key must produce a different context.
+			recvCtx, err = suite.SetupAuthR(enc, skR, info, pkR)
+			if err != nil {
+				t.Fatalf("SetupAuthR(pkR): %v", err)
+			}
+			if _, err = recvCtx.Open(nil, ct1); err != ErrOpen {
+				t.Fatalf("Open(wrong sender): expected ErrOpen, got %v", err)
+			}
+		}
+	}
+}
+
+func testExportOnly(t *testing.T) {
+	suite, err := NewSuite(KDFHKDFSHA256, AEADExportOnly)
+	if err != nil {
+		t.Fatalf("NewSuite: %v", err)
+	}
+
+	pkR, skR, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+
+	enc, senderCtx, err := suite.SetupBaseS(nil, pkR, nil)
+	if err != nil {
+		t.Fatalf("SetupBaseS: %v", err)
+	}
+	recvCtx, err := suite.SetupBaseR(enc, skR, nil)
+	if err != nil {
+		t.Fatalf("SetupBaseR: %v", err)
+	}
+
+	if _, err = senderCtx.Seal(nil, []byte("test")); err == nil {
+		t.Fatalf("Seal: expected error for export-only AEAD")
+	}
+	if _, err = recvCtx.Open(nil, []byte("test")); err == nil {
+		t.Fatalf("Open: expected error for export-only AEAD")
+	}
+
+	e1, err := senderCtx.Export([]byte("context"), 64)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	e2, err := recvCtx.Export([]byte("context"), 64)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if !bytes.Equal(e1, e2) {
+		t.Fatalf("exported secrets mismatch")
+	}
+
+	if _, err = senderCtx.Export(nil, 255*32+1); err == nil {
+		t.Fatalf("Export: expected error for oversized length")
+	}
+}
+
+func testPSKInputs(t *testing.T) {
+	suite, err := NewSuite(KDFHKDFSHA256, AEADAES128GCM)
+	if err != nil {
+		t.Fatalf("NewSuite: %v", err)
+	}
+
+	pkR, _, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+
+	psk, pskID := []byte("psk"), []byte("psk id")
+	if _, _, err = suite.SetupPSKS(nil, pkR, nil, psk, nil); err != errInconsistentPSKInputs {
+		t.Fatalf("SetupPSKS(no psk id): expected errInconsistentPSKInputs, got %v", err)
+	}
+	if _, _, err = suite.SetupPSKS(nil, pkR, nil, nil, pskID); err != errInconsistentPSKInputs {
+		t.Fatalf("SetupPSKS(no psk): expected errInconsistentPSKInputs, got %v", err)
+	}
+	if _, _, err = suite.SetupPSKS(nil, pkR, nil, nil, nil); err != errMissingPSKMode {
+		t.Fatalf("SetupPSKS(no psk inputs): expected errMissingPSKMode, got %v", err)
+	}
+	if _, _, err = suite.setupS(nil, ModeBase, pkR, nil, nil, psk, pskID); err != errInvalidPSKMode {
+		t.Fatalf("setupS(ModeBase, psk inputs): expected errInvalidPSKMode, got %v", err)
+	}
+}
+
+func testAuthInputs(t *testing.T) {
+	suite, err := NewSuite(KDFHKDFSHA256, AEADAES128GCM)
+	if err != nil {
+		t.Fatalf("NewSuite: %v", err)
+	}
+
+	pkR, skR, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+	pkS, skS, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+	enc, _, err := suite.SetupBaseS(nil, pkR, nil)
+	if err != nil {
+		t.Fatalf("SetupBaseS: %v", err)
+	}
+
+	// Auth modes must not silently fall back to the unauthenticated KEM.
+	psk, pskID := []byte("psk"), []byte("psk id")
+	if _, _, err = suite.SetupAuthS(nil, pkR, nil, nil); err != errMissingAuthMode {
+		t.Fatalf("SetupAuthS(no skS): expected errMissingAuthMode, got %v", err)
+	}
+	if _, err = suite.SetupAuthR(enc, skR, nil, nil); err != errMissingAuthMode {
+		t.Fatalf("SetupAuthR(no pkS): expected errMissingAuthMode, got %v", err)
+	}
+	if _, _, err = suite.SetupAuthPSKS(nil, pkR, nil, psk, pskID, nil); err != errMissingAuthMode {
+		t.Fatalf("SetupAuthPSKS(no skS): expected errMissingAuthMode, got %v", err)
+	}
+	if _, err = suite.SetupAuthPSKR(enc, skR, nil, psk, pskID, nil); err != errMissingAuthMode {
+		t.Fatalf("SetupAuthPSKR(no pkS): expected errMissingAuthMode, got %v", err)
+	}
+
+	// Non-auth modes must not be given a sender key.
+	if _, _, err = suite.setupS(nil, ModeBase, pkR, skS, nil, nil, nil); err != errInvalidAuthMode {
+		t.Fatalf("setupS(ModeBase, skS): expected errInvalidAuthMode, got %v", err)
+	}
+	if _, err = suite.setupR(ModeBase, enc, skR, pkS, nil, nil, nil); err != errInvalidAuthMode {
+		t.Fatalf("setupR(ModeBase, pkS): expected errInvalidAuthMode, got %v", err)
+	}
+	if _, _, err = suite.setupS(nil, ModePSK, pkR, skS, nil, psk, pskID); err != errInvalidAuthMode {
+		t.Fatalf("setupS(ModePSK, skS): expected errInvalidAuthMode, got %v", err)
+	}
+	if _, err = suite.setupR(ModePSK, enc, skR, pkS, nil, psk, pskID); err != errInvalidAuthMode {
+		t.Fatalf("setupR(ModePSK, pkS): expected errInvalidAuthMode, got %v", err)
+	}
+}
+
+func testBadInputs(t *testing.T) {
+	if _, err := NewSuite(0x0004, AEADAES128GCM); err == nil {
+		t.Fatalf("NewSuite: expected error for unsupported KDF")
+	}
+	if _, err := NewSuite(KDFHKDFSHA256, 0x0004); err == nil {
+		t.Fatalf("NewSuite: expected error for unsupported AEAD")
+	}
+
+	if _, _, err := DeriveKeyPair(make([]byte, 31)); err == nil {
+		t.Fatalf("DeriveKeyPair: expected error for short ikm")
+	}
+
+	_, skR, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+
+	// Low order (all zero) encapsulated key.
+	if _, err = Decap(make([]byte, EncapsulatedKeySize), skR); err == nil {
+		t.Fatalf("Decap: expected error for low order encapsulated key")
+	}
+	// Truncated encapsulated key.
+	if _, err = Decap(make([]byte, EncapsulatedKeySize-1), skR); err == nil {
+		t.Fatalf("Decap: expected error for truncated encapsulated key")
+	}
+	// Low order recipient public key.
+	if _, _, err = Encap(nil, &x25519.PublicKey{}); err == nil {
+		t.Fatalf("Encap: expected error for low order public key")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package hpke
+
+import (
+	"crypto"
+	cryptorand "crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+// KEM is a HPKE KEM identifier.
+type KEM uint16
+
+// KEMX25519HKDFSHA256 is DHKEM(X25519, HKDF-SHA256), the only supported KEM.
+const KEMX25519HKDFSHA256 KEM = 0x0020
+
+const (
+	// EncapsulatedKeySize is the size of an encapsulated key in bytes.
+	EncapsulatedKeySize = x25519.PublicKeySize
+
+	// SharedSecretSize is the size of a KEM shared secret in bytes.
+	SharedSecretSize = 32
+
+	kemHash = crypto.SHA256
+)
+
+var kemSuiteID = []byte{'K', 'E', 'M', 0x00, 0x20}
+
+// DeriveKeyPair deterministically derives a key pair from the input
+// keying material, which must be at least 32 bytes.
+func DeriveKeyPair(ikm []byte) (*x25519.PublicKey, *x25519.PrivateKey, error) {
+	if len(ikm) < x25519.PrivateKeySize {
+		return nil, nil, fmt.Errorf("hpke: insufficient input keying material")
+	}
+
+	dkpPRK := labeledExtract(kemHash, kemSuiteID, nil, "dkp_prk", ikm)
+	sk := labeledExpand(kemHash, kemSuiteID, dkpPRK, "sk", nil, x25519.PrivateKeySize)
+
+	var skR x25519.PrivateKey
+	copy(skR[:], sk)
+
+	return skR.Public(), &skR, nil
+}
+
+// Encap generates an ephemeral key pair using entropy from rand, and
+// returns the shared secret and the encapsulated key for the recipient
+// public key.  If rand is nil, crypto/rand.Reader will be used.
+func Encap(rand io.Reader, pkR *x25519.PublicKey) ([]byte, []byte, error) {
+	pkE, skE, err := generateEphemeral(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dh, err := skE.DiffieHellmanChecked(pkR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+
+	enc := pkE[:]
+	sharedSecret := extractAndExpand(dh[:], enc, pkR[:])
+
+	return sharedSecret, enc, nil
+}
+
+// Decap returns the shared secret corresponding to the encapsulated key,
+// for the recipient private key.
+func Decap(enc []byte, skR *x25519.PrivateKey) ([]byte, error) {
+	pkE, err := deserializePublicKey(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	dh, err := skR.DiffieHellmanChecked(pkE)
+	if err != nil {
+		return nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+
+	return extractAndExpand(dh[:], enc, skR.Public()[:]), nil
+}
+
+// AuthEncap generates an ephemeral key pair using entropy from rand, and
+// returns the shared secret and the encapsulated key for the recipient
+// public key, authenticated by the sender private key.  If rand is nil,
+// crypto/rand.Reader will be used.
+func AuthEncap(rand io.Reader, pkR *x25519.PublicKey, skS *x25519.PrivateKey) ([]byte, []byte, error) {
+	pkE, skE, err := generateEphemeral(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dhE, err := skE.DiffieHellmanChecked(pkR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+	dhS, err := skS.DiffieHellmanChecked(pkR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+
+	enc := pkE[:]
+	dh := append(dhE[:], dhS[:]...)
+	sharedSecret := extractAndExpand(dh, enc, pkR[:], skS.Public()[:])
+
+	return sharedSecret, enc, nil
+}
+
+// AuthDecap returns the shared secret corresponding to the encapsulated
+// key, for the recipient private key, authenticated by the sender public
+// key.
+func AuthDecap(enc []byte, skR *x25519.PrivateKey, pkS *x25519.PublicKey) ([]byte, error) {
+	pkE, err := deserializePublicKey(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	dhE, err := skR.DiffieHellmanChecked(pkE)
+	if err != nil {
+		return nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+	dhS, err := skR.DiffieHellmanChecked(pkS)
+	if err != nil {
+		return nil, fmt.Errorf("hpke: failed DH: %w", err)
+	}
+
+	dh := append(dhE[:], dhS[:]...)
+
+	return extractAndExpand(dh, enc, skR.Public()[:], pkS[:]), nil
+}
+
+func generateEphemeral(rand io.Reader) (*x25519.PublicKey, *x25519.PrivateKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var ikm [x25519.PrivateKeySize]byte
+	if _, err := io.ReadFull(rand, ikm[:]); err != nil {
+		return nil, nil, fmt.Errorf("hpke: failed to read entropy: %w", err)
+	}
+
+	return DeriveKeyPair(ikm[:])
+}
+
+func deserializePublicKey(b []byte) (*x25519.PublicKey, error) {
+	var pk x25519.PublicKey
+	if err := pk.UnmarshalBinary(b); err != nil {
+		return nil, fmt.Errorf("hpke: failed to deserialize public key: %w", err)
+	}
+	return &pk, nil
+}
+
+func extractAndExpand(dh []byte, kemContext ...[]byte) []byte {
+	var ctx []byte
+	for _, v := range kemContext {
+		ctx = append(ctx, v...)
+	}
+
+	eaePRK := labeledExtract(kemHash, kemSuiteID, nil, "eae_prk", dh)
+	return labeledExpand(kemHash, kemSuiteID, eaePRK, "shared_secret", ctx, SharedSecretSize)
+}
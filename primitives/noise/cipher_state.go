@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package noise
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const chachaPolyTagSize = chacha20poly1305.Overhead
+
+// CipherState is a Noise CipherState, used to encrypt and decrypt
+// messages with a symmetric key and nonce.
+type CipherState struct {
+	aead cipher.AEAD
+	n    uint64
+}
+
+func (cs *CipherState) initializeKey(key []byte) {
+	if len(key) != KeySize {
+		panic("noise: invalid cipher key size")
+	}
+
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		panic("noise: failed to initialize ChaChaPoly: " + err.Error())
+	}
+
+	cs.aead = aead
+	cs.n = 0
+}
+
+// HasKey returns true iff the CipherState has been initialized with a key.
+func (cs *CipherState) HasKey() bool {
+	return cs.aead != nil
+}
+
+// Nonce returns the current nonce.
+func (cs *CipherState) Nonce() uint64 {
+	return cs.n
+}
+
+// SetNonce sets the nonce.  This is intended for use with out-of-order
+// transport messages, and care MUST be taken to never reuse a nonce.
+func (cs *CipherState) SetNonce(n uint64) {
+	cs.n = n
+}
+
+// Encrypt appends the encryption of the plaintext, authenticated with
+// the associated data, to out, and returns the resulting slice.  If the
+// CipherState does not have a key, the plaintext is appended as is.
+func (cs *CipherState) Encrypt(out, ad, plaintext []byte) ([]byte, error) {
+	if cs.aead == nil {
+		return append(out, plaintext...), nil
+	}
+	if cs.n == math.MaxUint64 {
+		return nil, ErrNonceExhausted
+	}
+
+	out = cs.aead.Seal(out, cs.nonce(cs.n), plaintext, ad)
+	cs.n++
+
+	return out, nil
+}
+
+// Decrypt appends the decryption of the ciphertext, authenticated with
+// the associated data, to out, and returns the resulting slice.  If the
+// CipherState does not have a key, the ciphertext is appended as is.
+func (cs *CipherState) Decrypt(out, ad, ciphertext []byte) ([]byte, error) {
+	if cs.aead == nil {
+		return append(out, ciphertext...), nil
+	}
+	if cs.n == math.MaxUint64 {
+		return nil, ErrNonceExhausted
+	}
+
+	out, err := cs.aead.Open(out, cs.nonce(cs.n), ciphertext, ad)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	cs.n++
+
+	return out, nil
+}
+
+// Rekey updates the CipherState's key via a one-way function, as per
+// Section 11.3 of the specification.  The nonce is left unaltered.
+func (cs *CipherState) Rekey() {
+	if cs.aead == nil {
+		return
+	}
+
+	var zeros [KeySize]byte
+	newKey := cs.aead.Seal(nil, cs.nonce(math.MaxUint64), zeros[:], nil)
+
+	n := cs.n
+	cs.initializeKey(newKey[:KeySize])
+	cs.n = n
+}
+
+func (cs *CipherState) nonce(n uint64) []byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], n)
+	return nonce[:]
+}
@@ -0,0 +1,504 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package noise
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+var (
+	errHandshakeComplete   = fmt.Errorf("noise: handshake already complete")
+	errHandshakeIncomplete = fmt.Errorf("noise: handshake not complete")
+	errHandshakeFailed     = fmt.Errorf("noise: handshake previously failed")
+	errNotOurTurn          = fmt.Errorf("noise: out of order handshake message")
+)
+
+// Config is the configuration for a HandshakeState.
+type Config struct {
+	// Pattern is the handshake pattern.
+	Pattern *HandshakePattern
+
+	// Cipher is the cipher function.
+	Cipher Cipher
+
+	// Hash is the hash function.
+	Hash Hash
+
+	// Initiator is true iff the HandshakeState is for the initiator.
+	Initiator bool
+
+	// Prologue is the optional prologue.
+	Prologue []byte
+
+	// LocalStatic is the local static private key, if required by
+	// the handshake pattern.
+	LocalStatic *x25519.PrivateKey
+
+	// RemoteStatic is the remote static public key, if known in
+	// advance as required by the handshake pattern.
+	RemoteStatic *x25519.PublicKey
+
+	// PresharedKey is the optional pre-shared symmetric key.  If set,
+	// the "psk" modifier will be applied to the handshake pattern.
+	PresharedKey []byte
+
+	// PresharedKeyPlacement is the position of the "psk" token, where
+	// 0 places it at the start of the first handshake message, and
+	// n > 0 places it at the end of the n-th handshake message.
+	PresharedKeyPlacement int
+
+	// Rand is the entropy source used to generate ephemeral keys.
+	// If nil, crypto/rand.Reader will be used.
+	Rand io.Reader
+}
+
+func (cfg *Config) protocolName() string {
+	var pskModifier string
+	if cfg.PresharedKey != nil {
+		pskModifier = fmt.Sprintf("psk%d", cfg.PresharedKeyPlacement)
+	}
+
+	return "Noise_" + cfg.Pattern.name + pskModifier + "_" + dhName + "_" + string(cfg.Cipher) + "_" + string(cfg.Hash)
+}
+
+type symmetricState struct {
+	cs CipherState
+
+	newFn func() hash.Hash
+	ck    []byte
+	h     []byte
+}
+
+func (ss *symmetricState) initialize(newFn func() hash.Hash, protocolName string) {
+	ss.newFn = newFn
+
+	hashLen := newFn().Size()
+	switch len(protocolName) <= hashLen {
+	case true:
+		ss.h = make([]byte, hashLen)
+		copy(ss.h, protocolName)
+	case false:
+		h := newFn()
+		_, _ = h.Write([]byte(protocolName))
+		ss.h = h.Sum(nil)
+	}
+	ss.ck = append([]byte{}, ss.h...)
+}
+
+func (ss *symmetricState) mixKey(ikm []byte) {
+	outputs := hkdf(ss.newFn, ss.ck, ikm, 2)
+	ss.ck = outputs[0]
+	ss.cs.initializeKey(outputs[1][:KeySize])
+}
+
+func (ss *symmetricState) mixHash(data []byte) {
+	h := ss.newFn()
+	_, _ = h.Write(ss.h)
+	_, _ = h.Write(data)
+	ss.h = h.Sum(nil)
+}
+
+func (ss *symmetricState) mixKeyAndHash(ikm []byte) {
+	outputs := hkdf(ss.newFn, ss.ck, ikm, 3)
+	ss.ck = outputs[0]
+	ss.mixHash(outputs[1])
+	ss.cs.initializeKey(outputs[2][:KeySize])
+}
+
+func (ss *symmetricState) encryptAndHash(out, plaintext []byte) ([]byte, error) {
+	off := len(out)
+	out, err := ss.cs.Encrypt(out, ss.h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	ss.mixHash(out[off:])
+	return out, nil
+}
+
+func (ss *symmetricState) decryptAndHash(out, ciphertext []byte) ([]byte, error) {
+	out, err := ss.cs.Decrypt(out, ss.h, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	ss.mixHash(ciphertext)
+	return out, nil
+}
+
+func (ss *symmetricState) split() (*CipherState, *CipherState) {
+	outputs := hkdf(ss.newFn, ss.ck, nil, 2)
+
+	var c1, c2 CipherState
+	c1.initializeKey(outputs[0][:KeySize])
+	c2.initializeKey(outputs[1][:KeySize])
+
+	return &c1, &c2
+}
+
+// HandshakeState is a Noise HandshakeState.
+type HandshakeState struct {
+	ss symmetricState
+
+	s  *x25519.PrivateKey
+	e  *x25519.PrivateKey
+	rs *x25519.PublicKey
+	re *x25519.PublicKey
+
+	psk      []byte
+	messages [][]token
+	msgIdx   int
+
+	initiator bool
+	failed    bool
+	rand      io.Reader
+}
+
+// NewHandshakeState creates a new HandshakeState.
+func NewHandshakeState(cfg *Config) (*HandshakeState, error) {
+	if cfg.Pattern == nil {
+		return nil, fmt.Errorf("noise: no handshake pattern specified")
+	}
+	if cfg.Cipher != CipherChaChaPoly {
+		return nil, fmt.Errorf("noise: unsupported cipher: '%s'", cfg.Cipher)
+	}
+	newFn := cfg.Hash.newFn()
+	if newFn == nil {
+		return nil, fmt.Errorf("noise: unsupported hash: '%s'", cfg.Hash)
+	}
+
+	hs := &HandshakeState{
+		s:         cfg.LocalStatic,
+		rs:        cfg.RemoteStatic,
+		initiator: cfg.Initiator,
+		rand:      cfg.Rand,
+	}
+	if hs.rand == nil {
+		hs.rand = cryptorand.Reader
+	}
+
+	// Apply the psk modifier if needed.
+	hs.messages = make([][]token, 0, len(cfg.Pattern.messages))
+	for _, msg := range cfg.Pattern.messages {
+		hs.messages = append(hs.messages, append([]token{}, msg...))
+	}
+	if cfg.PresharedKey != nil {
+		if len(cfg.PresharedKey) != PresharedKeySize {
+			return nil, fmt.Errorf("noise: invalid pre-shared key size")
+		}
+		switch placement := cfg.PresharedKeyPlacement; {
+		case placement == 0:
+			hs.messages[0] = append([]token{tokenPSK}, hs.messages[0]...)
+		case placement > 0 && placement <= len(hs.messages):
+			hs.messages[placement-1] = append(hs.messages[placement-1], tokenPSK)
+		default:
+			return nil, fmt.Errorf("noise: invalid pre-shared key placement: %d", placement)
+		}
+		hs.psk = append([]byte{}, cfg.PresharedKey...)
+	}
+
+	// Ensure that the required static keys were provided.
+	if hs.needsLocalStatic(cfg.Pattern) && hs.s == nil {
+		return nil, fmt.Errorf("noise: local static key required")
+	}
+	remotePreMessages := cfg.Pattern.responderPreMessages
+	if !hs.initiator {
+		remotePreMessages = cfg.Pattern.initiatorPreMessages
+	}
+	if len(remotePreMessages) > 0 && hs.rs == nil {
+		return nil, fmt.Errorf("noise: remote static key required")
+	}
+
+	hs.ss.initialize(newFn, cfg.protocolName())
+	hs.ss.mixHash(cfg.Prologue)
+
+	// The standard patterns only use "s" in pre-messages, and the
+	// initiator's pre-messages are always processed first.
+	initiatorPre, responderPre := hs.localStaticPublic, func() []byte { return hs.rs[:] }
+	if !hs.initiator {
+		initiatorPre, responderPre = responderPre, initiatorPre
+	}
+	for range cfg.Pattern.initiatorPreMessages {
+		hs.ss.mixHash(initiatorPre())
+	}
+	for range cfg.Pattern.responderPreMessages {
+		hs.ss.mixHash(responderPre())
+	}
+
+	return hs, nil
+}
+
+func (hs *HandshakeState) needsLocalStatic(pattern *HandshakePattern) bool {
+	preMessages := pattern.initiatorPreMessages
+	if !hs.initiator {
+		preMessages = pattern.responderPreMessages
+	}
+	if len(preMessages) > 0 {
+		return true
+	}
+
+	for i, msg := range hs.messages {
+		isOurs := (i%2 == 0) == hs.initiator
+		for _, tok := range msg {
+			switch tok {
+			case tokenS:
+				if isOurs {
+					return true
+				}
+			case tokenSS:
+				return true
+			case tokenES:
+				if !hs.initiator {
+					return true
+				}
+			case tokenSE:
+				if hs.initiator {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func (hs *HandshakeState) localStaticPublic() []byte {
+	return hs.s.Public()[:]
+}
+
+// IsComplete returns true iff the handshake is complete.
+func (hs *HandshakeState) IsComplete() bool {
+	return hs.msgIdx == len(hs.messages)
+}
+
+// HandshakeHash returns the handshake hash, suitable for use as a channel
+// binding value once the handshake is complete.
+func (hs *HandshakeState) HandshakeHash() []byte {
+	return append([]byte{}, hs.ss.h...)
+}
+
+// RemoteStatic returns the remote static public key if known, or nil.
+func (hs *HandshakeState) RemoteStatic() *x25519.PublicKey {
+	if hs.rs == nil {
+		return nil
+	}
+	rs := *hs.rs
+	return &rs
+}
+
+// Split returns the pair of CipherStates to be used to encrypt and
+// decrypt transport messages, after the handshake is complete.
+func (hs *HandshakeState) Split() (*CipherState, *CipherState, error) {
+	if hs.failed {
+		return nil, nil, errHandshakeFailed
+	}
+	if !hs.IsComplete() {
+		return nil, nil, errHandshakeIncomplete
+	}
+
+	c1, c2 := hs.ss.split()
+	if !hs.initiator {
+		c1, c2 = c2, c1
+	}
+
+	return c1, c2, nil
+}
+
+// WriteMessage appends the next handshake message, containing the
+// payload, to out, and returns the resulting slice.
+//
+// If an error is returned, the HandshakeState can no longer be used.
+func (hs *HandshakeState) WriteMessage(out, payload []byte) ([]byte, error) {
+	if err := hs.checkTurn(true); err != nil {
+		return nil, err
+	}
+
+	off := len(out)
+	out, err := hs.writeMessage(out, payload)
+	if err == nil && len(out)-off > MaxMessageSize {
+		err = ErrMessageTooLarge
+	}
+	if err != nil {
+		hs.failed = true
+		return nil, err
+	}
+	hs.msgIdx++
+
+	return out, nil
+}
+
+func (hs *HandshakeState) writeMessage(out, payload []byte) ([]byte, error) {
+	var err error
+	for _, tok := range hs.messages[hs.msgIdx] {
+		switch tok {
+		case tokenE:
+			var e x25519.PrivateKey
+			if _, err = io.ReadFull(hs.rand, e[:]); err != nil {
+				return nil, fmt.Errorf("noise: failed to generate ephemeral key: %w", err)
+			}
+			hs.e = &e
+			ePub := e.Public()[:]
+			out = append(out, ePub...)
+			hs.ss.mixHash(ePub)
+			if hs.psk != nil {
+				hs.ss.mixKey(ePub)
+			}
+		case tokenS:
+			if out, err = hs.ss.encryptAndHash(out, hs.localStaticPublic()); err != nil {
+				return nil, err
+			}
+		case tokenPSK:
+			hs.ss.mixKeyAndHash(hs.psk)
+		default:
+			hs.mixDH(tok)
+		}
+	}
+
+	return hs.ss.encryptAndHash(out, payload)
+}
+
+// ReadMessage processes the next handshake message, and appends the
+// decrypted payload to out, and returns the resulting slice.
+//
+// If an error is returned, the HandshakeState can no longer be used.
+func (hs *HandshakeState) ReadMessage(out, message []byte) ([]byte, error) {
+	if err := hs.checkTurn(false); err != nil {
+		return nil, err
+	}
+	if len(message) > MaxMessageSize {
+		hs.failed = true
+		return nil, ErrMessageTooLarge
+	}
+
+	out, err := hs.readMessage(out, message)
+	if err != nil {
+		hs.failed = true
+		return nil, err
+	}
+	hs.msgIdx++
+
+	return out, nil
+}
+
+func (hs *HandshakeState) readMessage(out, message []byte) ([]byte, error) {
+	for _, tok := range hs.messages[hs.msgIdx] {
+		switch tok {
+		case tokenE:
+			if len(message) < dhLen {
+				return nil, ErrShortMessage
+			}
+			var re x25519.PublicKey
+			copy(re[:], message[:dhLen])
+			message = message[dhLen:]
+			hs.re = &re
+			hs.ss.mixHash(re[:])
+			if hs.psk != nil {
+				hs.ss.mixKey(re[:])
+			}
+		case tokenS:
+			sLen := dhLen
+			if hs.ss.cs.HasKey() {
+				sLen += chachaPolyTagSize
+			}
+			if len(message) < sLen {
+				return nil, ErrShortMessage
+			}
+			rsBytes, err := hs.ss.decryptAndHash(nil, message[:sLen])
+			if err != nil {
+				return nil, err
+			}
+			message = message[sLen:]
+			var rs x25519.PublicKey
+			copy(rs[:], rsBytes)
+			hs.rs = &rs
+		case tokenPSK:
+			hs.ss.mixKeyAndHash(hs.psk)
+		default:
+			hs.mixDH(tok)
+		}
+	}
+
+	if hs.ss.cs.HasKey() && len(message) < chachaPolyTagSize {
+		return nil, ErrShortMessage
+	}
+
+	return hs.ss.decryptAndHash(out, message)
+}
+
+func (hs *HandshakeState) mixDH(tok token) {
+	var (
+		priv *x25519.PrivateKey
+		pub  *x25519.PublicKey
+	)
+	switch tok {
+	case tokenEE:
+		priv, pub = hs.e, hs.re
+	case tokenES:
+		switch hs.initiator {
+		case true:
+			priv, pub = hs.e, hs.rs
+		case false:
+			priv, pub = hs.s, hs.re
+		}
+	case tokenSE:
+		switch hs.initiator {
+		case true:
+			priv, pub = hs.s, hs.re
+		case false:
+			priv, pub = hs.e, hs.rs
+		}
+	case tokenSS:
+		priv, pub = hs.s, hs.rs
+	default:
+		panic(fmt.Sprintf("noise: unexpected token: %d", tok))
+	}
+
+	sharedSecret := priv.DiffieHellman(pub)
+	hs.ss.mixKey(sharedSecret[:])
+}
+
+func (hs *HandshakeState) checkTurn(isWrite bool) error {
+	if hs.failed {
+		return errHandshakeFailed
+	}
+	if hs.IsComplete() {
+		return errHandshakeComplete
+	}
+
+	initiatorsTurn := hs.msgIdx%2 == 0
+	if (initiatorsTurn == hs.initiator) != isWrite {
+		return errNotOurTurn
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package noise implements the Noise Protocol Framework handshake state
+// machine, with the 25519 DH functions, as specified in revision 34 of
+// the specification.  See https://noiseprotocol.org/noise.html.
+package noise
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2s"
+
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+const (
+	// MaxMessageSize is the maximum size of a Noise message in bytes.
+	MaxMessageSize = 65535
+
+	// KeySize is the size of a cipher key in bytes.
+	KeySize = 32
+
+	// PresharedKeySize is the size of a pre-shared symmetric key in bytes.
+	PresharedKeySize = 32
+
+	dhName = "25519"
+	dhLen  = 32
+)
+
+var (
+	// ErrMessageTooLarge is the error returned when a message exceeds
+	// MaxMessageSize.
+	ErrMessageTooLarge = fmt.Errorf("noise: message too large")
+
+	// ErrShortMessage is the error returned when a message is truncated.
+	ErrShortMessage = fmt.Errorf("noise: message is too short")
+
+	// ErrOpen is the error returned when decryption fails.
+	ErrOpen = fmt.Errorf("noise: message authentication failed")
+
+	// ErrNonceExhausted is the error returned when a CipherState's
+	// nonce has been exhausted.
+	ErrNonceExhausted = fmt.Errorf("noise: nonce exhausted")
+)
+
+// Cipher is a Noise cipher function.
+type Cipher string
+
+// CipherChaChaPoly is the ChaChaPoly cipher function.
+const CipherChaChaPoly Cipher = "ChaChaPoly"
+
+// Hash is a Noise hash function.
+type Hash string
+
+const (
+	// HashBLAKE2s is the BLAKE2s hash function.
+	HashBLAKE2s Hash = "BLAKE2s"
+
+	// HashSHA256 is the SHA256 hash function.
+	HashSHA256 Hash = "SHA256"
+)
+
+func (h Hash) newFn() func() hash.Hash {
+	switch h {
+	case HashBLAKE2s:
+		return newBlake2s
+	case HashSHA256:
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+func newBlake2s() hash.Hash {
+	h, err := blake2s.New256(nil)
+	if err != nil {
+		panic("noise: failed to initialize BLAKE2s: " + err.Error())
+	}
+	return h
+}
+
+// hkdf is the Noise HKDF function, returning numOutputs outputs, each
+// the size of the hash function's digest.
+func hkdf(newFn func() hash.Hash, chainingKey, ikm []byte, numOutputs int) [][]byte {
+	mac := hmac.New(newFn, chainingKey)
+	_, _ = mac.Write(ikm)
+	tempKey := mac.Sum(nil)
+
+	outputs := make([][]byte, 0, numOutputs)
+
+	var prev []byte
+	for i := 1; i <= numOutputs; i++ {
+		mac = hmac.New(newFn, tempKey)
+		_, _ = mac.Write(prev)
+		_, _ = mac.Write([]byte{byte(i)})
+		prev = mac.Sum(nil)
+		outputs = append(outputs, prev)
+	}
+
+	return outputs
+}
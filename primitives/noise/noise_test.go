@@ -0,0 +1,272 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package noise
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+type testHandshake struct {
+	initiator *HandshakeState
+	responder *HandshakeState
+}
+
+func newTestHandshake(t *testing.T, pattern *HandshakePattern, hash Hash) (*testHandshake, *x25519.PrivateKey, *x25519.PrivateKey) {
+	_, skI, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+	_, skR, err := x25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("x25519.GenerateKey: %v", err)
+	}
+
+	cfgI := &Config{
+		Pattern:   pattern,
+		Cipher:    CipherChaChaPoly,
+		Hash:      hash,
+		Initiator: true,
+		Prologue:  []byte("test prologue"),
+	}
+	cfgR := *cfgI
+	cfgR.Initiator = false
+
+	switch pattern {
+	case PatternXX:
+		cfgI.LocalStatic, cfgR.LocalStatic = skI, skR
+	case PatternIK:
+		cfgI.LocalStatic, cfgR.LocalStatic = skI, skR
+		cfgI.RemoteStatic = skR.Public()
+	case PatternNK:
+		cfgR.LocalStatic = skR
+		cfgI.RemoteStatic = skR.Public()
+	default:
+		t.Fatalf("unsupported test pattern: %s", pattern.Name())
+	}
+
+	var hs testHandshake
+	if hs.initiator, err = NewHandshakeState(cfgI); err != nil {
+		t.Fatalf("NewHandshakeState(initiator): %v", err)
+	}
+	if hs.responder, err = NewHandshakeState(&cfgR); err != nil {
+		t.Fatalf("NewHandshakeState(responder): %v", err)
+	}
+
+	return &hs, skI, skR
+}
+
+func (hs *testHandshake) run(t *testing.T) (send, recv [2]*CipherState) {
+	for !hs.initiator.IsComplete() {
+		writer, reader := hs.initiator, hs.responder
+		if hs.initiator.msgIdx%2 != 0 {
+			writer, reader = hs.responder, hs.initiator
+		}
+
+		payload := []byte("handshake payload")
+		msg, err := writer.WriteMessage(nil, payload)
+		if err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		pt, err := reader.ReadMessage(nil, msg)
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if !bytes.Equal(pt, payload) {
+			t.Fatalf("payload mismatch")
+		}
+	}
+	if !hs.responder.IsComplete() {
+		t.Fatalf("responder handshake not complete")
+	}
+	if !bytes.Equal(hs.initiator.HandshakeHash(), hs.responder.HandshakeHash()) {
+		t.Fatalf("handshake hash mismatch")
+	}
+
+	var err error
+	if send[0], recv[0], err = hs.initiator.Split(); err != nil {
+		t.Fatalf("Split(initiator): %v", err)
+	}
+	if send[1], recv[1], err = hs.responder.Split(); err != nil {
+		t.Fatalf("Split(responder): %v", err)
+	}
+
+	return
+}
+
+func TestHandshake(t *testing.T) {
+	for _, hash := range []Hash{HashBLAKE2s, HashSHA256} {
+		for _, pattern := range []*HandshakePattern{PatternXX, PatternIK, PatternNK} {
+			t.Run(pattern.Name()+"/"+string(hash), func(t *testing.T) {
+				hs, skI, skR := newTestHandshake(t, pattern, hash)
+				send, recv := hs.run(t)
+
+				// Check that the static keys were learned.
+				if rs := hs.initiator.RemoteStatic(); rs == nil || *rs != *skR.Public() {
+					t.Fatalf("initiator has incorrect remote static key")
+				}
+				if pattern != PatternNK {
+					if rs := hs.responder.RemoteStatic(); rs == nil || *rs != *skI.Public() {
+						t.Fatalf("responder has incorrect remote static key")
+					}
+				}
+
+				// Exchange some transport messages.
+				for i := 0; i < 4; i++ {
+					s, r := send[i%2], recv[(i+1)%2]
+					ct, err := s.Encrypt(nil, []byte("ad"), []byte("transport message"))
+					if err != nil {
+						t.Fatalf("Encrypt: %v", err)
+					}
+					pt, err := r.Decrypt(nil, []byte("ad"), ct)
+					if err != nil {
+						t.Fatalf("Decrypt: %v", err)
+					}
+					if !bytes.Equal(pt, []byte("transport message")) {
+						t.Fatalf("transport payload mismatch")
+					}
+				}
+
+				// Rekey must keep both sides in sync.
+				send[0].Rekey()
+				recv[1].Rekey()
+				ct, _ := send[0].Encrypt(nil, nil, []byte("rekeyed"))
+				if _, err := recv[1].Decrypt(nil, nil, ct); err != nil {
+					t.Fatalf("Decrypt(rekeyed): %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	t.Run("OutOfTurn", func(t *testing.T) {
+		hs, _, _ := newTestHandshake(t, PatternXX, HashBLAKE2s)
+		if _, err := hs.responder.WriteMessage(nil, nil); err != errNotOurTurn {
+			t.Fatalf("responder.WriteMessage: expected errNotOurTurn, got %v", err)
+		}
+		if _, err := hs.initiator.ReadMessage(nil, nil); err != errNotOurTurn {
+			t.Fatalf("initiator.ReadMessage: expected errNotOurTurn, got %v", err)
+		}
+		if _, _, err := hs.initiator.Split(); err != errHandshakeIncomplete {
+			t.Fatalf("initiator.Split: expected errHandshakeIncomplete, got %v", err)
+		}
+
+		hs.run(t)
+		if _, err := hs.initiator.WriteMessage(nil, nil); err != errHandshakeComplete {
+			t.Fatalf("initiator.WriteMessage: expected errHandshakeComplete, got %v", err)
+		}
+	})
+	t.Run("Tampered", func(t *testing.T) {
+		hs, _, _ := newTestHandshake(t, PatternIK, HashSHA256)
+		msg, err := hs.initiator.WriteMessage(nil, []byte("payload"))
+		if err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		msg[len(msg)-1] ^= 0x01
+		if _, err = hs.responder.ReadMessage(nil, msg); err != ErrOpen {
+			t.Fatalf("ReadMessage(tampered): expected ErrOpen, got %v", err)
+		}
+		msg[len(msg)-1] ^= 0x01
+		if _, err = hs.responder.ReadMessage(nil, msg); err != errHandshakeFailed {
+			t.Fatalf("ReadMessage(after failure): expected errHandshakeFailed, got %v", err)
+		}
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		hs, _, _ := newTestHandshake(t, PatternXX, HashSHA256)
+		if _, err := hs.responder.ReadMessage(nil, make([]byte, dhLen-1)); err != ErrShortMessage {
+			t.Fatalf("ReadMessage(truncated): expected ErrShortMessage, got %v", err)
+		}
+	})
+	t.Run("WrongRemoteStatic", func(t *testing.T) {
+		hs, _, _ := newTestHandshake(t, PatternNK, HashBLAKE2s)
+		_, skOther, err := x25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("x25519.GenerateKey: %v", err)
+		}
+		hs.initiator, err = NewHandshakeState(&Config{
+			Pattern:      PatternNK,
+			Cipher:       CipherChaChaPoly,
+			Hash:         HashBLAKE2s,
+			Initiator:    true,
+			Prologue:     []byte("test prologue"),
+			RemoteStatic: skOther.Public(),
+		})
+		if err != nil {
+			t.Fatalf("NewHandshakeState: %v", err)
+		}
+		msg, err := hs.initiator.WriteMessage(nil, []byte("payload"))
+		if err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+		if _, err = hs.responder.ReadMessage(nil, msg); err != ErrOpen {
+			t.Fatalf("ReadMessage: expected ErrOpen, got %v", err)
+		}
+	})
+	t.Run("Config", func(t *testing.T) {
+		for i, cfg := range []*Config{
+			{Cipher: CipherChaChaPoly, Hash: HashBLAKE2s},
+			{Pattern: PatternNN, Cipher: "AESGCM", Hash: HashBLAKE2s},
+			{Pattern: PatternNN, Cipher: CipherChaChaPoly, Hash: "SHA512"},
+			{Pattern: PatternXX, Cipher: CipherChaChaPoly, Hash: HashBLAKE2s, Initiator: true},
+			{Pattern: PatternNK, Cipher: CipherChaChaPoly, Hash: HashBLAKE2s, Initiator: true},
+			{Pattern: PatternNN, Cipher: CipherChaChaPoly, Hash: HashBLAKE2s, PresharedKey: make([]byte, 31)},
+			{Pattern: PatternNN, Cipher: CipherChaChaPoly, Hash: HashBLAKE2s, PresharedKey: make([]byte, 32), PresharedKeyPlacement: 3},
+		} {
+			if _, err := NewHandshakeState(cfg); err == nil {
+				t.Fatalf("NewHandshakeState(%d): expected error", i)
+			}
+		}
+	})
+}
+
+func TestCipherState(t *testing.T) {
+	var cs CipherState
+	if cs.HasKey() {
+		t.Fatalf("HasKey: unexpected key")
+	}
+	pt := []byte("plaintext")
+	ct, err := cs.Encrypt(nil, nil, pt)
+	if err != nil || !bytes.Equal(ct, pt) {
+		t.Fatalf("Encrypt(no key) should be the identity function")
+	}
+
+	cs.initializeKey(make([]byte, KeySize))
+	cs.SetNonce(math.MaxUint64)
+	if _, err = cs.Encrypt(nil, nil, pt); err != ErrNonceExhausted {
+		t.Fatalf("Encrypt: expected ErrNonceExhausted, got %v", err)
+	}
+	if _, err = cs.Decrypt(nil, nil, pt); err != ErrNonceExhausted {
+		t.Fatalf("Decrypt: expected ErrNonceExhausted, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package noise
+
+type token int
+
+const (
+	tokenE token = iota
+	tokenS
+	tokenEE
+	tokenES
+	tokenSE
+	tokenSS
+	tokenPSK
+)
+
+// HandshakePattern is a Noise handshake pattern.
+type HandshakePattern struct {
+	name string
+
+	initiatorPreMessages []token
+	responderPreMessages []token
+	messages             [][]token
+}
+
+// Name returns the name of the handshake pattern.
+func (p *HandshakePattern) Name() string {
+	return p.name
+}
+
+// NumMessages returns the number of handshake messages in the pattern.
+func (p *HandshakePattern) NumMessages() int {
+	return len(p.messages)
+}
+
+// IsOneWay returns true iff the pattern is a one-way pattern.
+func (p *HandshakePattern) IsOneWay() bool {
+	return len(p.name) == 1
+}
+
+var (
+	// PatternN is the N one-way handshake pattern.
+	PatternN = &HandshakePattern{
+		name:                 "N",
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES},
+		},
+	}
+
+	// PatternK is the K one-way handshake pattern.
+	PatternK = &HandshakePattern{
+		name:                 "K",
+		initiatorPreMessages: []token{tokenS},
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES, tokenSS},
+		},
+	}
+
+	// PatternX is the X one-way handshake pattern.
+	PatternX = &HandshakePattern{
+		name:                 "X",
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES, tokenS, tokenSS},
+		},
+	}
+
+	// PatternNN is the NN interactive handshake pattern.
+	PatternNN = &HandshakePattern{
+		name: "NN",
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE},
+		},
+	}
+
+	// PatternNK is the NK interactive handshake pattern.
+	PatternNK = &HandshakePattern{
+		name:                 "NK",
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES},
+			{tokenE, tokenEE},
+		},
+	}
+
+	// PatternNX is the NX interactive handshake pattern.
+	PatternNX = &HandshakePattern{
+		name: "NX",
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE, tokenS, tokenES},
+		},
+	}
+
+	// PatternKN is the KN interactive handshake pattern.
+	PatternKN = &HandshakePattern{
+		name:                 "KN",
+		initiatorPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE, tokenSE},
+		},
+	}
+
+	// PatternKK is the KK interactive handshake pattern.
+	PatternKK = &HandshakePattern{
+		name:                 "KK",
+		initiatorPreMessages: []token{tokenS},
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES, tokenSS},
+			{tokenE, tokenEE, tokenSE},
+		},
+	}
+
+	// PatternKX is the KX interactive handshake pattern.
+	PatternKX = &HandshakePattern{
+		name:                 "KX",
+		initiatorPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE, tokenSE, tokenS, tokenES},
+		},
+	}
+
+	// PatternXN is the XN interactive handshake pattern.
+	PatternXN = &HandshakePattern{
+		name: "XN",
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE},
+			{tokenS, tokenSE},
+		},
+	}
+
+	// PatternXK is the XK interactive handshake pattern.
+	PatternXK = &HandshakePattern{
+		name:                 "XK",
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES},
+			{tokenE, tokenEE},
+			{tokenS, tokenSE},
+		},
+	}
+
+	// PatternXX is the XX interactive handshake pattern.
+	PatternXX = &HandshakePattern{
+		name: "XX",
+		messages: [][]token{
+			{tokenE},
+			{tokenE, tokenEE, tokenS, tokenES},
+			{tokenS, tokenSE},
+		},
+	}
+
+	// PatternIN is the IN interactive handshake pattern.
+	PatternIN = &HandshakePattern{
+		name: "IN",
+		messages: [][]token{
+			{tokenE, tokenS},
+			{tokenE, tokenEE, tokenSE},
+		},
+	}
+
+	// PatternIK is the IK interactive handshake pattern.
+	PatternIK = &HandshakePattern{
+		name:                 "IK",
+		responderPreMessages: []token{tokenS},
+		messages: [][]token{
+			{tokenE, tokenES, tokenS, tokenSS},
+			{tokenE, tokenEE, tokenSE},
+		},
+	}
+
+	// PatternIX is the IX interactive handshake pattern.
+	PatternIX = &HandshakePattern{
+		name: "IX",
+		messages: [][]token{
+			{tokenE, tokenS},
+			{tokenE, tokenEE, tokenSE, tokenS, tokenES},
+		},
+	}
+)
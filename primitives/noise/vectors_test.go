@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package noise
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+)
+
+var vectorPatterns = map[string]*HandshakePattern{
+	"N":  PatternN,
+	"K":  PatternK,
+	"X":  PatternX,
+	"NN": PatternNN,
+	"NK": PatternNK,
+	"NX": PatternNX,
+	"KN": PatternKN,
+	"KK": PatternKK,
+	"KX": PatternKX,
+	"XN": PatternXN,
+	"XK": PatternXK,
+	"XX": PatternXX,
+	"IN": PatternIN,
+	"IK": PatternIK,
+	"IX": PatternIX,
+}
+
+type vectorMessage struct {
+	payload    []byte
+	ciphertext []byte
+}
+
+type vectorTestCase struct {
+	name string
+
+	initStatic *x25519.PrivateKey
+	respStatic *x25519.PrivateKey
+	initRand   []byte
+	respRand   []byte
+	prologue   []byte
+	psk        []byte
+
+	messages []vectorMessage
+}
+
+func (tc *vectorTestCase) Run(t *testing.T) {
+	// Noise_<pattern>[psk<n>]_25519_<cipher>_<hash>
+	components := strings.Split(tc.name, "_")
+	if len(components) != 5 || components[0] != "Noise" || components[2] != dhName {
+		t.Fatalf("malformed protocol name: '%s'", tc.name)
+	}
+
+	patternName, pskPlacement := components[1], 0
+	if idx := strings.Index(patternName, "psk"); idx >= 0 {
+		var err error
+		if pskPlacement, err = strconv.Atoi(patternName[idx+3:]); err != nil {
+			t.Fatalf("malformed psk modifier: %v", err)
+		}
+		patternName = patternName[:idx]
+	}
+	pattern := vectorPatterns[patternName]
+	if pattern == nil {
+		t.Fatalf("unknown pattern: '%s'", patternName)
+	}
+
+	cfgI := &Config{
+		Pattern:               pattern,
+		Cipher:                Cipher(components[3]),
+		Hash:                  Hash(components[4]),
+		Initiator:             true,
+		Prologue:              tc.prologue,
+		PresharedKey:          tc.psk,
+		PresharedKeyPlacement: pskPlacement,
+		Rand:                  bytes.NewReader(tc.initRand),
+	}
+	cfgR := *cfgI
+	cfgR.Initiator = false
+	cfgR.Rand = bytes.NewReader(tc.respRand)
+
+	// Figure out which static keys each side has and knows in advance
+	// from the pattern name.
+	switch pattern.IsOneWay() {
+	case true:
+		cfgR.LocalStatic = tc.respStatic
+		cfgI.RemoteStatic = tc.respStatic.Public()
+		if patternName != "N" {
+			cfgI.LocalStatic = tc.initStatic
+		}
+		if patternName == "K" {
+			cfgR.RemoteStatic = tc.initStatic.Public()
+		}
+	case false:
+		switch patternName[0] {
+		case 'K':
+			cfgR.RemoteStatic = tc.initStatic.Public()
+			fallthrough
+		case 'X', 'I':
+			cfgI.LocalStatic = tc.initStatic
+		}
+		switch patternName[1] {
+		case 'K':
+			cfgI.RemoteStatic = tc.respStatic.Public()
+			fallthrough
+		case 'X':
+			cfgR.LocalStatic = tc.respStatic
+		}
+	}
+
+	hsI, err := NewHandshakeState(cfgI)
+	if err != nil {
+		t.Fatalf("NewHandshakeState(initiator): %v", err)
+	}
+	hsR, err := NewHandshakeState(&cfgR)
+	if err != nil {
+		t.Fatalf("NewHandshakeState(responder): %v", err)
+	}
+
+	var csI, csR [2]*CipherState
+	for i, msg := range tc.messages {
+		var ct, pt []byte
+		switch i < pattern.NumMessages() {
+		case true:
+			writer, reader := hsI, hsR
+			if i%2 != 0 {
+				writer, reader = hsR, hsI
+			}
+
+			if ct, err = writer.WriteMessage(nil, msg.payload); err != nil {
+				t.Fatalf("WriteMessage(%d): %v", i, err)
+			}
+			if pt, err = reader.ReadMessage(nil, ct); err != nil {
+				t.Fatalf("ReadMessage(%d): %v", i, err)
+			}
+
+			if i == pattern.NumMessages()-1 {
+				if !hsI.IsComplete() || !hsR.IsComplete() {
+					t.Fatalf("handshake not complete")
+				}
+				if !bytes.Equal(hsI.HandshakeHash(), hsR.HandshakeHash()) {
+					t.Fatalf("handshake hash mismatch")
+				}
+				if csI[0], csI[1], err = hsI.Split(); err != nil {
+					t.Fatalf("Split(initiator): %v", err)
+				}
+				if csR[0], csR[1], err = hsR.Split(); err != nil {
+					t.Fatalf("Split(responder): %v", err)
+				}
+			}
+		case false:
+			send, recv := csI[0], csR[1]
+			if (i-pattern.NumMessages())%2 != 0 {
+				send, recv = csR[0], csI[1]
+			}
+
+			if ct, err = send.Encrypt(nil, nil, msg.payload); err != nil {
+				t.Fatalf("Encrypt(%d): %v", i, err)
+			}
+			if pt, err = recv.Decrypt(nil, nil, ct); err != nil {
+				t.Fatalf("Decrypt(%d): %v", i, err)
+			}
+		}
+
+		if !bytes.Equal(ct, msg.ciphertext) {
+			t.Fatalf("msg[%d] ciphertext mismatch (Got: %x)", i, ct)
+		}
+		if !bytes.Equal(pt, msg.payload) {
+			t.Fatalf("msg[%d] payload mismatch (Got: %x)", i, pt)
+		}
+	}
+}
+
+func TestVectors(t *testing.T) {
+	// The test vectors are the 25519, ChaChaPoly, and BLAKE2s/SHA256
+	// subset of those distributed with github.com/flynn/noise (v1.1.0),
+	// which are generated by that implementation.  They are not the
+	// cacophony vectors, which are not included here.
+	f, err := os.Open("testdata/vectors.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	var (
+		testCases []*vectorTestCase
+		tc        *vectorTestCase
+		payload   []byte
+	)
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			t.Fatalf("malformed line: '%s'", line)
+		}
+		k, v := kv[0], kv[1]
+
+		if k == "handshake" {
+			tc = &vectorTestCase{
+				name: v,
+			}
+			testCases = append(testCases, tc)
+			continue
+		}
+		if tc == nil {
+			t.Fatalf("vector field without handshake: '%s'", line)
+		}
+
+		b := testhelpers.MustUnhex(t, v)
+		switch {
+		case k == "init_static":
+			tc.initStatic = vectorPrivateKey(b)
+		case k == "resp_static":
+			tc.respStatic = vectorPrivateKey(b)
+		case k == "gen_init_ephemeral":
+			tc.initRand = b
+		case k == "gen_resp_ephemeral":
+			tc.respRand = b
+		case k == "prologue":
+			tc.prologue = b
+		case k == "preshared_key":
+			tc.psk = b
+		case strings.HasSuffix(k, "_payload"):
+			payload = b
+		case strings.HasSuffix(k, "_ciphertext"):
+			tc.messages = append(tc.messages, vectorMessage{
+				payload:    payload,
+				ciphertext: b,
+			})
+		default:
+			t.Fatalf("unknown vector field: '%s'", k)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(testCases) == 0 {
+		t.Fatalf("no test vectors")
+	}
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i)+"/"+tc.name, tc.Run)
+	}
+}
+
+func vectorPrivateKey(b []byte) *x25519.PrivateKey {
+	var sk x25519.PrivateKey
+	copy(sk[:], b)
+	return &sk
+}
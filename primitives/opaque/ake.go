@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import "crypto/sha512"
+
+type sessionKeys struct {
+	km2        []byte
+	km3        []byte
+	sessionKey []byte
+}
+
+func (cfg *Config) preamble(clientIdentity, ke1, serverIdentity, credentialResponse, serverNonce, serverPublicKeyshare []byte) []byte {
+	return concat(
+		[]byte(labelPreamble),
+		i2osp2(len(cfg.Context)), cfg.Context,
+		i2osp2(len(clientIdentity)), clientIdentity,
+		ke1,
+		i2osp2(len(serverIdentity)), serverIdentity,
+		credentialResponse,
+		serverNonce,
+		serverPublicKeyshare,
+	)
+}
+
+func deriveKeys(ikm, preambleHash []byte) *sessionKeys {
+	prk := extract(nil, ikm)
+	handshakeSecret := deriveSecret(prk, labelHandshakeSecret, preambleHash)
+	return &sessionKeys{
+		km2:        deriveSecret(handshakeSecret, labelServerMAC, nil),
+		km3:        deriveSecret(handshakeSecret, labelClientMAC, nil),
+		sessionKey: deriveSecret(prk, labelSessionKey, preambleHash),
+	}
+}
+
+// authenticate derives the session keys from the 3DH shared secrets
+// and transcript, and returns the keys, the server MAC and the
+// expected client MAC.
+func authenticate(ikm, preamble []byte) (*sessionKeys, []byte, []byte) {
+	preambleHash := sha512.Sum512(preamble)
+	keys := deriveKeys(ikm, preambleHash[:])
+
+	serverMAC := mac(keys.km2, preambleHash[:])
+	h := sha512.New()
+	_, _ = h.Write(preamble)
+	_, _ = h.Write(serverMAC)
+	clientMAC := mac(keys.km3, h.Sum(nil))
+
+	return keys, serverMAC, clientMAC
+}
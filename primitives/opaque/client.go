@@ -0,0 +1,233 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/oprf"
+)
+
+// Client is an OPAQUE client.
+type Client struct {
+	cfg  *Config
+	oprf *oprf.Client
+}
+
+// ClientRegistrationState is the client's state between creating a
+// RegistrationRequest and finalizing the registration.
+type ClientRegistrationState struct {
+	fd *oprf.FinalizeData
+}
+
+// ClientLoginState is the client's state between generating a KE1 and
+// a KE3.
+type ClientLoginState struct {
+	fd           *oprf.FinalizeData
+	clientSecret *PrivateKey
+	ke1          []byte
+}
+
+// NewClient creates a new client with the configuration.
+func NewClient(cfg *Config) (*Client, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	oprfClient, err := oprf.Ristretto255SHA512.NewClient(oprf.ModeOPRF, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		cfg:  cfg,
+		oprf: oprfClient,
+	}, nil
+}
+
+func (c *Client) blind(rand io.Reader, password []byte) (*oprf.FinalizeData, []byte, error) {
+	fd, req, err := c.oprf.Blind(rand, [][]byte{password}, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opaque: failed to blind password: %w", err)
+	}
+	return fd, req.BlindedElements[0], nil
+}
+
+func (c *Client) randomizedPassword(fd *oprf.FinalizeData, evaluatedMessage []byte) ([]byte, error) {
+	outputs, err := c.oprf.Finalize(fd, &oprf.Evaluation{
+		EvaluatedElements: [][]byte{evaluatedMessage},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: evaluated message: %v", ErrDeserialize, err)
+	}
+	return c.cfg.randomizedPassword(outputs[0])
+}
+
+// CreateRegistrationRequest begins the registration of password, using
+// entropy from rand.  If rand is nil, crypto/rand.Reader will be used.
+func (c *Client) CreateRegistrationRequest(rand io.Reader, password []byte) (*ClientRegistrationState, *RegistrationRequest, error) {
+	fd, blindedMessage, err := c.blind(rand, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	st := &ClientRegistrationState{
+		fd: fd,
+	}
+	return st, &RegistrationRequest{BlindedMessage: blindedMessage}, nil
+}
+
+// FinalizeRegistrationRequest completes the registration using the
+// server's response, and returns the RegistrationRecord to be sent to
+// the server and the export key, using entropy from rand.  If rand is
+// nil, crypto/rand.Reader will be used.
+func (c *Client) FinalizeRegistrationRequest(rand io.Reader, st *ClientRegistrationState, resp *RegistrationResponse, ids *Identities) (*RegistrationRecord, []byte, error) {
+	clientIdentity, serverIdentity, err := ids.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = NewPublicKey(resp.ServerPublicKey); err != nil {
+		return nil, nil, err
+	}
+
+	randomizedPassword, err := c.randomizedPassword(st.fd, resp.EvaluatedMessage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nonce [NonceSize]byte
+	if err = readRandom(rand, nonce[:]); err != nil {
+		return nil, nil, err
+	}
+	env, clientPublicKey, maskingKey, exportKey, err := store(randomizedPassword, nonce, resp.ServerPublicKey, serverIdentity, clientIdentity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &RegistrationRecord{
+		ClientPublicKey: clientPublicKey,
+		MaskingKey:      maskingKey,
+		Envelope:        *env,
+	}, exportKey, nil
+}
+
+// GenerateKE1 begins a login with password, using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+func (c *Client) GenerateKE1(rand io.Reader, password []byte) (*ClientLoginState, *KE1, error) {
+	fd, blindedMessage, err := c.blind(rand, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ke1 := &KE1{
+		BlindedMessage: blindedMessage,
+	}
+	if err = readRandom(rand, ke1.ClientNonce[:]); err != nil {
+		return nil, nil, err
+	}
+	var seed [SeedSize]byte
+	if err = readRandom(rand, seed[:]); err != nil {
+		return nil, nil, err
+	}
+	clientSecret, err := DeriveKey(seed[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	ke1.ClientPublicKeyshare = clientSecret.Public().bytes()
+
+	ke1Bytes, err := ke1.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	st := &ClientLoginState{
+		fd:           fd,
+		clientSecret: clientSecret,
+		ke1:          ke1Bytes,
+	}
+
+	return st, ke1, nil
+}
+
+// GenerateKE3 authenticates the server's KE2, and returns the KE3 to be
+// sent to the server, the session key, and the export key.
+func (c *Client) GenerateKE3(st *ClientLoginState, ke2 *KE2, ids *Identities) (*KE3, []byte, []byte, error) {
+	clientIdentity, serverIdentity, err := ids.get()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverPublicKeyshare, err := NewPublicKey(ke2.ServerPublicKeyshare)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Recover the credentials.
+	randomizedPassword, err := c.randomizedPassword(st.fd, ke2.EvaluatedMessage)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	maskingKey := expand(randomizedPassword, []byte(labelMaskingKey), hashSize)
+	pad := credentialResponsePad(maskingKey, ke2.MaskingNonce[:])
+	var unmasked [maskedResponseSize]byte
+	xor(unmasked[:], pad, ke2.MaskedResponse[:])
+
+	serverPublicKey, err := NewPublicKey(unmasked[:PublicKeySize])
+	if err != nil {
+		// This is almost certainly the result of an incorrect password.
+		return nil, nil, nil, ErrEnvelopeRecovery
+	}
+	var env Envelope
+	env.setBytes(unmasked[PublicKeySize:])
+	clientPrivateKey, serverIdentity, clientIdentity, exportKey, err := recoverEnvelope(randomizedPassword, serverPublicKey.bytes(), &env, serverIdentity, clientIdentity)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Authenticate the server.
+	ikm := concat(
+		diffieHellman(st.clientSecret, serverPublicKeyshare),
+		diffieHellman(st.clientSecret, serverPublicKey),
+		diffieHellman(clientPrivateKey, serverPublicKeyshare),
+	)
+	preamble := c.cfg.preamble(
+		clientIdentity,
+		st.ke1,
+		serverIdentity,
+		ke2.credentialResponseBytes(),
+		ke2.ServerNonce[:],
+		ke2.ServerPublicKeyshare,
+	)
+	keys, expectedServerMAC, clientMAC := authenticate(ikm, preamble)
+	if !macEqual(expectedServerMAC, ke2.ServerMAC[:]) {
+		return nil, nil, nil, ErrServerAuthentication
+	}
+
+	var ke3 KE3
+	copy(ke3.ClientMAC[:], clientMAC)
+
+	return &ke3, keys.sessionKey, exportKey, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+func cleartextCredentials(serverPublicKey, clientPublicKey, serverIdentity, clientIdentity []byte) ([]byte, []byte, []byte) {
+	if serverIdentity == nil {
+		serverIdentity = serverPublicKey
+	}
+	if clientIdentity == nil {
+		clientIdentity = clientPublicKey
+	}
+	b := concat(
+		serverPublicKey,
+		i2osp2(len(serverIdentity)), serverIdentity,
+		i2osp2(len(clientIdentity)), clientIdentity,
+	)
+	return b, serverIdentity, clientIdentity
+}
+
+func (cfg *Config) randomizedPassword(oprfOutput []byte) ([]byte, error) {
+	stretched, err := cfg.KSF.Stretch(oprfOutput)
+	if err != nil {
+		return nil, err
+	}
+	return extract(nil, concat(oprfOutput, stretched)), nil
+}
+
+type envelopeKeys struct {
+	authKey   []byte
+	exportKey []byte
+	sk        *PrivateKey
+}
+
+func deriveEnvelopeKeys(randomizedPassword, nonce []byte) (*envelopeKeys, error) {
+	seed := expand(randomizedPassword, concat(nonce, []byte(labelPrivateKey)), SeedSize)
+	sk, err := deriveKeyPair(seed, labelDeriveDHKeyPair)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeKeys{
+		authKey:   expand(randomizedPassword, concat(nonce, []byte(labelAuthKey)), hashSize),
+		exportKey: expand(randomizedPassword, concat(nonce, []byte(labelExportKey)), hashSize),
+		sk:        sk,
+	}, nil
+}
+
+// store creates the envelope, returning it along with the client's
+// public key, the masking key, and the export key.
+func store(randomizedPassword []byte, nonce [NonceSize]byte, serverPublicKey, serverIdentity, clientIdentity []byte) (*Envelope, []byte, []byte, []byte, error) {
+	maskingKey := expand(randomizedPassword, []byte(labelMaskingKey), hashSize)
+	keys, err := deriveEnvelopeKeys(randomizedPassword, nonce[:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	clientPublicKey := keys.sk.Public().bytes()
+
+	creds, _, _ := cleartextCredentials(serverPublicKey, clientPublicKey, serverIdentity, clientIdentity)
+	env := &Envelope{
+		Nonce: nonce,
+	}
+	copy(env.AuthTag[:], mac(keys.authKey, nonce[:], creds))
+
+	return env, clientPublicKey, maskingKey, keys.exportKey, nil
+}
+
+// recoverEnvelope opens the envelope, returning the client's private
+// key, the cleartext credentials (server and client identities), and
+// the export key.
+func recoverEnvelope(randomizedPassword, serverPublicKey []byte, env *Envelope, serverIdentity, clientIdentity []byte) (*PrivateKey, []byte, []byte, []byte, error) {
+	keys, err := deriveEnvelopeKeys(randomizedPassword, env.Nonce[:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	creds, serverIdentity, clientIdentity := cleartextCredentials(serverPublicKey, keys.sk.Public().bytes(), serverIdentity, clientIdentity)
+	expectedTag := mac(keys.authKey, env.Nonce[:], creds)
+	if !macEqual(expectedTag, env.AuthTag[:]) {
+		return nil, nil, nil, nil, ErrEnvelopeRecovery
+	}
+
+	return keys.sk, serverIdentity, clientIdentity, keys.exportKey, nil
+}
+
+func credentialResponsePad(maskingKey, maskingNonce []byte) []byte {
+	return expand(maskingKey, concat(maskingNonce, []byte(labelCredentialResponsePad)), maskedResponseSize)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/oprf"
+)
+
+const (
+	// PrivateKeySize is the size of a PrivateKey in bytes (Nsk).
+	PrivateKeySize = scalar.ScalarSize
+
+	// PublicKeySize is the size of a PublicKey in bytes (Npk).
+	PublicKeySize = curve.CompressedPointSize
+)
+
+// PrivateKey is a ristretto255 Diffie-Hellman private key.
+type PrivateKey struct {
+	k   *scalar.Scalar
+	pub *PublicKey
+}
+
+// Public returns the public key corresponding to the private key.
+func (k *PrivateKey) Public() *PublicKey {
+	return k.pub
+}
+
+// MarshalBinary encodes a PrivateKey into binary form.
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	return k.k.MarshalBinary()
+}
+
+// PublicKey is a ristretto255 Diffie-Hellman public key.
+type PublicKey struct {
+	p          curve.RistrettoPoint
+	compressed curve.CompressedRistretto
+}
+
+// MarshalBinary encodes a PublicKey into binary form.
+func (k *PublicKey) MarshalBinary() ([]byte, error) {
+	return k.bytes(), nil
+}
+
+func (k *PublicKey) bytes() []byte {
+	return append([]byte{}, k.compressed[:]...)
+}
+
+func newPrivateKey(k *scalar.Scalar) *PrivateKey {
+	var pub PublicKey
+	pub.p.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, k)
+	pub.compressed.SetRistrettoPoint(&pub.p)
+	return &PrivateKey{
+		k:   k,
+		pub: &pub,
+	}
+}
+
+// NewPrivateKey constructs a PrivateKey from the byte representation.
+func NewPrivateKey(b []byte) (*PrivateKey, error) {
+	k, err := scalar.NewFromCanonicalBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	if k.Equal(scalar.New()) == 1 {
+		return nil, fmt.Errorf("%w: private key is zero", ErrDeserialize)
+	}
+	return newPrivateKey(k), nil
+}
+
+// NewPublicKey constructs a PublicKey from the byte representation.
+func NewPublicKey(b []byte) (*PublicKey, error) {
+	var pub PublicKey
+	if err := pub.setBytes(b); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
+func (k *PublicKey) setBytes(b []byte) error {
+	if _, err := k.compressed.SetBytes(b); err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	if _, err := k.p.SetCompressed(&k.compressed); err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	if k.p.IsIdentity() {
+		return fmt.Errorf("%w: public key is the identity", ErrDeserialize)
+	}
+	return nil
+}
+
+// GenerateKey generates a new random private key using entropy from
+// rand.  If rand is nil, crypto/rand.Reader will be used.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	var seed [SeedSize]byte
+	if err := readRandom(rand, seed[:]); err != nil {
+		return nil, err
+	}
+	return DeriveKey(seed[:])
+}
+
+// DeriveKey deterministically derives a private key from a 32-byte
+// seed.
+func DeriveKey(seed []byte) (*PrivateKey, error) {
+	return deriveKeyPair(seed, labelDeriveDHKeyPair)
+}
+
+func deriveKeyPair(seed []byte, info string) (*PrivateKey, error) {
+	sk, err := oprf.Ristretto255SHA512.DeriveKey(oprf.ModeOPRF, seed, []byte(info))
+	if err != nil {
+		return nil, fmt.Errorf("opaque: failed to derive key pair: %w", err)
+	}
+	b, _ := sk.MarshalBinary()
+	return NewPrivateKey(b)
+}
+
+func diffieHellman(k *PrivateKey, pub *PublicKey) []byte {
+	var (
+		p          curve.RistrettoPoint
+		compressed curve.CompressedRistretto
+	)
+	p.Mul(&pub.p, k.k)
+	compressed.SetRistrettoPoint(&p)
+	return compressed[:]
+}
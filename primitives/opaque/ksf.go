@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KSF is a key stretching function, used to harden the OPRF output
+// against offline dictionary attacks.
+type KSF interface {
+	// Stretch returns the stretched output for msg, which must be
+	// hashSize (64) bytes in length.
+	Stretch(msg []byte) ([]byte, error)
+}
+
+type identityKSF struct{}
+
+func (identityKSF) Stretch(msg []byte) ([]byte, error) {
+	return append([]byte{}, msg...), nil
+}
+
+// IdentityKSF is the identity key stretching function, which provides
+// no additional hardening.  It is only suitable for testing, or for
+// inputs that are not low-entropy passwords.
+var IdentityKSF KSF = identityKSF{}
+
+// Argon2idKSF is the Argon2id key stretching function, with an all-zero
+// salt.
+type Argon2idKSF struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+	// Memory is the size of the memory in KiB.
+	Memory uint32
+	// Threads is the degree of parallelism.
+	Threads uint8
+}
+
+// Stretch returns the stretched output for msg.
+func (ksf *Argon2idKSF) Stretch(msg []byte) ([]byte, error) {
+	if ksf.Time == 0 || ksf.Memory == 0 || ksf.Threads == 0 {
+		return nil, fmt.Errorf("opaque: invalid Argon2id parameters")
+	}
+	var salt [16]byte
+	return argon2.IDKey(msg, salt[:], ksf.Time, ksf.Memory, ksf.Threads, hashSize), nil
+}
+
+// NewArgon2idKSF returns the Argon2id key stretching function with the
+// parameters recommended by RFC 9807 (t = 1, m = 2^21 KiB, p = 4).
+func NewArgon2idKSF() *Argon2idKSF {
+	return &Argon2idKSF{
+		Time:    1,
+		Memory:  1 << 21,
+		Threads: 4,
+	}
+}
+
+// ScryptKSF is the scrypt key stretching function, with an all-zero
+// salt.
+type ScryptKSF struct {
+	// N is the CPU/memory cost parameter.
+	N int
+	// R is the block size parameter.
+	R int
+	// P is the parallelization parameter.
+	P int
+}
+
+// Stretch returns the stretched output for msg.
+func (ksf *ScryptKSF) Stretch(msg []byte) ([]byte, error) {
+	var salt [16]byte
+	b, err := scrypt.Key(msg, salt[:], ksf.N, ksf.R, ksf.P, hashSize)
+	if err != nil {
+		return nil, fmt.Errorf("opaque: scrypt failed: %w", err)
+	}
+	return b, nil
+}
+
+// NewScryptKSF returns the scrypt key stretching function with the
+// parameters recommended by RFC 9807 (N = 32768, r = 8, p = 1).
+func NewScryptKSF() *ScryptKSF {
+	return &ScryptKSF{
+		N: 32768,
+		R: 8,
+		P: 1,
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import "fmt"
+
+// Envelope is the client's envelope, stored by the server as part of
+// the RegistrationRecord.
+type Envelope struct {
+	Nonce   [NonceSize]byte
+	AuthTag [MACSize]byte
+}
+
+func (env *Envelope) bytes() []byte {
+	return concat(env.Nonce[:], env.AuthTag[:])
+}
+
+func (env *Envelope) setBytes(b []byte) {
+	copy(env.Nonce[:], b[:NonceSize])
+	copy(env.AuthTag[:], b[NonceSize:])
+}
+
+// RegistrationRequest is the client's registration request.
+type RegistrationRequest struct {
+	BlindedMessage []byte
+}
+
+// MarshalBinary encodes a RegistrationRequest into binary form.
+func (req *RegistrationRequest) MarshalBinary() ([]byte, error) {
+	if len(req.BlindedMessage) != elementSize {
+		return nil, fmt.Errorf("opaque: invalid RegistrationRequest")
+	}
+	return append([]byte{}, req.BlindedMessage...), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled RegistrationRequest.
+func (req *RegistrationRequest) UnmarshalBinary(data []byte) error {
+	if len(data) != RegistrationRequestSize {
+		return fmt.Errorf("%w: invalid RegistrationRequest length", ErrDeserialize)
+	}
+	req.BlindedMessage = append([]byte{}, data...)
+	return nil
+}
+
+// NewRegistrationRequestFromBytes constructs a RegistrationRequest
+// from its binary representation.
+func NewRegistrationRequestFromBytes(data []byte) (*RegistrationRequest, error) {
+	var req RegistrationRequest
+	if err := req.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// RegistrationResponse is the server's registration response.
+type RegistrationResponse struct {
+	EvaluatedMessage []byte
+	ServerPublicKey  []byte
+}
+
+// MarshalBinary encodes a RegistrationResponse into binary form.
+func (resp *RegistrationResponse) MarshalBinary() ([]byte, error) {
+	if len(resp.EvaluatedMessage) != elementSize || len(resp.ServerPublicKey) != PublicKeySize {
+		return nil, fmt.Errorf("opaque: invalid RegistrationResponse")
+	}
+	return concat(resp.EvaluatedMessage, resp.ServerPublicKey), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled RegistrationResponse.
+func (resp *RegistrationResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != RegistrationResponseSize {
+		return fmt.Errorf("%w: invalid RegistrationResponse length", ErrDeserialize)
+	}
+	resp.EvaluatedMessage = append([]byte{}, data[:elementSize]...)
+	resp.ServerPublicKey = append([]byte{}, data[elementSize:]...)
+	return nil
+}
+
+// NewRegistrationResponseFromBytes constructs a RegistrationResponse
+// from its binary representation.
+func NewRegistrationResponseFromBytes(data []byte) (*RegistrationResponse, error) {
+	var resp RegistrationResponse
+	if err := resp.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RegistrationRecord is the client's registration record, which is
+// stored by the server.
+type RegistrationRecord struct {
+	ClientPublicKey []byte
+	MaskingKey      []byte
+	Envelope        Envelope
+}
+
+// MarshalBinary encodes a RegistrationRecord into binary form.
+func (rec *RegistrationRecord) MarshalBinary() ([]byte, error) {
+	if len(rec.ClientPublicKey) != PublicKeySize || len(rec.MaskingKey) != hashSize {
+		return nil, fmt.Errorf("opaque: invalid RegistrationRecord")
+	}
+	return concat(rec.ClientPublicKey, rec.MaskingKey, rec.Envelope.bytes()), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled RegistrationRecord.
+func (rec *RegistrationRecord) UnmarshalBinary(data []byte) error {
+	if len(data) != RegistrationRecordSize {
+		return fmt.Errorf("%w: invalid RegistrationRecord length", ErrDeserialize)
+	}
+	rec.ClientPublicKey = append([]byte{}, data[:PublicKeySize]...)
+	data = data[PublicKeySize:]
+	rec.MaskingKey = append([]byte{}, data[:hashSize]...)
+	rec.Envelope.setBytes(data[hashSize:])
+	return nil
+}
+
+// NewRegistrationRecordFromBytes constructs a RegistrationRecord from
+// its binary representation.
+func NewRegistrationRecordFromBytes(data []byte) (*RegistrationRecord, error) {
+	var rec RegistrationRecord
+	if err := rec.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// KE1 is the client's first login message.
+type KE1 struct {
+	BlindedMessage       []byte
+	ClientNonce          [NonceSize]byte
+	ClientPublicKeyshare []byte
+}
+
+// MarshalBinary encodes a KE1 into binary form.
+func (ke1 *KE1) MarshalBinary() ([]byte, error) {
+	if len(ke1.BlindedMessage) != elementSize || len(ke1.ClientPublicKeyshare) != PublicKeySize {
+		return nil, fmt.Errorf("opaque: invalid KE1")
+	}
+	return concat(ke1.BlindedMessage, ke1.ClientNonce[:], ke1.ClientPublicKeyshare), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled KE1.
+func (ke1 *KE1) UnmarshalBinary(data []byte) error {
+	if len(data) != KE1Size {
+		return fmt.Errorf("%w: invalid KE1 length", ErrDeserialize)
+	}
+	ke1.BlindedMessage = append([]byte{}, data[:elementSize]...)
+	data = data[elementSize:]
+	copy(ke1.ClientNonce[:], data[:NonceSize])
+	ke1.ClientPublicKeyshare = append([]byte{}, data[NonceSize:]...)
+	return nil
+}
+
+// NewKE1FromBytes constructs a KE1 from its binary representation.
+func NewKE1FromBytes(data []byte) (*KE1, error) {
+	var ke1 KE1
+	if err := ke1.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &ke1, nil
+}
+
+// KE2 is the server's login message.
+type KE2 struct {
+	EvaluatedMessage     []byte
+	MaskingNonce         [NonceSize]byte
+	MaskedResponse       [maskedResponseSize]byte
+	ServerNonce          [NonceSize]byte
+	ServerPublicKeyshare []byte
+	ServerMAC            [MACSize]byte
+}
+
+func (ke2 *KE2) credentialResponseBytes() []byte {
+	return concat(ke2.EvaluatedMessage, ke2.MaskingNonce[:], ke2.MaskedResponse[:])
+}
+
+// MarshalBinary encodes a KE2 into binary form.
+func (ke2 *KE2) MarshalBinary() ([]byte, error) {
+	if len(ke2.EvaluatedMessage) != elementSize || len(ke2.ServerPublicKeyshare) != PublicKeySize {
+		return nil, fmt.Errorf("opaque: invalid KE2")
+	}
+	return concat(
+		ke2.credentialResponseBytes(),
+		ke2.ServerNonce[:],
+		ke2.ServerPublicKeyshare,
+		ke2.ServerMAC[:],
+	), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled KE2.
+func (ke2 *KE2) UnmarshalBinary(data []byte) error {
+	if len(data) != KE2Size {
+		return fmt.Errorf("%w: invalid KE2 length", ErrDeserialize)
+	}
+	ke2.EvaluatedMessage = append([]byte{}, data[:elementSize]...)
+	data = data[elementSize:]
+	copy(ke2.MaskingNonce[:], data[:NonceSize])
+	data = data[NonceSize:]
+	copy(ke2.MaskedResponse[:], data[:maskedResponseSize])
+	data = data[maskedResponseSize:]
+	copy(ke2.ServerNonce[:], data[:NonceSize])
+	data = data[NonceSize:]
+	ke2.ServerPublicKeyshare = append([]byte{}, data[:PublicKeySize]...)
+	copy(ke2.ServerMAC[:], data[PublicKeySize:])
+	return nil
+}
+
+// NewKE2FromBytes constructs a KE2 from its binary representation.
+func NewKE2FromBytes(data []byte) (*KE2, error) {
+	var ke2 KE2
+	if err := ke2.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &ke2, nil
+}
+
+// KE3 is the client's final login message.
+type KE3 struct {
+	ClientMAC [MACSize]byte
+}
+
+// MarshalBinary encodes a KE3 into binary form.
+func (ke3 *KE3) MarshalBinary() ([]byte, error) {
+	return append([]byte{}, ke3.ClientMAC[:]...), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled KE3.
+func (ke3 *KE3) UnmarshalBinary(data []byte) error {
+	if len(data) != KE3Size {
+		return fmt.Errorf("%w: invalid KE3 length", ErrDeserialize)
+	}
+	copy(ke3.ClientMAC[:], data)
+	return nil
+}
+
+// NewKE3FromBytes constructs a KE3 from its binary representation.
+func NewKE3FromBytes(data []byte) (*KE3, error) {
+	var ke3 KE3
+	if err := ke3.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &ke3, nil
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package opaque implements the OPAQUE-3DH augmented password-authenticated
+// key exchange protocol as specified in RFC 9807, with the
+// ristretto255-SHA512 OPRF, HKDF-SHA512, HMAC-SHA512, SHA-512 and
+// ristretto255 configuration.
+//
+// The server never learns the client's password, and a compromise of
+// the server's stored registration records only permits an offline
+// dictionary attack after the OPRF key (derived from the server's OPRF
+// seed) is also compromised.
+package opaque
+
+import (
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+const (
+	// NonceSize is the size of the nonces in bytes (Nn).
+	NonceSize = 32
+
+	// SeedSize is the size of a key derivation seed in bytes (Nseed).
+	SeedSize = 32
+
+	// OPRFSeedSize is the size of the server's OPRF seed in bytes.
+	OPRFSeedSize = hashSize
+
+	// MACSize is the size of a MAC in bytes (Nm).
+	MACSize = hashSize
+
+	// SessionKeySize is the size of the session key in bytes (Nx).
+	SessionKeySize = hashSize
+
+	// ExportKeySize is the size of the export key in bytes (Nh).
+	ExportKeySize = hashSize
+
+	// EnvelopeSize is the size of an Envelope in bytes.
+	EnvelopeSize = NonceSize + MACSize
+
+	// RegistrationRequestSize is the size of a serialized
+	// RegistrationRequest in bytes.
+	RegistrationRequestSize = elementSize
+
+	// RegistrationResponseSize is the size of a serialized
+	// RegistrationResponse in bytes.
+	RegistrationResponseSize = elementSize + PublicKeySize
+
+	// RegistrationRecordSize is the size of a serialized
+	// RegistrationRecord in bytes.
+	RegistrationRecordSize = PublicKeySize + hashSize + EnvelopeSize
+
+	// KE1Size is the size of a serialized KE1 in bytes.
+	KE1Size = elementSize + NonceSize + PublicKeySize
+
+	// KE2Size is the size of a serialized KE2 in bytes.
+	KE2Size = credentialResponseSize + NonceSize + PublicKeySize + MACSize
+
+	// KE3Size is the size of a serialized KE3 in bytes.
+	KE3Size = MACSize
+
+	hashSize               = sha512.Size
+	elementSize            = 32
+	maskedResponseSize     = PublicKeySize + EnvelopeSize
+	credentialResponseSize = elementSize + NonceSize + maskedResponseSize
+
+	maxIdentitySize = 65535
+
+	labelOprfKey               = "OprfKey"
+	labelDeriveKeyPair         = "OPAQUE-DeriveKeyPair"
+	labelDeriveDHKeyPair       = "OPAQUE-DeriveDiffieHellmanKeyPair"
+	labelMaskingKey            = "MaskingKey"
+	labelAuthKey               = "AuthKey"
+	labelExportKey             = "ExportKey"
+	labelPrivateKey            = "PrivateKey"
+	labelCredentialResponsePad = "CredentialResponsePad"
+	labelPreamble              = "OPAQUEv1-"
+	labelExpandLabelPrefix     = "OPAQUE-"
+	labelHandshakeSecret       = "HandshakeSecret"
+	labelSessionKey            = "SessionKey"
+	labelServerMAC             = "ServerMAC"
+	labelClientMAC             = "ClientMAC"
+)
+
+var (
+	// ErrEnvelopeRecovery is the error returned when the client fails
+	// to recover its credentials, typically due to an incorrect password.
+	ErrEnvelopeRecovery = fmt.Errorf("opaque: envelope recovery failed")
+
+	// ErrServerAuthentication is the error returned when the client
+	// fails to authenticate the server.
+	ErrServerAuthentication = fmt.Errorf("opaque: server authentication failed")
+
+	// ErrClientAuthentication is the error returned when the server
+	// fails to authenticate the client.
+	ErrClientAuthentication = fmt.Errorf("opaque: client authentication failed")
+
+	// ErrDeserialize is the error returned when a message, key, or
+	// group element fails to deserialize.
+	ErrDeserialize = fmt.Errorf("opaque: failed to deserialize")
+)
+
+// Config is an OPAQUE configuration, which must match between the
+// client and the server.
+type Config struct {
+	// Context is the application-specific context that is bound into
+	// the handshake transcript.
+	Context []byte
+
+	// KSF is the key stretching function applied to the OPRF output.
+	KSF KSF
+}
+
+func (cfg *Config) validate() error {
+	if cfg == nil {
+		return fmt.Errorf("opaque: missing configuration")
+	}
+	if cfg.KSF == nil {
+		return fmt.Errorf("opaque: missing key stretching function")
+	}
+	if len(cfg.Context) > maxIdentitySize {
+		return fmt.Errorf("opaque: context too long")
+	}
+	return nil
+}
+
+// Identities are the optional client and server identities bound into
+// the handshake.  If an identity is nil, the corresponding public key
+// is used in its place.
+type Identities struct {
+	Client []byte
+	Server []byte
+}
+
+func (ids *Identities) get() ([]byte, []byte, error) {
+	if ids == nil {
+		return nil, nil, nil
+	}
+	if len(ids.Client) > maxIdentitySize || len(ids.Server) > maxIdentitySize {
+		return nil, nil, fmt.Errorf("opaque: identity too long")
+	}
+	return ids.Client, ids.Server, nil
+}
+
+func extract(salt, ikm []byte) []byte {
+	return hkdf.Extract(sha512.New, ikm, salt)
+}
+
+func expand(prk []byte, info []byte, l int) []byte {
+	out := make([]byte, l)
+	if _, err := hkdf.Expand(sha512.New, prk, info).Read(out); err != nil {
+		panic("opaque: failed to expand: " + err.Error())
+	}
+	return out
+}
+
+func expandLabel(secret []byte, label string, context []byte, l int) []byte {
+	fullLabel := labelExpandLabelPrefix + label
+	info := make([]byte, 0, 2+1+len(fullLabel)+1+len(context))
+	info = append(info, i2osp2(l)...)
+	info = append(info, byte(len(fullLabel)))
+	info = append(info, fullLabel...)
+	info = append(info, byte(len(context)))
+	info = append(info, context...)
+	return expand(secret, info, l)
+}
+
+func deriveSecret(secret []byte, label string, transcriptHash []byte) []byte {
+	return expandLabel(secret, label, transcriptHash, hashSize)
+}
+
+func mac(key []byte, msg ...[]byte) []byte {
+	h := hmac.New(sha512.New, key)
+	for _, v := range msg {
+		_, _ = h.Write(v)
+	}
+	return h.Sum(nil)
+}
+
+func macEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+func i2osp2(n int) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(n))
+	return b[:]
+}
+
+func concat(parts ...[]byte) []byte {
+	var l int
+	for _, v := range parts {
+		l += len(v)
+	}
+	out := make([]byte, 0, l)
+	for _, v := range parts {
+		out = append(out, v...)
+	}
+	return out
+}
+
+func xor(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+func readRandom(rand io.Reader, b []byte) error {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return fmt.Errorf("opaque: failed to read random data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,486 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+type testVector struct {
+	name string
+
+	context              string
+	oprfSeed             string
+	credentialIdentifier string
+	password             string
+	envelopeNonce        string
+	maskingNonce         string
+	serverPrivateKey     string
+	serverPublicKey      string
+	serverNonce          string
+	clientNonce          string
+	clientKeyshareSeed   string
+	serverKeyshareSeed   string
+	blindRegistration    string
+	blindLogin           string
+
+	registrationRequest  string
+	registrationResponse string
+	registrationUpload   string
+	ke1                  string
+	ke2                  string
+	ke3                  string
+	exportKey            string
+	sessionKey           string
+}
+
+var testVectors = []testVector{
+	// RFC 9807 Appendix C.1.1, "OPAQUE-3DH Real Test Vector 1".
+	{
+		name:                 "RealTestVector1",
+		context:              "4f50415155452d504f43",
+		oprfSeed:             "f433d0227b0b9dd54f7c4422b600e764e47fb503f1f9a0f0a47c6606b054a7fdc65347f1a08f277e22358bbabe26f823fca82c7848e9a75661f4ec5d5c1989ef",
+		credentialIdentifier: "31323334",
+		password:             "436f7272656374486f72736542617474657279537461706c65",
+		envelopeNonce:        "ac13171b2f17bc2c74997f0fce1e1f35bec6b91fe2e12dbd323d23ba7a38dfec",
+		maskingNonce:         "38fe59af0df2c79f57b8780278f5ae47355fe1f817119041951c80f612fdfc6d",
+		serverPrivateKey:     "47451a85372f8b3537e249d7b54188091fb18edde78094b43e2ba42b5eb89f0d",
+		serverPublicKey:      "b2fe7af9f48cc502d016729d2fe25cdd433f2c4bc904660b2a382c9b79df1a78",
+		serverNonce:          "71cd9960ecef2fe0d0f7494986fa3d8b2bb01963537e60efb13981e138e3d4a1",
+		clientNonce:          "da7e07376d6d6f034cfa9bb537d11b8c6b4238c334333d1f0aebb380cae6a6cc",
+		clientKeyshareSeed:   "82850a697b42a505f5b68fcdafce8c31f0af2b581f063cf1091933541936304b",
+		serverKeyshareSeed:   "05a4f54206eef1ba2f615bc0aa285cb22f26d1153b5b40a1e85ff80da12f982f",
+		blindRegistration:    "76cfbfe758db884bebb33582331ba9f159720ca8784a2a070a265d9c2d6abe01",
+		blindLogin:           "6ecc102d2e7a7cf49617aad7bbe188556792d4acd60a1a8a8d2b65d4b0790308",
+
+		registrationRequest:  "5059ff249eb1551b7ce4991f3336205bde44a105a032e747d21bf382e75f7a71",
+		registrationResponse: "7408a268083e03abc7097fc05b587834539065e86fb0c7b6342fcf5e01e5b019b2fe7af9f48cc502d016729d2fe25cdd433f2c4bc904660b2a382c9b79df1a78",
+		registrationUpload:   "76a845464c68a5d2f7e442436bb1424953b17d3e2e289ccbaccafb57ac5c36751ac5844383c7708077dea41cbefe2fa15724f449e535dd7dd562e66f5ecfb95864eadddec9db5874959905117dad40a4524111849799281fefe3c51fa82785c5ac13171b2f17bc2c74997f0fce1e1f35bec6b91fe2e12dbd323d23ba7a38dfec634b0f5b96109c198a8027da51854c35bee90d1e1c781806d07d49b76de6a28b8d9e9b6c93b9f8b64d16dddd9c5bfb5fea48ee8fd2f75012a8b308605cdd8ba5",
+		ke1:                  "c4dedb0ba6ed5d965d6f250fbe554cd45cba5dfcce3ce836e4aee778aa3cd44dda7e07376d6d6f034cfa9bb537d11b8c6b4238c334333d1f0aebb380cae6a6cc6e29bee50701498605b2c085d7b241ca15ba5c32027dd21ba420b94ce60da326",
+		ke2:                  "7e308140890bcde30cbcea28b01ea1ecfbd077cff62c4def8efa075aabcbb47138fe59af0df2c79f57b8780278f5ae47355fe1f817119041951c80f612fdfc6dd6ec60bcdb26dc455ddf3e718f1020490c192d70dfc7e403981179d8073d1146a4f9aa1ced4e4cd984c657eb3b54ced3848326f70331953d91b02535af44d9fedc80188ca46743c52786e0382f95ad85c08f6afcd1ccfbff95e2bdeb015b166c6b20b92f832cc6df01e0b86a7efd92c1c804ff865781fa93f2f20b446c8371b671cd9960ecef2fe0d0f7494986fa3d8b2bb01963537e60efb13981e138e3d4a1c4f62198a9d6fa9170c42c3c71f1971b29eb1d5d0bd733e40816c91f7912cc4a660c48dae03e57aaa38f3d0cffcfc21852ebc8b405d15bd6744945ba1a93438a162b6111699d98a16bb55b7bdddfe0fc5608b23da246e7bd73b47369169c5c90",
+		ke3:                  "4455df4f810ac31a6748835888564b536e6da5d9944dfea9e34defb9575fe5e2661ef61d2ae3929bcf57e53d464113d364365eb7d1a57b629707ca48da18e442",
+		exportKey:            "1ef15b4fa99e8a852412450ab78713aad30d21fa6966c9b8c9fb3262a970dc62950d4dd4ed62598229b1b72794fc0335199d9f7fcc6eaedde92cc04870e63f16",
+		sessionKey:           "42afde6f5aca0cfa5c163763fbad55e73a41db6b41bc87b8e7b62214a8eedc6731fa3cb857d657ab9b3764b89a84e91ebcb4785166fbb02cedfcbdfda215b96f",
+	},
+}
+
+// scalarReader returns the entropy that produces the (canonical) scalar
+// s when read by oprf's 64-byte wide reduction.
+func scalarReader(t *testing.T, s string) []byte {
+	return append(testhelpers.MustUnhex(t, s), make([]byte, 32)...)
+}
+
+func assertHex(t *testing.T, what string, actual []byte, expected string) {
+	if !bytes.Equal(actual, testhelpers.MustUnhex(t, expected)) {
+		t.Fatalf("%s: got %x, expected %s", what, actual, expected)
+	}
+}
+
+func (tv *testVector) run(t *testing.T) {
+	cfg := &Config{
+		Context: testhelpers.MustUnhex(t, tv.context),
+		KSF:     IdentityKSF,
+	}
+	password := testhelpers.MustUnhex(t, tv.password)
+	credentialIdentifier := testhelpers.MustUnhex(t, tv.credentialIdentifier)
+
+	sk, err := NewPrivateKey(testhelpers.MustUnhex(t, tv.serverPrivateKey))
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	pkBytes, _ := sk.Public().MarshalBinary()
+	assertHex(t, "server_public_key", pkBytes, tv.serverPublicKey)
+
+	server, err := NewServer(cfg, sk, testhelpers.MustUnhex(t, tv.oprfSeed))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// Registration.
+	regState, regReq, err := client.CreateRegistrationRequest(
+		bytes.NewReader(scalarReader(t, tv.blindRegistration)),
+		password,
+	)
+	if err != nil {
+		t.Fatalf("CreateRegistrationRequest: %v", err)
+	}
+	b, _ := regReq.MarshalBinary()
+	assertHex(t, "registration_request", b, tv.registrationRequest)
+
+	regResp, err := server.CreateRegistrationResponse(regReq, credentialIdentifier)
+	if err != nil {
+		t.Fatalf("CreateRegistrationResponse: %v", err)
+	}
+	b, _ = regResp.MarshalBinary()
+	assertHex(t, "registration_response", b, tv.registrationResponse)
+
+	record, exportKey, err := client.FinalizeRegistrationRequest(
+		bytes.NewReader(testhelpers.MustUnhex(t, tv.envelopeNonce)),
+		regState,
+		regResp,
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("FinalizeRegistrationRequest: %v", err)
+	}
+	b, _ = record.MarshalBinary()
+	assertHex(t, "registration_upload", b, tv.registrationUpload)
+	assertHex(t, "export_key (registration)", exportKey, tv.exportKey)
+
+	// Login.
+	loginState, ke1, err := client.GenerateKE1(
+		bytes.NewReader(concat(
+			scalarReader(t, tv.blindLogin),
+			testhelpers.MustUnhex(t, tv.clientNonce),
+			testhelpers.MustUnhex(t, tv.clientKeyshareSeed),
+		)),
+		password,
+	)
+	if err != nil {
+		t.Fatalf("GenerateKE1: %v", err)
+	}
+	b, _ = ke1.MarshalBinary()
+	assertHex(t, "KE1", b, tv.ke1)
+
+	serverState, ke2, err := server.GenerateKE2(
+		bytes.NewReader(concat(
+			testhelpers.MustUnhex(t, tv.maskingNonce),
+			testhelpers.MustUnhex(t, tv.serverNonce),
+			testhelpers.MustUnhex(t, tv.serverKeyshareSeed),
+		)),
+		record,
+		credentialIdentifier,
+		ke1,
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("GenerateKE2: %v", err)
+	}
+	b, _ = ke2.MarshalBinary()
+	assertHex(t, "KE2", b, tv.ke2)
+
+	ke3, sessionKey, exportKey, err := client.GenerateKE3(loginState, ke2, nil)
+	if err != nil {
+		t.Fatalf("GenerateKE3: %v", err)
+	}
+	b, _ = ke3.MarshalBinary()
+	assertHex(t, "KE3", b, tv.ke3)
+	assertHex(t, "session_key (client)", sessionKey, tv.sessionKey)
+	assertHex(t, "export_key (login)", exportKey, tv.exportKey)
+
+	sessionKey, err = server.Finish(serverState, ke3)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	assertHex(t, "session_key (server)", sessionKey, tv.sessionKey)
+}
+
+func TestVectors(t *testing.T) {
+	for i := range testVectors {
+		tv := &testVectors[i]
+		t.Run(tv.name, tv.run)
+	}
+}
+
+type testSession struct {
+	cfg    *Config
+	client *Client
+	server *Server
+}
+
+func newTestSession(t *testing.T) *testSession {
+	cfg := &Config{
+		Context: []byte("curve25519-voi/opaque: test"),
+		KSF:     &ScryptKSF{N: 16, R: 8, P: 1},
+	}
+
+	sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	var oprfSeed [OPRFSeedSize]byte
+	if _, err = rand.Read(oprfSeed[:]); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	server, err := NewServer(cfg, sk, oprfSeed[:])
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	return &testSession{
+		cfg:    cfg,
+		client: client,
+		server: server,
+	}
+}
+
+func (s *testSession) register(t *testing.T, password, credentialIdentifier []byte, ids *Identities) (*RegistrationRecord, []byte) {
+	regState, regReq, err := s.client.CreateRegistrationRequest(nil, password)
+	if err != nil {
+		t.Fatalf("CreateRegistrationRequest: %v", err)
+	}
+	regResp, err := s.server.CreateRegistrationResponse(regReq, credentialIdentifier)
+	if err != nil {
+		t.Fatalf("CreateRegistrationResponse: %v", err)
+	}
+	record, exportKey, err := s.client.FinalizeRegistrationRequest(nil, regState, regResp, ids)
+	if err != nil {
+		t.Fatalf("FinalizeRegistrationRequest: %v", err)
+	}
+
+	// Round-trip the record through its serialized form, as a server
+	// would store it.
+	b, err := record.MarshalBinary()
+	if err != nil {
+		t.Fatalf("RegistrationRecord.MarshalBinary: %v", err)
+	}
+	if record, err = NewRegistrationRecordFromBytes(b); err != nil {
+		t.Fatalf("NewRegistrationRecordFromBytes: %v", err)
+	}
+
+	return record, exportKey
+}
+
+func (s *testSession) login(t *testing.T, password, credentialIdentifier []byte, record *RegistrationRecord, clientIds, serverIds *Identities) (*ServerLoginState, *KE2, []byte, []byte, error) {
+	loginState, ke1, err := s.client.GenerateKE1(nil, password)
+	if err != nil {
+		t.Fatalf("GenerateKE1: %v", err)
+	}
+	b, _ := ke1.MarshalBinary()
+	if ke1, err = NewKE1FromBytes(b); err != nil {
+		t.Fatalf("NewKE1FromBytes: %v", err)
+	}
+
+	serverState, ke2, err := s.server.GenerateKE2(nil, record, credentialIdentifier, ke1, serverIds)
+	if err != nil {
+		t.Fatalf("GenerateKE2: %v", err)
+	}
+	b, _ = ke2.MarshalBinary()
+	if ke2, err = NewKE2FromBytes(b); err != nil {
+		t.Fatalf("NewKE2FromBytes: %v", err)
+	}
+
+	ke3, sessionKey, exportKey, err := s.client.GenerateKE3(loginState, ke2, clientIds)
+	if err != nil {
+		return serverState, ke2, nil, nil, err
+	}
+	b, _ = ke3.MarshalBinary()
+	if ke3, err = NewKE3FromBytes(b); err != nil {
+		t.Fatalf("NewKE3FromBytes: %v", err)
+	}
+
+	serverSessionKey, err := s.server.Finish(serverState, ke3)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if !bytes.Equal(sessionKey, serverSessionKey) {
+		t.Fatalf("session key mismatch")
+	}
+
+	return serverState, ke2, sessionKey, exportKey, nil
+}
+
+func TestOPAQUE(t *testing.T) {
+	s := newTestSession(t)
+
+	password := []byte("correct horse battery staple")
+	credentialIdentifier := []byte("alice@example.com")
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		record, regExportKey := s.register(t, password, credentialIdentifier, nil)
+
+		_, _, sessionKey, exportKey, err := s.login(t, password, credentialIdentifier, record, nil, nil)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		if !bytes.Equal(exportKey, regExportKey) {
+			t.Fatalf("export key mismatch")
+		}
+
+		_, _, sessionKey2, _, err := s.login(t, password, credentialIdentifier, record, nil, nil)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		if bytes.Equal(sessionKey, sessionKey2) {
+			t.Fatalf("session keys are not fresh")
+		}
+	})
+
+	t.Run("Identities", func(t *testing.T) {
+		ids := &Identities{
+			Client: []byte("alice"),
+			Server: []byte("bob"),
+		}
+		record, _ := s.register(t, password, credentialIdentifier, ids)
+
+		if _, _, _, _, err := s.login(t, password, credentialIdentifier, record, ids, ids); err != nil {
+			t.Fatalf("login: %v", err)
+		}
+
+		_, _, _, _, err := s.login(t, password, credentialIdentifier, record, &Identities{Client: []byte("alice")}, ids)
+		if !errors.Is(err, ErrEnvelopeRecovery) {
+			t.Fatalf("login(mismatched identities): expected ErrEnvelopeRecovery, got %v", err)
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		record, _ := s.register(t, password, credentialIdentifier, nil)
+
+		_, _, _, _, err := s.login(t, []byte("Tr0ub4dor&3"), credentialIdentifier, record, nil, nil)
+		if !errors.Is(err, ErrEnvelopeRecovery) {
+			t.Fatalf("login(wrong password): expected ErrEnvelopeRecovery, got %v", err)
+		}
+
+		// The OPRF key is bound to the credential identifier.
+		_, _, _, _, err = s.login(t, password, []byte("mallory@example.com"), record, nil, nil)
+		if !errors.Is(err, ErrEnvelopeRecovery) {
+			t.Fatalf("login(wrong credential identifier): expected ErrEnvelopeRecovery, got %v", err)
+		}
+	})
+
+	t.Run("FakeRecord", func(t *testing.T) {
+		record, err := GenerateFakeRecord(nil)
+		if err != nil {
+			t.Fatalf("GenerateFakeRecord: %v", err)
+		}
+
+		_, _, _, _, err = s.login(t, password, credentialIdentifier, record, nil, nil)
+		if !errors.Is(err, ErrEnvelopeRecovery) {
+			t.Fatalf("login(fake record): expected ErrEnvelopeRecovery, got %v", err)
+		}
+	})
+
+	t.Run("BadServerMAC", func(t *testing.T) {
+		record, _ := s.register(t, password, credentialIdentifier, nil)
+
+		loginState, ke1, err := s.client.GenerateKE1(nil, password)
+		if err != nil {
+			t.Fatalf("GenerateKE1: %v", err)
+		}
+		_, ke2, err := s.server.GenerateKE2(nil, record, credentialIdentifier, ke1, nil)
+		if err != nil {
+			t.Fatalf("GenerateKE2: %v", err)
+		}
+		ke2.ServerMAC[0] ^= 0x01
+		if _, _, _, err = s.client.GenerateKE3(loginState, ke2, nil); !errors.Is(err, ErrServerAuthentication) {
+			t.Fatalf("GenerateKE3: expected ErrServerAuthentication, got %v", err)
+		}
+	})
+
+	t.Run("BadClientMAC", func(t *testing.T) {
+		record, _ := s.register(t, password, credentialIdentifier, nil)
+
+		loginState, ke1, err := s.client.GenerateKE1(nil, password)
+		if err != nil {
+			t.Fatalf("GenerateKE1: %v", err)
+		}
+		serverState, ke2, err := s.server.GenerateKE2(nil, record, credentialIdentifier, ke1, nil)
+		if err != nil {
+			t.Fatalf("GenerateKE2: %v", err)
+		}
+		ke3, _, _, err := s.client.GenerateKE3(loginState, ke2, nil)
+		if err != nil {
+			t.Fatalf("GenerateKE3: %v", err)
+		}
+		ke3.ClientMAC[0] ^= 0x01
+		if _, err = s.server.Finish(serverState, ke3); !errors.Is(err, ErrClientAuthentication) {
+			t.Fatalf("Finish: expected ErrClientAuthentication, got %v", err)
+		}
+	})
+}
+
+func TestMalformed(t *testing.T) {
+	s := newTestSession(t)
+
+	t.Run("Config", func(t *testing.T) {
+		if _, err := NewClient(&Config{}); err == nil {
+			t.Fatalf("NewClient: accepted missing KSF")
+		}
+		if _, err := NewServer(s.cfg, s.server.sk, make([]byte, OPRFSeedSize-1)); err == nil {
+			t.Fatalf("NewServer: accepted short OPRF seed")
+		}
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		if _, err := NewRegistrationRequestFromBytes(make([]byte, RegistrationRequestSize+1)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewRegistrationRequestFromBytes: expected ErrDeserialize, got %v", err)
+		}
+		if _, err := NewRegistrationResponseFromBytes(make([]byte, RegistrationResponseSize-1)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewRegistrationResponseFromBytes: expected ErrDeserialize, got %v", err)
+		}
+		if _, err := NewRegistrationRecordFromBytes(nil); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewRegistrationRecordFromBytes: expected ErrDeserialize, got %v", err)
+		}
+		if _, err := NewKE1FromBytes(make([]byte, KE1Size-1)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewKE1FromBytes: expected ErrDeserialize, got %v", err)
+		}
+		if _, err := NewKE2FromBytes(make([]byte, KE2Size+1)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewKE2FromBytes: expected ErrDeserialize, got %v", err)
+		}
+		if _, err := NewKE3FromBytes(make([]byte, KE3Size-1)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewKE3FromBytes: expected ErrDeserialize, got %v", err)
+		}
+	})
+
+	t.Run("IdentityElements", func(t *testing.T) {
+		identity := make([]byte, 32)
+
+		req := &RegistrationRequest{BlindedMessage: identity}
+		if _, err := s.server.CreateRegistrationResponse(req, []byte("id")); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("CreateRegistrationResponse: expected ErrDeserialize, got %v", err)
+		}
+
+		record, err := GenerateFakeRecord(nil)
+		if err != nil {
+			t.Fatalf("GenerateFakeRecord: %v", err)
+		}
+		_, ke1, err := s.client.GenerateKE1(nil, []byte("password"))
+		if err != nil {
+			t.Fatalf("GenerateKE1: %v", err)
+		}
+		ke1.ClientPublicKeyshare = identity
+		if _, _, err = s.server.GenerateKE2(nil, record, []byte("id"), ke1, nil); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("GenerateKE2: expected ErrDeserialize, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package opaque
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/oprf"
+)
+
+// Server is an OPAQUE server.
+type Server struct {
+	cfg      *Config
+	sk       *PrivateKey
+	oprfSeed []byte
+}
+
+// ServerLoginState is the server's state between generating a KE2 and
+// receiving the client's KE3.
+type ServerLoginState struct {
+	expectedClientMAC []byte
+	sessionKey        []byte
+}
+
+// NewServer creates a new server with the configuration, long-term
+// private key, and OPRFSeedSize-byte OPRF seed.  The private key and
+// OPRF seed must remain the same for all clients.
+func NewServer(cfg *Config, sk *PrivateKey, oprfSeed []byte) (*Server, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if sk == nil {
+		return nil, fmt.Errorf("opaque: missing private key")
+	}
+	if len(oprfSeed) != OPRFSeedSize {
+		return nil, fmt.Errorf("opaque: invalid OPRF seed length")
+	}
+	return &Server{
+		cfg:      cfg,
+		sk:       sk,
+		oprfSeed: append([]byte{}, oprfSeed...),
+	}, nil
+}
+
+// PublicKey returns the server's long-term public key.
+func (s *Server) PublicKey() *PublicKey {
+	return s.sk.Public()
+}
+
+func (s *Server) evaluate(blindedMessage, credentialIdentifier []byte) ([]byte, error) {
+	seed := expand(s.oprfSeed, concat(credentialIdentifier, []byte(labelOprfKey)), SeedSize)
+	oprfKey, err := oprf.Ristretto255SHA512.DeriveKey(oprf.ModeOPRF, seed, []byte(labelDeriveKeyPair))
+	if err != nil {
+		return nil, fmt.Errorf("opaque: failed to derive OPRF key: %w", err)
+	}
+	oprfServer, err := oprf.Ristretto255SHA512.NewServer(oprf.ModeOPRF, oprfKey)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := oprfServer.BlindEvaluate(nil, &oprf.EvaluationRequest{
+		BlindedElements: [][]byte{blindedMessage},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: blinded message: %v", ErrDeserialize, err)
+	}
+	return eval.EvaluatedElements[0], nil
+}
+
+// CreateRegistrationResponse responds to a client's registration
+// request for the credential identifier, which uniquely identifies
+// the client's record.
+func (s *Server) CreateRegistrationResponse(req *RegistrationRequest, credentialIdentifier []byte) (*RegistrationResponse, error) {
+	evaluatedMessage, err := s.evaluate(req.BlindedMessage, credentialIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistrationResponse{
+		EvaluatedMessage: evaluatedMessage,
+		ServerPublicKey:  s.sk.Public().bytes(),
+	}, nil
+}
+
+// GenerateKE2 responds to a client's KE1 for the registration record
+// and credential identifier, using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// If no record exists for the credential identifier, the server should
+// use a record from GenerateFakeRecord, to avoid revealing which
+// clients are registered.
+func (s *Server) GenerateKE2(rand io.Reader, record *RegistrationRecord, credentialIdentifier []byte, ke1 *KE1, ids *Identities) (*ServerLoginState, *KE2, error) {
+	clientIdentity, serverIdentity, err := ids.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	ke1Bytes, err := ke1.MarshalBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	clientPublicKeyshare, err := NewPublicKey(ke1.ClientPublicKeyshare)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientPublicKey, err := NewPublicKey(record.ClientPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(record.MaskingKey) != hashSize {
+		return nil, nil, fmt.Errorf("%w: invalid masking key", ErrDeserialize)
+	}
+
+	// Create the credential response.
+	evaluatedMessage, err := s.evaluate(ke1.BlindedMessage, credentialIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	ke2 := &KE2{
+		EvaluatedMessage: evaluatedMessage,
+	}
+	if err = readRandom(rand, ke2.MaskingNonce[:]); err != nil {
+		return nil, nil, err
+	}
+	pad := credentialResponsePad(record.MaskingKey, ke2.MaskingNonce[:])
+	xor(ke2.MaskedResponse[:], pad, concat(s.sk.Public().bytes(), record.Envelope.bytes()))
+
+	// Respond to the client's authentication request.
+	if err = readRandom(rand, ke2.ServerNonce[:]); err != nil {
+		return nil, nil, err
+	}
+	var seed [SeedSize]byte
+	if err = readRandom(rand, seed[:]); err != nil {
+		return nil, nil, err
+	}
+	serverPrivateKeyshare, err := DeriveKey(seed[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	ke2.ServerPublicKeyshare = serverPrivateKeyshare.Public().bytes()
+
+	_, serverIdentity, clientIdentity = cleartextCredentials(s.sk.Public().bytes(), record.ClientPublicKey, serverIdentity, clientIdentity)
+	ikm := concat(
+		diffieHellman(serverPrivateKeyshare, clientPublicKeyshare),
+		diffieHellman(s.sk, clientPublicKeyshare),
+		diffieHellman(serverPrivateKeyshare, clientPublicKey),
+	)
+	preamble := s.cfg.preamble(
+		clientIdentity,
+		ke1Bytes,
+		serverIdentity,
+		ke2.credentialResponseBytes(),
+		ke2.ServerNonce[:],
+		ke2.ServerPublicKeyshare,
+	)
+	keys, serverMAC, clientMAC := authenticate(ikm, preamble)
+	copy(ke2.ServerMAC[:], serverMAC)
+
+	st := &ServerLoginState{
+		expectedClientMAC: clientMAC,
+		sessionKey:        keys.sessionKey,
+	}
+
+	return st, ke2, nil
+}
+
+// Finish authenticates the client's KE3, and returns the session key.
+func (s *Server) Finish(st *ServerLoginState, ke3 *KE3) ([]byte, error) {
+	if !macEqual(st.expectedClientMAC, ke3.ClientMAC[:]) {
+		return nil, ErrClientAuthentication
+	}
+	return st.sessionKey, nil
+}
+
+// GenerateFakeRecord generates a fake registration record, for use by
+// the server in GenerateKE2 when a client is not registered, using
+// entropy from rand.  If rand is nil, crypto/rand.Reader will be used.
+func GenerateFakeRecord(rand io.Reader) (*RegistrationRecord, error) {
+	sk, err := GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	rec := &RegistrationRecord{
+		ClientPublicKey: sk.Public().bytes(),
+		MaskingKey:      make([]byte, hashSize),
+	}
+	if err = readRandom(rand, rec.MaskingKey); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// EvaluationRequest is a client's request for the server to evaluate
+// the PRF over one or more blinded inputs.
+type EvaluationRequest struct {
+	// BlindedElements are the serialized blinded elements.
+	BlindedElements [][]byte
+}
+
+// Evaluation is a server's response to an EvaluationRequest.
+type Evaluation struct {
+	// EvaluatedElements are the serialized evaluated elements.
+	EvaluatedElements [][]byte
+
+	// Proof is the batched DLEQ proof of correct evaluation, which is
+	// only present in the VOPRF and POPRF modes.
+	Proof []byte
+}
+
+// FinalizeData is the client state required to finalize an evaluation.
+type FinalizeData struct {
+	inputs          [][]byte
+	blinds          []*scalar.Scalar
+	blindedElements []element
+	info            []byte
+	tweakedKey      element
+}
+
+// Client is an OPRF client.
+type Client struct {
+	suite *Suite
+	mode  Mode
+	pub   *PublicKey
+}
+
+// Blind blinds the inputs using entropy from rand, and returns the
+// data required to finalize the evaluation, and the request to send
+// to the server.  If rand is nil, crypto/rand.Reader will be used.
+//
+// The public info is only supported in the POPRF mode, and must be
+// nil otherwise.
+func (c *Client) Blind(rand io.Reader, inputs [][]byte, info []byte) (*FinalizeData, *EvaluationRequest, error) {
+	blinds := make([]*scalar.Scalar, 0, len(inputs))
+	for range inputs {
+		blind, err := randomScalar(rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		blinds = append(blinds, blind)
+	}
+	return c.blindWithScalars(inputs, info, blinds)
+}
+
+func (c *Client) blindWithScalars(inputs [][]byte, info []byte, blinds []*scalar.Scalar) (*FinalizeData, *EvaluationRequest, error) {
+	if len(inputs) == 0 || len(inputs) != len(blinds) {
+		return nil, nil, fmt.Errorf("oprf: invalid number of inputs")
+	}
+	if c.mode != ModePOPRF && info != nil {
+		return nil, nil, errInfoInMode
+	}
+	if err := checkInputLength(info); err != nil {
+		return nil, nil, err
+	}
+
+	fd := &FinalizeData{
+		inputs:          make([][]byte, 0, len(inputs)),
+		blinds:          blinds,
+		blindedElements: make([]element, 0, len(inputs)),
+		info:            append([]byte{}, info...),
+	}
+
+	if c.mode == ModePOPRF {
+		m := c.suite.hashToScalar(c.mode, framedInfo(info))
+		fd.tweakedKey = c.suite.g.basepointMul(m).add(c.pub.e)
+		if fd.tweakedKey.isIdentity() {
+			return nil, nil, ErrInvalidInput
+		}
+	}
+
+	req := &EvaluationRequest{
+		BlindedElements: make([][]byte, 0, len(inputs)),
+	}
+	for i, input := range inputs {
+		if err := checkInputLength(input); err != nil {
+			return nil, nil, err
+		}
+
+		inputElement, err := c.suite.hashToGroup(c.mode, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		if inputElement.isIdentity() {
+			return nil, nil, ErrInvalidInput
+		}
+
+		blindedElement := inputElement.mul(blinds[i])
+		fd.inputs = append(fd.inputs, append([]byte{}, input...))
+		fd.blindedElements = append(fd.blindedElements, blindedElement)
+		req.BlindedElements = append(req.BlindedElements, blindedElement.bytes())
+	}
+
+	return fd, req, nil
+}
+
+// Finalize verifies the server's evaluation (in the VOPRF and POPRF
+// modes), unblinds it, and returns the PRF outputs for each input.
+func (c *Client) Finalize(fd *FinalizeData, eval *Evaluation) ([][]byte, error) {
+	n := len(fd.inputs)
+	if len(eval.EvaluatedElements) != n {
+		return nil, fmt.Errorf("oprf: invalid number of evaluated elements")
+	}
+
+	evaluatedElements, err := c.suite.deserializeElements(eval.EvaluatedElements)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.mode {
+	case ModeVOPRF:
+		if err = c.suite.verifyProof(c.mode, c.pub.e, fd.blindedElements, evaluatedElements, eval.Proof); err != nil {
+			return nil, err
+		}
+	case ModePOPRF:
+		if err = c.suite.verifyProof(c.mode, fd.tweakedKey, evaluatedElements, fd.blindedElements, eval.Proof); err != nil {
+			return nil, err
+		}
+	}
+
+	outputs := make([][]byte, 0, n)
+	for i, input := range fd.inputs {
+		blindInv := scalar.New().Invert(fd.blinds[i])
+		unblindedElement := evaluatedElements[i].mul(blindInv)
+		outputs = append(outputs, finalizeHash(input, fd.info, c.mode == ModePOPRF, unblindedElement.bytes()))
+	}
+
+	return outputs, nil
+}
+
+// NewClient creates a new client for the mode.  The server's public
+// key is required in the VOPRF and POPRF modes, and ignored otherwise.
+func (s *Suite) NewClient(mode Mode, pub *PublicKey) (*Client, error) {
+	if !mode.isValid() {
+		return nil, errInvalidMode
+	}
+	if mode != ModeOPRF {
+		if pub == nil {
+			return nil, fmt.Errorf("oprf: public key required in %s mode", mode)
+		}
+		if pub.suite != s {
+			return nil, fmt.Errorf("oprf: public key is for a different suite")
+		}
+	}
+
+	return &Client{
+		suite: s,
+		mode:  mode,
+		pub:   pub,
+	}, nil
+}
+
+func framedInfo(info []byte) []byte {
+	return append([]byte("Info"), lengthPrefixed(info)...)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"crypto/sha512"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// computeComposites implements ComputeComposites, and if k is non-nil,
+// ComputeCompositesFast.
+func (s *Suite) computeComposites(mode Mode, k *scalar.Scalar, B element, C, D []element) (element, element) {
+	seedDST := append([]byte("Seed-"), s.contextString(mode)...)
+	h := sha512.New()
+	writeLengthPrefixed(h, B.bytes())
+	writeLengthPrefixed(h, seedDST)
+	seed := h.Sum(nil)
+
+	M, Z := s.g.identity(), s.g.identity()
+	for i := range C {
+		di := s.hashToScalar(
+			mode,
+			lengthPrefixed(seed),
+			i2osp2(i),
+			lengthPrefixed(C[i].bytes()),
+			lengthPrefixed(D[i].bytes()),
+			[]byte("Composite"),
+		)
+		M = C[i].mul(di).add(M)
+		if k == nil {
+			Z = D[i].mul(di).add(Z)
+		}
+	}
+	if k != nil {
+		Z = M.mul(k)
+	}
+
+	return M, Z
+}
+
+func (s *Suite) challenge(mode Mode, B, M, Z, t2, t3 element) *scalar.Scalar {
+	return s.hashToScalar(
+		mode,
+		lengthPrefixed(B.bytes()),
+		lengthPrefixed(M.bytes()),
+		lengthPrefixed(Z.bytes()),
+		lengthPrefixed(t2.bytes()),
+		lengthPrefixed(t3.bytes()),
+		[]byte("Challenge"),
+	)
+}
+
+// generateProof implements GenerateProof, proving that `B = k * A`
+// and `D[i] = k * C[i]`, with A being the generator.
+func (s *Suite) generateProof(mode Mode, rand io.Reader, k *scalar.Scalar, B element, C, D []element) ([]byte, error) {
+	r, err := randomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	return s.generateProofWithRandomness(mode, r, k, B, C, D), nil
+}
+
+func (s *Suite) generateProofWithRandomness(mode Mode, r, k *scalar.Scalar, B element, C, D []element) []byte {
+	M, Z := s.computeComposites(mode, k, B, C, D)
+
+	t2 := s.g.basepointMul(r)
+	t3 := M.mul(r)
+
+	c := s.challenge(mode, B, M, Z, t2, t3)
+	sc := scalar.New().Mul(c, k)
+	sc.Sub(r, sc)
+
+	proof := make([]byte, ProofSize)
+	_ = c.ToBytes(proof[:ScalarSize])
+	_ = sc.ToBytes(proof[ScalarSize:])
+	return proof
+}
+
+// verifyProof implements VerifyProof.
+func (s *Suite) verifyProof(mode Mode, B element, C, D []element, proof []byte) error {
+	if len(proof) != ProofSize {
+		return ErrVerify
+	}
+	c, err := s.deserializeScalar(proof[:ScalarSize])
+	if err != nil {
+		return ErrVerify
+	}
+	sc, err := s.deserializeScalar(proof[ScalarSize:])
+	if err != nil {
+		return ErrVerify
+	}
+
+	M, Z := s.computeComposites(mode, nil, B, C, D)
+
+	t2 := s.g.basepointMul(sc).add(B.mul(c))
+	t3 := M.mul(sc).add(Z.mul(c))
+
+	expectedC := s.challenge(mode, B, M, Z, t2, t3)
+	if expectedC.Equal(c) != 1 {
+		return ErrVerify
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/h2c"
+)
+
+const elementSize = curve.CompressedPointSize
+
+// element is a prime-order group element.
+type element interface {
+	add(other element) element
+	mul(s *scalar.Scalar) element
+	equal(other element) bool
+	isIdentity() bool
+	bytes() []byte
+}
+
+// group is a prime-order group.
+type group interface {
+	identity() element
+	basepointMul(s *scalar.Scalar) element
+	hashToGroup(dst, message []byte) (element, error)
+	deserializeElement(b []byte) (element, error)
+}
+
+type ristrettoElement struct {
+	p curve.RistrettoPoint
+}
+
+func (e *ristrettoElement) add(other element) element {
+	var v ristrettoElement
+	v.p.Add(&e.p, &other.(*ristrettoElement).p)
+	return &v
+}
+
+func (e *ristrettoElement) mul(s *scalar.Scalar) element {
+	var v ristrettoElement
+	v.p.Mul(&e.p, s)
+	return &v
+}
+
+func (e *ristrettoElement) equal(other element) bool {
+	return e.p.Equal(&other.(*ristrettoElement).p) == 1
+}
+
+func (e *ristrettoElement) isIdentity() bool {
+	return e.p.IsIdentity()
+}
+
+func (e *ristrettoElement) bytes() []byte {
+	var compressed curve.CompressedRistretto
+	compressed.SetRistrettoPoint(&e.p)
+	return compressed[:]
+}
+
+type ristretto255Group struct{}
+
+func (g ristretto255Group) identity() element {
+	var v ristrettoElement
+	v.p.Identity()
+	return &v
+}
+
+func (g ristretto255Group) basepointMul(s *scalar.Scalar) element {
+	var v ristrettoElement
+	v.p.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, s)
+	return &v
+}
+
+func (g ristretto255Group) hashToGroup(dst, message []byte) (element, error) {
+	p, err := h2c.Ristretto255_XMD_R255MAP_RO(crypto.SHA512, dst, message)
+	if err != nil {
+		return nil, fmt.Errorf("oprf: failed to hash to group: %w", err)
+	}
+
+	var v ristrettoElement
+	v.p.Set(p)
+	return &v, nil
+}
+
+func (g ristretto255Group) deserializeElement(b []byte) (element, error) {
+	var compressed curve.CompressedRistretto
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+
+	var v ristrettoElement
+	if _, err := v.p.SetCompressed(&compressed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	if v.p.IsIdentity() {
+		return nil, fmt.Errorf("%w: element is the identity", ErrDeserialize)
+	}
+
+	return &v, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// PrivateKey is an OPRF private key.
+type PrivateKey struct {
+	suite *Suite
+	k     *scalar.Scalar
+	pub   *PublicKey
+}
+
+// Public returns the public key corresponding to the private key.
+func (k *PrivateKey) Public() *PublicKey {
+	return k.pub
+}
+
+// MarshalBinary encodes a PrivateKey into binary form.
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	return k.k.MarshalBinary()
+}
+
+// PublicKey is an OPRF public key.
+type PublicKey struct {
+	suite *Suite
+	e     element
+}
+
+// MarshalBinary encodes a PublicKey into binary form.
+func (k *PublicKey) MarshalBinary() ([]byte, error) {
+	return k.e.bytes(), nil
+}
+
+func (s *Suite) newPrivateKey(k *scalar.Scalar) *PrivateKey {
+	return &PrivateKey{
+		suite: s,
+		k:     k,
+		pub: &PublicKey{
+			suite: s,
+			e:     s.g.basepointMul(k),
+		},
+	}
+}
+
+// NewPrivateKey constructs a PrivateKey from the byte representation.
+func (s *Suite) NewPrivateKey(b []byte) (*PrivateKey, error) {
+	k, err := s.deserializeScalar(b)
+	if err != nil {
+		return nil, err
+	}
+	if k.Equal(scalar.New()) == 1 {
+		return nil, fmt.Errorf("%w: private key is zero", ErrDeserialize)
+	}
+	return s.newPrivateKey(k), nil
+}
+
+// NewPublicKey constructs a PublicKey from the byte representation.
+func (s *Suite) NewPublicKey(b []byte) (*PublicKey, error) {
+	e, err := s.g.deserializeElement(b)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		suite: s,
+		e:     e,
+	}, nil
+}
+
+// GenerateKey generates a new random private key using entropy from
+// rand.  If rand is nil, crypto/rand.Reader will be used.
+func (s *Suite) GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	k, err := randomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	return s.newPrivateKey(k), nil
+}
+
+// DeriveKey deterministically derives a private key for the mode from
+// a 32-byte seed and info.
+func (s *Suite) DeriveKey(mode Mode, seed, info []byte) (*PrivateKey, error) {
+	if !mode.isValid() {
+		return nil, errInvalidMode
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("%w: invalid seed length", ErrDeriveKeyPair)
+	}
+	if err := checkInputLength(info); err != nil {
+		return nil, err
+	}
+
+	deriveInput := append(append([]byte{}, seed...), lengthPrefixed(info)...)
+	dst := append([]byte("DeriveKeyPair"), s.contextString(mode)...)
+	zero := scalar.New()
+	for counter := 0; counter <= maxDeriveKeyPairCounter; counter++ {
+		k := s.hashToScalarDST(dst, deriveInput, []byte{byte(counter)})
+		if k.Equal(zero) == 0 {
+			return s.newPrivateKey(k), nil
+		}
+	}
+
+	return nil, ErrDeriveKeyPair
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package oprf implements the Oblivious Pseudorandom Function (OPRF),
+// Verifiable OPRF (VOPRF), and Partially-Oblivious PRF (POPRF) protocols
+// as specified in RFC 9497.
+package oprf
+
+import (
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+	"github.com/oasisprotocol/curve25519-voi/primitives/h2c"
+)
+
+// Mode is a protocol variant.
+type Mode uint8
+
+const (
+	// ModeOPRF is the base OPRF mode.
+	ModeOPRF Mode = 0x00
+	// ModeVOPRF is the verifiable OPRF mode.
+	ModeVOPRF Mode = 0x01
+	// ModePOPRF is the partially-oblivious PRF mode.
+	ModePOPRF Mode = 0x02
+)
+
+// String returns the string representation of a Mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeOPRF:
+		return "OPRF"
+	case ModeVOPRF:
+		return "VOPRF"
+	case ModePOPRF:
+		return "POPRF"
+	default:
+		return fmt.Sprintf("[unknown mode: 0x%02x]", uint8(m))
+	}
+}
+
+func (m Mode) isValid() bool {
+	return m <= ModePOPRF
+}
+
+const (
+	// ScalarSize is the size of a serialized scalar in bytes.
+	ScalarSize = scalar.ScalarSize
+
+	// ElementSize is the size of a serialized group element in bytes.
+	ElementSize = elementSize
+
+	// OutputSize is the size of the PRF output in bytes.
+	OutputSize = sha512.Size
+
+	// ProofSize is the size of a serialized proof in bytes.
+	ProofSize = 2 * ScalarSize
+
+	maxDeriveKeyPairCounter = 255
+)
+
+var (
+	// ErrVerify is the error returned when a proof fails to verify.
+	ErrVerify = fmt.Errorf("oprf: proof verification failed")
+
+	// ErrDeserialize is the error returned when a scalar or element
+	// fails to deserialize.
+	ErrDeserialize = fmt.Errorf("oprf: failed to deserialize")
+
+	// ErrInvalidInput is the error returned when an input maps to the
+	// identity element.
+	ErrInvalidInput = fmt.Errorf("oprf: invalid input")
+
+	// ErrInverse is the error returned when the POPRF tweaked private
+	// key is not invertible.
+	ErrInverse = fmt.Errorf("oprf: tweaked private key is not invertible")
+
+	// ErrDeriveKeyPair is the error returned when key derivation fails.
+	ErrDeriveKeyPair = fmt.Errorf("oprf: failed to derive key pair")
+
+	errInvalidMode = fmt.Errorf("oprf: invalid mode")
+	errInfoInMode  = fmt.Errorf("oprf: info is only supported in POPRF mode")
+)
+
+// Ristretto255SHA512 is the ristretto255-SHA512 ciphersuite.
+var Ristretto255SHA512 = &Suite{
+	id: "ristretto255-SHA512",
+	g:  ristretto255Group{},
+}
+
+// Suite is an OPRF ciphersuite.
+type Suite struct {
+	id string
+	g  group
+}
+
+// Name returns the identifier of the ciphersuite.
+func (s *Suite) Name() string {
+	return s.id
+}
+
+// String returns the string representation of the ciphersuite.
+func (s *Suite) String() string {
+	return s.Name()
+}
+
+func (s *Suite) contextString(mode Mode) []byte {
+	ctx := []byte("OPRFV1-")
+	ctx = append(ctx, byte(mode), '-')
+	return append(ctx, s.id...)
+}
+
+// hashToGroup implements HashToGroup with the default DST.
+func (s *Suite) hashToGroup(mode Mode, input []byte) (element, error) {
+	dst := append([]byte("HashToGroup-"), s.contextString(mode)...)
+	return s.g.hashToGroup(dst, input)
+}
+
+// hashToScalar implements HashToScalar with the provided DST.
+func (s *Suite) hashToScalarDST(dst []byte, input ...[]byte) *scalar.Scalar {
+	var msg []byte
+	for _, v := range input {
+		msg = append(msg, v...)
+	}
+
+	var uniformBytes [scalar.ScalarWideSize]byte
+	if err := h2c.ExpandMessageXMD(uniformBytes[:], crypto.SHA512, dst, msg); err != nil {
+		panic("oprf: failed to expand message: " + err.Error())
+	}
+	sc, err := scalar.NewFromBytesModOrderWide(uniformBytes[:])
+	if err != nil {
+		panic("oprf: failed to reduce scalar: " + err.Error())
+	}
+	return sc
+}
+
+// hashToScalar implements HashToScalar with the default DST.
+func (s *Suite) hashToScalar(mode Mode, input ...[]byte) *scalar.Scalar {
+	dst := append([]byte("HashToScalar-"), s.contextString(mode)...)
+	return s.hashToScalarDST(dst, input...)
+}
+
+func (s *Suite) deserializeScalar(b []byte) (*scalar.Scalar, error) {
+	if len(b) != ScalarSize {
+		return nil, fmt.Errorf("%w: invalid scalar length", ErrDeserialize)
+	}
+	sc, err := scalar.NewFromCanonicalBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserialize, err)
+	}
+	return sc, nil
+}
+
+func (s *Suite) deserializeElements(bs [][]byte) ([]element, error) {
+	elements := make([]element, 0, len(bs))
+	for _, b := range bs {
+		e, err := s.g.deserializeElement(b)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
+
+// finalizeHash computes the PRF output from the unblinded element.
+func finalizeHash(input, info []byte, includeInfo bool, unblindedElement []byte) []byte {
+	h := sha512.New()
+	writeLengthPrefixed(h, input)
+	if includeInfo {
+		writeLengthPrefixed(h, info)
+	}
+	writeLengthPrefixed(h, unblindedElement)
+	_, _ = h.Write([]byte("Finalize"))
+	return h.Sum(nil)
+}
+
+func i2osp2(n int) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(n))
+	return b[:]
+}
+
+func lengthPrefixed(b []byte) []byte {
+	return append(i2osp2(len(b)), b...)
+}
+
+func writeLengthPrefixed(w io.Writer, b []byte) {
+	_, _ = w.Write(i2osp2(len(b)))
+	_, _ = w.Write(b)
+}
+
+func randomScalar(rand io.Reader) (*scalar.Scalar, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	zero := scalar.New()
+	for {
+		sc, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return nil, fmt.Errorf("oprf: failed to generate random scalar: %w", err)
+		}
+		if sc.Equal(zero) == 0 {
+			return sc, nil
+		}
+	}
+}
+
+func checkInputLength(b []byte) error {
+	if len(b) > 0xffff {
+		return fmt.Errorf("oprf: input too large")
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestOPRF(t *testing.T) {
+	for _, suite := range []*Suite{
+		Ristretto255SHA512,
+	} {
+		suite := suite
+		t.Run(suite.Name(), func(t *testing.T) {
+			for _, mode := range []Mode{
+				ModeOPRF,
+				ModeVOPRF,
+				ModePOPRF,
+			} {
+				mode := mode
+				t.Run(mode.String(), func(t *testing.T) {
+					testOPRF(t, suite, mode)
+				})
+			}
+		})
+	}
+}
+
+func testOPRF(t *testing.T, suite *Suite, mode Mode) {
+	sk, err := suite.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	server, err := suite.NewServer(mode, sk)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	// Round-trip the public key through its serialized form, as a
+	// client would receive it.
+	pkBytes, _ := server.PublicKey().MarshalBinary()
+	pk, err := suite.NewPublicKey(pkBytes)
+	if err != nil {
+		t.Fatalf("NewPublicKey: %v", err)
+	}
+	client, err := suite.NewClient(mode, pk)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	var info []byte
+	if mode == ModePOPRF {
+		info = []byte("test info")
+	}
+	inputs := [][]byte{
+		[]byte("input 0"),
+		[]byte("input 1"),
+		[]byte("input 2"),
+		{},
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		fd, req, err := client.Blind(rand.Reader, inputs, info)
+		if err != nil {
+			t.Fatalf("Blind: %v", err)
+		}
+		eval, err := server.BlindEvaluate(rand.Reader, req, info)
+		if err != nil {
+			t.Fatalf("BlindEvaluate: %v", err)
+		}
+		if mode == ModeOPRF && eval.Proof != nil {
+			t.Fatalf("unexpected proof in OPRF mode")
+		}
+		outputs, err := client.Finalize(fd, eval)
+		if err != nil {
+			t.Fatalf("Finalize: %v", err)
+		}
+
+		for i, input := range inputs {
+			if len(outputs[i]) != OutputSize {
+				t.Fatalf("outputs[%d]: invalid length %d", i, len(outputs[i]))
+			}
+			output, err := server.Evaluate(input, info)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !bytes.Equal(output, outputs[i]) {
+				t.Fatalf("outputs[%d]: mismatch with Evaluate", i)
+			}
+		}
+
+		// Blinding is randomized, the output is not.
+		fd2, req2, err := client.Blind(rand.Reader, inputs[:1], info)
+		if err != nil {
+			t.Fatalf("Blind: %v", err)
+		}
+		if bytes.Equal(req.BlindedElements[0], req2.BlindedElements[0]) {
+			t.Fatalf("blinded elements are not randomized")
+		}
+		eval2, err := server.BlindEvaluate(rand.Reader, req2, info)
+		if err != nil {
+			t.Fatalf("BlindEvaluate: %v", err)
+		}
+		outputs2, err := client.Finalize(fd2, eval2)
+		if err != nil {
+			t.Fatalf("Finalize: %v", err)
+		}
+		if !bytes.Equal(outputs[0], outputs2[0]) {
+			t.Fatalf("output is not deterministic")
+		}
+	})
+
+	t.Run("DifferentKey", func(t *testing.T) {
+		sk2, err := suite.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		server2, err := suite.NewServer(mode, sk2)
+		if err != nil {
+			t.Fatalf("NewServer: %v", err)
+		}
+
+		fd, req, err := client.Blind(rand.Reader, inputs, info)
+		if err != nil {
+			t.Fatalf("Blind: %v", err)
+		}
+		eval, err := server2.BlindEvaluate(rand.Reader, req, info)
+		if err != nil {
+			t.Fatalf("BlindEvaluate: %v", err)
+		}
+		outputs, err := client.Finalize(fd, eval)
+		switch mode {
+		case ModeOPRF:
+			if err != nil {
+				t.Fatalf("Finalize: %v", err)
+			}
+			expected, _ := server.Evaluate(inputs[0], info)
+			if bytes.Equal(outputs[0], expected) {
+				t.Fatalf("outputs match with different keys")
+			}
+		default:
+			if !errors.Is(err, ErrVerify) {
+				t.Fatalf("Finalize: expected ErrVerify, got %v", err)
+			}
+		}
+	})
+
+	if mode == ModeOPRF {
+		t.Run("InfoRejected", func(t *testing.T) {
+			if _, _, err := client.Blind(rand.Reader, inputs, []byte("info")); err == nil {
+				t.Fatalf("Blind: accepted info in OPRF mode")
+			}
+			if _, err := server.Evaluate(inputs[0], []byte("info")); err == nil {
+				t.Fatalf("Evaluate: accepted info in OPRF mode")
+			}
+		})
+		return
+	}
+
+	t.Run("BadProof", func(t *testing.T) {
+		fd, req, err := client.Blind(rand.Reader, inputs, info)
+		if err != nil {
+			t.Fatalf("Blind: %v", err)
+		}
+		eval, err := server.BlindEvaluate(rand.Reader, req, info)
+		if err != nil {
+			t.Fatalf("BlindEvaluate: %v", err)
+		}
+		if len(eval.Proof) != ProofSize {
+			t.Fatalf("invalid proof length: %d", len(eval.Proof))
+		}
+
+		badEval := &Evaluation{
+			EvaluatedElements: eval.EvaluatedElements,
+			Proof:             append([]byte{}, eval.Proof...),
+		}
+		badEval.Proof[0] ^= 0x01
+		if _, err = client.Finalize(fd, badEval); !errors.Is(err, ErrVerify) {
+			t.Fatalf("Finalize(tampered proof): expected ErrVerify, got %v", err)
+		}
+
+		badEval.Proof = eval.Proof[:ProofSize-1]
+		if _, err = client.Finalize(fd, badEval); err == nil {
+			t.Fatalf("Finalize(truncated proof): accepted")
+		}
+
+		// Swap two evaluated elements, which leaves each element
+		// valid but breaks the batched proof.
+		badEval = &Evaluation{
+			EvaluatedElements: append([][]byte{}, eval.EvaluatedElements...),
+			Proof:             eval.Proof,
+		}
+		badEval.EvaluatedElements[0], badEval.EvaluatedElements[1] = badEval.EvaluatedElements[1], badEval.EvaluatedElements[0]
+		if _, err = client.Finalize(fd, badEval); !errors.Is(err, ErrVerify) {
+			t.Fatalf("Finalize(swapped elements): expected ErrVerify, got %v", err)
+		}
+
+		badEval.EvaluatedElements = eval.EvaluatedElements[:1]
+		if _, err = client.Finalize(fd, badEval); err == nil {
+			t.Fatalf("Finalize(truncated elements): accepted")
+		}
+
+		if _, err = client.Finalize(fd, eval); err != nil {
+			t.Fatalf("Finalize: %v", err)
+		}
+	})
+
+	if mode == ModePOPRF {
+		t.Run("InfoMismatch", func(t *testing.T) {
+			fd, req, err := client.Blind(rand.Reader, inputs, info)
+			if err != nil {
+				t.Fatalf("Blind: %v", err)
+			}
+			eval, err := server.BlindEvaluate(rand.Reader, req, []byte("other info"))
+			if err != nil {
+				t.Fatalf("BlindEvaluate: %v", err)
+			}
+			if _, err = client.Finalize(fd, eval); !errors.Is(err, ErrVerify) {
+				t.Fatalf("Finalize: expected ErrVerify, got %v", err)
+			}
+
+			a, _ := server.Evaluate(inputs[0], info)
+			b, _ := server.Evaluate(inputs[0], []byte("other info"))
+			if bytes.Equal(a, b) {
+				t.Fatalf("Evaluate: output independent of info")
+			}
+		})
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	suite := Ristretto255SHA512
+
+	sk, err := suite.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	server, err := suite.NewServer(ModeVOPRF, sk)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	t.Run("Keys", func(t *testing.T) {
+		if _, err := suite.NewPrivateKey(make([]byte, ScalarSize)); err == nil {
+			t.Fatalf("NewPrivateKey: accepted zero scalar")
+		}
+		if _, err := suite.NewPrivateKey(make([]byte, ScalarSize-1)); err == nil {
+			t.Fatalf("NewPrivateKey: accepted truncated scalar")
+		}
+		if _, err := suite.NewPublicKey(make([]byte, ElementSize)); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("NewPublicKey: expected ErrDeserialize for identity, got %v", err)
+		}
+
+		b, _ := sk.MarshalBinary()
+		sk2, err := suite.NewPrivateKey(b)
+		if err != nil {
+			t.Fatalf("NewPrivateKey: %v", err)
+		}
+		pk1, _ := sk.Public().MarshalBinary()
+		pk2, _ := sk2.Public().MarshalBinary()
+		if !bytes.Equal(pk1, pk2) {
+			t.Fatalf("public key mismatch after round-trip")
+		}
+
+		otherSuite := &Suite{
+			id: "other",
+			g:  ristretto255Group{},
+		}
+		if _, err = otherSuite.NewServer(ModeOPRF, sk); err == nil {
+			t.Fatalf("NewServer: accepted key for a different suite")
+		}
+		if _, err = suite.NewClient(ModeVOPRF, nil); err == nil {
+			t.Fatalf("NewClient: accepted missing public key")
+		}
+		if _, err = suite.NewClient(Mode(3), nil); err == nil {
+			t.Fatalf("NewClient: accepted invalid mode")
+		}
+	})
+
+	t.Run("DeriveKey", func(t *testing.T) {
+		if _, err := suite.DeriveKey(ModeOPRF, make([]byte, 31), nil); err == nil {
+			t.Fatalf("DeriveKey: accepted short seed")
+		}
+		seed := make([]byte, 32)
+		a, err := suite.DeriveKey(ModeOPRF, seed, []byte("a"))
+		if err != nil {
+			t.Fatalf("DeriveKey: %v", err)
+		}
+		b, err := suite.DeriveKey(ModeVOPRF, seed, []byte("a"))
+		if err != nil {
+			t.Fatalf("DeriveKey: %v", err)
+		}
+		aBytes, _ := a.MarshalBinary()
+		bBytes, _ := b.MarshalBinary()
+		if bytes.Equal(aBytes, bBytes) {
+			t.Fatalf("DeriveKey: key is independent of mode")
+		}
+	})
+
+	t.Run("EvaluationRequest", func(t *testing.T) {
+		if _, err := server.BlindEvaluate(rand.Reader, &EvaluationRequest{}, nil); err == nil {
+			t.Fatalf("BlindEvaluate: accepted empty request")
+		}
+		req := &EvaluationRequest{
+			BlindedElements: [][]byte{make([]byte, ElementSize)},
+		}
+		if _, err := server.BlindEvaluate(rand.Reader, req, nil); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("BlindEvaluate: expected ErrDeserialize for identity, got %v", err)
+		}
+		req.BlindedElements[0] = bytes.Repeat([]byte{0xff}, ElementSize)
+		if _, err := server.BlindEvaluate(rand.Reader, req, nil); !errors.Is(err, ErrDeserialize) {
+			t.Fatalf("BlindEvaluate: expected ErrDeserialize for invalid element, got %v", err)
+		}
+	})
+}
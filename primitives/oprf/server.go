@@ -0,0 +1,169 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// Server is an OPRF server.
+type Server struct {
+	suite *Suite
+	mode  Mode
+	sk    *PrivateKey
+}
+
+// PublicKey returns the server's public key.
+func (srv *Server) PublicKey() *PublicKey {
+	return srv.sk.Public()
+}
+
+// BlindEvaluate evaluates the PRF over the blinded elements in the
+// request, and in the VOPRF and POPRF modes, proves that the evaluation
+// was done correctly using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// The public info is only supported in the POPRF mode, and must be
+// nil otherwise.
+func (srv *Server) BlindEvaluate(rand io.Reader, req *EvaluationRequest, info []byte) (*Evaluation, error) {
+	var r *scalar.Scalar
+	if srv.mode != ModeOPRF {
+		var err error
+		if r, err = randomScalar(rand); err != nil {
+			return nil, err
+		}
+	}
+	return srv.blindEvaluateWithRandomness(r, req, info)
+}
+
+func (srv *Server) blindEvaluateWithRandomness(r *scalar.Scalar, req *EvaluationRequest, info []byte) (*Evaluation, error) {
+	if len(req.BlindedElements) == 0 {
+		return nil, fmt.Errorf("oprf: invalid number of blinded elements")
+	}
+	blindedElements, err := srv.suite.deserializeElements(req.BlindedElements)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := srv.evaluationKey(info)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluatedElements := make([]element, 0, len(blindedElements))
+	eval := &Evaluation{
+		EvaluatedElements: make([][]byte, 0, len(blindedElements)),
+	}
+	for _, blindedElement := range blindedElements {
+		evaluatedElement := blindedElement.mul(k)
+		evaluatedElements = append(evaluatedElements, evaluatedElement)
+		eval.EvaluatedElements = append(eval.EvaluatedElements, evaluatedElement.bytes())
+	}
+
+	switch srv.mode {
+	case ModeVOPRF:
+		eval.Proof = srv.suite.generateProofWithRandomness(srv.mode, r, srv.sk.k, srv.sk.pub.e, blindedElements, evaluatedElements)
+	case ModePOPRF:
+		// The proof is of `evaluated = t^-1 * blinded`, expressed as
+		// `blinded = t * evaluated`, for the tweaked key `t * G`.
+		t := scalar.New().Invert(k)
+		tweakedKey := srv.suite.g.basepointMul(t)
+		eval.Proof = srv.suite.generateProofWithRandomness(srv.mode, r, t, tweakedKey, evaluatedElements, blindedElements)
+	}
+
+	return eval, nil
+}
+
+// Evaluate evaluates the PRF over the input directly, without blinding,
+// and returns the PRF output.
+//
+// The public info is only supported in the POPRF mode, and must be
+// nil otherwise.
+func (srv *Server) Evaluate(input, info []byte) ([]byte, error) {
+	if err := checkInputLength(input); err != nil {
+		return nil, err
+	}
+	inputElement, err := srv.suite.hashToGroup(srv.mode, input)
+	if err != nil {
+		return nil, err
+	}
+	if inputElement.isIdentity() {
+		return nil, ErrInvalidInput
+	}
+
+	k, err := srv.evaluationKey(info)
+	if err != nil {
+		return nil, err
+	}
+	issuedElement := inputElement.mul(k)
+
+	return finalizeHash(input, info, srv.mode == ModePOPRF, issuedElement.bytes()), nil
+}
+
+// evaluationKey returns the scalar that inputs are multiplied by,
+// which is the private key in the OPRF and VOPRF modes, and the
+// inverse of the tweaked private key in the POPRF mode.
+func (srv *Server) evaluationKey(info []byte) (*scalar.Scalar, error) {
+	if srv.mode != ModePOPRF {
+		if info != nil {
+			return nil, errInfoInMode
+		}
+		return srv.sk.k, nil
+	}
+
+	if err := checkInputLength(info); err != nil {
+		return nil, err
+	}
+	m := srv.suite.hashToScalar(srv.mode, framedInfo(info))
+	t := scalar.New().Add(srv.sk.k, m)
+	if t.Equal(scalar.New()) == 1 {
+		return nil, ErrInverse
+	}
+	return t.Invert(t), nil
+}
+
+// NewServer creates a new server for the mode.
+func (s *Suite) NewServer(mode Mode, sk *PrivateKey) (*Server, error) {
+	if !mode.isValid() {
+		return nil, errInvalidMode
+	}
+	if sk.suite != s {
+		return nil, fmt.Errorf("oprf: private key is for a different suite")
+	}
+
+	return &Server{
+		suite: s,
+		mode:  mode,
+		sk:    sk,
+	}, nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package oprf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+type testVectorSuite struct {
+	Identifier string             `json:"identifier"`
+	Mode       Mode               `json:"mode"`
+	Hash       string             `json:"hash"`
+	PkSm       string             `json:"pkSm"`
+	SkSm       string             `json:"skSm"`
+	Seed       string             `json:"seed"`
+	KeyInfo    string             `json:"keyInfo"`
+	GroupDST   string             `json:"groupDST"`
+	Vectors    []testVectorValues `json:"vectors"`
+}
+
+type testVectorValues struct {
+	Batch             int    `json:"Batch"`
+	Blind             string `json:"Blind"`
+	Info              string `json:"Info"`
+	BlindedElement    string `json:"BlindedElement"`
+	EvaluationElement string `json:"EvaluationElement"`
+	Proof             struct {
+		Proof string `json:"proof"`
+		R     string `json:"r"`
+	} `json:"Proof"`
+	Input  string `json:"Input"`
+	Output string `json:"Output"`
+}
+
+func unhexList(t *testing.T, s string) [][]byte {
+	var ret [][]byte
+	for _, v := range strings.Split(s, ",") {
+		ret = append(ret, testhelpers.MustUnhex(t, v))
+	}
+	return ret
+}
+
+func mustScalar(t *testing.T, s string) *scalar.Scalar {
+	sc, err := scalar.NewFromCanonicalBytes(testhelpers.MustUnhex(t, s))
+	if err != nil {
+		t.Fatalf("scalar.NewFromCanonicalBytes: %v", err)
+	}
+	return sc
+}
+
+func assertEqualLists(t *testing.T, what string, actual, expected [][]byte) {
+	if len(actual) != len(expected) {
+		t.Fatalf("%s: length mismatch %d != %d", what, len(actual), len(expected))
+	}
+	for i := range actual {
+		if !bytes.Equal(actual[i], expected[i]) {
+			t.Fatalf("%s[%d]: got %x, expected %x", what, i, actual[i], expected[i])
+		}
+	}
+}
+
+func TestVectors(t *testing.T) {
+	// From RFC 9497 Appendix A.1.
+	f, err := os.Open("testdata/rfc9497.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	var suites []testVectorSuite
+	if err = json.NewDecoder(rd).Decode(&suites); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tvs := range suites {
+		tvs := tvs
+		t.Run(fmt.Sprintf("%s/%s", tvs.Identifier, tvs.Mode), func(t *testing.T) {
+			suite := Ristretto255SHA512
+			if tvs.Identifier != suite.Name() {
+				t.Fatalf("unexpected suite: %s", tvs.Identifier)
+			}
+
+			expectedDST := append([]byte("HashToGroup-"), suite.contextString(tvs.Mode)...)
+			if !bytes.Equal(expectedDST, testhelpers.MustUnhex(t, tvs.GroupDST)) {
+				t.Fatalf("HashToGroup DST mismatch")
+			}
+
+			sk, err := suite.DeriveKey(tvs.Mode, testhelpers.MustUnhex(t, tvs.Seed), testhelpers.MustUnhex(t, tvs.KeyInfo))
+			if err != nil {
+				t.Fatalf("DeriveKey: %v", err)
+			}
+			skBytes, _ := sk.MarshalBinary()
+			if !bytes.Equal(skBytes, testhelpers.MustUnhex(t, tvs.SkSm)) {
+				t.Fatalf("skSm: got %x", skBytes)
+			}
+			if tvs.Mode != ModeOPRF {
+				pkBytes, _ := sk.Public().MarshalBinary()
+				if !bytes.Equal(pkBytes, testhelpers.MustUnhex(t, tvs.PkSm)) {
+					t.Fatalf("pkSm: got %x", pkBytes)
+				}
+			}
+
+			server, err := suite.NewServer(tvs.Mode, sk)
+			if err != nil {
+				t.Fatalf("NewServer: %v", err)
+			}
+			client, err := suite.NewClient(tvs.Mode, sk.Public())
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+
+			for i, vec := range tvs.Vectors {
+				t.Run(fmt.Sprintf("TestCase/%d", i), func(t *testing.T) {
+					inputs := unhexList(t, vec.Input)
+					var blinds []*scalar.Scalar
+					for _, b := range strings.Split(vec.Blind, ",") {
+						blinds = append(blinds, mustScalar(t, b))
+					}
+					if len(inputs) != vec.Batch || len(blinds) != vec.Batch {
+						t.Fatalf("malformed test vector")
+					}
+					var info []byte
+					if tvs.Mode == ModePOPRF {
+						info = testhelpers.MustUnhex(t, vec.Info)
+					}
+
+					fd, req, err := client.blindWithScalars(inputs, info, blinds)
+					if err != nil {
+						t.Fatalf("Blind: %v", err)
+					}
+					assertEqualLists(t, "BlindedElement", req.BlindedElements, unhexList(t, vec.BlindedElement))
+
+					var r *scalar.Scalar
+					if tvs.Mode != ModeOPRF {
+						r = mustScalar(t, vec.Proof.R)
+					}
+					eval, err := server.blindEvaluateWithRandomness(r, req, info)
+					if err != nil {
+						t.Fatalf("BlindEvaluate: %v", err)
+					}
+					assertEqualLists(t, "EvaluationElement", eval.EvaluatedElements, unhexList(t, vec.EvaluationElement))
+					if tvs.Mode != ModeOPRF && !bytes.Equal(eval.Proof, testhelpers.MustUnhex(t, vec.Proof.Proof)) {
+						t.Fatalf("Proof: got %x", eval.Proof)
+					}
+
+					outputs, err := client.Finalize(fd, eval)
+					if err != nil {
+						t.Fatalf("Finalize: %v", err)
+					}
+					expectedOutputs := unhexList(t, vec.Output)
+					assertEqualLists(t, "Output", outputs, expectedOutputs)
+
+					for j, input := range inputs {
+						output, err := server.Evaluate(input, info)
+						if err != nil {
+							t.Fatalf("Evaluate: %v", err)
+						}
+						if !bytes.Equal(output, expectedOutputs[j]) {
+							t.Fatalf("Evaluate[%d]: got %x", j, output)
+						}
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package pedersen implements Pedersen commitments, and Pedersen vector
+// commitments over the ristretto255 group.
+package pedersen
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+// CommitmentSize is the size of a serialized Commitment in bytes.
+const CommitmentSize = curve.CompressedPointSize
+
+const generatorsDomainSep = "curve25519-voi/pedersen: generators"
+
+var (
+	// ErrVectorLength is the error returned when a vector does not
+	// match the number of vector generators.
+	ErrVectorLength = fmt.Errorf("pedersen: vector length exceeds generators")
+
+	errInvalidCommitment = fmt.Errorf("pedersen: invalid commitment")
+)
+
+// Generators is a set of generators used to form commitments.
+//
+// G is the ristretto255 basepoint, and H and the vector generators
+// are derived from a domain separation string via SHAKE256 and the
+// ristretto255 hash-to-group map, so that no discrete log relationship
+// between any of them is known.
+type Generators struct {
+	g  *curve.RistrettoPoint
+	h  *curve.RistrettoPoint
+	gs []*curve.RistrettoPoint
+}
+
+// G returns the generator used for the committed value.
+func (gens *Generators) G() *curve.RistrettoPoint {
+	return curve.NewRistrettoPoint().Set(gens.g)
+}
+
+// H returns the generator used for the blinding factor.
+func (gens *Generators) H() *curve.RistrettoPoint {
+	return curve.NewRistrettoPoint().Set(gens.h)
+}
+
+// VectorSize returns the maximum length of a vector that can be
+// committed to with the generators.
+func (gens *Generators) VectorSize() int {
+	return len(gens.gs)
+}
+
+// VectorGenerator returns the i-th generator used for vector commitments.
+func (gens *Generators) VectorGenerator(i int) *curve.RistrettoPoint {
+	return curve.NewRistrettoPoint().Set(gens.gs[i])
+}
+
+// Commit returns the commitment `value * G + blinding * H`.
+func (gens *Generators) Commit(value, blinding *scalar.Scalar) *Commitment {
+	var c Commitment
+	c.inner.MultiscalarMul(
+		[]*scalar.Scalar{value, blinding},
+		[]*curve.RistrettoPoint{gens.g, gens.h},
+	)
+	return &c
+}
+
+// CommitVector returns the commitment `sum(values[i] * G_i) + blinding * H`.
+func (gens *Generators) CommitVector(values []*scalar.Scalar, blinding *scalar.Scalar) (*Commitment, error) {
+	scalars, points, err := gens.vectorTerms(values, blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Commitment
+	c.inner.MultiscalarMul(scalars, points)
+	return &c, nil
+}
+
+// Verify returns true iff c is a commitment to value with blinding.
+func (gens *Generators) Verify(c *Commitment, value, blinding *scalar.Scalar) bool {
+	var expected curve.RistrettoPoint
+	expected.MultiscalarMulVartime(
+		[]*scalar.Scalar{value, blinding},
+		[]*curve.RistrettoPoint{gens.g, gens.h},
+	)
+	return expected.Equal(&c.inner) == 1
+}
+
+// VerifyVector returns true iff c is a vector commitment to values with
+// blinding.
+func (gens *Generators) VerifyVector(c *Commitment, values []*scalar.Scalar, blinding *scalar.Scalar) bool {
+	scalars, points, err := gens.vectorTerms(values, blinding)
+	if err != nil {
+		return false
+	}
+
+	var expected curve.RistrettoPoint
+	expected.MultiscalarMulVartime(scalars, points)
+	return expected.Equal(&c.inner) == 1
+}
+
+func (gens *Generators) vectorTerms(values []*scalar.Scalar, blinding *scalar.Scalar) ([]*scalar.Scalar, []*curve.RistrettoPoint, error) {
+	n := len(values)
+	if n > len(gens.gs) {
+		return nil, nil, ErrVectorLength
+	}
+
+	scalars := make([]*scalar.Scalar, 0, n+1)
+	scalars = append(append(scalars, values...), blinding)
+	points := make([]*curve.RistrettoPoint, 0, n+1)
+	points = append(append(points, gens.gs[:n]...), gens.h)
+
+	return scalars, points, nil
+}
+
+// NewGenerators derives a set of generators from the domain separation
+// string domain, supporting vector commitments of up to vectorSize
+// elements.  Generators derived from the same domain are identical,
+// regardless of vectorSize, up to the smaller of the sizes.
+func NewGenerators(domain []byte, vectorSize int) *Generators {
+	xof := sha3.NewShake256()
+	_, _ = xof.Write([]byte(generatorsDomainSep))
+	var l [8]byte
+	binary.LittleEndian.PutUint64(l[:], uint64(len(domain)))
+	_, _ = xof.Write(l[:])
+	_, _ = xof.Write(domain)
+
+	nextPoint := func() *curve.RistrettoPoint {
+		var b [curve.RistrettoUniformSize]byte
+		_, _ = xof.Read(b[:])
+		p, err := curve.NewRistrettoPoint().SetUniformBytes(b[:])
+		if err != nil {
+			panic("pedersen: failed to derive generator: " + err.Error())
+		}
+		return p
+	}
+
+	gens := &Generators{
+		g:  curve.NewRistrettoPoint().Set(curve.RISTRETTO_BASEPOINT_POINT),
+		h:  nextPoint(),
+		gs: make([]*curve.RistrettoPoint, 0, vectorSize),
+	}
+	for i := 0; i < vectorSize; i++ {
+		gens.gs = append(gens.gs, nextPoint())
+	}
+
+	return gens
+}
+
+// Commitment is a Pedersen commitment.
+type Commitment struct {
+	inner curve.RistrettoPoint
+}
+
+// Point returns the group element representing the commitment.
+func (c *Commitment) Point() *curve.RistrettoPoint {
+	return curve.NewRistrettoPoint().Set(&c.inner)
+}
+
+// Set sets c to the commitment t, and returns c.
+func (c *Commitment) Set(t *Commitment) *Commitment {
+	c.inner.Set(&t.inner)
+	return c
+}
+
+// Add sets `c = a + b`, and returns c.  The result is a commitment to
+// the sum of the values with the sum of the blinding factors.
+func (c *Commitment) Add(a, b *Commitment) *Commitment {
+	c.inner.Add(&a.inner, &b.inner)
+	return c
+}
+
+// Sub sets `c = a - b`, and returns c.  The result is a commitment to
+// the difference of the values with the difference of the blinding
+// factors.
+func (c *Commitment) Sub(a, b *Commitment) *Commitment {
+	c.inner.Sub(&a.inner, &b.inner)
+	return c
+}
+
+// Neg sets `c = -t`, and returns c.
+func (c *Commitment) Neg(t *Commitment) *Commitment {
+	c.inner.Neg(&t.inner)
+	return c
+}
+
+// Mul sets `c = t * s`, and returns c.  The result is a commitment to
+// the product of the value(s) and s, with the product of the blinding
+// factor and s.
+func (c *Commitment) Mul(t *Commitment, s *scalar.Scalar) *Commitment {
+	c.inner.Mul(&t.inner, s)
+	return c
+}
+
+// Equal returns 1 iff the commitments are equal, 0 otherwise.
+// This function will execute in constant-time.
+func (c *Commitment) Equal(other *Commitment) int {
+	return c.inner.Equal(&other.inner)
+}
+
+// MarshalBinary encodes a Commitment into binary form.
+func (c *Commitment) MarshalBinary() ([]byte, error) {
+	return c.inner.MarshalBinary()
+}
+
+// UnmarshalBinary decodes a binary marshaled Commitment.
+func (c *Commitment) UnmarshalBinary(data []byte) error {
+	c.inner.Identity()
+
+	var compressed curve.CompressedRistretto
+	if _, err := compressed.SetBytes(data); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidCommitment, err)
+	}
+	if _, err := c.inner.SetCompressed(&compressed); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidCommitment, err)
+	}
+	return nil
+}
+
+// NewCommitmentFromBytes constructs a Commitment from the byte
+// representation.
+func NewCommitmentFromBytes(b []byte) (*Commitment, error) {
+	var c Commitment
+	if err := c.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// NewCommitment creates a commitment from a group element.
+func NewCommitment(p *curve.RistrettoPoint) *Commitment {
+	var c Commitment
+	c.inner.Set(p)
+	return &c
+}
+
+// BatchVerifier accumulates commitment openings, so that they can be
+// checked together.
+type BatchVerifier struct {
+	gens *Generators
+
+	entries []batchEntry
+
+	anyInvalid bool
+}
+
+type batchEntry struct {
+	c        *curve.RistrettoPoint
+	values   []*scalar.Scalar
+	blinding *scalar.Scalar
+	isVector bool
+}
+
+// Add adds an opening of a single value commitment to the batch.
+func (v *BatchVerifier) Add(c *Commitment, value, blinding *scalar.Scalar) {
+	v.entries = append(v.entries, batchEntry{
+		c:        c.Point(),
+		values:   []*scalar.Scalar{scalar.New().Set(value)},
+		blinding: scalar.New().Set(blinding),
+	})
+}
+
+// AddVector adds an opening of a vector commitment to the batch.
+func (v *BatchVerifier) AddVector(c *Commitment, values []*scalar.Scalar, blinding *scalar.Scalar) {
+	if len(values) > len(v.gens.gs) {
+		v.anyInvalid = true
+		return
+	}
+
+	e := batchEntry{
+		c:        c.Point(),
+		values:   make([]*scalar.Scalar, 0, len(values)),
+		blinding: scalar.New().Set(blinding),
+		isVector: true,
+	}
+	for _, value := range values {
+		e.values = append(e.values, scalar.New().Set(value))
+	}
+	v.entries = append(v.entries, e)
+}
+
+// Verify checks all openings in the current batch using entropy from
+// rand, returning true iff all openings are valid.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// If a failure arises it is unknown which opening failed, the caller
+// must verify each opening individually.
+func (v *BatchVerifier) Verify(rand io.Reader) bool {
+	switch {
+	case len(v.entries) == 0:
+		// Abort early on an empty batch, which probably indicates a bug.
+		return false
+	case v.anyInvalid:
+		return false
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	// The batch verification equation is
+	//
+	// sum(z_i * C_i) - sum(z_i * v_i) * G - sum(z_i * v_ij) * G_j
+	//   - sum(z_i * r_i) * H = 0
+	//
+	// where z_i is a random weight for each opening.
+	gScalar, hScalar := scalar.New(), scalar.New()
+	gsScalars := make([]*scalar.Scalar, len(v.gens.gs))
+	for i := range gsScalars {
+		gsScalars[i] = scalar.New()
+	}
+
+	scalars := make([]*scalar.Scalar, 0, len(v.entries)+2+len(gsScalars))
+	points := make([]*curve.RistrettoPoint, 0, len(v.entries)+2+len(gsScalars))
+
+	var tmp scalar.Scalar
+	for _, e := range v.entries {
+		z, err := scalar.New().SetRandom(rand)
+		if err != nil {
+			return false
+		}
+		scalars = append(scalars, z)
+		points = append(points, e.c)
+
+		hScalar.Add(hScalar, tmp.Mul(z, e.blinding))
+		switch e.isVector {
+		case true:
+			for j, value := range e.values {
+				gsScalars[j].Add(gsScalars[j], tmp.Mul(z, value))
+			}
+		case false:
+			gScalar.Add(gScalar, tmp.Mul(z, e.values[0]))
+		}
+	}
+
+	scalars = append(scalars, gScalar.Neg(gScalar), hScalar.Neg(hScalar))
+	points = append(points, v.gens.g, v.gens.h)
+	for i := range gsScalars {
+		scalars = append(scalars, gsScalars[i].Neg(gsScalars[i]))
+	}
+	points = append(points, v.gens.gs...)
+
+	var check curve.RistrettoPoint
+	return check.MultiscalarMulVartime(scalars, points).IsIdentity()
+}
+
+// NewBatchVerifier creates an empty BatchVerifier for commitments made
+// with the provided generators.
+func NewBatchVerifier(gens *Generators) *BatchVerifier {
+	return &BatchVerifier{
+		gens: gens,
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package pedersen
+
+import (
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+func mustRandomScalar(t *testing.T) *scalar.Scalar {
+	s, err := scalar.New().SetRandom(nil)
+	if err != nil {
+		t.Fatalf("scalar.SetRandom: %v", err)
+	}
+	return s
+}
+
+func mustRandomScalars(t *testing.T, n int) []*scalar.Scalar {
+	ret := make([]*scalar.Scalar, n)
+	for i := range ret {
+		ret[i] = mustRandomScalar(t)
+	}
+	return ret
+}
+
+func TestGenerators(t *testing.T) {
+	gens := NewGenerators([]byte("test"), 8)
+	if gens.VectorSize() != 8 {
+		t.Fatalf("unexpected vector size: %d", gens.VectorSize())
+	}
+	if gens.G().Equal(curve.RISTRETTO_BASEPOINT_POINT) != 1 {
+		t.Fatalf("G is not the basepoint")
+	}
+
+	// All generators must be distinct.
+	all := []*curve.RistrettoPoint{gens.G(), gens.H()}
+	for i := 0; i < gens.VectorSize(); i++ {
+		all = append(all, gens.VectorGenerator(i))
+	}
+	for i := range all {
+		if all[i].IsIdentity() {
+			t.Fatalf("generator %d is the identity", i)
+		}
+		for j := i + 1; j < len(all); j++ {
+			if all[i].Equal(all[j]) == 1 {
+				t.Fatalf("generators %d and %d are equal", i, j)
+			}
+		}
+	}
+
+	// Derivation is deterministic, and prefix-stable.
+	larger := NewGenerators([]byte("test"), 16)
+	if larger.H().Equal(gens.H()) != 1 {
+		t.Fatalf("H is not deterministic")
+	}
+	for i := 0; i < gens.VectorSize(); i++ {
+		if larger.VectorGenerator(i).Equal(gens.VectorGenerator(i)) != 1 {
+			t.Fatalf("vector generator %d is not prefix-stable", i)
+		}
+	}
+
+	// Different domains produce different generators.
+	if NewGenerators([]byte("other"), 0).H().Equal(gens.H()) == 1 {
+		t.Fatalf("H is not domain separated")
+	}
+}
+
+func TestCommitment(t *testing.T) {
+	gens := NewGenerators([]byte("test"), 8)
+
+	t.Run("Single", func(t *testing.T) {
+		v, r := mustRandomScalar(t), mustRandomScalar(t)
+		c := gens.Commit(v, r)
+		if !gens.Verify(c, v, r) {
+			t.Fatalf("Verify: failed")
+		}
+		if gens.Verify(c, r, v) {
+			t.Fatalf("Verify(swapped): succeeded")
+		}
+
+		b, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: %v", err)
+		}
+		if len(b) != CommitmentSize {
+			t.Fatalf("unexpected serialized size: %d", len(b))
+		}
+		c2, err := NewCommitmentFromBytes(b)
+		if err != nil {
+			t.Fatalf("NewCommitmentFromBytes: %v", err)
+		}
+		if c2.Equal(c) != 1 {
+			t.Fatalf("serialization round-trip mismatch")
+		}
+		if _, err = NewCommitmentFromBytes(b[:31]); err == nil {
+			t.Fatalf("NewCommitmentFromBytes(truncated): succeeded")
+		}
+	})
+	t.Run("Vector", func(t *testing.T) {
+		values, r := mustRandomScalars(t, 5), mustRandomScalar(t)
+		c, err := gens.CommitVector(values, r)
+		if err != nil {
+			t.Fatalf("CommitVector: %v", err)
+		}
+		if !gens.VerifyVector(c, values, r) {
+			t.Fatalf("VerifyVector: failed")
+		}
+		if gens.VerifyVector(c, values[:4], r) {
+			t.Fatalf("VerifyVector(truncated): succeeded")
+		}
+
+		if _, err = gens.CommitVector(mustRandomScalars(t, 9), r); err != ErrVectorLength {
+			t.Fatalf("CommitVector(too long): %v", err)
+		}
+	})
+	t.Run("Homomorphic", func(t *testing.T) {
+		v1, r1 := mustRandomScalar(t), mustRandomScalar(t)
+		v2, r2 := mustRandomScalar(t), mustRandomScalar(t)
+		c1, c2 := gens.Commit(v1, r1), gens.Commit(v2, r2)
+
+		var c Commitment
+		c.Add(c1, c2)
+		if !gens.Verify(&c, scalar.New().Add(v1, v2), scalar.New().Add(r1, r2)) {
+			t.Fatalf("Add: failed")
+		}
+		c.Sub(c1, c2)
+		if !gens.Verify(&c, scalar.New().Sub(v1, v2), scalar.New().Sub(r1, r2)) {
+			t.Fatalf("Sub: failed")
+		}
+		c.Neg(c1)
+		if !gens.Verify(&c, scalar.New().Neg(v1), scalar.New().Neg(r1)) {
+			t.Fatalf("Neg: failed")
+		}
+		c.Mul(c1, v2)
+		if !gens.Verify(&c, scalar.New().Mul(v1, v2), scalar.New().Mul(r1, v2)) {
+			t.Fatalf("Mul: failed")
+		}
+	})
+}
+
+func TestBatchVerifier(t *testing.T) {
+	gens := NewGenerators([]byte("test"), 4)
+
+	newBatch := func(badIdx int) *BatchVerifier {
+		v := NewBatchVerifier(gens)
+		for i := 0; i < 8; i++ {
+			r := mustRandomScalar(t)
+			switch i%2 == 0 {
+			case true:
+				value := mustRandomScalar(t)
+				c := gens.Commit(value, r)
+				if i == badIdx {
+					value = scalar.New().Add(value, scalar.One())
+				}
+				v.Add(c, value, r)
+			case false:
+				values := mustRandomScalars(t, 1+i%4)
+				c, err := gens.CommitVector(values, r)
+				if err != nil {
+					t.Fatalf("CommitVector: %v", err)
+				}
+				if i == badIdx {
+					r = scalar.New().Add(r, scalar.One())
+				}
+				v.AddVector(c, values, r)
+			}
+		}
+		return v
+	}
+
+	if !newBatch(-1).Verify(nil) {
+		t.Fatalf("Verify: failed")
+	}
+	for _, badIdx := range []int{0, 3} {
+		if newBatch(badIdx).Verify(nil) {
+			t.Fatalf("Verify(bad %d): succeeded", badIdx)
+		}
+	}
+	if NewBatchVerifier(gens).Verify(nil) {
+		t.Fatalf("Verify(empty): succeeded")
+	}
+
+	v := newBatch(-1)
+	v.AddVector(gens.Commit(scalar.One(), scalar.One()), mustRandomScalars(t, 5), scalar.One())
+	if v.Verify(nil) {
+		t.Fatalf("Verify(vector too long): succeeded")
+	}
+}
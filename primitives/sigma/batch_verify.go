@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sigma
+
+import (
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+// BatchVerifier accumulates batchable proofs for a statement, so that
+// they can be verified together.
+type BatchVerifier struct {
+	st *Statement
+
+	entries []*batchEntry
+	err     error
+}
+
+type batchEntry struct {
+	pts         *Points
+	challenge   *scalar.Scalar
+	commitments []element
+	responses   []*scalar.Scalar
+}
+
+// Add adds a batchable proof, with the public points pts, to the
+// current batch.
+func (v *BatchVerifier) Add(t *merlin.Transcript, pts *Points, proof *BatchableProof) {
+	e, err := v.newEntry(t, pts, proof)
+	if err != nil {
+		if v.err == nil {
+			v.err = err
+		}
+		return
+	}
+	v.entries = append(v.entries, e)
+}
+
+func (v *BatchVerifier) newEntry(t *merlin.Transcript, pts *Points, proof *BatchableProof) (*batchEntry, error) {
+	st := v.st
+	if len(proof.responses) != len(st.scalarLabels) || len(proof.commitments) != len(st.constraints) {
+		return nil, ErrVerification
+	}
+	if err := pts.check(st); err != nil {
+		return nil, err
+	}
+	if err := st.appendToTranscript(t, pts, true); err != nil {
+		return nil, err
+	}
+
+	commitments := make([]element, 0, len(proof.commitments))
+	for i, b := range proof.commitments {
+		commitment, err := st.group.deserializeElement(b)
+		if err != nil || commitment.isIdentity() {
+			return nil, ErrVerification
+		}
+		transcriptAppendBlindingCommitment(t, st.pointLabels[st.constraints[i].lhs], b)
+		commitments = append(commitments, commitment)
+	}
+
+	return &batchEntry{
+		pts:         pts,
+		challenge:   transcriptChallengeScalar(t, "chal"),
+		commitments: commitments,
+		responses:   proof.responses,
+	}, nil
+}
+
+// Verify checks all proofs in the current batch using entropy from
+// rand, returning nil iff all proofs are valid.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// If a failure arises it is unknown which proof failed, the caller
+// must verify each proof individually.
+func (v *BatchVerifier) Verify(rand io.Reader) error {
+	switch {
+	case v.err != nil:
+		return v.err
+	case len(v.entries) == 0:
+		// Abort early on an empty batch, which probably indicates a bug.
+		return ErrVerification
+	}
+
+	// For each proof, and each constraint `P = sum(x_i * Q_i)` with
+	// commitment A, check that
+	//
+	//   sum(r_i * Q_i) - c * P - A = 0
+	//
+	// weighted by a random z.
+	var scalars []*scalar.Scalar
+	var elements []element
+	var tmp scalar.Scalar
+	for _, e := range v.entries {
+		for i := range v.st.constraints {
+			c := &v.st.constraints[i]
+			z, err := scalar.New().SetRandom(rand)
+			if err != nil {
+				return err
+			}
+
+			for _, term := range c.terms {
+				scalars = append(scalars, scalar.New().Mul(z, e.responses[term.Scalar]))
+				elements = append(elements, e.pts.elements[term.Point])
+			}
+			scalars = append(scalars, scalar.New().Neg(tmp.Mul(z, e.challenge)))
+			elements = append(elements, e.pts.elements[c.lhs])
+			scalars = append(scalars, scalar.New().Neg(z))
+			elements = append(elements, e.commitments[i])
+		}
+	}
+
+	if !v.st.group.multiscalarMulVartime(scalars, elements).isIdentity() {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+// NewBatchVerifier creates an empty BatchVerifier for the statement.
+func NewBatchVerifier(st *Statement) *BatchVerifier {
+	return &BatchVerifier{
+		st: st,
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sigma
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+const elementSize = curve.CompressedPointSize
+
+// Group is a prime-order group that statements can be made over.
+type Group interface {
+	// Name returns the name of the group.
+	Name() string
+
+	domainSep() string
+	multiscalarMul(scalars []*scalar.Scalar, elements []element) element
+	multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element
+	deserializeElement(b []byte) (element, error)
+}
+
+// element is a prime-order group element.
+type element interface {
+	isIdentity() bool
+	bytes() []byte
+}
+
+var (
+	// Ristretto255 is the ristretto255 group.
+	Ristretto255 Group = ristretto255Group{}
+
+	// Edwards25519 is the prime-order subgroup of the Edwards25519
+	// curve.  Points that are not in the prime-order subgroup are
+	// rejected.
+	Edwards25519 Group = edwards25519Group{}
+)
+
+type ristrettoElement struct {
+	p curve.RistrettoPoint
+}
+
+func (e *ristrettoElement) isIdentity() bool {
+	return e.p.IsIdentity()
+}
+
+func (e *ristrettoElement) bytes() []byte {
+	var compressed curve.CompressedRistretto
+	compressed.SetRistrettoPoint(&e.p)
+	return compressed[:]
+}
+
+type ristretto255Group struct{}
+
+func (g ristretto255Group) Name() string {
+	return "ristretto255"
+}
+
+func (g ristretto255Group) domainSep() string {
+	return "schnorrzkp/1.0/ristretto255"
+}
+
+func (g ristretto255Group) points(elements []element) []*curve.RistrettoPoint {
+	points := make([]*curve.RistrettoPoint, 0, len(elements))
+	for _, e := range elements {
+		points = append(points, &e.(*ristrettoElement).p)
+	}
+	return points
+}
+
+func (g ristretto255Group) multiscalarMul(scalars []*scalar.Scalar, elements []element) element {
+	var v ristrettoElement
+	v.p.MultiscalarMul(scalars, g.points(elements))
+	return &v
+}
+
+func (g ristretto255Group) multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element {
+	var v ristrettoElement
+	v.p.MultiscalarMulVartime(scalars, g.points(elements))
+	return &v
+}
+
+func (g ristretto255Group) deserializeElement(b []byte) (element, error) {
+	var compressed curve.CompressedRistretto
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("sigma: failed to deserialize element: %w", err)
+	}
+
+	var v ristrettoElement
+	if _, err := v.p.SetCompressed(&compressed); err != nil {
+		return nil, fmt.Errorf("sigma: failed to decompress element: %w", err)
+	}
+
+	return &v, nil
+}
+
+type edwardsElement struct {
+	p curve.EdwardsPoint
+}
+
+func (e *edwardsElement) isIdentity() bool {
+	return e.p.IsIdentity()
+}
+
+func (e *edwardsElement) bytes() []byte {
+	var compressed curve.CompressedEdwardsY
+	compressed.SetEdwardsPoint(&e.p)
+	return compressed[:]
+}
+
+type edwards25519Group struct{}
+
+func (g edwards25519Group) Name() string {
+	return "edwards25519"
+}
+
+func (g edwards25519Group) domainSep() string {
+	return "schnorrzkp/1.0/edwards25519"
+}
+
+func (g edwards25519Group) points(elements []element) []*curve.EdwardsPoint {
+	points := make([]*curve.EdwardsPoint, 0, len(elements))
+	for _, e := range elements {
+		points = append(points, &e.(*edwardsElement).p)
+	}
+	return points
+}
+
+func (g edwards25519Group) multiscalarMul(scalars []*scalar.Scalar, elements []element) element {
+	var v edwardsElement
+	v.p.MultiscalarMul(scalars, g.points(elements))
+	return &v
+}
+
+func (g edwards25519Group) multiscalarMulVartime(scalars []*scalar.Scalar, elements []element) element {
+	var v edwardsElement
+	v.p.MultiscalarMulVartime(scalars, g.points(elements))
+	return &v
+}
+
+func (g edwards25519Group) deserializeElement(b []byte) (element, error) {
+	var compressed curve.CompressedEdwardsY
+	if _, err := compressed.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("sigma: failed to deserialize element: %w", err)
+	}
+	if !compressed.IsCanonicalVartime() {
+		return nil, fmt.Errorf("sigma: non-canonical element")
+	}
+
+	var v edwardsElement
+	if _, err := v.p.SetCompressedY(&compressed); err != nil {
+		return nil, fmt.Errorf("sigma: failed to decompress element: %w", err)
+	}
+	if !v.p.IsTorsionFree() {
+		return nil, errNotPrimeOrder
+	}
+
+	return &v, nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sigma
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// CompactProof is a proof consisting of the challenge and the responses.
+type CompactProof struct {
+	challenge *scalar.Scalar
+	responses []*scalar.Scalar
+}
+
+// MarshalBinary encodes a CompactProof into binary form, as
+// `challenge || responses`.
+func (proof *CompactProof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, (1+len(proof.responses))*scalar.ScalarSize)
+	b, err := appendScalar(b, proof.challenge)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range proof.responses {
+		if b, err = appendScalar(b, s); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// ParseCompactProof decodes a binary marshaled CompactProof for the
+// statement.
+func (st *Statement) ParseCompactProof(b []byte) (*CompactProof, error) {
+	if len(b) != (1+len(st.scalarLabels))*scalar.ScalarSize {
+		return nil, ErrFormat
+	}
+
+	challenge, err := scalar.NewFromCanonicalBytes(b[:scalar.ScalarSize])
+	if err != nil {
+		return nil, ErrFormat
+	}
+	responses, err := parseScalars(b[scalar.ScalarSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompactProof{
+		challenge: challenge,
+		responses: responses,
+	}, nil
+}
+
+// BatchableProof is a proof consisting of the commitments and the
+// responses.
+type BatchableProof struct {
+	commitments [][]byte
+	responses   []*scalar.Scalar
+}
+
+// MarshalBinary encodes a BatchableProof into binary form, as
+// `commitments || responses`.
+func (proof *BatchableProof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, len(proof.commitments)*elementSize+len(proof.responses)*scalar.ScalarSize)
+	for _, c := range proof.commitments {
+		b = append(b, c...)
+	}
+
+	var err error
+	for _, s := range proof.responses {
+		if b, err = appendScalar(b, s); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// ParseBatchableProof decodes a binary marshaled BatchableProof for the
+// statement.  Note that the commitments are only decompressed during
+// verification.
+func (st *Statement) ParseBatchableProof(b []byte) (*BatchableProof, error) {
+	numCommitments := len(st.constraints)
+	if len(b) != numCommitments*elementSize+len(st.scalarLabels)*scalar.ScalarSize {
+		return nil, ErrFormat
+	}
+
+	commitments := make([][]byte, 0, numCommitments)
+	for i := 0; i < numCommitments; i++ {
+		commitments = append(commitments, append([]byte{}, b[i*elementSize:(i+1)*elementSize]...))
+	}
+	responses, err := parseScalars(b[numCommitments*elementSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatchableProof{
+		commitments: commitments,
+		responses:   responses,
+	}, nil
+}
+
+func appendScalar(b []byte, s *scalar.Scalar) ([]byte, error) {
+	var tmp [scalar.ScalarSize]byte
+	if err := s.ToBytes(tmp[:]); err != nil {
+		return nil, fmt.Errorf("sigma: failed to serialize scalar: %w", err)
+	}
+	return append(b, tmp[:]...), nil
+}
+
+func parseScalars(b []byte) ([]*scalar.Scalar, error) {
+	n := len(b) / scalar.ScalarSize
+	ret := make([]*scalar.Scalar, 0, n)
+	for i := 0; i < n; i++ {
+		s, err := scalar.NewFromCanonicalBytes(b[i*scalar.ScalarSize : (i+1)*scalar.ScalarSize])
+		if err != nil {
+			return nil, ErrFormat
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
@@ -0,0 +1,401 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package sigma implements non-interactive zero-knowledge proofs of
+// knowledge of secret scalars satisfying a set of linear relations
+// over prime-order group elements (Sigma protocols), made
+// non-interactive with a Merlin transcript.
+//
+// This is in the spirit of the `zkp` crate's `define_proof` macro, with
+// the statement described at runtime.  For example, a proof of
+// discrete log equality (DLEQ) is described as:
+//
+//	st := sigma.NewStatement(sigma.Ristretto255, "DLEQ")
+//	x := st.Scalar("x")
+//	A, B, G, H := st.Point("A"), st.Point("B"), st.Point("G"), st.Point("H")
+//	st.Constrain(A, sigma.Term{Scalar: x, Point: G})
+//	st.Constrain(B, sigma.Term{Scalar: x, Point: H})
+//
+// Proofs come in two encodings: compact proofs, consisting of the
+// challenge and the responses, and batchable proofs, consisting of
+// the commitments and the responses, which can be verified in a batch.
+package sigma
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+var (
+	// ErrVerification is the error returned when a proof fails to verify.
+	ErrVerification = fmt.Errorf("sigma: proof verification failed")
+
+	// ErrFormat is the error returned when a proof is malformed.
+	ErrFormat = fmt.Errorf("sigma: proof data could not be parsed")
+
+	// ErrUnassigned is the error returned when a variable has not been
+	// assigned a value.
+	ErrUnassigned = fmt.Errorf("sigma: variable not assigned")
+
+	errWrongGroup    = fmt.Errorf("sigma: point is not in the statement's group")
+	errNotPrimeOrder = fmt.Errorf("sigma: point is not in the prime-order subgroup")
+)
+
+// ScalarVar is a secret scalar variable in a Statement.
+type ScalarVar int
+
+// PointVar is a public point variable in a Statement.
+type PointVar int
+
+// Term is the product of a secret scalar and a public point.
+type Term struct {
+	Scalar ScalarVar
+	Point  PointVar
+}
+
+type constraint struct {
+	lhs   PointVar
+	terms []Term
+}
+
+// Statement describes a set of linear relations of the form
+// `P = x_0 * Q_0 + ... + x_n * Q_n`, where the x_i are secret scalars,
+// and P and the Q_i are public points.
+type Statement struct {
+	group Group
+	name  string
+
+	scalarLabels []string
+	pointLabels  []string
+	constraints  []constraint
+}
+
+// Group returns the group that the statement is over.
+func (st *Statement) Group() Group {
+	return st.group
+}
+
+// Name returns the name of the statement.
+func (st *Statement) Name() string {
+	return st.name
+}
+
+// NumScalars returns the number of secret scalars in the statement.
+func (st *Statement) NumScalars() int {
+	return len(st.scalarLabels)
+}
+
+// NumPoints returns the number of public points in the statement.
+func (st *Statement) NumPoints() int {
+	return len(st.pointLabels)
+}
+
+// NumConstraints returns the number of constraints in the statement.
+func (st *Statement) NumConstraints() int {
+	return len(st.constraints)
+}
+
+// Scalar allocates a secret scalar variable with the given label.
+func (st *Statement) Scalar(label string) ScalarVar {
+	st.scalarLabels = append(st.scalarLabels, label)
+	return ScalarVar(len(st.scalarLabels) - 1)
+}
+
+// Point allocates a public point variable with the given label.
+func (st *Statement) Point(label string) PointVar {
+	st.pointLabels = append(st.pointLabels, label)
+	return PointVar(len(st.pointLabels) - 1)
+}
+
+// Constrain adds the constraint `lhs = sum(terms)` to the statement.
+// This will panic if any of the variables were not allocated by st,
+// or if terms is empty.
+func (st *Statement) Constrain(lhs PointVar, terms ...Term) {
+	st.checkPointVar(lhs)
+	if len(terms) == 0 {
+		panic("sigma: constraint has no terms")
+	}
+	for _, term := range terms {
+		st.checkPointVar(term.Point)
+		if term.Scalar < 0 || int(term.Scalar) >= len(st.scalarLabels) {
+			panic("sigma: invalid scalar variable")
+		}
+	}
+
+	st.constraints = append(st.constraints, constraint{
+		lhs:   lhs,
+		terms: append([]Term{}, terms...),
+	})
+}
+
+func (st *Statement) checkPointVar(v PointVar) {
+	if v < 0 || int(v) >= len(st.pointLabels) {
+		panic("sigma: invalid point variable")
+	}
+}
+
+// NewPoints creates an empty assignment of the statement's public points.
+func (st *Statement) NewPoints() *Points {
+	return &Points{
+		st:       st,
+		elements: make([]element, len(st.pointLabels)),
+	}
+}
+
+// NewStatement creates a new empty statement over the group, with
+// a name used for domain separation.
+func NewStatement(group Group, name string) *Statement {
+	return &Statement{
+		group: group,
+		name:  name,
+	}
+}
+
+// Points is an assignment of values to a Statement's public points.
+type Points struct {
+	st       *Statement
+	elements []element
+}
+
+// SetRistretto assigns a ristretto255 point to the variable v.
+func (pts *Points) SetRistretto(v PointVar, p *curve.RistrettoPoint) error {
+	pts.st.checkPointVar(v)
+	if pts.st.group != Ristretto255 {
+		return errWrongGroup
+	}
+
+	var e ristrettoElement
+	e.p.Set(p)
+	pts.elements[v] = &e
+	return nil
+}
+
+// SetEdwards assigns an Edwards25519 point to the variable v.  The
+// point must be in the prime-order subgroup.
+func (pts *Points) SetEdwards(v PointVar, p *curve.EdwardsPoint) error {
+	pts.st.checkPointVar(v)
+	if pts.st.group != Edwards25519 {
+		return errWrongGroup
+	}
+	if !p.IsTorsionFree() {
+		return errNotPrimeOrder
+	}
+
+	var e edwardsElement
+	e.p.Set(p)
+	pts.elements[v] = &e
+	return nil
+}
+
+// SetBytes assigns the point encoded by b to the variable v.
+func (pts *Points) SetBytes(v PointVar, b []byte) error {
+	pts.st.checkPointVar(v)
+	e, err := pts.st.group.deserializeElement(b)
+	if err != nil {
+		return err
+	}
+	pts.elements[v] = e
+	return nil
+}
+
+func (pts *Points) check(st *Statement) error {
+	if pts.st != st {
+		return fmt.Errorf("sigma: points are for a different statement")
+	}
+	for _, e := range pts.elements {
+		if e == nil {
+			return ErrUnassigned
+		}
+	}
+	return nil
+}
+
+func (st *Statement) appendToTranscript(t *merlin.Transcript, pts *Points, validate bool) error {
+	transcriptDomainSep(t, st.group, st.name)
+	for _, label := range st.scalarLabels {
+		transcriptAppendScalarVar(t, label)
+	}
+	for i, label := range st.pointLabels {
+		e := pts.elements[i]
+		if validate && e.isIdentity() {
+			return ErrVerification
+		}
+		transcriptAppendPointVar(t, label, e.bytes())
+	}
+	return nil
+}
+
+// constraintTerms returns the scalars and elements of the right hand
+// side of the constraint, with the scalars taken from scalars.
+func (st *Statement) constraintTerms(c *constraint, scalars []*scalar.Scalar, pts *Points) ([]*scalar.Scalar, []element) {
+	s := make([]*scalar.Scalar, 0, len(c.terms)+1)
+	e := make([]element, 0, len(c.terms)+1)
+	for _, term := range c.terms {
+		s = append(s, scalars[term.Scalar])
+		e = append(e, pts.elements[term.Point])
+	}
+	return s, e
+}
+
+// prove runs the prover, and returns the challenge, the responses,
+// and the commitments.
+func (st *Statement) prove(t *merlin.Transcript, witness []*scalar.Scalar, pts *Points, rand io.Reader) (*scalar.Scalar, []*scalar.Scalar, [][]byte, error) {
+	if len(witness) != len(st.scalarLabels) {
+		return nil, nil, nil, ErrUnassigned
+	}
+	for _, s := range witness {
+		if s == nil {
+			return nil, nil, nil, ErrUnassigned
+		}
+	}
+	if err := pts.check(st); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := st.appendToTranscript(t, pts, false); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Derive the blinding factors from the transcript, the witness, and
+	// the entropy source.
+	rb := t.BuildRng()
+	var tmp [scalar.ScalarSize]byte
+	for _, s := range witness {
+		if err := s.ToBytes(tmp[:]); err != nil {
+			return nil, nil, nil, fmt.Errorf("sigma: failed to serialize scalar: %w", err)
+		}
+		rb.RekeyWithWitnessBytes("", tmp[:])
+	}
+	rng, err := rb.Finalize(rand)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("sigma: failed to construct transcript rng: %w", err)
+	}
+	blindings := make([]*scalar.Scalar, 0, len(witness))
+	for range witness {
+		b, err := scalar.New().SetRandom(rng)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		blindings = append(blindings, b)
+	}
+
+	// Commit to each blinded left hand side.
+	commitments := make([][]byte, 0, len(st.constraints))
+	for i := range st.constraints {
+		c := &st.constraints[i]
+		scalars, elements := st.constraintTerms(c, blindings, pts)
+		commitment := st.group.multiscalarMul(scalars, elements).bytes()
+		transcriptAppendBlindingCommitment(t, st.pointLabels[c.lhs], commitment)
+		commitments = append(commitments, commitment)
+	}
+
+	// Obtain a scalar challenge and compute the responses.
+	challenge := transcriptChallengeScalar(t, "chal")
+	responses := make([]*scalar.Scalar, 0, len(witness))
+	for i, s := range witness {
+		r := scalar.New().Mul(s, challenge)
+		responses = append(responses, r.Add(r, blindings[i]))
+	}
+
+	return challenge, responses, commitments, nil
+}
+
+// ProveCompact creates a compact proof of knowledge of the witness,
+// which assigns a value to each secret scalar, using entropy from
+// rand mixed with the transcript and witness.  If rand is nil,
+// crypto/rand.Reader will be used.
+func (st *Statement) ProveCompact(t *merlin.Transcript, witness []*scalar.Scalar, pts *Points, rand io.Reader) (*CompactProof, error) {
+	challenge, responses, _, err := st.prove(t, witness, pts, rand)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactProof{
+		challenge: challenge,
+		responses: responses,
+	}, nil
+}
+
+// ProveBatchable creates a batchable proof of knowledge of the witness,
+// which assigns a value to each secret scalar, using entropy from
+// rand mixed with the transcript and witness.  If rand is nil,
+// crypto/rand.Reader will be used.
+func (st *Statement) ProveBatchable(t *merlin.Transcript, witness []*scalar.Scalar, pts *Points, rand io.Reader) (*BatchableProof, error) {
+	_, responses, commitments, err := st.prove(t, witness, pts, rand)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchableProof{
+		commitments: commitments,
+		responses:   responses,
+	}, nil
+}
+
+// VerifyCompact verifies a compact proof.
+func (st *Statement) VerifyCompact(t *merlin.Transcript, pts *Points, proof *CompactProof) error {
+	if len(proof.responses) != len(st.scalarLabels) {
+		return ErrVerification
+	}
+	if err := pts.check(st); err != nil {
+		return err
+	}
+	if err := st.appendToTranscript(t, pts, true); err != nil {
+		return err
+	}
+
+	// Recompute the prover's commitments based on their claimed
+	// challenge value.
+	minusC := scalar.New().Neg(proof.challenge)
+	for i := range st.constraints {
+		c := &st.constraints[i]
+		scalars, elements := st.constraintTerms(c, proof.responses, pts)
+		scalars = append(scalars, minusC)
+		elements = append(elements, pts.elements[c.lhs])
+		commitment := st.group.multiscalarMulVartime(scalars, elements).bytes()
+		transcriptAppendBlindingCommitment(t, st.pointLabels[c.lhs], commitment)
+	}
+
+	challenge := transcriptChallengeScalar(t, "chal")
+	if challenge.Equal(proof.challenge) != 1 {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+// VerifyBatchable verifies a batchable proof.
+func (st *Statement) VerifyBatchable(t *merlin.Transcript, pts *Points, proof *BatchableProof) error {
+	v := NewBatchVerifier(st)
+	v.Add(t, pts, proof)
+	return v.Verify(nil)
+}
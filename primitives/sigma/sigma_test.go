@@ -0,0 +1,317 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sigma
+
+import (
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const testTranscriptLabel = "sigma test"
+
+type testCase struct {
+	st      *Statement
+	witness []*scalar.Scalar
+	pts     *Points
+}
+
+func mustRandomScalar(t *testing.T) *scalar.Scalar {
+	s, err := scalar.New().SetRandom(nil)
+	if err != nil {
+		t.Fatalf("scalar.SetRandom: %v", err)
+	}
+	return s
+}
+
+// newDLEQStatement builds a statement of `A = x * G && B = x * H`.
+func newDLEQStatement(group Group) *Statement {
+	st := NewStatement(group, "DLEQ")
+	x := st.Scalar("x")
+	A, B, G, H := st.Point("A"), st.Point("B"), st.Point("G"), st.Point("H")
+	st.Constrain(A, Term{x, G})
+	st.Constrain(B, Term{x, H})
+	return st
+}
+
+// newDLEQ builds a random instance of the DLEQ statement.
+func newDLEQ(t *testing.T, st *Statement) *testCase {
+	group := st.Group()
+	A, B, G, H := PointVar(0), PointVar(1), PointVar(2), PointVar(3)
+
+	xVal := mustRandomScalar(t)
+	h := mustRandomScalar(t)
+	pts := st.NewPoints()
+	switch group {
+	case Ristretto255:
+		gPt := curve.NewRistrettoPoint().Set(curve.RISTRETTO_BASEPOINT_POINT)
+		hPt := curve.NewRistrettoPoint().Mul(gPt, h)
+		for _, v := range []struct {
+			v PointVar
+			p *curve.RistrettoPoint
+		}{
+			{A, curve.NewRistrettoPoint().Mul(gPt, xVal)},
+			{B, curve.NewRistrettoPoint().Mul(hPt, xVal)},
+			{G, gPt},
+			{H, hPt},
+		} {
+			if err := pts.SetRistretto(v.v, v.p); err != nil {
+				t.Fatalf("SetRistretto: %v", err)
+			}
+		}
+	case Edwards25519:
+		gPt := curve.NewEdwardsPoint().Set(curve.ED25519_BASEPOINT_POINT)
+		hPt := curve.NewEdwardsPoint().Mul(gPt, h)
+		for _, v := range []struct {
+			v PointVar
+			p *curve.EdwardsPoint
+		}{
+			{A, curve.NewEdwardsPoint().Mul(gPt, xVal)},
+			{B, curve.NewEdwardsPoint().Mul(hPt, xVal)},
+			{G, gPt},
+			{H, hPt},
+		} {
+			if err := pts.SetEdwards(v.v, v.p); err != nil {
+				t.Fatalf("SetEdwards: %v", err)
+			}
+		}
+	}
+
+	return &testCase{
+		st:      st,
+		witness: []*scalar.Scalar{xVal},
+		pts:     pts,
+	}
+}
+
+// newOpening builds a proof of knowledge of the opening of two
+// Pedersen commitments sharing the value, `C_0 = v * G + r_0 * H &&
+// C_1 = v * G + r_1 * H`.
+func newOpening(t *testing.T) *testCase {
+	st := NewStatement(Ristretto255, "PedersenOpening")
+	v, r0, r1 := st.Scalar("v"), st.Scalar("r0"), st.Scalar("r1")
+	C0, C1, G, H := st.Point("C0"), st.Point("C1"), st.Point("G"), st.Point("H")
+	st.Constrain(C0, Term{v, G}, Term{r0, H})
+	st.Constrain(C1, Term{v, G}, Term{r1, H})
+
+	witness := []*scalar.Scalar{mustRandomScalar(t), mustRandomScalar(t), mustRandomScalar(t)}
+	gPt := curve.NewRistrettoPoint().Set(curve.RISTRETTO_BASEPOINT_POINT)
+	hPt, err := curve.NewRistrettoPoint().SetRandom(nil)
+	if err != nil {
+		t.Fatalf("RistrettoPoint.SetRandom: %v", err)
+	}
+
+	pts := st.NewPoints()
+	commit := func(r *scalar.Scalar) *curve.RistrettoPoint {
+		return curve.NewRistrettoPoint().MultiscalarMul([]*scalar.Scalar{witness[0], r}, []*curve.RistrettoPoint{gPt, hPt})
+	}
+	_ = pts.SetRistretto(C0, commit(witness[1]))
+	_ = pts.SetRistretto(C1, commit(witness[2]))
+	_ = pts.SetRistretto(G, gPt)
+	_ = pts.SetRistretto(H, hPt)
+
+	return &testCase{
+		st:      st,
+		witness: witness,
+		pts:     pts,
+	}
+}
+
+// reparsePoints round-trips the points through their encoding, as
+// a verifier would receive them.
+func reparsePoints(t *testing.T, tc *testCase) *Points {
+	pts := tc.st.NewPoints()
+	for i, e := range tc.pts.elements {
+		if err := pts.SetBytes(PointVar(i), e.bytes()); err != nil {
+			t.Fatalf("SetBytes: %v", err)
+		}
+	}
+	return pts
+}
+
+func TestSigma(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		fn   func(*testing.T) *testCase
+	}{
+		{"DLEQ/ristretto255", func(t *testing.T) *testCase { return newDLEQ(t, newDLEQStatement(Ristretto255)) }},
+		{"DLEQ/edwards25519", func(t *testing.T) *testCase { return newDLEQ(t, newDLEQStatement(Edwards25519)) }},
+		{"PedersenOpening", newOpening},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			tc := v.fn(t)
+
+			t.Run("Compact", func(t *testing.T) {
+				proof, err := tc.st.ProveCompact(merlin.NewTranscript(testTranscriptLabel), tc.witness, tc.pts, nil)
+				if err != nil {
+					t.Fatalf("ProveCompact: %v", err)
+				}
+				b, err := proof.MarshalBinary()
+				if err != nil {
+					t.Fatalf("MarshalBinary: %v", err)
+				}
+				if len(b) != 32*(1+tc.st.NumScalars()) {
+					t.Fatalf("unexpected proof size: %d", len(b))
+				}
+				proof, err = tc.st.ParseCompactProof(b)
+				if err != nil {
+					t.Fatalf("ParseCompactProof: %v", err)
+				}
+
+				pts := reparsePoints(t, tc)
+				if err = tc.st.VerifyCompact(merlin.NewTranscript(testTranscriptLabel), pts, proof); err != nil {
+					t.Fatalf("VerifyCompact: %v", err)
+				}
+				if err = tc.st.VerifyCompact(merlin.NewTranscript("bad transcript"), pts, proof); err != ErrVerification {
+					t.Fatalf("VerifyCompact(bad transcript): %v", err)
+				}
+
+				b[len(b)-1] ^= 0x01
+				if proof, err = tc.st.ParseCompactProof(b); err == nil {
+					if err = tc.st.VerifyCompact(merlin.NewTranscript(testTranscriptLabel), pts, proof); err != ErrVerification {
+						t.Fatalf("VerifyCompact(tampered): %v", err)
+					}
+				}
+				if _, err = tc.st.ParseCompactProof(b[:len(b)-1]); err != ErrFormat {
+					t.Fatalf("ParseCompactProof(truncated): %v", err)
+				}
+			})
+			t.Run("Batchable", func(t *testing.T) {
+				proof, err := tc.st.ProveBatchable(merlin.NewTranscript(testTranscriptLabel), tc.witness, tc.pts, nil)
+				if err != nil {
+					t.Fatalf("ProveBatchable: %v", err)
+				}
+				b, err := proof.MarshalBinary()
+				if err != nil {
+					t.Fatalf("MarshalBinary: %v", err)
+				}
+				if len(b) != 32*(tc.st.NumConstraints()+tc.st.NumScalars()) {
+					t.Fatalf("unexpected proof size: %d", len(b))
+				}
+				proof, err = tc.st.ParseBatchableProof(b)
+				if err != nil {
+					t.Fatalf("ParseBatchableProof: %v", err)
+				}
+
+				pts := reparsePoints(t, tc)
+				if err = tc.st.VerifyBatchable(merlin.NewTranscript(testTranscriptLabel), pts, proof); err != nil {
+					t.Fatalf("VerifyBatchable: %v", err)
+				}
+				if err = tc.st.VerifyBatchable(merlin.NewTranscript("bad transcript"), pts, proof); err != ErrVerification {
+					t.Fatalf("VerifyBatchable(bad transcript): %v", err)
+				}
+			})
+			t.Run("WrongWitness", func(t *testing.T) {
+				witness := append([]*scalar.Scalar{}, tc.witness...)
+				witness[0] = scalar.New().Add(witness[0], scalar.One())
+
+				proof, err := tc.st.ProveCompact(merlin.NewTranscript(testTranscriptLabel), witness, tc.pts, nil)
+				if err != nil {
+					t.Fatalf("ProveCompact: %v", err)
+				}
+				if err = tc.st.VerifyCompact(merlin.NewTranscript(testTranscriptLabel), tc.pts, proof); err != ErrVerification {
+					t.Fatalf("VerifyCompact(wrong witness): %v", err)
+				}
+
+				if _, err = tc.st.ProveCompact(merlin.NewTranscript(testTranscriptLabel), witness[1:], tc.pts, nil); err != ErrUnassigned {
+					t.Fatalf("ProveCompact(short witness): %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestBatchVerifier(t *testing.T) {
+	const numProofs = 8
+
+	st := newDLEQStatement(Ristretto255)
+
+	var (
+		allPts []*Points
+		proofs []*BatchableProof
+	)
+	for i := 0; i < numProofs; i++ {
+		tc := newDLEQ(t, st)
+		proof, err := st.ProveBatchable(merlin.NewTranscript(testTranscriptLabel), tc.witness, tc.pts, nil)
+		if err != nil {
+			t.Fatalf("ProveBatchable: %v", err)
+		}
+		allPts = append(allPts, tc.pts)
+		proofs = append(proofs, proof)
+	}
+
+	v := NewBatchVerifier(st)
+	for i := range proofs {
+		v.Add(merlin.NewTranscript(testTranscriptLabel), allPts[i], proofs[i])
+	}
+	if err := v.Verify(nil); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Swap the points of two of the proofs.
+	v = NewBatchVerifier(st)
+	for i := range proofs {
+		pts := allPts[i]
+		if i == 3 {
+			pts = allPts[4]
+		}
+		v.Add(merlin.NewTranscript(testTranscriptLabel), pts, proofs[i])
+	}
+	if err := v.Verify(nil); err != ErrVerification {
+		t.Fatalf("Verify(bad): %v", err)
+	}
+
+	if err := NewBatchVerifier(st).Verify(nil); err != ErrVerification {
+		t.Fatalf("Verify(empty): %v", err)
+	}
+}
+
+func TestPoints(t *testing.T) {
+	st := NewStatement(Edwards25519, "test")
+	P := st.Point("P")
+
+	pts := st.NewPoints()
+	if err := pts.SetRistretto(P, curve.RISTRETTO_BASEPOINT_POINT); err != errWrongGroup {
+		t.Fatalf("SetRistretto(wrong group): %v", err)
+	}
+	if err := pts.SetEdwards(P, curve.EIGHT_TORSION[1]); err != errNotPrimeOrder {
+		t.Fatalf("SetEdwards(torsion): %v", err)
+	}
+	var compressed curve.CompressedEdwardsY
+	compressed.SetEdwardsPoint(curve.EIGHT_TORSION[1])
+	if err := pts.SetBytes(P, compressed[:]); err != errNotPrimeOrder {
+		t.Fatalf("SetBytes(torsion): %v", err)
+	}
+	if err := pts.check(st); err != ErrUnassigned {
+		t.Fatalf("check(unassigned): %v", err)
+	}
+}
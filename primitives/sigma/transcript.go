@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sigma
+
+import (
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+func transcriptDomainSep(t *merlin.Transcript, group Group, label string) {
+	t.AppendMessage("dom-sep", []byte(group.domainSep()))
+	t.AppendMessage("dom-sep", []byte(label))
+}
+
+func transcriptAppendScalarVar(t *merlin.Transcript, label string) {
+	t.AppendMessage("scvar", []byte(label))
+}
+
+func transcriptAppendPointVar(t *merlin.Transcript, label string, point []byte) {
+	t.AppendMessage("ptvar", []byte(label))
+	t.AppendMessage("val", point)
+}
+
+func transcriptAppendBlindingCommitment(t *merlin.Transcript, label string, point []byte) {
+	t.AppendMessage("blindcom", []byte(label))
+	t.AppendMessage("val", point)
+}
+
+func transcriptChallengeScalar(t *merlin.Transcript, label string) *scalar.Scalar {
+	var b [scalar.ScalarWideSize]byte
+	t.ExtractBytes(b[:], label)
+	s, err := scalar.NewFromBytesModOrderWide(b[:])
+	if err != nil {
+		panic("sigma: failed to derive challenge scalar: " + err.Error())
+	}
+	return s
+}
@@ -0,0 +1,333 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package spake2 implements the SPAKE2 balanced password-authenticated
+// key exchange as specified in RFC 9382, with the
+// SPAKE2-edwards25519-SHA256-HKDF-HMAC-SHA256 ciphersuite.
+//
+// RFC 9382 only publishes test vectors for P-256, where the scalar w in
+// the transcript TT is a big-endian integer.  This package encodes w as
+// a 32-byte canonical little-endian scalar, following the edwards25519
+// scalar encoding of RFC 8032 Section 5.1.  Implementations that encode
+// w differently will fail key confirmation against this one.
+package spake2
+
+import (
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
+)
+
+const (
+	// ShareSize is the size of a public share (pA or pB) in bytes.
+	ShareSize = curve.CompressedPointSize
+
+	// ConfirmationSize is the size of a key confirmation MAC in bytes.
+	ConfirmationSize = sha256.Size
+
+	// SharedKeySize is the size of the shared key (Ke) in bytes.
+	SharedKeySize = sha256.Size / 2
+
+	// PasswordHashSize is the size of the memory-hard function output
+	// used to derive the password scalar in bytes.
+	PasswordHashSize = scalar.ScalarWideSize
+
+	labelConfirmationKeys = "ConfirmationKeys"
+)
+
+var (
+	// ErrInvalidShare is the error returned when the peer's share is
+	// malformed, or results in the identity element.
+	ErrInvalidShare = fmt.Errorf("spake2: invalid share")
+
+	// ErrConfirmation is the error returned when the peer's key
+	// confirmation MAC fails to verify.
+	ErrConfirmation = fmt.Errorf("spake2: key confirmation failed")
+
+	// M and N are the RFC 9382 edwards25519 group elements, generated
+	// from the seeds "edwards25519 point generation seed (M)" and
+	// "edwards25519 point generation seed (N)".
+	constM = mustDecodePoint(curve.CompressedEdwardsY{
+		0xd0, 0x48, 0x03, 0x2c, 0x6e, 0xa0, 0xb6, 0xd6, 0x97, 0xdd, 0xc2, 0xe8, 0x6b, 0xda, 0x85, 0xa3,
+		0x3a, 0xda, 0xc9, 0x20, 0xf1, 0xbf, 0x18, 0xe1, 0xb0, 0xc6, 0xd1, 0x66, 0xa5, 0xce, 0xcd, 0xaf,
+	})
+	constN = mustDecodePoint(curve.CompressedEdwardsY{
+		0xd3, 0xbf, 0xb5, 0x18, 0xf4, 0x4f, 0x34, 0x30, 0xf2, 0x9d, 0x0c, 0x92, 0xaf, 0x50, 0x38, 0x65,
+		0xa1, 0xed, 0x32, 0x81, 0xdc, 0x69, 0xb3, 0x5d, 0xd8, 0x68, 0xba, 0x85, 0xf8, 0x86, 0xc4, 0xab,
+	})
+)
+
+// Role is a protocol participant role.
+type Role uint8
+
+const (
+	// RoleA is the role of party A (typically the client).
+	RoleA Role = iota
+	// RoleB is the role of party B (typically the server).
+	RoleB
+)
+
+// String returns the string representation of a Role.
+func (r Role) String() string {
+	switch r {
+	case RoleA:
+		return "A"
+	case RoleB:
+		return "B"
+	default:
+		return "[invalid role]"
+	}
+}
+
+// Config is the per-session SPAKE2 configuration, which must match
+// between the two parties.
+type Config struct {
+	// IdentityA is the identity of party A, and may be empty.
+	IdentityA []byte
+
+	// IdentityB is the identity of party B, and may be empty.
+	IdentityB []byte
+
+	// AAD is optional additional authenticated data, that is bound
+	// into the key confirmation keys.
+	AAD []byte
+}
+
+// NewPasswordScalar derives the password scalar w from the
+// PasswordHashSize-byte output of a memory-hard function applied to
+// the password.
+func NewPasswordScalar(mhfOutput []byte) (*scalar.Scalar, error) {
+	if len(mhfOutput) != PasswordHashSize {
+		return nil, fmt.Errorf("spake2: invalid memory-hard function output length")
+	}
+	return scalar.NewFromBytesModOrderWide(mhfOutput)
+}
+
+// State is a SPAKE2 session.
+type State struct {
+	role Role
+	cfg  Config
+	w    *scalar.Scalar
+	x    *scalar.Scalar
+
+	share [ShareSize]byte
+
+	sharedKey           []byte
+	expectedPeerConfirm []byte
+	finished            bool
+}
+
+// New creates a new SPAKE2 session for the role and password scalar,
+// using entropy from rand.  If rand is nil, crypto/rand.Reader will be
+// used.
+func New(role Role, w *scalar.Scalar, cfg *Config, rand io.Reader) (*State, error) {
+	if role != RoleA && role != RoleB {
+		return nil, fmt.Errorf("spake2: invalid role")
+	}
+	if w == nil {
+		return nil, fmt.Errorf("spake2: missing password scalar")
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var x *scalar.Scalar
+	for {
+		var err error
+		if x, err = scalar.New().SetRandom(rand); err != nil {
+			return nil, fmt.Errorf("spake2: failed to generate scalar: %w", err)
+		}
+		if x.Equal(scalar.New()) == 0 {
+			break
+		}
+	}
+
+	return newState(role, w, cfg, x), nil
+}
+
+func newState(role Role, w *scalar.Scalar, cfg *Config, x *scalar.Scalar) *State {
+	st := &State{
+		role: role,
+		w:    scalar.New().Set(w),
+		x:    x,
+	}
+	if cfg != nil {
+		st.cfg = Config{
+			IdentityA: append([]byte{}, cfg.IdentityA...),
+			IdentityB: append([]byte{}, cfg.IdentityB...),
+			AAD:       append([]byte{}, cfg.AAD...),
+		}
+	}
+
+	// pA = w*M + X, pB = w*N + Y
+	var p, blind curve.EdwardsPoint
+	p.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, x)
+	blind.Mul(st.ownBlindPoint(), w)
+	p.Add(&p, &blind)
+
+	var compressed curve.CompressedEdwardsY
+	compressed.SetEdwardsPoint(&p)
+	copy(st.share[:], compressed[:])
+
+	return st
+}
+
+func (st *State) ownBlindPoint() *curve.EdwardsPoint {
+	if st.role == RoleA {
+		return constM
+	}
+	return constN
+}
+
+func (st *State) peerBlindPoint() *curve.EdwardsPoint {
+	if st.role == RoleA {
+		return constN
+	}
+	return constM
+}
+
+// Share returns the public share (pA or pB) to be sent to the peer.
+func (st *State) Share() []byte {
+	return append([]byte{}, st.share[:]...)
+}
+
+// Finish processes the peer's public share, and returns the key
+// confirmation MAC to be sent to the peer.
+func (st *State) Finish(peerShare []byte) ([]byte, error) {
+	if st.finished {
+		return nil, fmt.Errorf("spake2: session already finished")
+	}
+
+	var (
+		compressed curve.CompressedEdwardsY
+		peer       curve.EdwardsPoint
+	)
+	if _, err := compressed.SetBytes(peerShare); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShare, err)
+	}
+	if _, err := peer.SetCompressedY(&compressed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShare, err)
+	}
+
+	// K = h*x*(pB - w*N), K = h*y*(pA - w*M)
+	var k, blind curve.EdwardsPoint
+	blind.Mul(st.peerBlindPoint(), st.w)
+	k.Sub(&peer, &blind)
+	k.MulByCofactor(&k)
+	k.Mul(&k, st.x)
+	if k.IsIdentity() {
+		return nil, ErrInvalidShare
+	}
+
+	var kBytes curve.CompressedEdwardsY
+	kBytes.SetEdwardsPoint(&k)
+	wBytes, _ := st.w.MarshalBinary()
+
+	pA, pB := st.share[:], peerShare
+	if st.role == RoleB {
+		pA, pB = pB, pA
+	}
+
+	// TT = len(A) || A || len(B) || B || len(pA) || pA || len(pB) || pB
+	//      || len(K) || K || len(w) || w
+	h := sha256.New()
+	for _, v := range [][]byte{
+		st.cfg.IdentityA,
+		st.cfg.IdentityB,
+		pA,
+		pB,
+		kBytes[:],
+		wBytes,
+	} {
+		var l [8]byte
+		binary.LittleEndian.PutUint64(l[:], uint64(len(v)))
+		_, _ = h.Write(l[:])
+		_, _ = h.Write(v)
+	}
+	tt := h.Sum(nil)
+
+	// Ke || Ka = Hash(TT)
+	ke, ka := tt[:SharedKeySize], tt[SharedKeySize:]
+
+	// KcA || KcB = KDF(Ka, nil, "ConfirmationKeys" || AAD, L)
+	var kc [sha256.Size]byte
+	kdf := hkdf.New(sha256.New, ka, nil, append([]byte(labelConfirmationKeys), st.cfg.AAD...))
+	if _, err := io.ReadFull(kdf, kc[:]); err != nil {
+		panic("spake2: failed to derive confirmation keys: " + err.Error())
+	}
+	kcA, kcB := kc[:len(kc)/2], kc[len(kc)/2:]
+
+	// cA = MAC(KcA, TT), cB = MAC(KcB, TT)
+	cA, cB := mac(kcA, tt), mac(kcB, tt)
+
+	st.finished = true
+	st.sharedKey = append([]byte{}, ke...)
+	if st.role == RoleA {
+		st.expectedPeerConfirm = cB
+		return cA, nil
+	}
+	st.expectedPeerConfirm = cA
+	return cB, nil
+}
+
+// Verify checks the peer's key confirmation MAC, and returns the
+// shared key on success.
+func (st *State) Verify(peerConfirmation []byte) ([]byte, error) {
+	if !st.finished {
+		return nil, fmt.Errorf("spake2: session not finished")
+	}
+	if subtle.ConstantTimeCompare(peerConfirmation, st.expectedPeerConfirm) != 1 {
+		return nil, ErrConfirmation
+	}
+	return append([]byte{}, st.sharedKey...), nil
+}
+
+func mac(key, msg []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(msg)
+	return h.Sum(nil)
+}
+
+func mustDecodePoint(compressed curve.CompressedEdwardsY) *curve.EdwardsPoint {
+	var p curve.EdwardsPoint
+	if _, err := p.SetCompressedY(&compressed); err != nil {
+		panic("spake2: failed to decompress constant: " + err.Error())
+	}
+	return &p
+}
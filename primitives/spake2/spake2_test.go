@@ -0,0 +1,311 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package spake2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+func TestConstants(t *testing.T) {
+	// RFC 9382 Appendix A: The element is the first iterated SHA-256
+	// hash of the seed that decodes to a point of prime order.
+	for _, v := range []struct {
+		seed     string
+		expected *curve.EdwardsPoint
+	}{
+		{"edwards25519 point generation seed (M)", constM},
+		{"edwards25519 point generation seed (N)", constN},
+	} {
+		h := []byte(v.seed)
+		for i := 1; i < 1000; i++ {
+			digest := sha256.Sum256(h)
+			h = digest[:]
+
+			var (
+				compressed curve.CompressedEdwardsY
+				p          curve.EdwardsPoint
+			)
+			copy(compressed[:], h)
+			if _, err := p.SetCompressedY(&compressed); err != nil {
+				continue
+			}
+			if p.IsIdentity() || !p.IsTorsionFree() {
+				continue
+			}
+
+			if p.Equal(v.expected) != 1 {
+				t.Fatalf("%s: generated point mismatch", v.seed)
+			}
+			break
+		}
+	}
+}
+
+func testPasswordScalar(t *testing.T, password string) *scalar.Scalar {
+	// A real deployment would use a memory-hard function here.
+	var mhfOutput [PasswordHashSize]byte
+	digest := sha256.Sum256([]byte(password))
+	copy(mhfOutput[:], digest[:])
+
+	w, err := NewPasswordScalar(mhfOutput[:])
+	if err != nil {
+		t.Fatalf("NewPasswordScalar: %v", err)
+	}
+	return w
+}
+
+func runExchange(t *testing.T, a, b *State) ([]byte, []byte, error) {
+	confirmA, err := a.Finish(b.Share())
+	if err != nil {
+		t.Fatalf("A.Finish: %v", err)
+	}
+	confirmB, err := b.Finish(a.Share())
+	if err != nil {
+		t.Fatalf("B.Finish: %v", err)
+	}
+
+	keyB, errB := b.Verify(confirmA)
+	keyA, errA := a.Verify(confirmB)
+	if (errA == nil) != (errB == nil) {
+		t.Fatalf("key confirmation mismatch: %v %v", errA, errB)
+	}
+	if errA != nil {
+		return nil, nil, errA
+	}
+	return keyA, keyB, nil
+}
+
+func TestSPAKE2(t *testing.T) {
+	w := testPasswordScalar(t, "password")
+	cfg := &Config{
+		IdentityA: []byte("client"),
+		IdentityB: []byte("server"),
+		AAD:       []byte("curve25519-voi/spake2: test"),
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, cfg := range []*Config{cfg, nil} {
+			a, err := New(RoleA, w, cfg, nil)
+			if err != nil {
+				t.Fatalf("New(A): %v", err)
+			}
+			b, err := New(RoleB, w, cfg, nil)
+			if err != nil {
+				t.Fatalf("New(B): %v", err)
+			}
+			if len(a.Share()) != ShareSize {
+				t.Fatalf("invalid share length: %d", len(a.Share()))
+			}
+
+			keyA, keyB, err := runExchange(t, a, b)
+			if err != nil {
+				t.Fatalf("exchange: %v", err)
+			}
+			if len(keyA) != SharedKeySize || !bytes.Equal(keyA, keyB) {
+				t.Fatalf("shared key mismatch")
+			}
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		a, _ := New(RoleA, w, cfg, nil)
+		b, _ := New(RoleB, testPasswordScalar(t, "hunter2"), cfg, nil)
+		if _, _, err := runExchange(t, a, b); !errors.Is(err, ErrConfirmation) {
+			t.Fatalf("exchange: expected ErrConfirmation, got %v", err)
+		}
+	})
+
+	t.Run("ConfigMismatch", func(t *testing.T) {
+		for _, cfgB := range []*Config{
+			{IdentityA: cfg.IdentityA, IdentityB: []byte("mallory"), AAD: cfg.AAD},
+			{IdentityA: cfg.IdentityA, IdentityB: cfg.IdentityB},
+		} {
+			a, _ := New(RoleA, w, cfg, nil)
+			b, _ := New(RoleB, w, cfgB, nil)
+			if _, _, err := runExchange(t, a, b); !errors.Is(err, ErrConfirmation) {
+				t.Fatalf("exchange: expected ErrConfirmation, got %v", err)
+			}
+		}
+	})
+
+	t.Run("SameRole", func(t *testing.T) {
+		// The M/N blinding prevents reflecting a share back.
+		a, _ := New(RoleA, w, cfg, nil)
+		a2, _ := New(RoleA, w, cfg, nil)
+		confirmA, err := a.Finish(a2.Share())
+		if err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		confirmA2, err := a2.Finish(a.Share())
+		if err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		if _, err = a.Verify(confirmA2); !errors.Is(err, ErrConfirmation) {
+			t.Fatalf("Verify: expected ErrConfirmation, got %v", err)
+		}
+		if _, err = a2.Verify(confirmA); !errors.Is(err, ErrConfirmation) {
+			t.Fatalf("Verify: expected ErrConfirmation, got %v", err)
+		}
+	})
+
+	t.Run("InvalidShare", func(t *testing.T) {
+		// pB = w*N + T, for T of small order, results in K = identity.
+		var p curve.EdwardsPoint
+		p.Mul(constN, w)
+		for _, torsion := range curve.EIGHT_TORSION {
+			var share curve.EdwardsPoint
+			share.Add(&p, torsion)
+			var compressed curve.CompressedEdwardsY
+			compressed.SetEdwardsPoint(&share)
+
+			a, _ := New(RoleA, w, cfg, nil)
+			if _, err := a.Finish(compressed[:]); !errors.Is(err, ErrInvalidShare) {
+				t.Fatalf("Finish: expected ErrInvalidShare, got %v", err)
+			}
+		}
+
+		a, _ := New(RoleA, w, cfg, nil)
+		if _, err := a.Finish(make([]byte, ShareSize-1)); !errors.Is(err, ErrInvalidShare) {
+			t.Fatalf("Finish(truncated): expected ErrInvalidShare, got %v", err)
+		}
+	})
+
+	t.Run("Misuse", func(t *testing.T) {
+		if _, err := New(Role(2), w, cfg, nil); err == nil {
+			t.Fatalf("New: accepted invalid role")
+		}
+		if _, err := NewPasswordScalar(make([]byte, 32)); err == nil {
+			t.Fatalf("NewPasswordScalar: accepted short input")
+		}
+
+		a, _ := New(RoleA, w, cfg, nil)
+		b, _ := New(RoleB, w, cfg, nil)
+		if _, err := a.Verify(make([]byte, ConfirmationSize)); err == nil {
+			t.Fatalf("Verify: accepted before Finish")
+		}
+		if _, err := a.Finish(b.Share()); err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		if _, err := a.Finish(b.Share()); err == nil {
+			t.Fatalf("Finish: accepted twice")
+		}
+	})
+}
+
+func TestVector(t *testing.T) {
+	// RFC 9382 only publishes test vectors for the P-256 ciphersuite.
+	// This vector was cross-checked against a standalone implementation
+	// written from the RFC text, that shares no code with this package.
+	const (
+		xHex  = "151f91b0652ad2d15b58d4fd88a07f4c9b4646ff92d1ab29565fb234a8001b0b"
+		yHex  = "90a63ae4c73b0779848ed930bc6963148c0d8478e858f05d9456761161a7c404"
+		wHex  = "55c5ccdf832015f86ed952293f16d71772603d0d6aabbdd62a11ef721d154208"
+		pAHex = "70cfea95d5df4702afafb1660bf8e712420581e614671062cedafcfe8fd71380"
+		pBHex = "a952935d14822c9231ab16d20ae9f638efc31bc8dfc28e8926bfeb7b7e332311"
+		kHex  = "f8237973ac6c76e17d3694cbb96a95a3815fe50945c2cbc16c05e4c4e618dd7d"
+		ttHex = "0600000000000000636c69656e74" + "0600000000000000736572766572" +
+			"2000000000000000" + pAHex +
+			"2000000000000000" + pBHex +
+			"2000000000000000" + kHex +
+			"2000000000000000" + wHex
+		keHex = "f39f18e55dabd9ff9c2b4215e82e22d8"
+		cAHex = "ce17e41325fc20b421c7646e047f67d0fa10607cce0cb3830eefab581c3e3f96"
+		cBHex = "a3cb58b820da9908d017213b0def46e2df152cd03261d065aafe4c112855ba55"
+	)
+
+	cfg := &Config{
+		IdentityA: []byte("client"),
+		IdentityB: []byte("server"),
+	}
+
+	w := testPasswordScalar(t, "password")
+	wBytes, _ := w.MarshalBinary()
+	if expected := testhelpers.MustUnhex(t, wHex); !bytes.Equal(wBytes, expected) {
+		t.Fatalf("w mismatch: got %x", wBytes)
+	}
+
+	x, err := scalar.NewFromCanonicalBytes(testhelpers.MustUnhex(t, xHex))
+	if err != nil {
+		t.Fatalf("NewFromCanonicalBytes(x): %v", err)
+	}
+	y, err := scalar.NewFromCanonicalBytes(testhelpers.MustUnhex(t, yHex))
+	if err != nil {
+		t.Fatalf("NewFromCanonicalBytes(y): %v", err)
+	}
+
+	a, b := newState(RoleA, w, cfg, x), newState(RoleB, w, cfg, y)
+	if expected := testhelpers.MustUnhex(t, pAHex); !bytes.Equal(a.Share(), expected) {
+		t.Fatalf("pA mismatch: got %x", a.Share())
+	}
+	if expected := testhelpers.MustUnhex(t, pBHex); !bytes.Equal(b.Share(), expected) {
+		t.Fatalf("pB mismatch: got %x", b.Share())
+	}
+
+	// Ke is the first half of Hash(TT), which commits to K.
+	tt := testhelpers.MustUnhex(t, ttHex)
+	ttDigest := sha256.Sum256(tt)
+	if !bytes.Equal(ttDigest[:SharedKeySize], testhelpers.MustUnhex(t, keHex)) {
+		t.Fatalf("vector TT does not hash to Ke")
+	}
+
+	confirmA, err := a.Finish(b.Share())
+	if err != nil {
+		t.Fatalf("A.Finish: %v", err)
+	}
+	if expected := testhelpers.MustUnhex(t, cAHex); !bytes.Equal(confirmA, expected) {
+		t.Fatalf("cA mismatch: got %x", confirmA)
+	}
+	confirmB, err := b.Finish(a.Share())
+	if err != nil {
+		t.Fatalf("B.Finish: %v", err)
+	}
+	if expected := testhelpers.MustUnhex(t, cBHex); !bytes.Equal(confirmB, expected) {
+		t.Fatalf("cB mismatch: got %x", confirmB)
+	}
+
+	keyA, err := a.Verify(confirmB)
+	if err != nil {
+		t.Fatalf("A.Verify: %v", err)
+	}
+	keyB, err := b.Verify(confirmA)
+	if err != nil {
+		t.Fatalf("B.Verify: %v", err)
+	}
+	if expected := testhelpers.MustUnhex(t, keHex); !bytes.Equal(keyA, expected) || !bytes.Equal(keyB, expected) {
+		t.Fatalf("Ke mismatch: got %x %x", keyA, keyB)
+	}
+}
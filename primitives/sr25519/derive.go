@@ -0,0 +1,243 @@
+// Copyright (c) 2019 Web 3 Foundation. All rights reserved.
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const (
+	// ChainCodeSize is the size of a ChainCode in bytes.
+	ChainCodeSize = 32
+
+	hdkdTranscriptLabel = "SchnorrRistrettoHDKD"
+	hdkdSignBytesLabel  = "sign-bytes"
+	hdkdChainCodeLabel  = "chain-code"
+	hdkdPublicKeyLabel  = "public-key"
+	hdkdSecretKeyLabel  = "secret-key"
+	hdkdScalarLabel     = "HDKD-scalar"
+	hdkdChainCodeOutput = "HDKD-chaincode"
+	hdkdHardLabel       = "HDKD-hard"
+	hdkdNonceLabel      = "HDKD-nonce"
+)
+
+// ChainCode is a hierarchical deterministic key derivation chain code,
+// that serves to separate key derivations that use the same input.
+type ChainCode [ChainCodeSize]byte
+
+// MarshalBinary encodes a ChainCode into binary form.
+func (cc *ChainCode) MarshalBinary() ([]byte, error) {
+	return append([]byte{}, cc[:]...), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled ChainCode.
+func (cc *ChainCode) UnmarshalBinary(data []byte) error {
+	if l := len(data); l != ChainCodeSize {
+		return fmt.Errorf("sr25519: bad ChainCode size: %v", l)
+	}
+
+	copy(cc[:], data)
+
+	return nil
+}
+
+// Equal reports if cc and other have the same value.  This function
+// will execute in constant time.
+func (cc *ChainCode) Equal(other *ChainCode) bool {
+	return subtle.ConstantTimeCompare(cc[:], other[:]) == 1
+}
+
+// NewChainCodeFromBytes constructs a ChainCode from the byte representation.
+func NewChainCodeFromBytes(b []byte) (*ChainCode, error) {
+	var cc ChainCode
+	if err := cc.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &cc, nil
+}
+
+// NewDerivationTranscript initializes a new key derivation transcript
+// on the provided (possibly empty) byte string, as is done by schnorrkel's
+// `derived_key_simple`.
+func NewDerivationTranscript(i []byte) *SigningTranscript {
+	t := merlin.NewTranscript(hdkdTranscriptLabel)
+	t.AppendMessage(hdkdSignBytesLabel, i)
+	return &SigningTranscript{
+		t: t,
+	}
+}
+
+func (pk *PublicKey) deriveScalarAndChainCode(t *SigningTranscript, cc *ChainCode) (*scalar.Scalar, *ChainCode) {
+	t.commitBytes(hdkdChainCodeLabel, cc[:])
+	t.commitPoint(hdkdPublicKeyLabel, &pk.compressed)
+
+	s := t.challengeScalar(hdkdScalarLabel)
+
+	var newCC ChainCode
+	t.challengeBytes(newCC[:], hdkdChainCodeOutput)
+
+	return s, &newCC
+}
+
+// DeriveKey derives a child PublicKey and ChainCode from a public key,
+// a transcript and a chain code, via "soft" derivation.  The derived
+// public key will correspond to the secret key derived via
+// SecretKey.DeriveKey with the same transcript and chain code.
+func (pk *PublicKey) DeriveKey(transcript *SigningTranscript, cc *ChainCode) (*PublicKey, *ChainCode, error) {
+	if pk.point == nil {
+		return nil, nil, fmt.Errorf("sr25519: attempted to derive from uninitialized PublicKey")
+	}
+
+	t := transcript.clone()
+	s, newCC := pk.deriveScalarAndChainCode(t, cc)
+
+	var point curve.RistrettoPoint
+	point.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, s)
+	point.Add(pk.point, &point)
+
+	return newPublicKeyFromPoint(&point), newCC, nil
+}
+
+// DeriveKeySimple derives a child PublicKey and ChainCode from a public
+// key, a byte string, and a chain code, via "soft" derivation.
+func (pk *PublicKey) DeriveKeySimple(cc *ChainCode, i []byte) (*PublicKey, *ChainCode, error) {
+	return pk.DeriveKey(NewDerivationTranscript(i), cc)
+}
+
+// DeriveKey derives a child SecretKey and ChainCode from a secret key,
+// a transcript, a chain code and provided entropy source, via "soft"
+// derivation.  If rng is nil, crypto/rand.Reader will be used.
+//
+// Note: The derived nonce is randomized, so while the derived key scalar
+// (and public key) are deterministic, the byte serialization of the
+// derived SecretKey is not.
+func (sk *SecretKey) DeriveKey(rng io.Reader, transcript *SigningTranscript, cc *ChainCode) (*SecretKey, *ChainCode, error) {
+	if sk.key == nil {
+		return nil, nil, fmt.Errorf("sr25519: attempted to derive from uninitialized SecretKey")
+	}
+
+	t := transcript.clone()
+	s, newCC := sk.PublicKey().deriveScalarAndChainCode(t, cc)
+
+	skBytes, err := sk.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The nonce only serves to protect the signature from bad entropy
+	// sources, so it is derived with the witness mechanism so as to make
+	// it independent from the derived scalar and chain code.
+	derived := &SecretKey{
+		key: scalar.New().Add(sk.key, s),
+	}
+	if err = t.witnessBytes(derived.nonce[:], hdkdNonceLabel, [][]byte{sk.nonce[:], skBytes}, rng); err != nil {
+		return nil, nil, fmt.Errorf("sr25519: failed to derive nonce: %w", err)
+	}
+
+	return derived, newCC, nil
+}
+
+// DeriveKeySimple derives a child SecretKey and ChainCode from a secret
+// key, a byte string, a chain code and provided entropy source, via "soft"
+// derivation.  If rng is nil, crypto/rand.Reader will be used.
+func (sk *SecretKey) DeriveKeySimple(rng io.Reader, cc *ChainCode, i []byte) (*SecretKey, *ChainCode, error) {
+	return sk.DeriveKey(rng, NewDerivationTranscript(i), cc)
+}
+
+// HardDeriveMiniSecretKey derives a MiniSecretKey and ChainCode from a
+// secret key, an optional chain code, and a byte string, via "hard"
+// derivation.  Unlike "soft" derivation, the derived key can not be
+// obtained from the public key alone.
+func (sk *SecretKey) HardDeriveMiniSecretKey(cc *ChainCode, i []byte) (*MiniSecretKey, *ChainCode, error) {
+	if sk.key == nil {
+		return nil, nil, fmt.Errorf("sr25519: attempted to derive from uninitialized SecretKey")
+	}
+
+	var keyBytes [SecretKeyScalarSize]byte
+	if err := sk.key.ToBytes(keyBytes[:]); err != nil {
+		return nil, nil, fmt.Errorf("sr25519: failed to serialize key scalar: %w", err)
+	}
+
+	t := NewDerivationTranscript(i)
+	if cc != nil {
+		t.commitBytes(hdkdChainCodeLabel, cc[:])
+	}
+	t.commitBytes(hdkdSecretKeyLabel, keyBytes[:])
+
+	var (
+		msk   MiniSecretKey
+		newCC ChainCode
+	)
+	t.challengeBytes(msk[:], hdkdHardLabel)
+	t.challengeBytes(newCC[:], hdkdChainCodeOutput)
+
+	return &msk, &newCC, nil
+}
+
+// HardDeriveMiniSecretKey derives a MiniSecretKey and ChainCode from a
+// mini secret key expanded with the specified mode, an optional chain
+// code, and a byte string, via "hard" derivation.
+func (msk *MiniSecretKey) HardDeriveMiniSecretKey(cc *ChainCode, i []byte, mode ExpansionMode) (*MiniSecretKey, *ChainCode, error) {
+	sk, err := msk.Expand(mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sk.HardDeriveMiniSecretKey(cc, i)
+}
+
+// DeriveKey derives a child KeyPair and ChainCode from a key pair,
+// a transcript, a chain code and provided entropy source, via "soft"
+// derivation.  If rng is nil, crypto/rand.Reader will be used.
+func (kp *KeyPair) DeriveKey(rng io.Reader, transcript *SigningTranscript, cc *ChainCode) (*KeyPair, *ChainCode, error) {
+	if kp.sk == nil {
+		return nil, nil, fmt.Errorf("sr25519: attempted to derive from uninitialized KeyPair")
+	}
+
+	sk, newCC, err := kp.sk.DeriveKey(rng, transcript, cc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sk.KeyPair(), newCC, nil
+}
+
+// DeriveKeySimple derives a child KeyPair and ChainCode from a key pair,
+// a byte string, a chain code and provided entropy source, via "soft"
+// derivation.  If rng is nil, crypto/rand.Reader will be used.
+func (kp *KeyPair) DeriveKeySimple(rng io.Reader, cc *ChainCode, i []byte) (*KeyPair, *ChainCode, error) {
+	return kp.DeriveKey(rng, NewDerivationTranscript(i), cc)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+func TestDeriveVector(t *testing.T) {
+	// Test vectors from Substrate (sp-core), using the secret seed of
+	// the well-known development phrase
+	// "bottom drive obey lake curtain smoke basket hold race lonely fit walk".
+	const (
+		seedHex         = "fac7959dbfe72f052e5a0c3c8d6530f202b02fd8f9f5ca3580ec8deb7797479e"
+		pkHex           = "46ebddef8cd9bb167dc30878d7113b7e168e6f0646beffd77d69d39bad76b47a"
+		hardAliceSeed   = "e5be9a5092b81bca64be81d212e7f2f9eba183bb7a90954f7b76361f6edb5c0a"
+		hardAlicePkHex  = "d43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d"
+		softAlicePkHex  = "d6c71059dbbe9ad2b0ed3f289738b800836eb425544ce694825285b958ca755e"
+		junctionAliceCC = "14416c6963650000000000000000000000000000000000000000000000000000"
+	)
+
+	msk, err := NewMiniSecretKeyFromBytes(testhelpers.MustUnhex(t, seedHex))
+	if err != nil {
+		t.Fatalf("NewMiniSecretKeyFromBytes: %v", err)
+	}
+	sk := msk.ExpandEd25519()
+	kp := sk.KeyPair()
+
+	pkBytes, _ := kp.PublicKey().MarshalBinary()
+	if !bytes.Equal(pkBytes, testhelpers.MustUnhex(t, pkHex)) {
+		t.Fatalf("public key mismatch: %x", pkBytes)
+	}
+
+	cc, err := NewChainCodeFromBytes(testhelpers.MustUnhex(t, junctionAliceCC))
+	if err != nil {
+		t.Fatalf("NewChainCodeFromBytes: %v", err)
+	}
+
+	t.Run("Hard", func(t *testing.T) {
+		hardMsk, _, err := msk.HardDeriveMiniSecretKey(cc, nil, ExpansionModeEd25519)
+		if err != nil {
+			t.Fatalf("HardDeriveMiniSecretKey: %v", err)
+		}
+		if !bytes.Equal(hardMsk[:], testhelpers.MustUnhex(t, hardAliceSeed)) {
+			t.Fatalf("hard derived mini secret key mismatch: %x", hardMsk[:])
+		}
+
+		hardPkBytes, _ := hardMsk.ExpandEd25519().PublicKey().MarshalBinary()
+		if !bytes.Equal(hardPkBytes, testhelpers.MustUnhex(t, hardAlicePkHex)) {
+			t.Fatalf("hard derived public key mismatch: %x", hardPkBytes)
+		}
+	})
+
+	t.Run("Soft", func(t *testing.T) {
+		softPk, softPkCC, err := kp.PublicKey().DeriveKeySimple(cc, nil)
+		if err != nil {
+			t.Fatalf("PublicKey.DeriveKeySimple: %v", err)
+		}
+		softPkBytes, _ := softPk.MarshalBinary()
+		if !bytes.Equal(softPkBytes, testhelpers.MustUnhex(t, softAlicePkHex)) {
+			t.Fatalf("soft derived public key mismatch: %x", softPkBytes)
+		}
+
+		softKp, softKpCC, err := kp.DeriveKeySimple(nil, cc, nil)
+		if err != nil {
+			t.Fatalf("KeyPair.DeriveKeySimple: %v", err)
+		}
+		if !softKp.PublicKey().Equal(softPk) {
+			t.Fatalf("soft derived key pair public key mismatch")
+		}
+		if !softKpCC.Equal(softPkCC) {
+			t.Fatalf("soft derived chain code mismatch")
+		}
+
+		// Ensure that the derived key pair can produce signatures that
+		// verify with the derived public key.
+		st := NewSigningContext([]byte("test derive")).NewTranscriptBytes([]byte("message"))
+		sig, err := softKp.Sign(nil, st)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if !softPk.Verify(st, sig) {
+			t.Fatalf("signature with soft derived key failed to verify")
+		}
+	})
+}
@@ -151,6 +151,31 @@ func (msk *MiniSecretKey) ExpandEd25519() *SecretKey {
 	return sk
 }
 
+// ExpansionMode is the method used to expand a MiniSecretKey into a
+// SecretKey.
+type ExpansionMode int
+
+const (
+	// ExpansionModeUniform expands via ExpandUniform.
+	ExpansionModeUniform ExpansionMode = iota
+
+	// ExpansionModeEd25519 expands via ExpandEd25519.
+	ExpansionModeEd25519
+)
+
+// Expand expands a MiniSecretKey into a SecretKey using the specified
+// expansion mode.
+func (msk *MiniSecretKey) Expand(mode ExpansionMode) (*SecretKey, error) {
+	switch mode {
+	case ExpansionModeUniform:
+		return msk.ExpandUniform(), nil
+	case ExpansionModeEd25519:
+		return msk.ExpandEd25519(), nil
+	default:
+		return nil, fmt.Errorf("sr25519: invalid expansion mode: %v", mode)
+	}
+}
+
 // NewMiniSecretKeyFromBytes constructs a MiniSecretKey from the byte
 // representation.
 func NewMiniSecretKeyFromBytes(b []byte) (*MiniSecretKey, error) {
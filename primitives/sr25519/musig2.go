@@ -0,0 +1,486 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/nocopy"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const (
+	// MuSig2PublicNonceSize is the size of a MuSig2PublicNonce in bytes.
+	MuSig2PublicNonceSize = 2 * curve.CompressedPointSize
+
+	// MuSig2PartialSignatureSize is the size of a MuSig2PartialSignature
+	// in bytes.
+	MuSig2PartialSignatureSize = scalar.ScalarSize
+
+	musig2KeyAggLabel      = "MuSig2-KeyAgg"
+	musig2KeyAggPkLabel    = "musig2:pk"
+	musig2KeyAggCoeffLabel = "musig2:a"
+	musig2NonceLabel       = "MuSig2-Nonce"
+	musig2NonceR1Label     = "musig2:r1"
+	musig2NonceR2Label     = "musig2:r2"
+	musig2NonceCoeffProto  = "MuSig2-NonceCoef"
+	musig2AggPkLabel       = "musig2:X"
+	musig2AggR1Label       = "musig2:R1"
+	musig2AggR2Label       = "musig2:R2"
+	musig2NonceCoeffLabel  = "musig2:b"
+)
+
+// MuSig2KeyAgg is a MuSig2 aggregated public key, along with the
+// per-signer key aggregation coefficients.
+type MuSig2KeyAgg struct {
+	publicKeys []*PublicKey
+	coeffs     []*scalar.Scalar
+	aggregated *PublicKey
+}
+
+// PublicKey returns the aggregated public key, which verifies the
+// signatures produced by the MuSig2 protocol.
+func (ka *MuSig2KeyAgg) PublicKey() *PublicKey {
+	return ka.aggregated
+}
+
+// PublicKeys returns the ordered list of public keys that were aggregated.
+func (ka *MuSig2KeyAgg) PublicKeys() []*PublicKey {
+	return append([]*PublicKey{}, ka.publicKeys...)
+}
+
+func (ka *MuSig2KeyAgg) indexOf(pk *PublicKey) int {
+	for i, v := range ka.publicKeys {
+		if v.Equal(pk) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewMuSig2KeyAgg aggregates the ordered list of public keys into a
+// MuSig2 aggregated public key.  All signers must use the same list in
+// the same order.
+func NewMuSig2KeyAgg(publicKeys []*PublicKey) (*MuSig2KeyAgg, error) {
+	n := len(publicKeys)
+	if n == 0 {
+		return nil, fmt.Errorf("sr25519: no public keys to aggregate")
+	}
+
+	t := &SigningTranscript{
+		t: merlin.NewTranscript(musig2KeyAggLabel),
+	}
+	for i, pk := range publicKeys {
+		if pk.point == nil {
+			return nil, fmt.Errorf("sr25519: uninitialized public key: %d", i)
+		}
+		for _, other := range publicKeys[:i] {
+			if pk.Equal(other) {
+				return nil, fmt.Errorf("sr25519: duplicate public key: %d", i)
+			}
+		}
+		t.commitPoint(musig2KeyAggPkLabel, &pk.compressed)
+	}
+
+	ka := &MuSig2KeyAgg{
+		publicKeys: append([]*PublicKey{}, publicKeys...),
+		coeffs:     make([]*scalar.Scalar, 0, n),
+	}
+	points := make([]*curve.RistrettoPoint, 0, n)
+	for _, pk := range publicKeys {
+		tt := t.clone()
+		tt.commitPoint(musig2KeyAggPkLabel, &pk.compressed)
+		ka.coeffs = append(ka.coeffs, tt.challengeScalar(musig2KeyAggCoeffLabel))
+		points = append(points, pk.point)
+	}
+
+	var aggPoint curve.RistrettoPoint
+	if aggPoint.MultiscalarMulVartime(ka.coeffs, points).IsIdentity() {
+		return nil, fmt.Errorf("sr25519: aggregated public key is the identity")
+	}
+	ka.aggregated = newPublicKeyFromPoint(&aggPoint)
+
+	return ka, nil
+}
+
+// MuSig2PublicNonce is a signer's public MuSig2 nonce commitment.
+type MuSig2PublicNonce struct {
+	r1 curve.CompressedRistretto
+	r2 curve.CompressedRistretto
+
+	points [2]*curve.RistrettoPoint
+}
+
+// MarshalBinary encodes a MuSig2PublicNonce into binary form.
+func (pn *MuSig2PublicNonce) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, MuSig2PublicNonceSize)
+	b = append(b, pn.r1[:]...)
+	b = append(b, pn.r2[:]...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary marshaled MuSig2PublicNonce.
+func (pn *MuSig2PublicNonce) UnmarshalBinary(data []byte) error {
+	pn.r1.Identity()
+	pn.r2.Identity()
+	pn.points = [2]*curve.RistrettoPoint{}
+
+	if l := len(data); l != MuSig2PublicNonceSize {
+		return fmt.Errorf("sr25519: bad MuSig2PublicNonce size: %v", l)
+	}
+
+	var (
+		compressed [2]curve.CompressedRistretto
+		points     [2]curve.RistrettoPoint
+	)
+	for i := range compressed {
+		if err := compressed[i].UnmarshalBinary(data[i*curve.CompressedPointSize : (i+1)*curve.CompressedPointSize]); err != nil {
+			return fmt.Errorf("sr25519: failed to deserialize MuSig2 nonce: %w", err)
+		}
+		if _, err := points[i].SetCompressed(&compressed[i]); err != nil {
+			return fmt.Errorf("sr25519: failed to decompress MuSig2 nonce: %w", err)
+		}
+	}
+
+	pn.r1, pn.r2 = compressed[0], compressed[1]
+	pn.points = [2]*curve.RistrettoPoint{&points[0], &points[1]}
+
+	return nil
+}
+
+// Equal reports if pn and other have the same value.
+func (pn *MuSig2PublicNonce) Equal(other *MuSig2PublicNonce) bool {
+	return pn.r1.Equal(&other.r1)&pn.r2.Equal(&other.r2) == 1
+}
+
+// NewMuSig2PublicNonceFromBytes constructs a MuSig2PublicNonce from the
+// byte representation.
+func NewMuSig2PublicNonceFromBytes(b []byte) (*MuSig2PublicNonce, error) {
+	var pn MuSig2PublicNonce
+	if err := pn.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &pn, nil
+}
+
+// MuSig2PartialSignature is a signer's MuSig2 partial signature.
+type MuSig2PartialSignature struct {
+	s *scalar.Scalar
+}
+
+// MarshalBinary encodes a MuSig2PartialSignature into binary form.
+func (ps *MuSig2PartialSignature) MarshalBinary() ([]byte, error) {
+	if ps.s == nil {
+		return make([]byte, MuSig2PartialSignatureSize), nil
+	}
+	return ps.s.MarshalBinary()
+}
+
+// UnmarshalBinary decodes a binary marshaled MuSig2PartialSignature.
+func (ps *MuSig2PartialSignature) UnmarshalBinary(data []byte) error {
+	ps.s = nil
+
+	if l := len(data); l != MuSig2PartialSignatureSize {
+		return fmt.Errorf("sr25519: bad MuSig2PartialSignature size: %v", l)
+	}
+
+	s, err := scalar.NewFromCanonicalBytes(data)
+	if err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize MuSig2 partial signature: %w", err)
+	}
+	ps.s = s
+
+	return nil
+}
+
+// NewMuSig2PartialSignatureFromBytes constructs a MuSig2PartialSignature
+// from the byte representation.
+func NewMuSig2PartialSignatureFromBytes(b []byte) (*MuSig2PartialSignature, error) {
+	var ps MuSig2PartialSignature
+	if err := ps.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// MuSig2Session is the state of a MuSig2 signing session, after all
+// signers' public nonces have been collected.  It is used by signers to
+// produce partial signatures, and by the aggregator to verify and
+// combine partial signatures.
+type MuSig2Session struct {
+	keyAgg *MuSig2KeyAgg
+	nonces []*MuSig2PublicNonce
+
+	b *scalar.Scalar
+	c *scalar.Scalar
+	r curve.CompressedRistretto
+}
+
+// NewMuSig2Session creates a MuSig2 signing session over a transcript,
+// given the aggregated key and the signers' public nonces, in the same
+// order as the public keys that were aggregated.
+func NewMuSig2Session(keyAgg *MuSig2KeyAgg, transcript *SigningTranscript, nonces []*MuSig2PublicNonce) (*MuSig2Session, error) {
+	if len(nonces) != len(keyAgg.publicKeys) {
+		return nil, fmt.Errorf("sr25519: MuSig2 nonce count mismatch")
+	}
+
+	var r1, r2 curve.RistrettoPoint
+	r1.Identity()
+	r2.Identity()
+	for i, pn := range nonces {
+		if pn.points[0] == nil || pn.points[1] == nil {
+			return nil, fmt.Errorf("sr25519: uninitialized MuSig2 nonce: %d", i)
+		}
+		r1.Add(&r1, pn.points[0])
+		r2.Add(&r2, pn.points[1])
+	}
+
+	var r1Compressed, r2Compressed curve.CompressedRistretto
+	r1Compressed.SetRistrettoPoint(&r1)
+	r2Compressed.SetRistrettoPoint(&r2)
+
+	// b = H_non(X, R1, R2, m)
+	t := transcript.clone()
+	t.protoName(musig2NonceCoeffProto)
+	t.commitPoint(musig2AggPkLabel, &keyAgg.aggregated.compressed)
+	t.commitPoint(musig2AggR1Label, &r1Compressed)
+	t.commitPoint(musig2AggR2Label, &r2Compressed)
+	b := t.challengeScalar(musig2NonceCoeffLabel)
+
+	// R = R1 + b * R2
+	var r curve.RistrettoPoint
+	r.Mul(&r2, b)
+	r.Add(&r1, &r)
+
+	session := &MuSig2Session{
+		keyAgg: keyAgg,
+		nonces: append([]*MuSig2PublicNonce{}, nonces...),
+		b:      b,
+	}
+	session.r.SetRistrettoPoint(&r)
+
+	// c = H_sig(X, R, m), as in a regular signature.
+	t = transcript.clone()
+	t.protoName(protoLabel)
+	t.commitPoint(aLabel, &keyAgg.aggregated.compressed)
+	t.commitPoint(rLabel, &session.r)
+	session.c = t.challengeScalar(cLabel)
+
+	return session, nil
+}
+
+// VerifyPartialSignature verifies a partial signature produced by the
+// signer with the provided public key.
+func (session *MuSig2Session) VerifyPartialSignature(pk *PublicKey, partialSig *MuSig2PartialSignature) bool {
+	idx := session.keyAgg.indexOf(pk)
+	if idx < 0 || partialSig.s == nil {
+		return false
+	}
+
+	// s_i * B = R_i1 + b * R_i2 + c * a_i * X_i
+	nonce := session.nonces[idx]
+	var (
+		ca    scalar.Scalar
+		check curve.RistrettoPoint
+	)
+	ca.Mul(session.c, session.keyAgg.coeffs[idx])
+	check.MultiscalarMulVartime(
+		[]*scalar.Scalar{session.b, &ca},
+		[]*curve.RistrettoPoint{nonce.points[1], pk.point},
+	)
+	check.Add(&check, nonce.points[0])
+
+	var expected curve.RistrettoPoint
+	expected.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, partialSig.s)
+
+	return expected.Equal(&check) == 1
+}
+
+// Aggregate combines the partial signatures of all signers, in the same
+// order as the public keys that were aggregated, into a signature that
+// can be verified with the aggregated public key.
+//
+// Note: The partial signatures are not individually verified, the
+// caller should use VerifyPartialSignature to identify misbehaving
+// signers if the resulting signature fails to verify.
+func (session *MuSig2Session) Aggregate(partialSigs []*MuSig2PartialSignature) (*Signature, error) {
+	if len(partialSigs) != len(session.keyAgg.publicKeys) {
+		return nil, fmt.Errorf("sr25519: MuSig2 partial signature count mismatch")
+	}
+
+	sig := &Signature{
+		rCompressed: session.r,
+		s:           scalar.New(),
+	}
+	for i, ps := range partialSigs {
+		if ps.s == nil {
+			return nil, fmt.Errorf("sr25519: uninitialized MuSig2 partial signature: %d", i)
+		}
+		sig.s.Add(sig.s, ps.s)
+	}
+
+	return sig, nil
+}
+
+// MuSig2Signer is a signer's state for a single MuSig2 signing session.
+// It holds the secret nonces, which are erased when a partial signature
+// is produced, so that each MuSig2Signer can sign at most once.
+//
+// A MuSig2Signer must not be copied.
+type MuSig2Signer struct {
+	_ nocopy.NoCopy
+
+	kp     *KeyPair
+	keyAgg *MuSig2KeyAgg
+	idx    int
+
+	nonces *musig2SecretNonces
+
+	publicNonce MuSig2PublicNonce
+}
+
+// musig2SecretNonces is the one-time secret nonce pair of a
+// MuSig2Signer.  It is shared by reference, so that the nonces are
+// consumed at most once, even by copies of the signer.
+type musig2SecretNonces struct {
+	sync.Mutex
+
+	r1 *scalar.Scalar
+	r2 *scalar.Scalar
+}
+
+// take removes the secret nonces, so that no other caller can use them.
+func (nonces *musig2SecretNonces) take() (*scalar.Scalar, *scalar.Scalar, error) {
+	nonces.Lock()
+	r1, r2 := nonces.r1, nonces.r2
+	nonces.r1, nonces.r2 = nil, nil
+	nonces.Unlock()
+
+	if r1 == nil || r2 == nil {
+		return nil, nil, fmt.Errorf("sr25519: MuSig2 nonces already used")
+	}
+	zero := scalar.New()
+	if r1.Equal(zero)|r2.Equal(zero) == 1 {
+		return nil, nil, fmt.Errorf("sr25519: invalid MuSig2 nonces")
+	}
+	return r1, r2, nil
+}
+
+// NewMuSig2Signer creates the signer state for a single MuSig2 signing
+// session, generating fresh nonces with the provided entropy source.  If
+// rng is nil, crypto/rand.Reader will be used.
+func (kp *KeyPair) NewMuSig2Signer(rng io.Reader, keyAgg *MuSig2KeyAgg) (*MuSig2Signer, error) {
+	if kp.sk == nil || kp.pk == nil {
+		return nil, fmt.Errorf("sr25519: attempted to sign with uninitialized KeyPair")
+	}
+
+	idx := keyAgg.indexOf(kp.pk)
+	if idx < 0 {
+		return nil, fmt.Errorf("sr25519: public key not part of MuSig2 aggregated key")
+	}
+
+	t := &SigningTranscript{
+		t: merlin.NewTranscript(musig2NonceLabel),
+	}
+	t.commitPoint(aLabel, &kp.pk.compressed)
+	t.commitPoint(musig2AggPkLabel, &keyAgg.aggregated.compressed)
+
+	r1, err := t.witnessScalar(musig2NonceR1Label, [][]byte{kp.sk.nonce[:]}, rng)
+	if err != nil {
+		return nil, fmt.Errorf("sr25519: failed to generate witness scalar: %w", err)
+	}
+	r2, err := t.witnessScalar(musig2NonceR2Label, [][]byte{kp.sk.nonce[:]}, rng)
+	if err != nil {
+		return nil, fmt.Errorf("sr25519: failed to generate witness scalar: %w", err)
+	}
+
+	signer := &MuSig2Signer{
+		kp:     kp,
+		keyAgg: keyAgg,
+		idx:    idx,
+		nonces: &musig2SecretNonces{
+			r1: r1,
+			r2: r2,
+		},
+	}
+
+	var points [2]curve.RistrettoPoint
+	points[0].MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, r1)
+	points[1].MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, r2)
+	signer.publicNonce.r1.SetRistrettoPoint(&points[0])
+	signer.publicNonce.r2.SetRistrettoPoint(&points[1])
+	signer.publicNonce.points = [2]*curve.RistrettoPoint{&points[0], &points[1]}
+
+	return signer, nil
+}
+
+// PublicNonce returns the signer's public nonce, to be sent to the other
+// signers.
+func (signer *MuSig2Signer) PublicNonce() *MuSig2PublicNonce {
+	pn := signer.publicNonce
+	return &pn
+}
+
+// Sign produces the signer's partial signature for the session.  The
+// secret nonces are erased, and subsequent calls will return an error.
+func (signer *MuSig2Signer) Sign(session *MuSig2Session) (*MuSig2PartialSignature, error) {
+	if session.keyAgg != signer.keyAgg && !session.keyAgg.aggregated.Equal(signer.keyAgg.aggregated) {
+		return nil, fmt.Errorf("sr25519: MuSig2 session aggregated key mismatch")
+	}
+	if !session.nonces[signer.idx].Equal(&signer.publicNonce) {
+		return nil, fmt.Errorf("sr25519: MuSig2 session nonce mismatch")
+	}
+
+	// Consume the secret nonces before using them, so that concurrent
+	// or repeated calls can not sign with the same nonces twice.
+	r1, r2, err := signer.nonces.take()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		r1.Zero()
+		r2.Zero()
+	}()
+
+	// s_i = r_i1 + b * r_i2 + c * a_i * x_i
+	var tmp scalar.Scalar
+	s := scalar.New().Mul(session.c, signer.keyAgg.coeffs[signer.idx])
+	s.Mul(s, signer.kp.sk.key)
+	s.Add(s, tmp.Mul(session.b, r2))
+	s.Add(s, r1)
+
+	return &MuSig2PartialSignature{
+		s: s,
+	}, nil
+}
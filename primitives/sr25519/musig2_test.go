@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+func TestMuSig2(t *testing.T) {
+	const n = 5
+
+	var (
+		keyPairs   []*KeyPair
+		publicKeys []*PublicKey
+	)
+	for i := 0; i < n; i++ {
+		kp, err := GenerateKeyPair(nil)
+		if err != nil {
+			t.Fatalf("GenerateKeyPair: %v", err)
+		}
+		keyPairs = append(keyPairs, kp)
+		publicKeys = append(publicKeys, kp.PublicKey())
+	}
+
+	keyAgg, err := NewMuSig2KeyAgg(publicKeys)
+	if err != nil {
+		t.Fatalf("NewMuSig2KeyAgg: %v", err)
+	}
+
+	// Round 1: Generate and exchange nonces.
+	var (
+		signers []*MuSig2Signer
+		nonces  []*MuSig2PublicNonce
+	)
+	for _, kp := range keyPairs {
+		signer, err := kp.NewMuSig2Signer(nil, keyAgg)
+		if err != nil {
+			t.Fatalf("NewMuSig2Signer: %v", err)
+		}
+		signers = append(signers, signer)
+
+		b, err := signer.PublicNonce().MarshalBinary()
+		if err != nil {
+			t.Fatalf("MuSig2PublicNonce.MarshalBinary: %v", err)
+		}
+		nonce, err := NewMuSig2PublicNonceFromBytes(b)
+		if err != nil {
+			t.Fatalf("NewMuSig2PublicNonceFromBytes: %v", err)
+		}
+		nonces = append(nonces, nonce)
+	}
+
+	// Round 2: Produce, verify and aggregate partial signatures.
+	sc := NewSigningContext([]byte("test musig2"))
+	session, err := NewMuSig2Session(keyAgg, sc.NewTranscriptBytes([]byte("message")), nonces)
+	if err != nil {
+		t.Fatalf("NewMuSig2Session: %v", err)
+	}
+
+	var partialSigs []*MuSig2PartialSignature
+	for i, signer := range signers {
+		ps, err := signer.Sign(session)
+		if err != nil {
+			t.Fatalf("MuSig2Signer.Sign: %v", err)
+		}
+		if !session.VerifyPartialSignature(publicKeys[i], ps) {
+			t.Fatalf("VerifyPartialSignature: failed for signer %d", i)
+		}
+		if session.VerifyPartialSignature(publicKeys[(i+1)%n], ps) {
+			t.Fatalf("VerifyPartialSignature: accepted wrong signer")
+		}
+		partialSigs = append(partialSigs, ps)
+	}
+
+	if _, err = signers[0].Sign(session); err == nil {
+		t.Fatalf("MuSig2Signer.Sign: allowed nonce reuse")
+	}
+
+	sig, err := session.Aggregate(partialSigs)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+
+	aggPk := keyAgg.PublicKey()
+	if !aggPk.Verify(sc.NewTranscriptBytes([]byte("message")), sig) {
+		t.Fatalf("aggregated signature failed to verify")
+	}
+	if aggPk.Verify(sc.NewTranscriptBytes([]byte("wrong message")), sig) {
+		t.Fatalf("aggregated signature verified with wrong message")
+	}
+
+	// Ensure the aggregated signature survives serialization.
+	sigBytes, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Signature.MarshalBinary: %v", err)
+	}
+	sig2, err := NewSignatureFromBytes(sigBytes)
+	if err != nil {
+		t.Fatalf("NewSignatureFromBytes: %v", err)
+	}
+	if !aggPk.Verify(sc.NewTranscriptBytes([]byte("message")), sig2) {
+		t.Fatalf("deserialized aggregated signature failed to verify")
+	}
+
+	t.Run("Bad", func(t *testing.T) {
+		if _, err := NewMuSig2KeyAgg(append(publicKeys, publicKeys[0])); err == nil {
+			t.Fatalf("NewMuSig2KeyAgg: accepted duplicate public key")
+		}
+
+		outsider, _ := GenerateKeyPair(nil)
+		if _, err := outsider.NewMuSig2Signer(nil, keyAgg); err == nil {
+			t.Fatalf("NewMuSig2Signer: accepted non-participant")
+		}
+
+		signer, _ := keyPairs[0].NewMuSig2Signer(nil, keyAgg)
+		if _, err := signer.Sign(session); err == nil {
+			t.Fatalf("MuSig2Signer.Sign: accepted session with mismatched nonce")
+		}
+	})
+
+	t.Run("NonceReuse", func(t *testing.T) {
+		newSession := func() ([]*MuSig2Signer, *MuSig2Session) {
+			var (
+				signers []*MuSig2Signer
+				nonces  []*MuSig2PublicNonce
+			)
+			for _, kp := range keyPairs {
+				signer, err := kp.NewMuSig2Signer(nil, keyAgg)
+				if err != nil {
+					t.Fatalf("NewMuSig2Signer: %v", err)
+				}
+				signers = append(signers, signer)
+				nonces = append(nonces, signer.PublicNonce())
+			}
+			session, err := NewMuSig2Session(keyAgg, sc.NewTranscriptBytes([]byte("message")), nonces)
+			if err != nil {
+				t.Fatalf("NewMuSig2Session: %v", err)
+			}
+			return signers, session
+		}
+
+		// A shallow copy (as `copied := *signer` would make) shares
+		// the secret nonces with the original.
+		signers, session := newSession()
+		signer := signers[0]
+		copied := &MuSig2Signer{
+			kp:          signer.kp,
+			keyAgg:      signer.keyAgg,
+			idx:         signer.idx,
+			nonces:      signer.nonces,
+			publicNonce: signer.publicNonce,
+		}
+		if _, err := signer.Sign(session); err != nil {
+			t.Fatalf("MuSig2Signer.Sign: %v", err)
+		}
+		if _, err := copied.Sign(session); err == nil {
+			t.Fatalf("MuSig2Signer.Sign: copy allowed nonce reuse")
+		}
+
+		// Concurrent calls must produce at most one partial signature.
+		signers, session = newSession()
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			successes int
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := signers[0].Sign(session); err == nil {
+					mu.Lock()
+					successes++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if successes != 1 {
+			t.Fatalf("MuSig2Signer.Sign: %d concurrent signatures", successes)
+		}
+
+		// Zeroed nonces would expose c * a_i * x_i.
+		signers, session = newSession()
+		signers[0].nonces.r1, signers[0].nonces.r2 = scalar.New(), scalar.New()
+		if _, err := signers[0].Sign(session); err == nil {
+			t.Fatalf("MuSig2Signer.Sign: accepted zero nonces")
+		}
+	})
+}
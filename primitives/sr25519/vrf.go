@@ -0,0 +1,561 @@
+// Copyright (c) 2019 isis agora lovecruft. All rights reserved.
+// Copyright (c) 2019 Web 3 Foundation. All rights reserved.
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/scalar128"
+	"github.com/oasisprotocol/curve25519-voi/primitives/merlin"
+)
+
+const (
+	// VRFPreOutputSize is the size of a VRFPreOutput in bytes.
+	VRFPreOutputSize = curve.CompressedPointSize
+
+	// VRFProofSize is the size of a VRFProof in bytes.
+	VRFProofSize = 64
+
+	// VRFProofBatchableSize is the size of a VRFProofBatchable in bytes.
+	VRFProofBatchableSize = 96
+
+	vrfNonMalleableLabel = "vrf-nm-pk"
+	vrfHashLabel         = "VRFHash"
+	vrfInLabel           = "vrf-in"
+	vrfOutLabel          = "vrf-out"
+	vrfResultLabel       = "VRFResult"
+	vrfExtraLabel        = "VRF"
+
+	dleqProtoLabel = "DLEQProof"
+	dleqHLabel     = "vrf:h"
+	dleqPkLabel    = "vrf:pk"
+	dleqRLabel     = "vrf:R=g^r"
+	dleqHrLabel    = "vrf:h^r"
+	dleqHskLabel   = "vrf:h^sk"
+	dleqCLabel     = "prove"
+
+	dleqWitnessScalarLabel = "proving\x000"
+)
+
+// VRFPreOutput is the VRF output point, prior to the input point being
+// attached.
+type VRFPreOutput struct {
+	compressed curve.CompressedRistretto
+	point      *curve.RistrettoPoint
+}
+
+// MarshalBinary encodes a VRFPreOutput into binary form.
+func (po *VRFPreOutput) MarshalBinary() ([]byte, error) {
+	return append([]byte{}, po.compressed[:]...), nil
+}
+
+// UnmarshalBinary decodes a binary marshaled VRFPreOutput.
+func (po *VRFPreOutput) UnmarshalBinary(data []byte) error {
+	po.compressed.Identity()
+	po.point = nil
+
+	if l := len(data); l != VRFPreOutputSize {
+		return fmt.Errorf("sr25519: bad VRFPreOutput size: %v", l)
+	}
+
+	var compressed curve.CompressedRistretto
+	if err := compressed.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF pre-output: %w", err)
+	}
+
+	var point curve.RistrettoPoint
+	if _, err := point.SetCompressed(&compressed); err != nil {
+		return fmt.Errorf("sr25519: failed to decompress VRF pre-output: %w", err)
+	}
+
+	po.compressed = compressed
+	po.point = &point
+
+	return nil
+}
+
+// NewVRFPreOutputFromBytes constructs a VRFPreOutput from the byte
+// representation.
+func NewVRFPreOutputFromBytes(b []byte) (*VRFPreOutput, error) {
+	var po VRFPreOutput
+	if err := po.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+// VRFInOut is a VRF input and output point pair.
+type VRFInOut struct {
+	input            curve.RistrettoPoint
+	inputCompressed  curve.CompressedRistretto
+	output           curve.RistrettoPoint
+	outputCompressed curve.CompressedRistretto
+}
+
+// PreOutput returns the VRFPreOutput corresponding to the VRFInOut.
+func (inout *VRFInOut) PreOutput() *VRFPreOutput {
+	return &VRFPreOutput{
+		compressed: inout.outputCompressed,
+		point:      curve.NewRistrettoPoint().Set(&inout.output),
+	}
+}
+
+// MakeBytes fills dest with VRF output bytes, derived from the input
+// and output points, and a context string.
+func (inout *VRFInOut) MakeBytes(dest, context []byte) {
+	t := &SigningTranscript{
+		t: merlin.NewTranscript(vrfResultLabel),
+	}
+	t.commitBytes("", context)
+	inout.commit(t)
+	t.challengeBytes(dest, "")
+}
+
+func (inout *VRFInOut) commit(t *SigningTranscript) {
+	t.commitPoint(vrfInLabel, &inout.inputCompressed)
+	t.commitPoint(vrfOutLabel, &inout.outputCompressed)
+}
+
+func newVRFInOut(input, output *curve.RistrettoPoint) *VRFInOut {
+	var inout VRFInOut
+	inout.input.Set(input)
+	inout.inputCompressed.SetRistrettoPoint(input)
+	inout.output.Set(output)
+	inout.outputCompressed.SetRistrettoPoint(output)
+	return &inout
+}
+
+// VRFProof is a short VRF proof, consisting of the challenge and
+// response scalars of a DLEQ proof.
+type VRFProof struct {
+	c *scalar.Scalar
+	s *scalar.Scalar
+}
+
+// MarshalBinary encodes a VRFProof into binary form.
+func (proof *VRFProof) MarshalBinary() ([]byte, error) {
+	b := make([]byte, VRFProofSize)
+	if proof.c == nil || proof.s == nil {
+		return b, nil
+	}
+
+	if err := proof.c.ToBytes(b[:scalar.ScalarSize]); err != nil {
+		return nil, fmt.Errorf("sr25519: failed to serialize VRF proof challenge: %w", err)
+	}
+	if err := proof.s.ToBytes(b[scalar.ScalarSize:]); err != nil {
+		return nil, fmt.Errorf("sr25519: failed to serialize VRF proof response: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary marshaled VRFProof.
+func (proof *VRFProof) UnmarshalBinary(data []byte) error {
+	proof.c, proof.s = nil, nil
+
+	if l := len(data); l != VRFProofSize {
+		return fmt.Errorf("sr25519: bad VRFProof size: %v", l)
+	}
+
+	c, err := scalar.NewFromCanonicalBytes(data[:scalar.ScalarSize])
+	if err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF proof challenge: %w", err)
+	}
+	s, err := scalar.NewFromCanonicalBytes(data[scalar.ScalarSize:])
+	if err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF proof response: %w", err)
+	}
+
+	proof.c, proof.s = c, s
+
+	return nil
+}
+
+// NewVRFProofFromBytes constructs a VRFProof from the byte representation.
+func NewVRFProofFromBytes(b []byte) (*VRFProof, error) {
+	var proof VRFProof
+	if err := proof.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// VRFProofBatchable is a longer VRF proof, which supports batch
+// verification.
+type VRFProofBatchable struct {
+	r  curve.CompressedRistretto
+	hr curve.CompressedRistretto
+	s  *scalar.Scalar
+}
+
+// MarshalBinary encodes a VRFProofBatchable into binary form.
+func (proof *VRFProofBatchable) MarshalBinary() ([]byte, error) {
+	b := make([]byte, VRFProofBatchableSize)
+	if proof.s == nil {
+		return b, nil
+	}
+
+	copy(b[0:32], proof.r[:])
+	copy(b[32:64], proof.hr[:])
+	if err := proof.s.ToBytes(b[64:]); err != nil {
+		return nil, fmt.Errorf("sr25519: failed to serialize VRF proof response: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary marshaled VRFProofBatchable.
+func (proof *VRFProofBatchable) UnmarshalBinary(data []byte) error {
+	proof.r.Identity()
+	proof.hr.Identity()
+	proof.s = nil
+
+	if l := len(data); l != VRFProofBatchableSize {
+		return fmt.Errorf("sr25519: bad VRFProofBatchable size: %v", l)
+	}
+
+	s, err := scalar.NewFromCanonicalBytes(data[64:])
+	if err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF proof response: %w", err)
+	}
+
+	// Copy (but do not decompress) the points.
+	if _, err = proof.r.SetBytes(data[0:32]); err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF proof R: %w", err)
+	}
+	if _, err = proof.hr.SetBytes(data[32:64]); err != nil {
+		return fmt.Errorf("sr25519: failed to deserialize VRF proof Hr: %w", err)
+	}
+	proof.s = s
+
+	return nil
+}
+
+// NewVRFProofBatchableFromBytes constructs a VRFProofBatchable from the
+// byte representation.
+func NewVRFProofBatchableFromBytes(b []byte) (*VRFProofBatchable, error) {
+	var proof VRFProofBatchable
+	if err := proof.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// ShortenVRF converts a VRFProofBatchable into a VRFProof, given the
+// public key, transcript and VRF pre-output that the proof is for.
+func (proof *VRFProofBatchable) ShortenVRF(pk *PublicKey, transcript *SigningTranscript, preOutput *VRFPreOutput) (*VRFProof, error) {
+	inout, err := pk.vrfAttachHash(transcript, preOutput)
+	if err != nil {
+		return nil, err
+	}
+	return proof.shortenDLEQ(newVRFExtraTranscript(), pk, inout), nil
+}
+
+func (proof *VRFProofBatchable) shortenDLEQ(t *SigningTranscript, pk *PublicKey, inout *VRFInOut) *VRFProof {
+	t.protoName(dleqProtoLabel)
+	t.commitPoint(dleqHLabel, &inout.inputCompressed)
+	t.commitPoint(dleqRLabel, &proof.r)
+	t.commitPoint(dleqHrLabel, &proof.hr)
+	t.commitPoint(dleqPkLabel, &pk.compressed)
+	t.commitPoint(dleqHskLabel, &inout.outputCompressed)
+
+	return &VRFProof{
+		c: t.challengeScalar(dleqCLabel),
+		s: scalar.New().Set(proof.s),
+	}
+}
+
+func newVRFExtraTranscript() *SigningTranscript {
+	return &SigningTranscript{
+		t: merlin.NewTranscript(vrfExtraLabel),
+	}
+}
+
+func (pk *PublicKey) vrfHash(transcript *SigningTranscript) *curve.RistrettoPoint {
+	t := transcript.clone()
+	t.commitPoint(vrfNonMalleableLabel, &pk.compressed)
+
+	var b [64]byte
+	t.challengeBytes(b[:], vrfHashLabel)
+
+	var p curve.RistrettoPoint
+	if _, err := p.SetUniformBytes(b[:]); err != nil {
+		panic("sr25519: failed to map VRF input to point: " + err.Error())
+	}
+
+	return &p
+}
+
+func (pk *PublicKey) vrfAttachHash(transcript *SigningTranscript, preOutput *VRFPreOutput) (*VRFInOut, error) {
+	if preOutput.point == nil {
+		return nil, fmt.Errorf("sr25519: uninitialized VRF pre-output")
+	}
+
+	return newVRFInOut(pk.vrfHash(transcript), preOutput.point), nil
+}
+
+// VRFSign computes the VRF output and proof for a transcript with a
+// key pair and provided entropy source.  If rng is nil,
+// crypto/rand.Reader will be used.
+//
+// Proofs are compatible with schnorrkel's Kusama mode (as used by
+// Substrate's BABE), which commits the public key to the proof
+// transcript after the nonce commitments.
+func (kp *KeyPair) VRFSign(rng io.Reader, transcript *SigningTranscript) (*VRFInOut, *VRFProof, *VRFProofBatchable, error) {
+	return kp.VRFSignExtra(rng, transcript, newVRFExtraTranscript())
+}
+
+// VRFSignExtra computes the VRF output and proof for a transcript with
+// a key pair and provided entropy source, additionally binding the proof
+// to the extra transcript.  If rng is nil, crypto/rand.Reader will be
+// used.
+func (kp *KeyPair) VRFSignExtra(rng io.Reader, transcript, extra *SigningTranscript) (*VRFInOut, *VRFProof, *VRFProofBatchable, error) {
+	if kp.sk == nil || kp.pk == nil {
+		return nil, nil, nil, fmt.Errorf("sr25519: attempted to sign with uninitialized KeyPair")
+	}
+
+	var output curve.RistrettoPoint
+	input := kp.pk.vrfHash(transcript)
+	output.Mul(input, kp.sk.key)
+	inout := newVRFInOut(input, &output)
+
+	proof, proofBatchable, err := kp.dleqProve(rng, extra, inout)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return inout, proof, proofBatchable, nil
+}
+
+func (kp *KeyPair) dleqProve(rng io.Reader, extra *SigningTranscript, inout *VRFInOut) (*VRFProof, *VRFProofBatchable, error) {
+	t := extra.clone()
+	t.protoName(dleqProtoLabel)
+	t.commitPoint(dleqHLabel, &inout.inputCompressed)
+
+	r, err := t.witnessScalar(dleqWitnessScalarLabel, [][]byte{kp.sk.nonce[:]}, rng)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sr25519: failed to generate witness scalar: %w", err)
+	}
+
+	var (
+		proofBatchable VRFProofBatchable
+		tmp            curve.RistrettoPoint
+	)
+	proofBatchable.r.SetRistrettoPoint(tmp.MulBasepoint(curve.RISTRETTO_BASEPOINT_TABLE, r))
+	t.commitPoint(dleqRLabel, &proofBatchable.r)
+
+	proofBatchable.hr.SetRistrettoPoint(tmp.Mul(&inout.input, r))
+	t.commitPoint(dleqHrLabel, &proofBatchable.hr)
+
+	t.commitPoint(dleqPkLabel, &kp.pk.compressed)
+	t.commitPoint(dleqHskLabel, &inout.outputCompressed)
+
+	c := t.challengeScalar(dleqCLabel)
+	s := scalar.New().Mul(c, kp.sk.key)
+	s.Sub(r, s)
+
+	proofBatchable.s = s
+	proof := &VRFProof{
+		c: c,
+		s: scalar.New().Set(s),
+	}
+
+	return proof, &proofBatchable, nil
+}
+
+// VRFVerify verifies a VRF proof by a public key on a transcript and
+// pre-output, and returns the VRF input and output pair and the
+// corresponding batchable proof on success.
+func (pk *PublicKey) VRFVerify(transcript *SigningTranscript, preOutput *VRFPreOutput, proof *VRFProof) (*VRFInOut, *VRFProofBatchable, error) {
+	return pk.VRFVerifyExtra(transcript, preOutput, proof, newVRFExtraTranscript())
+}
+
+// VRFVerifyExtra verifies a VRF proof by a public key on a transcript,
+// pre-output, and extra transcript, and returns the VRF input and output
+// pair and the corresponding batchable proof on success.
+func (pk *PublicKey) VRFVerifyExtra(transcript *SigningTranscript, preOutput *VRFPreOutput, proof *VRFProof, extra *SigningTranscript) (*VRFInOut, *VRFProofBatchable, error) {
+	if pk.point == nil || proof.c == nil || proof.s == nil {
+		return nil, nil, fmt.Errorf("sr25519: uninitialized public key or VRF proof")
+	}
+
+	inout, err := pk.vrfAttachHash(transcript, preOutput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := extra.clone()
+	t.protoName(dleqProtoLabel)
+	t.commitPoint(dleqHLabel, &inout.inputCompressed)
+
+	// R = c * A + s * B
+	var (
+		proofBatchable VRFProofBatchable
+		tmp            curve.RistrettoPoint
+	)
+	proofBatchable.r.SetRistrettoPoint(tmp.DoubleScalarMulBasepointVartime(proof.c, pk.point, proof.s))
+	t.commitPoint(dleqRLabel, &proofBatchable.r)
+
+	// Hr = c * Output + s * Input
+	proofBatchable.hr.SetRistrettoPoint(tmp.MultiscalarMulVartime(
+		[]*scalar.Scalar{proof.c, proof.s},
+		[]*curve.RistrettoPoint{&inout.output, &inout.input},
+	))
+	t.commitPoint(dleqHrLabel, &proofBatchable.hr)
+
+	t.commitPoint(dleqPkLabel, &pk.compressed)
+	t.commitPoint(dleqHskLabel, &inout.outputCompressed)
+
+	if proof.c.Equal(t.challengeScalar(dleqCLabel)) != 1 {
+		return nil, nil, fmt.Errorf("sr25519: VRF proof verification failed")
+	}
+	proofBatchable.s = scalar.New().Set(proof.s)
+
+	return inout, &proofBatchable, nil
+}
+
+// VRFVerifyBatch verifies a batch of batchable VRF proofs using entropy
+// from rand, and returns the VRF input and output pairs on success.  If
+// rand is nil, crypto/rand.Reader will be used.  If the lengths of the
+// transcripts, preOutputs, proofs and publicKeys slices differ, this
+// function will panic.
+//
+// If a failure arises it is unknown which entry failed, the caller must
+// verify each entry individually.
+func VRFVerifyBatch(rand io.Reader, transcripts []*SigningTranscript, preOutputs []*VRFPreOutput, proofs []*VRFProofBatchable, publicKeys []*PublicKey) ([]*VRFInOut, error) {
+	n := len(transcripts)
+	if n != len(preOutputs) || n != len(proofs) || n != len(publicKeys) {
+		panic("sr25519: mismatched VRF batch verification input lengths")
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("sr25519: empty VRF batch")
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	inouts := make([]*VRFInOut, 0, n)
+	for i, pk := range publicKeys {
+		if pk.point == nil || proofs[i].s == nil {
+			return nil, fmt.Errorf("sr25519: uninitialized public key or VRF proof")
+		}
+		inout, err := pk.vrfAttachHash(transcripts[i], preOutputs[i])
+		if err != nil {
+			return nil, err
+		}
+		inouts = append(inouts, inout)
+	}
+
+	// Derive the randomizers from a rng keyed by the public keys, the
+	// input and output points, and the provided entropy source.
+	zsT := &SigningTranscript{
+		t: merlin.NewTranscript("VB-RNG"),
+	}
+	for i, pk := range publicKeys {
+		zsT.commitPoint("", &pk.compressed)
+		inouts[i].commit(zsT)
+	}
+	zsRng, err := zsT.witnessRng("", nil, rand)
+	if err != nil {
+		return nil, fmt.Errorf("sr25519: failed to instantiate delinearization rng: %w", err)
+	}
+
+	// The batch verification equations are
+	//
+	// [sum(z_i * s_i)]B + sum([z_i * c_i]A_i) - sum([z_i]R_i) = 0
+	// sum([z_i * s_i]Input_i) + sum([z_i * c_i]Output_i) - sum([z_i]Hr_i) = 0
+	//
+	// where z_i is a random 128-bit Scalar.
+	var (
+		bCoeff      scalar.Scalar
+		randomBytes [scalar.ScalarSize]byte
+	)
+	negZs := make([]*scalar.Scalar, 0, n)
+	zcs := make([]*scalar.Scalar, 0, n)
+	zss := make([]*scalar.Scalar, 0, n)
+	rs := make([]*curve.RistrettoPoint, 0, n)
+	hrs := make([]*curve.RistrettoPoint, 0, n)
+	as := make([]*curve.RistrettoPoint, 0, n)
+	inputs := make([]*curve.RistrettoPoint, 0, n)
+	outputs := make([]*curve.RistrettoPoint, 0, n)
+	for i, proof := range proofs {
+		if _, err = io.ReadFull(zsRng, randomBytes[:scalar.ScalarSize/2]); err != nil {
+			return nil, fmt.Errorf("sr25519: failed to generate batch verification scalar: %w", err)
+		}
+		scalar128.FixRawRangeVartime(&randomBytes)
+		z, err := scalar.NewFromBits(randomBytes[:])
+		if err != nil {
+			return nil, fmt.Errorf("sr25519: failed to deserialize batch verification scalar: %w", err)
+		}
+
+		var r, hr curve.RistrettoPoint
+		if _, err = r.SetCompressed(&proof.r); err != nil {
+			return nil, fmt.Errorf("sr25519: failed to decompress VRF proof R: %w", err)
+		}
+		if _, err = hr.SetCompressed(&proof.hr); err != nil {
+			return nil, fmt.Errorf("sr25519: failed to decompress VRF proof Hr: %w", err)
+		}
+
+		c := proof.shortenDLEQ(newVRFExtraTranscript(), publicKeys[i], inouts[i]).c
+
+		zs := scalar.New().Mul(z, proof.s)
+		bCoeff.Add(&bCoeff, zs)
+
+		zcs = append(zcs, c.Mul(c, z))
+		zss = append(zss, zs)
+		negZs = append(negZs, z.Neg(z))
+		rs = append(rs, &r)
+		hrs = append(hrs, &hr)
+		as = append(as, publicKeys[i].point)
+		inputs = append(inputs, &inouts[i].input)
+		outputs = append(outputs, &inouts[i].output)
+	}
+
+	var shouldBeId curve.RistrettoPoint
+
+	scalars := append(append(append([]*scalar.Scalar{}, negZs...), zcs...), &bCoeff)
+	points := append(append(append([]*curve.RistrettoPoint{}, rs...), as...), curve.RISTRETTO_BASEPOINT_POINT)
+	ok := shouldBeId.MultiscalarMulVartime(scalars, points).IsIdentity()
+
+	scalars = append(append(append(scalars[:0], negZs...), zcs...), zss...)
+	points = append(append(append(points[:0], hrs...), outputs...), inputs...)
+	ok = shouldBeId.MultiscalarMulVartime(scalars, points).IsIdentity() && ok
+
+	if !ok {
+		return nil, fmt.Errorf("sr25519: VRF batch verification failed")
+	}
+
+	return inouts, nil
+}
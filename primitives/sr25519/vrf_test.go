@@ -0,0 +1,310 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sr25519
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+func TestVRF(t *testing.T) {
+	kp, err := GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	pk := kp.PublicKey()
+
+	sc := NewSigningContext([]byte("test vrf"))
+	newTranscript := func(msg string) *SigningTranscript {
+		return sc.NewTranscriptBytes([]byte(msg))
+	}
+
+	inout, proof, proofBatchable, err := kp.VRFSign(nil, newTranscript("message"))
+	if err != nil {
+		t.Fatalf("VRFSign: %v", err)
+	}
+
+	t.Run("S11n", func(t *testing.T) {
+		b, err := inout.PreOutput().MarshalBinary()
+		if err != nil {
+			t.Fatalf("VRFPreOutput.MarshalBinary: %v", err)
+		}
+		if _, err = NewVRFPreOutputFromBytes(b); err != nil {
+			t.Fatalf("NewVRFPreOutputFromBytes: %v", err)
+		}
+
+		b, err = proof.MarshalBinary()
+		if err != nil {
+			t.Fatalf("VRFProof.MarshalBinary: %v", err)
+		}
+		proof2, err := NewVRFProofFromBytes(b)
+		if err != nil {
+			t.Fatalf("NewVRFProofFromBytes: %v", err)
+		}
+		if proof2.c.Equal(proof.c) != 1 || proof2.s.Equal(proof.s) != 1 {
+			t.Fatalf("VRFProof round-trip mismatch")
+		}
+
+		b, err = proofBatchable.MarshalBinary()
+		if err != nil {
+			t.Fatalf("VRFProofBatchable.MarshalBinary: %v", err)
+		}
+		proofBatchable2, err := NewVRFProofBatchableFromBytes(b)
+		if err != nil {
+			t.Fatalf("NewVRFProofBatchableFromBytes: %v", err)
+		}
+		b2, _ := proofBatchable2.MarshalBinary()
+		if !bytes.Equal(b, b2) {
+			t.Fatalf("VRFProofBatchable round-trip mismatch")
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		vInout, vProofBatchable, err := pk.VRFVerify(newTranscript("message"), inout.PreOutput(), proof)
+		if err != nil {
+			t.Fatalf("VRFVerify: %v", err)
+		}
+
+		var expected, actual [32]byte
+		inout.MakeBytes(expected[:], []byte("context"))
+		vInout.MakeBytes(actual[:], []byte("context"))
+		if expected != actual {
+			t.Fatalf("VRF output mismatch")
+		}
+
+		vInout.MakeBytes(actual[:], []byte("other context"))
+		if expected == actual {
+			t.Fatalf("VRF output not bound to context")
+		}
+
+		b, _ := proofBatchable.MarshalBinary()
+		vb, _ := vProofBatchable.MarshalBinary()
+		if !bytes.Equal(b, vb) {
+			t.Fatalf("recovered batchable proof mismatch")
+		}
+
+		shortened, err := proofBatchable.ShortenVRF(pk, newTranscript("message"), inout.PreOutput())
+		if err != nil {
+			t.Fatalf("ShortenVRF: %v", err)
+		}
+		if shortened.c.Equal(proof.c) != 1 || shortened.s.Equal(proof.s) != 1 {
+			t.Fatalf("shortened proof mismatch")
+		}
+	})
+
+	t.Run("Verify/Bad", func(t *testing.T) {
+		if _, _, err := pk.VRFVerify(newTranscript("wrong message"), inout.PreOutput(), proof); err == nil {
+			t.Fatalf("VRFVerify: accepted wrong message")
+		}
+
+		otherKp, _ := GenerateKeyPair(nil)
+		if _, _, err := otherKp.PublicKey().VRFVerify(newTranscript("message"), inout.PreOutput(), proof); err == nil {
+			t.Fatalf("VRFVerify: accepted wrong public key")
+		}
+
+		otherInout, _, _, _ := kp.VRFSign(nil, newTranscript("other message"))
+		if _, _, err := pk.VRFVerify(newTranscript("message"), otherInout.PreOutput(), proof); err == nil {
+			t.Fatalf("VRFVerify: accepted wrong pre-output")
+		}
+	})
+
+	t.Run("Extra", func(t *testing.T) {
+		extra := NewSigningContext([]byte("extra")).NewTranscriptBytes([]byte("extra data"))
+		inout, proof, _, err := kp.VRFSignExtra(nil, newTranscript("message"), extra)
+		if err != nil {
+			t.Fatalf("VRFSignExtra: %v", err)
+		}
+		if _, _, err = pk.VRFVerifyExtra(newTranscript("message"), inout.PreOutput(), proof, extra); err != nil {
+			t.Fatalf("VRFVerifyExtra: %v", err)
+		}
+		if _, _, err = pk.VRFVerify(newTranscript("message"), inout.PreOutput(), proof); err == nil {
+			t.Fatalf("VRFVerify: accepted proof with extra transcript")
+		}
+	})
+}
+
+func TestVRFVector(t *testing.T) {
+	// Test vectors produced by schnorrkel, as carried by go-schnorrkel's
+	// vrf_test.go.  All use the transcript built by
+	// `signing_context(b"yo!").bytes(b"meow")`.
+	//
+	// schnorrkel's DLEQ proofs come in two flavors, that differ only in
+	// where the public key is committed to the proof transcript.  This
+	// package implements the Kusama (Substrate BABE) ordering, so proofs
+	// made in the other mode must be rejected, while the VRF input,
+	// output, and derived bytes are identical in both.
+	for _, v := range []struct {
+		name        string
+		publicKey   string
+		input       string
+		output      string
+		proof       string
+		makeBytes16 string
+		kusama      bool
+	}{
+		{
+			// https://github.com/w3f/schnorrkel/blob/798ab3e0813aa478b520c5cf6dc6e02fd4e07f0a/src/vrf.rs#L922
+			name:      "schnorrkel",
+			publicKey: "c02a48ba140b5396f545a8de16a6a75f7df8b843c50aa16bcd748fa48f7fa654",
+			input:     "383427738f502b42aeb16515b10fc7e4b46ed08be59218e776afb437bf25963d",
+			output:    "005b3219d65e772447d8219855b822783da1a4df4c3528f64c26ebcc2b1fb31c",
+			proof:     "7817eb9f737acfce7be84bf373ff83b5dbf1c8ce1516ee10443156634c8b2700666ab588618dbb01eab7f11c1be5850820f6f5cec78e867ce2d95f1eb0f60503",
+			kusama:    true,
+		},
+		{
+			name:        "Kusama",
+			publicKey:   "0c84b70beabe60ac6fefa38994a3454fe63d8629455a86e58480063f8bdcca00",
+			input:       "bca2b6a1c31a37dfa6cd885cd382b8c2b751d7c0a80c2737daa508699b498044",
+			output:      "d62899f6584a7ff236c107055a332d05cf3b404486e813dff9584a7d404adc30",
+			proof:       "90c7b305fac7dcb10cdcf2c4a8ed6a033ec34a7f866b895ba568dff403048d0a8136861f31facdcbfe8e577bd86cbe70ccccbc1e5424f7d93b7d2d3870c3540f",
+			makeBytes16: "a939953200f3788a19fa4aebf789e428",
+			kusama:      true,
+		},
+		{
+			name:        "NotKusama",
+			publicKey:   "b20a94b086cd818b2d5a2a0e4774e3e90ffd38357b0759f0813d53d558492d6f",
+			input:       "76c0918691e2d11c3e0fbbec2be5ffa1487a80151c9b4813436432d948235f6f",
+			output:      "72adbc748f0b9df457d6e700ea229d913e9a44a1794231197b268a14cf690705",
+			proof:       "7bdb3cec316a71e5876299fc0a3f41aef2bf824177b1e30f67dbc064aecc88035f94f6696c3314ad7b6c0531fd15aa29d6018d615db634afcaba95d54539070e",
+			makeBytes16: "c1996812041b799295e40c11fbb87510",
+			kusama:      false,
+		},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			newTranscript := func() *SigningTranscript {
+				return NewSigningContext([]byte("yo!")).NewTranscriptBytes([]byte("meow"))
+			}
+
+			pk, err := NewPublicKeyFromBytes(testhelpers.MustUnhex(t, v.publicKey))
+			if err != nil {
+				t.Fatalf("NewPublicKeyFromBytes: %v", err)
+			}
+			preOutputBytes := testhelpers.MustUnhex(t, v.output)
+			preOutput, err := NewVRFPreOutputFromBytes(preOutputBytes)
+			if err != nil {
+				t.Fatalf("NewVRFPreOutputFromBytes: %v", err)
+			}
+			proofBytes := testhelpers.MustUnhex(t, v.proof)
+			proof, err := NewVRFProofFromBytes(proofBytes)
+			if err != nil {
+				t.Fatalf("NewVRFProofFromBytes: %v", err)
+			}
+
+			b, _ := preOutput.MarshalBinary()
+			if !bytes.Equal(b, preOutputBytes) {
+				t.Fatalf("VRFPreOutput round-trip mismatch: %x", b)
+			}
+			b, _ = proof.MarshalBinary()
+			if !bytes.Equal(b, proofBytes) {
+				t.Fatalf("VRFProof round-trip mismatch: %x", b)
+			}
+
+			inout, err := pk.vrfAttachHash(newTranscript(), preOutput)
+			if err != nil {
+				t.Fatalf("vrfAttachHash: %v", err)
+			}
+			if expected := testhelpers.MustUnhex(t, v.input); !bytes.Equal(inout.inputCompressed[:], expected) {
+				t.Fatalf("VRF input mismatch: got %x", inout.inputCompressed[:])
+			}
+
+			if v.makeBytes16 != "" {
+				var out [16]byte
+				inout.MakeBytes(out[:], []byte("substrate-babe-vrf"))
+				if expected := testhelpers.MustUnhex(t, v.makeBytes16); !bytes.Equal(out[:], expected) {
+					t.Fatalf("MakeBytes mismatch: got %x", out[:])
+				}
+			}
+
+			vInout, _, err := pk.VRFVerify(newTranscript(), preOutput, proof)
+			if v.kusama {
+				if err != nil {
+					t.Fatalf("VRFVerify: %v", err)
+				}
+				if vInout.inputCompressed != inout.inputCompressed || vInout.outputCompressed != inout.outputCompressed {
+					t.Fatalf("VRFVerify: VRFInOut mismatch")
+				}
+			} else if err == nil {
+				t.Fatalf("VRFVerify: accepted non-Kusama proof")
+			}
+		})
+	}
+}
+
+func TestVRFVerifyBatch(t *testing.T) {
+	const n = 16
+
+	sc := NewSigningContext([]byte("test vrf batch"))
+
+	var (
+		transcripts []*SigningTranscript
+		preOutputs  []*VRFPreOutput
+		proofs      []*VRFProofBatchable
+		publicKeys  []*PublicKey
+		expected    []*VRFInOut
+	)
+	for i := 0; i < n; i++ {
+		kp, err := GenerateKeyPair(nil)
+		if err != nil {
+			t.Fatalf("GenerateKeyPair: %v", err)
+		}
+
+		msg := []byte{byte(i)}
+		inout, _, proofBatchable, err := kp.VRFSign(nil, sc.NewTranscriptBytes(msg))
+		if err != nil {
+			t.Fatalf("VRFSign: %v", err)
+		}
+
+		transcripts = append(transcripts, sc.NewTranscriptBytes(msg))
+		preOutputs = append(preOutputs, inout.PreOutput())
+		proofs = append(proofs, proofBatchable)
+		publicKeys = append(publicKeys, kp.PublicKey())
+		expected = append(expected, inout)
+	}
+
+	inouts, err := VRFVerifyBatch(nil, transcripts, preOutputs, proofs, publicKeys)
+	if err != nil {
+		t.Fatalf("VRFVerifyBatch: %v", err)
+	}
+	for i := range inouts {
+		var a, b [32]byte
+		inouts[i].MakeBytes(a[:], nil)
+		expected[i].MakeBytes(b[:], nil)
+		if a != b {
+			t.Fatalf("VRFVerifyBatch: output %d mismatch", i)
+		}
+	}
+
+	publicKeys[0], publicKeys[1] = publicKeys[1], publicKeys[0]
+	if _, err = VRFVerifyBatch(nil, transcripts, preOutputs, proofs, publicKeys); err == nil {
+		t.Fatalf("VRFVerifyBatch: accepted invalid batch")
+	}
+}
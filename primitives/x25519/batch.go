@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+)
+
+// ScalarMultBatch sets dst[i] to the product in[i]*base[i], as ScalarMult,
+// sharing a single field inversion across the entire batch.
+//
+// When provided a low-order point, ScalarMultBatch will set the
+// corresponding dst to all zeroes, irrespective of the scalar.
+//
+// WARNING: This routine will panic if len(dst) != len(in) or
+// len(dst) != len(base).
+func ScalarMultBatch(dst, in, base []*[32]byte) {
+	if len(dst) != len(in) || len(dst) != len(base) {
+		panic("x25519: mismatched dst, in and base slice lengths")
+	}
+
+	scalars := make([]scalar.Scalar, len(in))
+	scalarPtrs := make([]*scalar.Scalar, len(in))
+	points := make([]*curve.MontgomeryPoint, len(base))
+	for i := range in {
+		setClampedScalar(&scalars[i], in[i])
+		scalarPtrs[i] = &scalars[i]
+		points[i] = (*curve.MontgomeryPoint)(base[i])
+	}
+
+	out := make([]curve.MontgomeryPoint, len(dst))
+	curve.MontgomeryMulBatch(out, points, scalarPtrs)
+	for i := range out {
+		copy(dst[i][:], out[i][:])
+	}
+}
+
+// ScalarBaseMultBatch sets dst[i] to the product in[i]*base, where base
+// is the standard generator, as ScalarBaseMult, sharing a single field
+// inversion across the entire batch.
+//
+// WARNING: This routine will panic if len(dst) != len(in).
+func ScalarBaseMultBatch(dst, in []*[32]byte) {
+	if len(dst) != len(in) {
+		panic("x25519: mismatched dst and in slice lengths")
+	}
+
+	scalars := make([]scalar.Scalar, len(in))
+	scalarPtrs := make([]*scalar.Scalar, len(in))
+	for i := range in {
+		setClampedScalar(&scalars[i], in[i])
+		scalarPtrs[i] = &scalars[i]
+	}
+
+	out := make([]curve.MontgomeryPoint, len(dst))
+	curve.MontgomeryMulBasepointBatch(out, curve.ED25519_BASEPOINT_TABLE, scalarPtrs)
+	for i := range out {
+		copy(dst[i][:], out[i][:])
+	}
+}
+
+// DiffieHellmanBatch performs Diffie-Hellman key exchanges between each
+// private key and the corresponding public key to produce shared secrets,
+// as PrivateKey.DiffieHellman, sharing a single field inversion across the
+// entire batch.
+//
+// When provided a low-order public key, the corresponding shared secret
+// will contain only zeroes, irrespective of the private key.  Use
+// SharedSecret.IsZero to handle this case appropriately, if needed.
+//
+// WARNING: This routine will panic if len(privateKeys) != len(publicKeys).
+func DiffieHellmanBatch(privateKeys []*PrivateKey, publicKeys []*PublicKey) []*SharedSecret {
+	if len(privateKeys) != len(publicKeys) {
+		panic("x25519: mismatched private and public key slice lengths")
+	}
+
+	secrets := make([]SharedSecret, len(privateKeys))
+	dst := make([]*[32]byte, len(privateKeys))
+	in := make([]*[32]byte, len(privateKeys))
+	base := make([]*[32]byte, len(publicKeys))
+	ret := make([]*SharedSecret, len(privateKeys))
+	for i := range privateKeys {
+		dst[i] = (*[PointSize]byte)(&secrets[i])
+		in[i] = (*[ScalarSize]byte)(privateKeys[i])
+		base[i] = (*[PointSize]byte)(publicKeys[i])
+		ret[i] = &secrets[i]
+	}
+
+	ScalarMultBatch(dst, in, base)
+
+	return ret
+}
+
+// GenerateKeyBatch generates n public/private key pairs using entropy
+// from rand, sharing a single field inversion across the entire batch.
+// If rand is nil, crypto/rand.Reader will be used.
+func GenerateKeyBatch(rand io.Reader, n int) ([]*PublicKey, []*PrivateKey, error) {
+	if n < 0 {
+		return nil, nil, fmt.Errorf("x25519: bad batch size: %d", n)
+	}
+
+	privateKeys := make([]*PrivateKey, 0, n)
+	for i := 0; i < n; i++ {
+		privateKey, err := GeneratePrivateKey(rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKeys = append(privateKeys, privateKey)
+	}
+
+	publicKeys := make([]PublicKey, n)
+	dst := make([]*[32]byte, n)
+	in := make([]*[32]byte, n)
+	ret := make([]*PublicKey, n)
+	for i := range privateKeys {
+		dst[i] = (*[PointSize]byte)(&publicKeys[i])
+		in[i] = (*[ScalarSize]byte)(privateKeys[i])
+		ret[i] = &publicKeys[i]
+	}
+
+	ScalarBaseMultBatch(dst, in)
+
+	return ret, privateKeys, nil
+}
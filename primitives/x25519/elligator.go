@@ -0,0 +1,178 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/subtle"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/elligator"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
+)
+
+// RepresentativeSize is the size, in bytes, of Elligator 2 representatives
+// as used in this package.
+const RepresentativeSize = 32
+
+// Representative is the type of Elligator 2 representatives of X25519
+// public keys.  A representative of a public key generated by
+// GenerateRepresentableKey is indistinguishable from 32 uniformly
+// random bytes.
+//
+// The encoding and decoding is compatible with Monocypher's
+// crypto_elligator_map and crypto_elligator_rev.
+type Representative [RepresentativeSize]byte
+
+// PublicKey returns the public key corresponding to the representative.
+// As representatives only occupy the low 254 bits, the 2 most significant
+// bits are ignored.
+func (r *Representative) PublicKey() *PublicKey {
+	var rBytes [RepresentativeSize]byte
+	copy(rBytes[:], r[:])
+	rBytes[31] &= 63
+
+	var fe field.Element
+	if _, err := fe.SetBytes(rBytes[:]); err != nil {
+		panic("x25519: failed to deserialize representative: " + err.Error())
+	}
+
+	var pub PublicKey
+	u, _ := elligator.MontgomeryFlavor(&fe)
+	_ = u.ToBytes(pub[:])
+
+	return &pub
+}
+
+// Representative returns an Elligator 2 representative of the public key,
+// and true iff one exists.  Roughly half of all public keys do not have
+// a representative.
+//
+// The tweak SHOULD be a uniformly random byte.  Its least significant bit
+// selects which of the two possible representatives is returned, and the
+// two most significant bits are used to fill the otherwise unused high
+// bits of the representative.  The remaining bits are ignored.
+//
+// Note: Public keys derived via PrivateKey.Public are never uniformly
+// distributed over the curve, and thus their representatives can be
+// distinguished from random.  Use GenerateRepresentableKey instead.
+//
+// This function will execute in constant-time, except for the return
+// value indicating if the representative exists.
+func (pub *PublicKey) Representative(tweak byte) (*Representative, bool) {
+	var u field.Element
+	if _, err := u.SetBytes(pub[:]); err != nil {
+		panic("x25519: failed to deserialize public key: " + err.Error())
+	}
+
+	rFe, ok := elligator.MontgomeryInverse(&u, int(tweak&1))
+	if ok != 1 {
+		return nil, false
+	}
+
+	var r Representative
+	_ = rFe.ToBytes(r[:])
+	r[31] |= tweak & 0xc0
+
+	return &r, true
+}
+
+// GenerateRepresentableKey generates a public/private key pair, where the
+// public key has an Elligator 2 representative, using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+//
+// The public key is not the same as that returned by PrivateKey.Public,
+// as it additionally has a random low-order component so that it is
+// uniformly distributed over the entire curve, in the same manner as
+// Monocypher's crypto_elligator_key_pair.  As X25519 clears the cofactor,
+// Diffie-Hellman with either public key will produce the same shared
+// secret.
+func GenerateRepresentableKey(rand io.Reader) (*PublicKey, *PrivateKey, *Representative, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	for {
+		privateKey, err := GeneratePrivateKey(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var tweak [1]byte
+		if _, err = io.ReadFull(rand, tweak[:]); err != nil {
+			return nil, nil, nil, err
+		}
+
+		// The only variable-time part of this routine is the number
+		// of attempts taken, which only leaks information about the
+		// discarded key pairs.
+		publicKey := privateKey.dirtyPublic()
+		if representative, ok := publicKey.Representative(tweak[0]); ok {
+			return publicKey, privateKey, representative, nil
+		}
+	}
+}
+
+// dirtyPublic returns the public key corresponding to the private key,
+// with a low-order component selected by the 3 least significant bits
+// of the private key.  This matches Monocypher's crypto_x25519_dirty_fast.
+func (priv *PrivateKey) dirtyPublic() *PublicKey {
+	var ec [ScalarSize]byte
+	copy(ec[:], priv[:])
+	clampScalar(ec[:])
+
+	var s scalar.Scalar
+	if _, err := s.SetBits(ec[:]); err != nil {
+		panic("x25519: failed to deserialize scalar: " + err.Error())
+	}
+
+	// Monocypher multiplies the clamped scalar (plus L times the
+	// 3 least significant bits) by a point of order 8L, which is
+	// equivalent to adding `[3 * lsb] * EIGHT_TORSION[1]`.
+	var lowOrder curve.EdwardsPoint
+	idx := int32(priv[0]*3) & 7
+	for i, p := range curve.EIGHT_TORSION {
+		lowOrder.ConditionalSelect(&lowOrder, p, subtle.ConstantTimeEq(int32(i), idx))
+	}
+
+	var (
+		edP   curve.EdwardsPoint
+		pub   PublicKey
+		montP curve.MontgomeryPoint
+	)
+	edP.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &s)
+	edP.Add(&edP, &lowOrder)
+	montP.SetEdwards(&edP)
+	copy(pub[:], montP[:])
+
+	return &pub
+}
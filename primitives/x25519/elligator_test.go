@@ -0,0 +1,245 @@
+// Copyright (c) 2021 Oasis Labs Inc. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+// 1. Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright
+// notice, this list of conditions and the following disclaimer in the
+// documentation and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS
+// IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED
+// TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A
+// PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED
+// TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF
+// LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING
+// NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+// SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/testhelpers"
+)
+
+// Test vectors stolen from Monocypher's tis-ci-vectors.h
+var elligatorTestVectors = []struct {
+	repr string
+	pub  string
+}{
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		"00000000000000000000000000000000000000000000000000000000000000c0",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		"673a505e107189ee54ca93310ac42e4545e9e59050aaac6f8b5f64295c8ec02f",
+		"242ae39ef158ed60f20b89396d7d7eef5374aba15dc312a6aea6d1e57cacf85e",
+	},
+	{
+		"922688fa428d42bc1fa8806998fbc5959ae801817e85a42a45e8ec25a0d7545a",
+		"696f341266c64bcfa7afa834f8c34b2730be11c932e08474d1a22f26ed82410b",
+	},
+	{
+		"0d3b0eb88b74ed13d5f6a130e03c4ad607817057dc227152827c0506a538bbba",
+		"0b00df174d9fb0b6ee584d2cf05613130bad18875268c38b377e86dfefef177f",
+	},
+	{
+		"01a3ea5658f4e00622eeacf724e0bd82068992fae66ed2b04a8599be16662ef5",
+		"7ae4c58bc647b5646c9f5ae4c2554ccbf7c6e428e7b242a574a5a9c293c21f7e",
+	},
+	{
+		"69599ab5a829c3e9515128d368da7354a8b69fcee4e34d0a668b783b6cae550f",
+		"09024abaaef243e3b69366397e8dfc1fdc14a0ecc7cf497cbe4f328839acce69",
+	},
+	{
+		"9172922f96d2fa41ea0daf961857056f1656ab8406db80eaeae76af58f8c9f50",
+		"beab745a2a4b4e7f1a7335c3ffcdbd85139f3a72b667a01ee3e3ae0e530b3372",
+	},
+	{
+		"6850a20ac5b6d2fa7af7042ad5be234d3311b9fb303753dd2b610bd566983281",
+		"1287388eb2beeff706edb9cf4fcfdd35757f22541b61528570b86e8915be1530",
+	},
+	{
+		"84417826c0e80af7cb25a73af1ba87594ff7048a26248b5757e52f2824e068f1",
+		"51acd2e8910e7d28b4993db7e97e2b995005f26736f60dcdde94bdf8cb542251",
+	},
+	{
+		"b0fbe152849f49034d2fa00ccc7b960fad7b30b6c4f9f2713eb01c147146ad31",
+		"98508bb3590886af3be523b61c3d0ce6490bb8b27029878caec57e4c750f993d",
+	},
+	{
+		"a0ca9ff75afae65598630b3b93560834c7f4dd29a557aa29c7becd49aeef3753",
+		"3c5fad0516bb8ec53da1c16e910c23f792b971c7e2a0ee57d57c32e3655a646b",
+	},
+}
+
+func TestElligator(t *testing.T) {
+	t.Run("Decode", testElligatorDecode)
+	t.Run("Encode", testElligatorEncode)
+	t.Run("DirtyPublic", testElligatorDirtyPublic)
+	t.Run("GenerateRepresentableKey", testElligatorGenerateRepresentableKey)
+}
+
+func testElligatorDecode(t *testing.T) {
+	for i, v := range elligatorTestVectors {
+		var r Representative
+		copy(r[:], testhelpers.MustUnhex(t, v.repr))
+
+		expected := testhelpers.MustUnhex(t, v.pub)
+		if pub := r.PublicKey(); !bytes.Equal(pub[:], expected) {
+			t.Fatalf("r[%d].PublicKey() != vector[%d] (Got: %x)", i, i, pub[:])
+		}
+	}
+}
+
+func testElligatorEncode(t *testing.T) {
+	for i, v := range elligatorTestVectors {
+		var pub PublicKey
+		copy(pub[:], testhelpers.MustUnhex(t, v.pub))
+
+		// Monocypher's inverse map will always fail for u = 0,
+		// even though the representative 0 maps to it.
+		if pub == (PublicKey{}) {
+			if _, ok := pub.Representative(0); ok {
+				t.Fatalf("pub[%d].Representative(0) succeeded for u = 0", i)
+			}
+			continue
+		}
+
+		// The vector's representative (with the 2 most significant bits
+		// masked off) must be one of the two possible representatives.
+		var expected Representative
+		copy(expected[:], testhelpers.MustUnhex(t, v.repr))
+		expected[31] &= 63
+
+		var found bool
+		for _, tweak := range []byte{0x00, 0x01, 0xc0, 0xc1, 0xfe} {
+			r, ok := pub.Representative(tweak)
+			if !ok {
+				t.Fatalf("pub[%d].Representative(%02x) failed", i, tweak)
+			}
+			if r[31]&0xc0 != tweak&0xc0 {
+				t.Fatalf("pub[%d].Representative(%02x) has incorrect high bits (Got: %x)", i, tweak, r[:])
+			}
+			if roundTrip := r.PublicKey(); *roundTrip != pub {
+				t.Fatalf("pub[%d].Representative(%02x).PublicKey() != pub (Got: %x)", i, tweak, roundTrip[:])
+			}
+
+			r[31] &= 63
+			found = found || *r == expected
+		}
+		if !found {
+			t.Fatalf("pub[%d].Representative() never returned vector[%d]", i, i)
+		}
+	}
+}
+
+func testElligatorDirtyPublic(t *testing.T) {
+	// Monocypher's crypto_x25519_dirty_small multiplies the clamped
+	// scalar plus L times the 3 least significant bits of the private
+	// key by this point of order 8L.  Check that the faster approach
+	// used by dirtyPublic yields the same results.
+	var dirtyBase curve.MontgomeryPoint
+	copy(dirtyBase[:], testhelpers.MustUnhex(t, "d8861aa2787ad9268b7474b682e3bec3ce369a1e5e3147a26d377cfd20b5df75"))
+
+	var dirtyBaseEd curve.EdwardsPoint
+	if _, err := dirtyBaseEd.SetMontgomery(&dirtyBase, 0); err != nil {
+		t.Fatalf("dirtyBaseEd.SetMontgomery: %v", err)
+	}
+
+	// [L] * dirtyBase, computed as [L - 1] * dirtyBase + dirtyBase.
+	var lMinusOne, lDirtyBase, eightDirtyBase curve.EdwardsPoint
+	lMinusOne.Mul(&dirtyBaseEd, scalar.New().Neg(scalar.One()))
+	lDirtyBase.Add(&lMinusOne, &dirtyBaseEd)
+	eightDirtyBase.MulByCofactor(&dirtyBaseEd)
+
+	for lsb := 0; lsb < 8; lsb++ {
+		priv, err := GeneratePrivateKey(nil)
+		if err != nil {
+			t.Fatalf("GeneratePrivateKey: %v", err)
+		}
+		priv[0] = (priv[0] & 248) | byte(lsb)
+
+		// [clamped] * dirtyBase = [clamped / 8] * ([8] * dirtyBase),
+		// as the clamped scalar is a multiple of 8.
+		var ec [ScalarSize]byte
+		copy(ec[:], priv[:])
+		clampScalar(ec[:])
+		for i := 0; i < ScalarSize; i++ {
+			ec[i] >>= 3
+			if i < ScalarSize-1 {
+				ec[i] |= ec[i+1] << 5
+			}
+		}
+		s, err := scalar.NewFromBits(ec[:])
+		if err != nil {
+			t.Fatalf("scalar.NewFromBits: %v", err)
+		}
+
+		var expectedEd curve.EdwardsPoint
+		expectedEd.Mul(&eightDirtyBase, s)
+		for i := 0; i < lsb; i++ {
+			expectedEd.Add(&expectedEd, &lDirtyBase)
+		}
+
+		var expected curve.MontgomeryPoint
+		expected.SetEdwards(&expectedEd)
+
+		if pub := priv.dirtyPublic(); !bytes.Equal(pub[:], expected[:]) {
+			t.Fatalf("priv.dirtyPublic() != expected (lsb: %d, Got: %x)", lsb, pub[:])
+		}
+	}
+}
+
+func testElligatorGenerateRepresentableKey(t *testing.T) {
+	peerPub, peerPriv, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	var highBits [4]bool
+	for i := 0; i < 64; i++ {
+		pub, priv, r, err := GenerateRepresentableKey(nil)
+		if err != nil {
+			t.Fatalf("GenerateRepresentableKey: %v", err)
+		}
+
+		if rPub := r.PublicKey(); *rPub != *pub {
+			t.Fatalf("r.PublicKey() != pub (Got: %x)", rPub[:])
+		}
+		highBits[r[31]>>6] = true
+
+		// The low-order component must not change the shared secret.
+		ss1 := peerPriv.DiffieHellman(pub)
+		ss2 := peerPriv.DiffieHellman(priv.Public())
+		ss3 := priv.DiffieHellman(peerPub)
+		if *ss1 != *ss2 || *ss1 != *ss3 {
+			t.Fatalf("shared secret mismatch")
+		}
+	}
+	for i, seen := range highBits {
+		if !seen {
+			t.Fatalf("high bits %d never generated", i)
+		}
+	}
+}
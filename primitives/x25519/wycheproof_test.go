@@ -128,6 +128,39 @@ func (tc *wycheproofTestCase) Run(t *testing.T) {
 			t.Fatalf("failed X25519(priv, pub): %x (expected %x)", out, sharedKey)
 		}
 	}
+
+	// The checked Diffie-Hellman routine has the same behavior by
+	// default, and additionally rejects low order and non-canonical
+	// public keys in strict mode.
+	var (
+		priv PrivateKey
+		pub  PublicKey
+	)
+	copy(priv[:], privateKey)
+	copy(pub[:], publicKey)
+	for _, strict := range []bool{false, true} {
+		shouldFail := flags[flagZeroSharedSecret]
+		if strict {
+			shouldFail = shouldFail || flags[flagLowOrderPublic] || flags[flagNonCanonicalPublic]
+		}
+
+		ss, err := priv.DiffieHellmanWithOptions(&pub, &DiffieHellmanOptions{
+			Strict: strict,
+		})
+		switch shouldFail {
+		case true:
+			if err == nil {
+				t.Fatalf("DiffieHellmanWithOptions(pub, strict: %v) returned no error when it should fail", strict)
+			}
+		case false:
+			if err != nil {
+				t.Fatalf("failed DiffieHellmanWithOptions(pub, strict: %v): %v", strict, err)
+			}
+			if !bytes.Equal(ss[:], sharedKey) {
+				t.Fatalf("failed DiffieHellmanWithOptions(pub, strict: %v): %x (expected %x)", strict, ss[:], sharedKey)
+			}
+		}
+	}
 }
 
 func TestWycheproof(t *testing.T) {
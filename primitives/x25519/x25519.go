@@ -42,6 +42,7 @@ import (
 
 	"github.com/oasisprotocol/curve25519-voi/curve"
 	"github.com/oasisprotocol/curve25519-voi/curve/scalar"
+	"github.com/oasisprotocol/curve25519-voi/internal/field"
 	_ "github.com/oasisprotocol/curve25519-voi/internal/toolchain"
 	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
 )
@@ -66,6 +67,46 @@ const (
 	seedSize = 32
 )
 
+var (
+	// ErrLowOrderPoint is the error returned when a Diffie-Hellman key
+	// exchange is performed with a public key that is a low-order point.
+	ErrLowOrderPoint = fmt.Errorf("x25519: low order point")
+
+	// ErrNonCanonicalPoint is the error returned when a strict
+	// Diffie-Hellman key exchange is performed with a public key that
+	// is not canonically encoded.
+	ErrNonCanonicalPoint = fmt.Errorf("x25519: non-canonical point")
+
+	// smallOrderPoints is the list of canonical u-coordinates of
+	// points of small order (on both the curve and the twist).
+	smallOrderPoints = [][PointSize]byte{
+		// 0 (order 2)
+		{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// 1 (order 4)
+		{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// 325606250916557431795983626356110631294008115727848805560023387167927233504 (order 8)
+		{0xe0, 0xeb, 0x7a, 0x7c, 0x3b, 0x41, 0xb8, 0xae, 0x16, 0x56, 0xe3, 0xfa, 0xf1, 0x9f, 0xc4, 0x6a, 0xda, 0x09, 0x8d, 0xeb, 0x9c, 0x32, 0xb1, 0xfd, 0x86, 0x62, 0x05, 0x16, 0x5f, 0x49, 0xb8, 0x00},
+		// 39382357235489614581723060781553021112529911719440698176882885853963445705823 (order 8)
+		{0x5f, 0x9c, 0x95, 0xbc, 0xa3, 0x50, 0x8c, 0x24, 0xb1, 0xd0, 0xb1, 0x55, 0x9c, 0x83, 0xef, 0x5b, 0x04, 0x44, 0x5c, 0xc4, 0x58, 0x1c, 0x8e, 0x86, 0xd8, 0x22, 0x4e, 0xdd, 0xd0, 0x9f, 0x11, 0x57},
+		// p - 1 (order 4, on the twist)
+		{0xec, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+	}
+)
+
+// DiffieHellmanOptions can be used to influence the behavior of
+// PrivateKey.DiffieHellmanWithOptions.
+type DiffieHellmanOptions struct {
+	// Strict rejects public keys that are not canonically encoded
+	// (u >= p, including having the most significant bit set), or
+	// are one of the known small-order u-coordinates, prior to
+	// performing the key exchange.
+	//
+	// Note: RFC 7748 requires that non-canonical values be accepted,
+	// so this is only suitable for protocols that explicitly call
+	// for this behavior.
+	Strict bool
+}
+
 // PrivateKey is the type of X25519 private keys.
 type PrivateKey [PrivateKeySize]byte
 
@@ -88,6 +129,39 @@ func (priv *PrivateKey) DiffieHellman(pub *PublicKey) *SharedSecret {
 	return &sec
 }
 
+// DiffieHellmanChecked performs a Diffie-Hellman key exchange between the
+// private key and the given public key to produce a shared secret,
+// returning ErrLowOrderPoint iff the shared secret is all zeroes.
+func (priv *PrivateKey) DiffieHellmanChecked(pub *PublicKey) (*SharedSecret, error) {
+	return priv.DiffieHellmanWithOptions(pub, nil)
+}
+
+// DiffieHellmanWithOptions performs a Diffie-Hellman key exchange between
+// the private key and the given public key to produce a shared secret,
+// with the specified options.  If opts is nil, the behavior will be
+// identical to DiffieHellmanChecked.
+func (priv *PrivateKey) DiffieHellmanWithOptions(pub *PublicKey, opts *DiffieHellmanOptions) (*SharedSecret, error) {
+	if opts == nil {
+		opts = &DiffieHellmanOptions{}
+	}
+
+	if opts.Strict {
+		if !pub.isCanonical() {
+			return nil, ErrNonCanonicalPoint
+		}
+		if pub.isSmallOrder() {
+			return nil, ErrLowOrderPoint
+		}
+	}
+
+	sec := priv.DiffieHellman(pub)
+	if sec.IsZero() {
+		return nil, ErrLowOrderPoint
+	}
+
+	return sec, nil
+}
+
 // PublicKey is the type of X25519 public keys.
 type PublicKey [PublicKeySize]byte
 
@@ -122,6 +196,27 @@ func (k *PublicKey) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+func (k *PublicKey) isCanonical() bool {
+	var (
+		fe      field.Element
+		feBytes [PublicKeySize]byte
+	)
+	if _, err := fe.SetBytes(k[:]); err != nil {
+		panic("x25519: failed to deserialize public key: " + err.Error())
+	}
+	_ = fe.ToBytes(feBytes[:])
+
+	return subtle.ConstantTimeCompare(k[:], feBytes[:]) == 1
+}
+
+func (k *PublicKey) isSmallOrder() bool {
+	var ret int
+	for i := range smallOrderPoints {
+		ret |= subtle.ConstantTimeCompare(k[:], smallOrderPoints[i][:])
+	}
+	return ret == 1
+}
+
 // SharedSecret is the type of the result of a Diffie-Hellman key exchange.
 type SharedSecret [SharedSecretSize]byte
 
@@ -174,14 +269,8 @@ var basePoint = [32]byte{9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0
 // zeroes, irrespective of the scalar. Instead, use the X25519 function, which
 // will return an error.
 func ScalarMult(dst, in, base *[32]byte) {
-	var ec [ScalarSize]byte
-	copy(ec[:], in[:])
-	clampScalar(ec[:])
-
 	var s scalar.Scalar
-	if _, err := s.SetBits(ec[:]); err != nil {
-		panic("x25519: failed to deserialize scalar: " + err.Error())
-	}
+	setClampedScalar(&s, in)
 
 	var montP curve.MontgomeryPoint
 	if _, err := montP.SetBytes(base[:]); err != nil {
@@ -202,20 +291,11 @@ func ScalarBaseMult(dst, in *[32]byte) {
 	// There is no codepath to use `x/crypto/curve25519`'s version
 	// as none of the targets use a precomputed implementation.
 
-	var ec [ScalarSize]byte
-	copy(ec[:], in[:])
-	clampScalar(ec[:])
-
 	var s scalar.Scalar
-	if _, err := s.SetBits(ec[:]); err != nil {
-		panic("x25519: failed to deserialize scalar: " + err.Error())
-	}
+	setClampedScalar(&s, in)
 
-	var (
-		edP   curve.EdwardsPoint
-		montP curve.MontgomeryPoint
-	)
-	montP.SetEdwards(edP.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &s))
+	var montP curve.MontgomeryPoint
+	montP.MulBasepoint(curve.ED25519_BASEPOINT_TABLE, &s)
 
 	copy(dst[:], montP[:])
 }
@@ -295,6 +375,16 @@ func EdPublicKeyToX25519(publicKey ed25519.PublicKey) ([]byte, bool) {
 	return montA[:], true
 }
 
+func setClampedScalar(s *scalar.Scalar, in *[ScalarSize]byte) {
+	var ec [ScalarSize]byte
+	copy(ec[:], in[:])
+	clampScalar(ec[:])
+
+	if _, err := s.SetBits(ec[:]); err != nil {
+		panic("x25519: failed to deserialize scalar: " + err.Error())
+	}
+}
+
 func clampScalar(s []byte) {
 	s[0] &= 248
 	s[31] &= 127
@@ -117,6 +117,87 @@ func testX25519LowOrderPoints(t *testing.T) {
 	}
 }
 
+func TestDiffieHellmanChecked(t *testing.T) {
+	_, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	peerPub, peerPriv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		expected := priv.DiffieHellman(peerPub)
+		for _, strict := range []bool{false, true} {
+			ss, err := priv.DiffieHellmanWithOptions(peerPub, &DiffieHellmanOptions{Strict: strict})
+			if err != nil {
+				t.Fatalf("DiffieHellmanWithOptions(strict: %v): %v", strict, err)
+			}
+			if *ss != *expected {
+				t.Fatalf("DiffieHellmanWithOptions(strict: %v) != DiffieHellman", strict)
+			}
+		}
+		ss, err := peerPriv.DiffieHellmanChecked(priv.Public())
+		if err != nil {
+			t.Fatalf("DiffieHellmanChecked: %v", err)
+		}
+		if *ss != *expected {
+			t.Fatalf("DiffieHellmanChecked != DiffieHellman")
+		}
+	})
+	t.Run("LowOrderPoints", func(t *testing.T) {
+		for i, p := range lowOrderPoints {
+			var pub PublicKey
+			copy(pub[:], p)
+
+			ss, err := priv.DiffieHellmanChecked(&pub)
+			if err != ErrLowOrderPoint {
+				t.Errorf("%d: expected ErrLowOrderPoint, got %v", i, err)
+			}
+			if ss != nil {
+				t.Errorf("%d: expected nil output, got %x", i, ss[:])
+			}
+
+			_, err = priv.DiffieHellmanWithOptions(&pub, &DiffieHellmanOptions{Strict: true})
+			if err == nil {
+				t.Errorf("%d: expected error in strict mode, got nil", i)
+			}
+		}
+	})
+	t.Run("NonCanonicalPoint", func(t *testing.T) {
+		// 2^255 - 1 is p + 18, and 2^255 + 18 has the high bit set,
+		// both of which are equivalent to 18.
+		var canonical, nonCanonical, highBit PublicKey
+		canonical[0] = 0x12
+		for i := range nonCanonical {
+			nonCanonical[i] = 0xff
+		}
+		nonCanonical[31] = 0x7f
+		highBit = canonical
+		highBit[31] |= 0x80
+
+		expected, err := priv.DiffieHellmanChecked(&canonical)
+		if err != nil {
+			t.Fatalf("DiffieHellmanChecked(canonical): %v", err)
+		}
+		for _, pub := range []*PublicKey{&nonCanonical, &highBit} {
+			ss, err := priv.DiffieHellmanChecked(pub)
+			if err != nil {
+				t.Fatalf("DiffieHellmanChecked(%x): %v", pub[:], err)
+			}
+			if *ss != *expected {
+				t.Fatalf("DiffieHellmanChecked(%x) != DiffieHellmanChecked(canonical)", pub[:])
+			}
+
+			_, err = priv.DiffieHellmanWithOptions(pub, &DiffieHellmanOptions{Strict: true})
+			if err != ErrNonCanonicalPoint {
+				t.Fatalf("DiffieHellmanWithOptions(%x, strict): expected ErrNonCanonicalPoint, got %v", pub[:], err)
+			}
+		}
+	})
+}
+
 func testX25519NonCanonicalPoint(t *testing.T) {
 	scalar := []byte{
 		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x40,
@@ -359,3 +440,149 @@ func TestX25519PublicKey(t *testing.T) {
 		}
 	})
 }
+
+func TestScalarMultBatch(t *testing.T) {
+	var dst, in, base, expected []*[32]byte
+	appendCase := func(point []byte) {
+		var s, p [32]byte
+		if _, err := rand.Read(s[:]); err != nil {
+			t.Fatal(err)
+		}
+		copy(p[:], point)
+
+		var out, e [32]byte
+		ScalarMult(&e, &s, &p)
+
+		dst = append(dst, &out)
+		in = append(in, &s)
+		base = append(base, &p)
+		expected = append(expected, &e)
+	}
+
+	for i := 0; i < 8; i++ {
+		var p [32]byte
+		if _, err := rand.Read(p[:]); err != nil {
+			t.Fatal(err)
+		}
+		appendCase(p[:]) // Includes non-canonical and twist points.
+	}
+	for _, p := range lowOrderPoints {
+		appendCase(p)
+	}
+
+	ScalarMultBatch(dst, in, base)
+	for i := range dst {
+		if !bytes.Equal(dst[i][:], expected[i][:]) {
+			t.Errorf("%d: ScalarMultBatch != ScalarMult: %x != %x", i, dst[i][:], expected[i][:])
+		}
+	}
+
+	ScalarMultBatch(nil, nil, nil) // Should not panic.
+}
+
+func TestScalarBaseMultBatch(t *testing.T) {
+	var dst, in []*[32]byte
+	for i := 0; i < 8; i++ {
+		var s [32]byte
+		if _, err := rand.Read(s[:]); err != nil {
+			t.Fatal(err)
+		}
+		dst = append(dst, new([32]byte))
+		in = append(in, &s)
+	}
+
+	ScalarBaseMultBatch(dst, in)
+	for i := range dst {
+		var expected [32]byte
+		ScalarBaseMult(&expected, in[i])
+		if !bytes.Equal(dst[i][:], expected[:]) {
+			t.Errorf("%d: ScalarBaseMultBatch != ScalarBaseMult: %x != %x", i, dst[i][:], expected[:])
+		}
+	}
+}
+
+func TestX25519DiffieHellmanBatch(t *testing.T) {
+	const n = 8
+
+	publicKeys, privateKeys, err := GenerateKeyBatch(nil, n)
+	if err != nil {
+		t.Fatalf("failed to generate key pairs: %s", err)
+	}
+	peerPublicKeys, _, err := GenerateKeyBatch(nil, n)
+	if err != nil {
+		t.Fatalf("failed to generate peer key pairs: %s", err)
+	}
+
+	for i := range publicKeys {
+		if expected := privateKeys[i].Public(); !bytes.Equal(publicKeys[i][:], expected[:]) {
+			t.Errorf("%d: generated key pair doesn't match", i)
+		}
+	}
+
+	// Low order public keys yield an all zero shared secret.
+	var lowOrder PublicKey
+	copy(lowOrder[:], lowOrderPoints[0])
+	peerPublicKeys[n/2] = &lowOrder
+
+	sharedSecrets := DiffieHellmanBatch(privateKeys, peerPublicKeys)
+	for i := range sharedSecrets {
+		expected := privateKeys[i].DiffieHellman(peerPublicKeys[i])
+		if !bytes.Equal(sharedSecrets[i][:], expected[:]) {
+			t.Errorf("%d: DiffieHellmanBatch != DiffieHellman: %x != %x", i, *sharedSecrets[i], *expected)
+		}
+	}
+	if !sharedSecrets[n/2].IsZero() {
+		t.Errorf("DiffieHellmanBatch(low order) != 0")
+	}
+}
+
+func TestX25519GenerateKeyBatch(t *testing.T) {
+	if _, _, err := GenerateKeyBatch(nil, -1); err == nil {
+		t.Errorf("GenerateKeyBatch(-1): expected error, got nil")
+	}
+
+	publicKeys, privateKeys, err := GenerateKeyBatch(nil, 0)
+	if err != nil {
+		t.Fatalf("GenerateKeyBatch(0): %s", err)
+	}
+	if len(publicKeys) != 0 || len(privateKeys) != 0 {
+		t.Errorf("GenerateKeyBatch(0): expected no keys, got %d/%d", len(publicKeys), len(privateKeys))
+	}
+}
+
+func BenchmarkScalarMultBatch(b *testing.B) {
+	const batchSize = 64
+
+	var dst, in, base []*[32]byte
+	for i := 0; i < batchSize; i++ {
+		var s, p [32]byte
+		s[0], p[0] = 1, 9
+		dst = append(dst, new([32]byte))
+		in = append(in, &s)
+		base = append(base, &p)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(32)
+	for i := 0; i < b.N; i += batchSize {
+		ScalarMultBatch(dst, in, base)
+	}
+}
+
+func BenchmarkScalarBaseMultBatch(b *testing.B) {
+	const batchSize = 64
+
+	var dst, in []*[32]byte
+	for i := 0; i < batchSize; i++ {
+		var s [32]byte
+		s[0] = 1
+		dst = append(dst, new([32]byte))
+		in = append(in, &s)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(32)
+	for i := 0; i < b.N; i += batchSize {
+		ScalarBaseMultBatch(dst, in)
+	}
+}